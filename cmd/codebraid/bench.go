@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/bundler"
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+	"github.com/yousuf/codebraid-mcp/internal/client"
+	"github.com/yousuf/codebraid-mcp/internal/codegen"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+	"github.com/yousuf/codebraid-mcp/internal/server"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// benchScript is the script "codebraid bench" times without -script: it calls no
+// tools, so it measures the pipeline itself
+const benchScript = `async function exec() {
+  const squares = Array.from({ length: 1000 }, (_, i) => i * i);
+  return squares.reduce((sum, n) => sum + n, 0);
+}
+`
+
+// benchStage is the timings of one stage of the pipeline
+type benchStage struct {
+	Name    string
+	samples []time.Duration
+}
+
+// time runs one sample of the stage, recording how long it took when it succeeds
+func (s *benchStage) time(run func() error) error {
+	start := time.Now()
+	if err := run(); err != nil {
+		return err
+	}
+	s.samples = append(s.samples, time.Since(start))
+	return nil
+}
+
+// MarshalJSON renders the stage as {"name", "runs", "minMs", "medianMs", "maxMs"}
+func (s *benchStage) MarshalJSON() ([]byte, error) {
+	fastest, median, slowest := s.summary()
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	return json.Marshal(struct {
+		Name     string  `json:"name"`
+		Runs     int     `json:"runs"`
+		MinMs    float64 `json:"minMs"`
+		MedianMs float64 `json:"medianMs"`
+		MaxMs    float64 `json:"maxMs"`
+	}{s.Name, len(s.samples), ms(fastest), ms(median), ms(slowest)})
+}
+
+// summary returns the fastest, median and slowest timings
+func (s *benchStage) summary() (fastest, median, slowest time.Duration) {
+	if len(s.samples) == 0 {
+		return 0, 0, 0
+	}
+	sorted := append([]time.Duration(nil), s.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[0], sorted[len(sorted)/2], sorted[len(sorted)-1]
+}
+
+// runBench implements "codebraid bench": it times connecting to each server and
+// each stage of running a script (library generation, transform, bundle and the
+// whole execution), to quantify regressions and tune deployments
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	cfgFlags := addConfigFlags(flags)
+	serverList := flags.String("server", "", "Comma-separated servers to benchmark with (default: all)")
+	scriptPath := flags.String("script", "", "TypeScript script to time (default: a script calling no tools)")
+	runs := flags.Int("n", 10, "How many times to run each stage but connecting")
+	addJSONFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid bench [-server a,b] [-script file.ts] [-n 10] [-json]\n\n")
+		fmt.Fprintf(flags.Output(), "Times connecting to the configured servers and the stages of executing a script:\n")
+		fmt.Fprintf(flags.Output(), "library generation, transform, bundle and the execution from end to end.\n")
+		fmt.Fprintf(flags.Output(), "Bundles are not read from the bundle cache, nor results from the result cache.\n")
+		fmt.Fprintf(flags.Output(), "A -script calling tools makes those calls -n times against the real servers.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *runs < 1 {
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("-n must be at least 1"))
+	}
+	code := benchScript
+	if *scriptPath != "" {
+		data, err := os.ReadFile(*scriptPath)
+		if err != nil {
+			return cli.WithCode(cli.ExitUsage, fmt.Errorf("failed to read script: %w", err))
+		}
+		code = string(data)
+		fmt.Fprintf(os.Stderr, "Warning: %s runs %d times, and so does every tool call it makes on the configured servers\n", *scriptPath, *runs)
+	}
+
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		return err
+	}
+	var only []string
+	if *serverList != "" {
+		only = strings.Split(*serverList, ",")
+		for _, name := range only {
+			if _, ok := cfg.McpServers[name]; !ok {
+				return cli.WithCode(cli.ExitUsage, fmt.Errorf("server %q is not configured", name))
+			}
+		}
+	} else {
+		for name := range cfg.McpServers {
+			only = append(only, name)
+		}
+	}
+	sort.Strings(only)
+
+	// Per-run messages would interleave with the report
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	if err := setupExecution(cfg); err != nil {
+		return cli.WithCode(cli.ExitConfig, err)
+	}
+
+	stages, err := connectStages(cfg, only)
+	if err != nil {
+		return err
+	}
+	pipeline, err := pipelineStages(cfg, only, code, *runs)
+	if err != nil {
+		return err
+	}
+	stages = append(stages, pipeline...)
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Stages []*benchStage `json:"stages"`
+		}{stages})
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tRUNS\tMIN\tMEDIAN\tMAX")
+	for _, stage := range stages {
+		fastest, median, slowest := stage.summary()
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", stage.Name, len(stage.samples), roundDuration(fastest), roundDuration(median), roundDuration(slowest))
+	}
+	return tw.Flush()
+}
+
+// roundDuration rounds a timing for display, keeping sub-millisecond ones readable
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(100 * time.Microsecond)
+}
+
+// connectStages times connecting to each server, all at once as the server
+// connects them
+func connectStages(cfg *config.Config, names []string) ([]*benchStage, error) {
+	stages := make([]*benchStage, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		stages[i] = &benchStage{Name: "connect " + name}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+			defer cancel()
+			errs[i] = stages[i].time(func() error {
+				mcpClient, err := client.NewMcpClient(ctx, name, cfg.McpServers[name], nil)
+				if err != nil {
+					return err
+				}
+				return mcpClient.Close()
+			})
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, cli.WithCode(cli.ExitServer, fmt.Errorf("failed to connect to server %q: %w", names[i], err))
+		}
+	}
+	return stages, nil
+}
+
+// pipelineStages times the stages of executing code in a session using the
+// servers: generating their libraries, transforming and bundling the code, and
+// executing it from end to end, each runs times
+func pipelineStages(cfg *config.Config, servers []string, code string, runs int) ([]*benchStage, error) {
+	sessionMgr := session.NewManager(cfg)
+	defer sessionMgr.CloseAll()
+
+	setup := &benchStage{Name: "session setup"}
+	sessionID := fmt.Sprintf("bench-%d", os.Getpid())
+	var sessionCtx *session.SessionContext
+	err := setup.time(func() error {
+		var err error
+		sessionCtx, err = sessionMgr.GetOrCreateSession(context.Background(), sessionID, session.Options{Servers: servers})
+		return err
+	})
+	if err != nil {
+		return nil, cli.WithCode(cli.ExitServer, fmt.Errorf("failed to create session: %w", err))
+	}
+	defer sessionMgr.DeleteSession(sessionID)
+
+	tmpDir, err := os.MkdirTemp("", "codebraid-bench-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	b, err := bundler.New(server.BundlerToolchain(cfg))
+	if err != nil {
+		return nil, cli.WithCode(cli.ExitConfig, fmt.Errorf("failed to create bundler: %w", err))
+	}
+	entry := bundler.PrepareEntry(code)
+
+	codegenStage := &benchStage{Name: "codegen"}
+	transformStage := &benchStage{Name: "transform"}
+	bundleStage := &benchStage{Name: "bundle (" + b.Toolchain() + ")"}
+	executeStage := &benchStage{Name: "execute (end to end)"}
+	tools := sessionCtx.Tools()
+	for i := 0; i < runs; i++ {
+		err := codegenStage.time(func() error {
+			return codegen.GenerateServerLibs(filepath.Join(tmpDir, fmt.Sprint(i)), tools, codegen.LibOptions{})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate libraries: %w", err)
+		}
+		err = transformStage.time(func() error {
+			_, _, err := bundler.Transform(entry, bundler.GetTransformOptions())
+			return err
+		})
+		if err != nil {
+			return nil, cli.WithCode(cli.ExitToolError, fmt.Errorf("failed to transform script: %w", err))
+		}
+		// Analyzing bypasses the bundle cache, so every run bundles
+		err = bundleStage.time(func() error {
+			_, err := b.Bundle(sessionCtx.BundleDir, entry, nil, true)
+			return err
+		})
+		if err != nil {
+			return nil, cli.WithCode(cli.ExitToolError, fmt.Errorf("failed to bundle script: %w", err))
+		}
+		// Analyzing bypasses the bundle cache here too, so executions include bundling
+		err = executeStage.time(func() error {
+			args := server.ExecuteCodeArgs{Code: code, NoCache: true, Analyze: true}
+			result, err := server.ExecuteCode(context.Background(), sessionMgr, sessionCtx, args, func(level, message string) {})
+			if err != nil {
+				return err
+			}
+			if result.IsError {
+				printRunResult(result)
+				return cli.WithCode(cli.ExitToolError, fmt.Errorf("script failed"))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []*benchStage{setup, codegenStage, transformStage, bundleStage, executeStage}, nil
+}
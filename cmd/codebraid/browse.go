@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+	"github.com/yousuf/codebraid-mcp/internal/client"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// browsedServer is a server as "codebraid browse" shows it: connected, or the
+// reason it is not
+type browsedServer struct {
+	name    string
+	client  *client.McpClient
+	err     error
+	latency time.Duration
+}
+
+// browser is the state of "codebraid browse"
+type browser struct {
+	servers map[string]config.McpServerConfig
+	list    []*browsedServer
+	in      *bufio.Scanner
+	out     io.Writer
+	timeout time.Duration // Bounds each tool call
+}
+
+// runBrowse implements "codebraid browse": an interactive terminal browser of
+// the configured servers, their health, tools and schemas, which calls tools
+// with arguments asked for field by field from their input schema
+func runBrowse(args []string) error {
+	flags := flag.NewFlagSet("browse", flag.ExitOnError)
+	cfgFlags := addConfigFlags(flags)
+	serverList := flags.String("server", "", "Comma-separated servers to browse (default: all)")
+	timeout := flags.Duration("timeout", 5*time.Minute, "How long to wait for a tool call")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid browse [-server a,b] [-timeout 5m]\n\n")
+		fmt.Fprintf(flags.Output(), "Browses the configured MCP servers and their tools interactively, and calls\n")
+		fmt.Fprintf(flags.Output(), "tools with arguments entered in a form built from their input schema.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		return err
+	}
+	servers := cfg.McpServers
+	if *serverList != "" {
+		servers = make(map[string]config.McpServerConfig)
+		for _, name := range strings.Split(*serverList, ",") {
+			server, ok := cfg.McpServers[name]
+			if !ok {
+				return cli.WithCode(cli.ExitUsage, fmt.Errorf("server %q is not configured", name))
+			}
+			servers[name] = server
+		}
+	}
+	if len(servers) == 0 {
+		return cli.WithCode(cli.ExitConfig, fmt.Errorf("no servers configured"))
+	}
+
+	// Connection messages would interleave with the screens
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	b := &browser{
+		servers: servers,
+		in:      bufio.NewScanner(os.Stdin),
+		out:     os.Stdout,
+		timeout: *timeout,
+	}
+	b.in.Buffer(nil, 1<<20)
+	b.connect()
+	defer b.close()
+	b.serversScreen()
+	return nil
+}
+
+// connect connects to every server, closing earlier connections
+func (b *browser) connect() {
+	b.close()
+	fmt.Fprintf(b.out, "Connecting to %d server(s)...\n", len(b.servers))
+	b.list = make([]*browsedServer, 0, len(b.servers))
+	for name := range b.servers {
+		b.list = append(b.list, &browsedServer{name: name})
+	}
+	sort.Slice(b.list, func(i, j int) bool { return b.list[i].name < b.list[j].name })
+
+	var wg sync.WaitGroup
+	for _, server := range b.list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+			defer cancel()
+			start := time.Now()
+			server.client, server.err = client.NewMcpClient(ctx, server.name, b.servers[server.name], nil)
+			server.latency = time.Since(start)
+		}()
+	}
+	wg.Wait()
+}
+
+// close closes the connections to the servers
+func (b *browser) close() {
+	for _, server := range b.list {
+		if server.client != nil {
+			server.client.Close()
+		}
+	}
+}
+
+// prompt asks for one line; ok is false at the end of the input
+func (b *browser) prompt(text string) (line string, ok bool) {
+	fmt.Fprintf(b.out, "%s> ", text)
+	if !b.in.Scan() {
+		fmt.Fprintln(b.out)
+		return "", false
+	}
+	return strings.TrimSpace(b.in.Text()), true
+}
+
+// choice parses the 1-based number of one of n items
+func choice(line string, n int) (int, bool) {
+	i, err := strconv.Atoi(line)
+	if err != nil || i < 1 || i > n {
+		return 0, false
+	}
+	return i - 1, true
+}
+
+// serversScreen lists the servers and their health until the user quits
+func (b *browser) serversScreen() {
+	for {
+		fmt.Fprintln(b.out, "\nServers")
+		tw := tabwriter.NewWriter(b.out, 0, 4, 2, ' ', 0)
+		for i, server := range b.list {
+			if server.err != nil {
+				fmt.Fprintf(tw, "  %d\t%s\tFAIL\t%s\n", i+1, server.name, firstLine(server.err.Error()))
+				continue
+			}
+			fmt.Fprintf(tw, "  %d\t%s\tok\t%d tool(s), connected in %s\n",
+				i+1, server.name, len(server.client.GetTools()), server.latency.Round(time.Millisecond))
+		}
+		tw.Flush()
+
+		line, ok := b.prompt("\nServer number, r to reconnect, q to quit")
+		switch {
+		case !ok || line == "q":
+			return
+		case line == "r":
+			b.connect()
+		case line == "":
+		default:
+			i, ok := choice(line, len(b.list))
+			if !ok {
+				fmt.Fprintf(b.out, "No server %q\n", line)
+				continue
+			}
+			if server := b.list[i]; server.err != nil {
+				fmt.Fprintf(b.out, "%s is not connected: %v\n", server.name, server.err)
+			} else if b.toolsScreen(server) {
+				return
+			}
+		}
+	}
+}
+
+// toolsScreen lists the tools of a server; it returns true when the user quits
+func (b *browser) toolsScreen(server *browsedServer) (quit bool) {
+	filter := ""
+	for {
+		var tools []*mcp.Tool
+		for _, tool := range server.client.GetTools() {
+			text := strings.ToLower(filter)
+			if strings.Contains(strings.ToLower(tool.Name), text) || strings.Contains(strings.ToLower(tool.Description), text) {
+				tools = append(tools, tool)
+			}
+		}
+		sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+
+		fmt.Fprintf(b.out, "\nTools of %s", server.name)
+		if filter != "" {
+			fmt.Fprintf(b.out, " matching %q", filter)
+		}
+		fmt.Fprintln(b.out)
+		tw := tabwriter.NewWriter(b.out, 0, 4, 2, ' ', 0)
+		for i, tool := range tools {
+			fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\n", i+1, tool.Name, annotationSummary(tool.Annotations), truncate(firstLine(tool.Description), 70))
+		}
+		tw.Flush()
+
+		line, ok := b.prompt("\nTool number, /text to filter, b to go back, q to quit")
+		switch {
+		case !ok || line == "q":
+			return true
+		case line == "b":
+			return false
+		case strings.HasPrefix(line, "/"):
+			filter = strings.TrimSpace(line[1:])
+		case line == "":
+		default:
+			i, ok := choice(line, len(tools))
+			if !ok {
+				fmt.Fprintf(b.out, "No tool %q\n", line)
+				continue
+			}
+			if b.toolScreen(server, tools[i]) {
+				return true
+			}
+		}
+	}
+}
+
+// toolScreen shows a tool and calls it; it returns true when the user quits
+func (b *browser) toolScreen(server *browsedServer, tool *mcp.Tool) (quit bool) {
+	for {
+		fmt.Fprintf(b.out, "\n%s.%s\n", server.name, tool.Name)
+		if tool.Description != "" {
+			fmt.Fprintf(b.out, "\n%s\n", strings.TrimSpace(tool.Description))
+		}
+		fmt.Fprintf(b.out, "\nAnnotations: %s\n", annotationSummary(tool.Annotations))
+		fmt.Fprintln(b.out, "Input:")
+		fields := schemaFields(tool.InputSchema)
+		if len(fields) == 0 {
+			fmt.Fprintln(b.out, "  (none)")
+		}
+		for _, field := range fields {
+			fmt.Fprintf(b.out, "  %s\n", field.describe())
+		}
+		if tool.OutputSchema != nil {
+			fmt.Fprintf(b.out, "Output: %s\n", inputSummary(tool.OutputSchema))
+		}
+
+		line, ok := b.prompt("\nc to call, j to call with JSON arguments, s for the schemas, b to go back, q to quit")
+		switch {
+		case !ok || line == "q":
+			return true
+		case line == "b":
+			return false
+		case line == "s":
+			schemas := map[string]any{"inputSchema": tool.InputSchema}
+			if tool.OutputSchema != nil {
+				schemas["outputSchema"] = tool.OutputSchema
+			}
+			b.printJSON(schemas)
+		case line == "c":
+			if args, ok := b.form(fields); ok {
+				b.call(server, tool, args)
+			}
+		case line == "j":
+			if args, ok := b.jsonArguments(); ok {
+				b.call(server, tool, args)
+			}
+		case line == "":
+		default:
+			fmt.Fprintf(b.out, "Unknown command %q\n", line)
+		}
+	}
+}
+
+// schemaField is one argument of a tool, as its form asks for it
+type schemaField struct {
+	name     string
+	schema   map[string]interface{}
+	required bool
+}
+
+// schemaFields returns the arguments of an input schema, the required ones first
+func schemaFields(inputSchema any) []schemaField {
+	schema, _ := inputSchema.(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, name := range list {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	fields := make([]schemaField, 0, len(properties))
+	for name, property := range properties {
+		property, _ := property.(map[string]interface{})
+		fields = append(fields, schemaField{name: name, schema: property, required: required[name]})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].required != fields[j].required {
+			return fields[i].required
+		}
+		return fields[i].name < fields[j].name
+	})
+	return fields
+}
+
+// describe describes a field in one line: "title: string (required) - The title"
+func (f schemaField) describe() string {
+	text := f.name + ": " + schemaTypeName(f.schema)
+	if f.required {
+		text += " (required)"
+	}
+	if description, _ := f.schema["description"].(string); description != "" {
+		text += " - " + firstLine(description)
+	}
+	if enum, ok := f.schema["enum"].([]interface{}); ok {
+		text += fmt.Sprintf(" [one of: %s]", joinValues(enum))
+	}
+	if value, ok := f.schema["default"]; ok {
+		text += fmt.Sprintf(" [default: %v]", value)
+	}
+	return text
+}
+
+// joinValues lists values separated by commas
+func joinValues(values []interface{}) string {
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(texts, ", ")
+}
+
+// form asks for the arguments of a tool field by field; ok is false when the
+// user cancels
+func (b *browser) form(fields []schemaField) (args map[string]interface{}, ok bool) {
+	args = make(map[string]interface{})
+	if len(fields) > 0 {
+		fmt.Fprintln(b.out, "\nEnter the arguments; leave optional ones empty to skip them, or enter ! to cancel.")
+	}
+	for _, field := range fields {
+		for {
+			fmt.Fprintf(b.out, "%s\n", field.describe())
+			line, ok := b.prompt("  " + field.name)
+			if !ok || line == "!" {
+				return nil, false
+			}
+			if line == "" {
+				if field.required {
+					fmt.Fprintf(b.out, "  %s is required\n", field.name)
+					continue
+				}
+				break
+			}
+			value, err := field.parse(line)
+			if err != nil {
+				fmt.Fprintf(b.out, "  %v\n", err)
+				continue
+			}
+			args[field.name] = value
+			break
+		}
+	}
+	return args, true
+}
+
+// parse converts what was entered for a field to a value of its type
+func (f schemaField) parse(text string) (interface{}, error) {
+	if enum, ok := f.schema["enum"].([]interface{}); ok {
+		for _, value := range enum {
+			if fmt.Sprint(value) == text {
+				return value, nil
+			}
+		}
+		if i, ok := choice(text, len(enum)); ok {
+			return enum[i], nil
+		}
+		return nil, fmt.Errorf("expected one of: %s", joinValues(enum))
+	}
+
+	typ, _ := f.schema["type"].(string)
+	switch typ {
+	case "string":
+		return text, nil
+	case "integer":
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer")
+		}
+		return n, nil
+	case "number":
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number")
+		}
+		return n, nil
+	case "boolean":
+		switch strings.ToLower(text) {
+		case "y", "yes", "true":
+			return true, nil
+		case "n", "no", "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("expected yes or no")
+	}
+
+	// Arrays, objects and values of several types are entered as JSON; text
+	// that is not JSON is taken as a string where one would do
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		if typ == "array" || typ == "object" {
+			return nil, fmt.Errorf("expected a JSON %s", typ)
+		}
+		return text, nil
+	}
+	return value, nil
+}
+
+// jsonArguments asks for the arguments of a tool as one JSON object
+func (b *browser) jsonArguments() (map[string]interface{}, bool) {
+	for {
+		line, ok := b.prompt("Arguments as a JSON object, or ! to cancel")
+		if !ok || line == "!" {
+			return nil, false
+		}
+		args := map[string]interface{}{}
+		if line == "" {
+			return args, true
+		}
+		if err := json.Unmarshal([]byte(line), &args); err != nil {
+			fmt.Fprintf(b.out, "Not a JSON object: %v\n", err)
+			continue
+		}
+		return args, true
+	}
+}
+
+// call calls a tool and prints its result
+func (b *browser) call(server *browsedServer, tool *mcp.Tool, args map[string]interface{}) {
+	encoded, _ := json.Marshal(args)
+	fmt.Fprintf(b.out, "\nCalling %s.%s with %s...\n", server.name, tool.Name, encoded)
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	start := time.Now()
+	result, err := server.client.CallTool(ctx, tool.Name, args)
+	if err != nil {
+		fmt.Fprintf(b.out, "Call failed: %v\n", err)
+		return
+	}
+	status := "Result"
+	if result.IsError {
+		status = "Error result"
+	}
+	fmt.Fprintf(b.out, "%s in %s:\n", status, time.Since(start).Round(time.Millisecond))
+	b.printJSON(result)
+}
+
+// printJSON prints a value as indented JSON
+func (b *browser) printJSON(value interface{}) {
+	enc := json.NewEncoder(b.out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		fmt.Fprintf(b.out, "failed to encode: %v\n", err)
+	}
+}
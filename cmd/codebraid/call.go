@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+)
+
+// runCall implements "codebraid call": it calls one tool of a configured server
+// and prints the result as JSON, to debug server configs without an MCP client
+func runCall(args []string) error {
+	flags := flag.NewFlagSet("call", flag.ExitOnError)
+	cfgFlags := addConfigFlags(flags)
+	argsJSON := flags.String("args", "", "Arguments of the tool as a JSON object")
+	argsFile := flags.String("args-file", "", "File holding the arguments as a JSON object, or - for stdin")
+	timeout := flags.Duration("timeout", 5*time.Minute, "How long to wait for the tool")
+	addJSONFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid call server tool [-args JSON | -args-file file|-]\n\n")
+		fmt.Fprintf(flags.Output(), "Calls a tool of a configured MCP server and prints its result as JSON.\n")
+		fmt.Fprintf(flags.Output(), "The tool is named as scripts see it, after the server's prefix and rename.\n\n")
+		flags.PrintDefaults()
+	}
+
+	// Flags may come before, between or after the server and tool names
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	var positional []string
+	for args = flags.Args(); len(args) > 0; args = flags.Args() {
+		positional = append(positional, args[0])
+		flags.Parse(args[1:])
+	}
+	if len(positional) != 2 {
+		flags.Usage()
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("expected a server and a tool name"))
+	}
+	serverName, toolName := positional[0], positional[1]
+
+	toolArgs, err := callArguments(*argsJSON, *argsFile)
+	if err != nil {
+		return cli.WithCode(cli.ExitUsage, err)
+	}
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		return err
+	}
+	hub, err := connectServers(context.Background(), cfg, []string{serverName})
+	if err != nil {
+		return err
+	}
+	defer hub.Close()
+	cacheTools(hub.Tools())
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	result, err := hub.CallTool(ctx, serverName, toolName, toolArgs)
+	if err != nil {
+		return cli.WithCode(cli.ExitServer, fmt.Errorf("failed to call %s.%s: %w", serverName, toolName, err))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	if result.IsError {
+		return cli.WithReportedCode(cli.ExitToolError, fmt.Errorf("%s.%s returned an error", serverName, toolName))
+	}
+	return nil
+}
+
+// callArguments returns the arguments of a call, from -args or -args-file
+func callArguments(argsJSON, argsFile string) (map[string]interface{}, error) {
+	if argsJSON != "" && argsFile != "" {
+		return nil, fmt.Errorf("-args and -args-file cannot be used together")
+	}
+
+	data, source := []byte(argsJSON), "-args"
+	switch argsFile {
+	case "":
+	case "-":
+		var err error
+		if data, err = io.ReadAll(os.Stdin); err != nil {
+			return nil, fmt.Errorf("failed to read arguments from stdin: %w", err)
+		}
+		source = "stdin"
+	default:
+		var err error
+		if data, err = os.ReadFile(argsFile); err != nil {
+			return nil, fmt.Errorf("failed to read arguments: %w", err)
+		}
+		source = argsFile
+	}
+
+	toolArgs := map[string]interface{}{}
+	if len(data) == 0 {
+		return toolArgs, nil
+	}
+	if err := json.Unmarshal(data, &toolArgs); err != nil {
+		return nil, fmt.Errorf("arguments in %s must be a JSON object: %w", source, err)
+	}
+	return toolArgs, nil
+}
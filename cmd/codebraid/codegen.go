@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/yousuf/codebraid-mcp/internal/codegen"
+)
+
+// generatedServer is a library in the "codebraid codegen -json" result
+type generatedServer struct {
+	Name      string   `json:"name"`
+	Functions int      `json:"functions"`
+	Files     []string `json:"files"`
+}
+
+// runCodegen implements "codebraid codegen": it connects to the configured
+// servers and writes the TypeScript libraries of their tools to a directory
+func runCodegen(args []string) error {
+	flags := flag.NewFlagSet("codegen", flag.ExitOnError)
+	cfgFlags := addConfigFlags(flags)
+	outputDir := flags.String("output-dir", "./generated", "Directory to write TypeScript files")
+	serverFilter := flags.String("server", "", "Generate only for specific server(s), comma-separated")
+	verbose := flags.Bool("verbose", false, "Enable verbose output")
+	workers := flags.Int("workers", runtime.NumCPU(), "Number of servers to generate concurrently")
+	clean := flags.Bool("clean", false, "Remove the generated files of servers and tools no longer configured")
+	addJSONFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid codegen [-output-dir dir] [-server a,b] [-clean] [-verbose] [-json]\n\n")
+		fmt.Fprintf(flags.Output(), "Generates the TypeScript libraries scripts import, one directory per server, so\n")
+		fmt.Fprintf(flags.Output(), "scripts can be written and type-checked outside the server.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if jsonOutput {
+		// Progress would mix with the JSON on stdout
+		*verbose = false
+	}
+
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		return err
+	}
+	if *verbose {
+		fmt.Printf("Loaded config from: %s\n", cfg.Path())
+		fmt.Println("Connecting to MCP servers...")
+	}
+	var only []string
+	if *serverFilter != "" {
+		only = strings.Split(*serverFilter, ",")
+	}
+	hub, err := connectServers(context.Background(), cfg, only)
+	if err != nil {
+		return err
+	}
+	defer hub.Close()
+	grouped := hub.Tools()
+
+	if *verbose {
+		fmt.Println("Discovering tools...")
+		for name, tools := range grouped {
+			fmt.Printf("  %s: %d tools\n", name, len(tools))
+		}
+	}
+
+	// Create output directory
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// The manifest records what earlier runs generated. Libraries of servers not
+	// generated this time are kept, unless -clean removes those of servers no
+	// longer configured.
+	previous, err := codegen.ReadManifest(*outputDir)
+	if err != nil {
+		return err
+	}
+	manifest := codegen.NewManifest()
+	for serverName, files := range previous.Servers {
+		if _, configured := cfg.McpServers[serverName]; *clean && !configured {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(*outputDir, serverName)); err == nil {
+			manifest.Servers[serverName] = files
+		}
+	}
+
+	// Generate TypeScript files, one directory per server
+	generatedServers := make([]string, 0, len(grouped))
+	totalFunctions := 0
+	for serverName, tools := range grouped {
+		generatedServers = append(generatedServers, serverName)
+		totalFunctions += len(tools)
+	}
+	sort.Strings(generatedServers)
+
+	err = codegen.GenerateServerLibs(*outputDir, grouped, codegen.LibOptions{
+		Workers: *workers,
+		OnServerGenerated: func(serverName string, files []string) {
+			manifest.Servers[serverName] = files
+			if !*verbose {
+				return
+			}
+			fmt.Printf("Generated %s/ directory with %d functions\n", serverName, len(grouped[serverName]))
+			for _, file := range files {
+				fmt.Printf("  - %s/%s\n", serverName, file)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate server libraries: %w", err)
+	}
+
+	generator := codegen.NewTypeScriptGenerator()
+
+	// Generate mcp-types.ts
+	if *verbose {
+		fmt.Println("\nGenerating mcp-types.ts...")
+	}
+	mcpTypesContent := generator.GenerateMCPTypesFile()
+	mcpTypesPath := filepath.Join(*outputDir, "mcp-types.ts")
+	if err := os.WriteFile(mcpTypesPath, []byte(mcpTypesContent), 0644); err != nil {
+		return fmt.Errorf("failed to write mcp-types.ts: %w", err)
+	}
+
+	// Remove what earlier runs generated for servers and tools that are gone
+	var removed []string
+	if *clean {
+		removed, err = manifest.Prune(*outputDir, previous)
+		if err != nil {
+			return fmt.Errorf("failed to remove stale files: %w", err)
+		}
+		if *verbose {
+			for _, file := range removed {
+				fmt.Printf("Removed %s\n", file)
+			}
+		}
+	}
+
+	// Generate top-level index.ts, exporting every library in the directory
+	if *verbose {
+		fmt.Println("Generating index.ts...")
+	}
+	indexContent := generator.GenerateIndexFile(manifest.ServerNames())
+	indexPath := filepath.Join(*outputDir, "index.ts")
+	if err := os.WriteFile(indexPath, []byte(indexContent), 0644); err != nil {
+		return fmt.Errorf("failed to write index.ts: %w", err)
+	}
+	if err := manifest.Write(*outputDir); err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		result := struct {
+			OutputDir string            `json:"outputDir"`
+			Servers   []generatedServer `json:"servers"`
+			Functions int               `json:"functions"`
+			Removed   []string          `json:"removed"`
+		}{OutputDir: *outputDir, Functions: totalFunctions, Removed: removed}
+		for _, server := range generatedServers {
+			result.Servers = append(result.Servers, generatedServer{
+				Name:      server,
+				Functions: len(grouped[server]),
+				Files:     manifest.Servers[server],
+			})
+		}
+		if result.Removed == nil {
+			result.Removed = []string{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	fmt.Printf("\n✓ Successfully generated TypeScript definitions\n")
+	fmt.Printf("  Servers: %d\n", len(generatedServers))
+	fmt.Printf("  Functions: %d\n", totalFunctions)
+	if *clean {
+		fmt.Printf("  Stale files removed: %d\n", len(removed))
+	}
+	fmt.Printf("  Output directory: %s\n", *outputDir)
+	fmt.Println("\nGenerated structure:")
+	fmt.Println("  ./lib/")
+	fmt.Println("  ├── mcp-types.ts")
+	fmt.Println("  ├── index.ts")
+	for _, server := range generatedServers {
+		fmt.Printf("  └── %s/\n", server)
+		fmt.Printf("      ├── index.ts\n")
+		fmt.Printf("      └── [%d function files]\n", len(grouped[server]))
+	}
+
+	return nil
+}
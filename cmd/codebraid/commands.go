@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+	"github.com/yousuf/codebraid-mcp/internal/client"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// commands are the subcommands of codebraid, by name; without one it serves
+var commands = map[string]func(args []string) error{
+	"serve":      runServe,
+	"codegen":    runCodegen,
+	"import":     runImport,
+	"list-tools": runListTools,
+	"call":       runCall,
+	"browse":     runBrowse,
+	"run":        runScript,
+	"bench":      runBench,
+	"doctor":     runDoctor,
+	"validate":   runValidate,
+	"config":     runConfig,
+	"completion": runCompletion,
+}
+
+// commandSummaries describe the commands in the usage, in the order listed
+var commandSummaries = [][2]string{
+	{"serve", "Serve the code execution tools over HTTP or stdio (the default)"},
+	{"codegen", "Generate the TypeScript libraries of the servers' tools"},
+	{"import", "Import the MCP servers of another client's config"},
+	{"list-tools", "List the tools of the configured servers"},
+	{"call", "Call one tool and print its result"},
+	{"browse", "Browse servers and tools interactively, and call tools"},
+	{"run", "Run a script through the execution pipeline"},
+	{"bench", "Time connecting to the servers and the pipeline stages"},
+	{"doctor", "Check the runtimes, the config and the servers"},
+	{"validate", "Check a config file"},
+	{"config", "Manage encrypted config values"},
+	{"completion", "Print the shell completion script"},
+}
+
+// printUsage lists the commands of codebraid
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: codebraid [-json] [command] [flags]\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, command := range commandSummaries {
+		fmt.Fprintf(tw, "  %s\t%s\n", command[0], command[1])
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun \"codebraid <command> -h\" for the flags of a command.\n")
+}
+
+// jsonOutput makes commands print JSON, errors included. It is set by -json,
+// given before the command or as one of its flags.
+var jsonOutput bool
+
+// runCommand runs a subcommand and returns the exit code, reporting its error
+// on stderr, or on stdout as JSON in JSON output mode
+func runCommand(name string, args []string) int {
+	err := commands[name](args)
+	var out io.Writer = os.Stderr
+	if jsonOutput {
+		out = os.Stdout
+	}
+	return cli.Report(out, "codebraid "+name, err, jsonOutput)
+}
+
+// addJSONFlag adds the -json flag to a command's flags
+func addJSONFlag(flags *flag.FlagSet) {
+	flags.BoolVar(&jsonOutput, "json", jsonOutput, "Print the output, and errors, as JSON")
+}
+
+// connectTimeout bounds connecting to the servers of a command
+const connectTimeout = time.Minute
+
+// configFlags are the flags of the commands that load the config
+type configFlags struct {
+	path    *string
+	profile *string
+}
+
+// addConfigFlags adds the flags locating the config to a command's flags
+func addConfigFlags(flags *flag.FlagSet) configFlags {
+	return configFlags{
+		path:    flags.String("config", os.Getenv("CODEBRAID_CONFIG"), "Path or https URL of the configuration file"),
+		profile: flags.String("profile", os.Getenv(config.ProfileEnv), "Named profile of the config file to apply"),
+	}
+}
+
+// load loads the config the way the server does
+func (f configFlags) load() (*config.Config, error) {
+	cfg, err := config.LoadWithOptions(config.LoadOptions{
+		ConfigPath:        *f.path,
+		SearchPaths:       config.DefaultSearchPaths(),
+		AllowEnvOverrides: true,
+		Profile:           *f.profile,
+		RemoteAuth:        os.Getenv(config.RemoteAuthEnv),
+	})
+	if err != nil {
+		return nil, cli.WithCode(cli.ExitConfig, fmt.Errorf("failed to load config: %w", err))
+	}
+	return cfg, nil
+}
+
+// connectServers connects to the configured servers, or to only those named.
+// Servers that fail to connect are reported and left out; it fails only when
+// none connect.
+func connectServers(ctx context.Context, cfg *config.Config, only []string) (*client.McpClientHub, error) {
+	servers := cfg.McpServers
+	if len(only) > 0 {
+		servers = make(map[string]config.McpServerConfig, len(only))
+		for _, name := range only {
+			server, ok := cfg.McpServers[name]
+			if !ok {
+				return nil, cli.WithCode(cli.ExitUsage, fmt.Errorf("server %q is not configured", name))
+			}
+			servers[name] = server
+		}
+	}
+	if len(servers) == 0 {
+		return nil, cli.WithCode(cli.ExitConfig, fmt.Errorf("no servers configured"))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	hub := client.NewMcpClientHub()
+	if _, err := hub.Reconcile(ctx, servers); err != nil {
+		if len(hub.Servers()) == 0 {
+			return nil, cli.WithCode(cli.ExitServer, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return hub, nil
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+)
+
+// completeCommand is the hidden command the completion scripts call with the
+// words typed so far; it prints the candidates for the last one, one per line
+const completeCommand = "__complete"
+
+// runComplete is registered here, as commands would otherwise refer to itself
+// through it
+func init() {
+	commands[completeCommand] = runComplete
+}
+
+// completionScripts are the scripts "codebraid completion" prints, by shell. They
+// leave the completing to codebraid itself, and fall back to file names when it
+// has no candidates.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for codebraid
+_codebraid() {
+	local IFS=$'\n'
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	COMPREPLY=($(compgen -W "$(codebraid __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)" -- "$cur"))
+	if [ ${#COMPREPLY[@]} -eq 0 ]; then
+		compopt -o default
+	fi
+}
+complete -F _codebraid codebraid
+`,
+	"zsh": `#compdef codebraid
+# zsh completion for codebraid
+_codebraid() {
+	local -a candidates
+	candidates=("${(@f)$(codebraid __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	if [[ -n ${candidates[1]} ]]; then
+		compadd -- "${candidates[@]}"
+	else
+		_files
+	fi
+}
+compdef _codebraid codebraid
+`,
+	"fish": `# fish completion for codebraid
+function __codebraid_complete
+	set -l words (commandline -opc) (commandline -ct)
+	codebraid __complete $words[2..-1] 2>/dev/null
+end
+complete -c codebraid -a '(__codebraid_complete)'
+`,
+}
+
+// runCompletion implements "codebraid completion": it prints the completion
+// script of a shell
+func runCompletion(args []string) error {
+	flags := flag.NewFlagSet("completion", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid completion bash|zsh|fish\n\n")
+		fmt.Fprintf(flags.Output(), "Prints the shell completion script of codebraid. To enable it:\n\n")
+		fmt.Fprintf(flags.Output(), "  bash:  source <(codebraid completion bash)\n")
+		fmt.Fprintf(flags.Output(), "  zsh:   codebraid completion zsh > \"${fpath[1]}/_codebraid\"\n")
+		fmt.Fprintf(flags.Output(), "  fish:  codebraid completion fish > ~/.config/fish/completions/codebraid.fish\n\n")
+		fmt.Fprintf(flags.Output(), "Tool names are completed from those \"codebraid list-tools\" and \"codebraid call\"\n")
+		fmt.Fprintf(flags.Output(), "last saw, as completing must not wait for the servers.\n")
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("expected a shell"))
+	}
+	script, ok := completionScripts[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("unsupported shell %q", flags.Arg(0)))
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// runComplete implements the hidden completion command. Completing must never
+// get in the way of typing, so it fails silently.
+func runComplete(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	for _, candidate := range completions(args[:len(args)-1], args[len(args)-1]) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// completions returns the candidates for the word being typed, current, after
+// the words before it
+func completions(words []string, current string) []string {
+	if len(words) > 0 && (words[0] == "-json" || words[0] == "--json") {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		var names []string
+		for name := range commands {
+			if !strings.HasPrefix(name, "__") {
+				names = append(names, name)
+			}
+		}
+		names = append(names, "-json")
+		return matching(names, current)
+	}
+
+	name, words := words[0], words[1:]
+	switch name {
+	case "completion":
+		if len(words) == 0 {
+			return matching([]string{"bash", "fish", "zsh"}, current)
+		}
+		return nil
+	case "config":
+		if len(words) == 0 {
+			return matching([]string{"decrypt", "encrypt", "keygen"}, current)
+		}
+		return nil
+	}
+	flags := commandFlags(name)
+	if flags == nil {
+		return nil
+	}
+
+	// Tell the flags' values from the arguments in the words before
+	var positional []string
+	expectsValue := ""
+	for _, word := range words {
+		if expectsValue != "" {
+			flags.Set(expectsValue, word)
+			expectsValue = ""
+			continue
+		}
+		if word == "--" || !strings.HasPrefix(word, "-") || word == "-" {
+			positional = append(positional, word)
+			continue
+		}
+		flagName, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		if f := flags.Lookup(flagName); f != nil {
+			if hasValue {
+				flags.Set(flagName, value)
+			} else if !isBoolFlag(f) {
+				expectsValue = flagName
+			}
+		}
+	}
+
+	switch {
+	case expectsValue == "server":
+		// -server takes a comma-separated list
+		done, last := "", current
+		if i := strings.LastIndex(current, ","); i >= 0 {
+			done, last = current[:i+1], current[i+1:]
+		}
+		var candidates []string
+		for _, server := range matching(configuredServers(flags), last) {
+			candidates = append(candidates, done+server)
+		}
+		return candidates
+	case expectsValue != "":
+		return nil
+	case strings.HasPrefix(current, "-"):
+		var names []string
+		flags.VisitAll(func(f *flag.Flag) {
+			names = append(names, "-"+f.Name)
+		})
+		return matching(names, current)
+	case name == "call" && len(positional) == 0:
+		return matching(configuredServers(flags), current)
+	case name == "call" && len(positional) == 1:
+		return matching(cachedTools()[positional[0]], current)
+	}
+	return nil
+}
+
+// matching returns the sorted candidates starting with prefix
+func matching(candidates []string, prefix string) []string {
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// isBoolFlag reports whether a flag takes no value, like -json
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// configuredServers returns the names of the servers of the config the flags
+// locate, or none when it cannot be loaded
+func configuredServers(flags *flag.FlagSet) []string {
+	if flags.Lookup("config") == nil {
+		return nil
+	}
+	cfgFlags := configFlags{path: new(string), profile: new(string)}
+	*cfgFlags.path = flags.Lookup("config").Value.String()
+	*cfgFlags.profile = flags.Lookup("profile").Value.String()
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(cfg.McpServers))
+	for name := range cfg.McpServers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Flags of a command are collected by running it with collectingFlags set:
+// parseFlags then hands its flags over in collectedFlags instead of parsing
+// them, stopping the command with errFlagsCollected.
+var (
+	collectingFlags   bool
+	collectedFlags    *flag.FlagSet
+	errFlagsCollected = errors.New("flags collected")
+)
+
+// parseFlags parses the arguments of a command; every command parses its flags
+// with it so they can be completed
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if collectingFlags {
+		collectedFlags = flags
+		return errFlagsCollected
+	}
+	flags.Parse(args)
+	return nil
+}
+
+// commandFlags returns the flags of a command, or nil for unknown commands
+func commandFlags(name string) *flag.FlagSet {
+	run, ok := commands[name]
+	if !ok || strings.HasPrefix(name, "__") {
+		return nil
+	}
+	collectingFlags, collectedFlags = true, nil
+	defer func() { collectingFlags = false }()
+	if err := run(nil); !errors.Is(err, errFlagsCollected) {
+		return nil
+	}
+	return collectedFlags
+}
+
+// toolCachePath is the file caching the tool names of the servers for
+// completion, as they were last listed
+func toolCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "codebraid", "completion-tools.json"), nil
+}
+
+// cachedTools returns the cached tool names, by server
+func cachedTools() map[string][]string {
+	tools := make(map[string][]string)
+	path, err := toolCachePath()
+	if err != nil {
+		return tools
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &tools)
+	}
+	return tools
+}
+
+// cacheTools records the tool names of servers for completion. Caching is a
+// convenience, so failing to is not an error.
+func cacheTools(servers map[string][]*mcp.Tool) {
+	path, err := toolCachePath()
+	if err != nil {
+		return
+	}
+	tools := cachedTools()
+	for server, serverTools := range servers {
+		names := make([]string, 0, len(serverTools))
+		for _, tool := range serverTools {
+			names = append(names, tool.Name)
+		}
+		tools[server] = names
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	os.WriteFile(path, data, 0o644)
+}
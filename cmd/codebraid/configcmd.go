@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// runConfig implements "codebraid config": creating the key of encrypted config
+// values, and encrypting or decrypting the values of a config file
+func runConfig(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  codebraid config keygen [-o file]                         Create a key for encrypted values\n")
+		fmt.Fprintf(os.Stderr, "  codebraid config encrypt [-keys regexp] [-w] config.json  Encrypt the values of secret-looking fields\n")
+		fmt.Fprintf(os.Stderr, "  codebraid config decrypt [-w] config.json                 Decrypt the encrypted values\n\n")
+		fmt.Fprintf(os.Stderr, "The key is read from %s, the file %s names, or %s.\n", config.ConfigKeyEnv, config.ConfigKeyFileEnv, config.DefaultKeyPath())
+	}
+	if len(args) == 0 {
+		usage()
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("missing config command"))
+	}
+
+	flags := flag.NewFlagSet("config "+args[0], flag.ExitOnError)
+	switch args[0] {
+	case "keygen":
+		output := flags.String("o", config.DefaultKeyPath(), "File to write the key to; - prints it")
+		flags.Parse(args[1:])
+		return keygen(*output)
+	case "encrypt", "decrypt":
+		keys := config.DefaultEncryptKeys
+		if args[0] == "encrypt" {
+			flags.StringVar(&keys, "keys", keys, "Regular expression matching the fields whose values are encrypted")
+		}
+		write := flags.Bool("w", false, "Write the result to the file instead of printing it")
+		flags.Parse(args[1:])
+		if flags.NArg() != 1 {
+			usage()
+			return cli.WithCode(cli.ExitUsage, fmt.Errorf("expected one config file"))
+		}
+		return cryptFile(args[0], flags.Arg(0), keys, *write)
+	default:
+		usage()
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("unknown config command %q", args[0]))
+	}
+}
+
+// keygen creates a key for encrypted values, refusing to replace an existing one
+func keygen(output string) error {
+	key, err := config.NewKey()
+	if err != nil {
+		return err
+	}
+	if output == "-" {
+		fmt.Println(key)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file (an existing key is never replaced): %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, key); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote key to %s; share it only with those who may read the encrypted values\n", output)
+	return nil
+}
+
+// cryptFile encrypts or decrypts the values of a config file
+func cryptFile(command, path, keys string, write bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	key, err := config.LoadKey()
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	var count int
+	done := "Encrypted"
+	if command == "encrypt" {
+		out, count, err = config.EncryptFile(data, key, keys)
+	} else {
+		out, count, err = config.DecryptFile(data, key)
+		done = "Decrypted"
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if !write {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, out, info.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s %d value(s) in %s\n", done, count, path)
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file beside path and renames it
+// into place, so an interrupted write never leaves a config half rewritten
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
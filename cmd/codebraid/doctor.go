@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/bundler"
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+	"github.com/yousuf/codebraid-mcp/internal/client"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+)
+
+// Statuses of a doctor check
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// checkLabels are how statuses are printed, so failures stand out
+var checkLabels = map[string]string{checkOK: "ok", checkWarn: "warn", checkFail: "FAIL", checkSkip: "-"}
+
+// How long doctor waits for a tool's version and for a server to answer
+const (
+	versionTimeout = 10 * time.Second
+	pingTimeout    = 30 * time.Second
+)
+
+// check is the outcome of one doctor check
+type check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// runDoctor implements "codebraid doctor": it checks the tools codebraid runs,
+// the config and every configured server, and says how to fix what is missing,
+// instead of users finding out when a script first runs
+func runDoctor(args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	cfgFlags := addConfigFlags(flags)
+	skipServers := flags.Bool("skip-servers", false, "Do not connect to the configured servers")
+	addJSONFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid doctor [-config file] [-profile name] [-skip-servers] [-json]\n\n")
+		fmt.Fprintf(flags.Output(), "Checks the external tools, the config and the configured MCP servers.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	var checks []check
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		checks = append(checks, check{Name: "config", Status: checkFail, Detail: err.Error(),
+			Fix: "fix the config, or point -config or CODEBRAID_CONFIG at the right file"})
+		cfg = &config.Config{}
+	} else {
+		detail := fmt.Sprintf("%s, %d server(s)", cfg.Path(), len(cfg.McpServers))
+		if cfg.Profile() != "" {
+			detail += fmt.Sprintf(", profile %q", cfg.Profile())
+		}
+		checks = append(checks, check{Name: "config", Status: checkOK, Detail: detail})
+	}
+
+	checks = append(checks, toolChecks(cfg)...)
+	if err == nil {
+		if *skipServers {
+			checks = append(checks, check{Name: "servers", Status: checkSkip, Detail: "not checked (-skip-servers)"})
+		} else {
+			checks = append(checks, serverChecks(cfg)...)
+		}
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if c.Status == checkFail {
+			failed++
+		}
+	}
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(struct {
+			OK     bool    `json:"ok"`
+			Checks []check `json:"checks"`
+		}{OK: failed == 0, Checks: checks})
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, c := range checks {
+			fmt.Printf("[%-4s] %-18s %s\n", checkLabels[c.Status], c.Name, c.Detail)
+			if c.Fix != "" && (c.Status == checkWarn || c.Status == checkFail) {
+				fmt.Printf("       %-18s fix: %s\n", "", c.Fix)
+			}
+		}
+	}
+	if failed > 0 {
+		return cli.WithReportedCode(cli.ExitFailure, fmt.Errorf("%d check(s) failed", failed))
+	}
+	return nil
+}
+
+// toolChecks checks the external tools the config needs; tools it does not need
+// are reported when found and skipped when missing
+func toolChecks(cfg *config.Config) []check {
+	runtime := cfg.GetSandboxRuntime()
+	toolchain := cfg.GetBundlerToolchain()
+	if toolchain == bundler.ToolchainAuto && runtime == sandbox.RuntimeBun {
+		toolchain = bundler.ToolchainBun
+	}
+	npxServers := serversRunning(cfg, "npx")
+	uvxServers := serversRunning(cfg, "uvx")
+
+	var checks []check
+	checks = append(checks, commandCheck("node", "node", []string{"--version"},
+		len(npxServers) > 0 || toolchain == bundler.ToolchainRspack,
+		"install Node.js from https://nodejs.org"))
+	checks = append(checks, commandCheck("npx", "npx", []string{"--version"},
+		len(npxServers) > 0,
+		"install Node.js, which includes npx"+neededBy(npxServers)))
+	if len(uvxServers) > 0 {
+		checks = append(checks, commandCheck("uvx", "uvx", []string{"--version"}, true,
+			"install uv from https://docs.astral.sh/uv"+neededBy(uvxServers)))
+	}
+	checks = append(checks, commandCheck("deno", "deno", []string{"--version"},
+		runtime == sandbox.RuntimeDeno,
+		"install Deno from https://deno.com, or change sandbox.runtime"))
+	checks = append(checks, commandCheck("bun", "bun", []string{"--version"},
+		runtime == sandbox.RuntimeBun || toolchain == bundler.ToolchainBun,
+		"install Bun from https://bun.sh, or change sandbox.runtime and bundler.toolchain"))
+	checks = append(checks, rspackCheck(toolchain == bundler.ToolchainRspack))
+	if cfg.GetBundlerTypeCheck() {
+		checks = append(checks, commandCheck("tsc", "tsc", []string{"--version"}, false,
+			"install TypeScript with: npm install -g typescript (type checks use npx otherwise)"))
+	}
+
+	switch runtime {
+	case sandbox.RuntimeAuto, sandbox.RuntimeWasm:
+		path := cfg.GetSandboxWasmPath()
+		c := check{Name: "wasm sandbox", Status: checkOK, Detail: path}
+		if _, err := os.Stat(path); err != nil {
+			c.Status, c.Detail = checkWarn, fmt.Sprintf("%s not found, scripts run with goja", path)
+			c.Fix = "build the plugin in wasm/, or set sandbox.wasmPath"
+			if runtime == sandbox.RuntimeWasm {
+				c.Status = checkFail
+			}
+		}
+		checks = append(checks, c)
+	case sandbox.RuntimeDocker:
+		docker := cfg.GetSandboxDocker()
+		checks = append(checks, commandCheck(docker.Binary, docker.Binary, []string{"--version"}, true,
+			"install Docker or Podman, or change sandbox.runtime"))
+	}
+	if cfg.GetSandboxPythonEnabled() {
+		checks = append(checks, pythonCheck(cfg.GetSandboxPython()))
+	}
+	return checks
+}
+
+// commandCheck checks that command is on PATH and reports its version. A missing
+// command fails the check when the config needs it.
+func commandCheck(name, command string, versionArgs []string, needed bool, fix string) check {
+	path, err := exec.LookPath(command)
+	if err != nil {
+		if !needed {
+			return check{Name: name, Status: checkSkip, Detail: "not found (not needed by this config)"}
+		}
+		return check{Name: name, Status: checkFail, Detail: "not found on PATH", Fix: fix}
+	}
+	version, err := commandVersion(path, versionArgs...)
+	if err != nil {
+		return check{Name: name, Status: checkWarn, Detail: fmt.Sprintf("%s does not run: %v", path, err), Fix: fix}
+	}
+	return check{Name: name, Status: checkOK, Detail: fmt.Sprintf("%s (%s)", version, path)}
+}
+
+// rspackCheck checks the rspack CLI, which compiles TypeScript with its builtin swc loader
+func rspackCheck(needed bool) check {
+	name := "rspack + swc"
+	bundler.Initialize()
+	path, err := bundler.GetRspackPath()
+	switch {
+	case err != nil && needed:
+		return check{Name: name, Status: checkFail, Detail: "not found",
+			Fix: "install it with: npm install -g @rspack/cli @rspack/core, or change bundler.toolchain"}
+	case err != nil:
+		return check{Name: name, Status: checkSkip, Detail: "not found (not needed by this config)"}
+	case path == "npx":
+		status := checkOK
+		if !needed {
+			status = checkSkip
+		}
+		return check{Name: name, Status: status, Detail: "runs through npx, downloaded on first use",
+			Fix: "install it with: npm install -g @rspack/cli @rspack/core, to bundle without downloading it"}
+	}
+	version, err := commandVersion(path, "--version")
+	if err != nil {
+		return check{Name: name, Status: checkWarn, Detail: fmt.Sprintf("%s does not run: %v", path, err),
+			Fix: "reinstall it with: npm install -g @rspack/cli @rspack/core"}
+	}
+	return check{Name: name, Status: checkOK, Detail: fmt.Sprintf("%s (%s)", version, path)}
+}
+
+// pythonCheck checks the interpreter Python scripts run with
+func pythonCheck(python config.PythonConfig) check {
+	switch {
+	case python.Venv != "":
+		interpreter := filepath.Join(python.Venv, "bin", "python")
+		return commandCheck("python", interpreter, []string{"--version"}, true,
+			fmt.Sprintf("create the venv with: python3 -m venv %s", python.Venv))
+	case python.UV:
+		return commandCheck("python (uv)", "uv", []string{"--version"}, true, "install uv from https://docs.astral.sh/uv")
+	}
+	command := python.Command
+	if command == "" {
+		command = "python3"
+	}
+	return commandCheck("python", command, []string{"--version"}, true,
+		"install Python 3, or set sandbox.python.command")
+}
+
+// commandVersion returns the first line a command prints with versionArgs
+func commandVersion(path string, versionArgs ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, versionArgs...).CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+	return firstLine(string(output)), nil
+}
+
+// serversRunning returns the names of the stdio servers run with command
+func serversRunning(cfg *config.Config, command string) []string {
+	var names []string
+	for name, server := range cfg.McpServers {
+		if filepath.Base(server.Command) == command {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// neededBy names the servers a tool is needed by, for a fix
+func neededBy(servers []string) string {
+	if len(servers) == 0 {
+		return ""
+	}
+	return " (needed by " + strings.Join(servers, ", ") + ")"
+}
+
+// serverChecks connects to every configured server at once and lists its tools
+func serverChecks(cfg *config.Config) []check {
+	// Connection messages would interleave with the report
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	names := make([]string, 0, len(cfg.McpServers))
+	for name := range cfg.McpServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]check, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = serverCheck(name, cfg.McpServers[name])
+		}()
+	}
+	wg.Wait()
+
+	disabled := cfg.DisabledServers()
+	skipped := make([]string, 0, len(disabled))
+	for name := range disabled {
+		skipped = append(skipped, name)
+	}
+	sort.Strings(skipped)
+	for _, name := range skipped {
+		checks = append(checks, check{Name: "server " + name, Status: checkSkip, Detail: "disabled: " + disabled[name]})
+	}
+	return checks
+}
+
+// serverCheck connects to one server and lists its tools
+func serverCheck(name string, server config.McpServerConfig) check {
+	c := check{Name: "server " + name}
+	if server.Type == "stdio" {
+		if _, err := exec.LookPath(server.Command); err != nil {
+			c.Status, c.Detail = checkFail, fmt.Sprintf("command %q not found", server.Command)
+			c.Fix = fmt.Sprintf("install %s, or fix mcpServers.%s.command", server.Command, name)
+			return c
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	start := time.Now()
+	mcpClient, err := client.NewMcpClient(ctx, name, server, nil)
+	if err != nil {
+		c.Status, c.Detail = checkFail, err.Error()
+		if server.Type == "stdio" {
+			c.Fix = fmt.Sprintf("run the command by hand to see why it fails: %s %s", server.Command, strings.Join(server.Args, " "))
+		} else {
+			c.Fix = fmt.Sprintf("check that %s is reachable and that mcpServers.%s.headers has the credentials it needs", server.URL, name)
+		}
+		return c
+	}
+	defer mcpClient.Close()
+	c.Status = checkOK
+	c.Detail = fmt.Sprintf("%d tool(s), connected in %s", len(mcpClient.GetTools()), time.Since(start).Round(time.Millisecond))
+	return c
+}
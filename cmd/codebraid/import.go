@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// runImport implements "codebraid import": it converts the servers of Claude
+// Desktop and Cursor configs into a codebraid config, written to stdout or added
+// to the -o file
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	output := flags.String("o", "", "Config file to add the servers to, created if missing (default: print the config)")
+	force := flags.Bool("force", false, "Replace servers the -o file already defines")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid import [-o config.json] [-force] [file ...]\n\n")
+		fmt.Fprintf(flags.Output(), "Imports MCP servers from claude_desktop_config.json or .cursor/mcp.json files.\n")
+		fmt.Fprintf(flags.Output(), "Without files, the Claude Desktop and Cursor configs found on this machine are read.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	sources := flags.Args()
+	if len(sources) == 0 {
+		for _, path := range config.DefaultImportPaths() {
+			if _, err := os.Stat(path); err == nil {
+				sources = append(sources, path)
+			}
+		}
+		if len(sources) == 0 {
+			return fmt.Errorf("no Claude Desktop or Cursor config found; pass the files to import")
+		}
+	}
+
+	// Servers of earlier sources win over servers of the same name in later ones
+	servers := make(map[string]config.McpServerConfig)
+	for _, source := range sources {
+		imported, warnings, err := config.ImportServers(source)
+		if err != nil {
+			return err
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", source, warning)
+		}
+		added := 0
+		for name, server := range imported {
+			if _, exists := servers[name]; exists {
+				fmt.Fprintf(os.Stderr, "%s: skipped server %q: already imported from an earlier file\n", source, name)
+				continue
+			}
+			servers[name] = server
+			added++
+		}
+		fmt.Fprintf(os.Stderr, "Imported %d server(s) from %s\n", added, source)
+	}
+	if len(servers) == 0 {
+		return fmt.Errorf("no servers to import")
+	}
+
+	// Add the servers to the output config, keeping everything else in it as is
+	target := map[string]interface{}{}
+	if *output != "" {
+		if data, err := os.ReadFile(*output); err == nil {
+			if err := json.Unmarshal(data, &target); err != nil {
+				return fmt.Errorf("failed to parse %q: %w", *output, err)
+			}
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %q: %w", *output, err)
+		}
+	}
+	existing, _ := target["mcpServers"].(map[string]interface{})
+	if existing == nil {
+		existing = make(map[string]interface{})
+	}
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, exists := existing[name]; exists && !*force {
+			fmt.Fprintf(os.Stderr, "Skipped server %q: %s already defines it (use -force to replace it)\n", name, *output)
+			continue
+		}
+		existing[name] = servers[name]
+	}
+	target["mcpServers"] = existing
+
+	data, err := json.MarshalIndent(target, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %q: %w", *output, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", *output)
+	return nil
+}
@@ -1,119 +1,97 @@
 package main
 
 import (
-	"context"
-	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strings"
 	"time"
 
-	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/yousuf/codebraid-mcp/internal/bundler"
+	"github.com/yousuf/codebraid-mcp/internal/cli"
 	"github.com/yousuf/codebraid-mcp/internal/config"
 	"github.com/yousuf/codebraid-mcp/internal/server"
-	"github.com/yousuf/codebraid-mcp/internal/session"
 )
 
 func main() {
-	// Parse command-line flags
-	var (
-		configPath = flag.String("config", os.Getenv("CODEBRAID_CONFIG"), "Path to configuration file")
-		portFlag   = flag.Int("port", 0, "HTTP server port (overrides config file)")
-		help       = flag.Bool("help", false, "Show usage information")
-	)
-	flag.Parse()
-
-	if *help {
-		flag.Usage()
-		os.Exit(0)
+	// Subcommands, optionally after -json; given no command, or only flags,
+	// codebraid serves as it did before it had commands
+	args := os.Args[1:]
+	if len(args) > 1 && (args[0] == "-json" || args[0] == "--json") {
+		if _, ok := commands[args[1]]; ok {
+			jsonOutput = true
+			args = args[1:]
+		}
 	}
-
-	// Load configuration with flexible options
-	cfg, err := config.LoadWithOptions(config.LoadOptions{
-		ConfigPath:        *configPath,
-		SearchPaths:       config.DefaultSearchPaths(),
-		AllowEnvOverrides: true,
-	})
-	if err != nil {
-		log.Fatalf("Failed to load config: %v\n\nHint: Specify a config file with -config flag or CODEBRAID_CONFIG env var", err)
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-
-	log.Printf("Loaded configuration with %d MCP server(s)", len(cfg.McpServers))
-
-	// Initialize bundler
-	if err = bundler.Initialize(); err != nil {
-		log.Fatalf("Failed to initialize bundler: %v\n\nHint: Install rspack with: npm install -g @rspack/cli @rspack/core", err)
+	if name == "help" {
+		printUsage(os.Stdout)
+		return
+	}
+	if _, ok := commands[name]; !ok {
+		printUsage(os.Stderr)
+		os.Exit(cli.Report(os.Stderr, "codebraid", cli.WithCode(cli.ExitUsage, fmt.Errorf("unknown command %q", name)), false))
 	}
-	log.Println("Bundler initialized successfully")
+	os.Exit(runCommand(name, args))
+}
 
-	// Determine server port (priority: flag > env > config > default)
-	port := *portFlag
-	if port == 0 {
-		if envPort := os.Getenv("CODEBRAID_PORT"); envPort != "" {
-			fmt.Sscanf(envPort, "%d", &port)
-		}
+// setupExecution configures the bundler and the sandbox hooks as the config asks
+func setupExecution(cfg *config.Config) error {
+	if err := bundler.Initialize(); err != nil {
+		return err
 	}
-	if port == 0 {
-		port = cfg.GetServerPort()
+	if cfg.GetBundlerIncremental() {
+		bundler.EnableIncremental()
 	}
-
-	// Create session manager
-	sessionMgr := session.NewManager(cfg)
-
-	// Create HTTP handler with proper session management
-	handler := mcp.NewStreamableHTTPHandler(func(req *http.Request) *mcp.Server {
-		// Create a new MCP server instance for each request
-		// This allows the SDK to manage sessions properly
-		return server.NewMcpServer(sessionMgr)
-	}, &mcp.StreamableHTTPOptions{
-		Stateless:      false,
-		JSONResponse:   false,
-		Logger:         nil,
-		EventStore:     nil,
-		SessionTimeout: 0,
-	})
-
-	// Setup HTTP server
-	timeout := time.Duration(cfg.GetServerTimeout()) * time.Second
-	httpServer := &http.Server{
-		Addr:         fmt.Sprintf(":%d", port),
-		Handler:      handler,
-		ReadTimeout:  timeout,
-		WriteTimeout: timeout,
-		IdleTimeout:  timeout * 4,
+	bundler.SetMaxBundleSize(cfg.GetMaxBundleKB() << 10)
+	bundler.SetRspackConfig(cfg.GetBundlerRspackConfig())
+	if _, err := bundler.NewToolchain(cfg.GetBundlerToolchain()); err != nil {
+		return err
 	}
 
-	// Start server in a goroutine
-	go func() {
-		log.Printf("CodeBraid MCP server listening on port %d", port)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
+	if cfg.GetBundleCacheEnabled() {
+		cacheDir := cfg.GetBundleCacheDir()
+		if err := bundler.EnableCache(cacheDir, int64(cfg.GetBundleCacheSizeMB())<<20); err != nil {
+			log.Printf("Bundle cache disabled: %v", err)
+		} else {
+			log.Printf("Bundle cache enabled at %s", cacheDir)
 		}
-	}()
-
-	// Wait for interrupt signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
-
-	log.Println("Shutting down server...")
-
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	}
 
-	if err := httpServer.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+	transform := cfg.GetTransform()
+	err := bundler.SetTransformOptions(bundler.TransformOptions{
+		Target:     transform.Target,
+		Format:     transform.Module,
+		Sourcemap:  !transform.DisableSourcemap,
+		Decorators: transform.Decorators,
+		TSX:        transform.TSX,
+		Minify:     transform.Minify,
+		External:   transform.External,
+		Alias:      transform.Alias,
+		Define:     transform.Define,
+	})
+	if err != nil {
+		return fmt.Errorf("invalid bundler transform: %w", err)
 	}
 
-	// Close all sessions
-	if err := sessionMgr.CloseAll(); err != nil {
-		log.Printf("Error closing sessions: %v", err)
+	if err := server.SetSandboxHooks(cfg); err != nil {
+		return fmt.Errorf("invalid sandbox hooks: %w", err)
 	}
 
-	log.Println("Server stopped")
+	if packages := cfg.GetBundlerPackages(); len(packages) > 0 {
+		err := bundler.EnablePackages(bundler.PackageOptions{
+			Allow:          packages,
+			CacheDir:       cfg.GetPackageCacheDir(),
+			InstallTimeout: time.Duration(cfg.GetPackageInstallTimeout()) * time.Second,
+			MaxSizeMB:      cfg.GetPackageMaxSizeMB(),
+		})
+		if err != nil {
+			return fmt.Errorf("invalid bundler packages: %w", err)
+		}
+		log.Printf("Scripts may import %d npm package(s): %s", len(packages), strings.Join(packages, ", "))
+	}
+	return nil
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+	"github.com/yousuf/codebraid-mcp/internal/server"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// runScript implements "codebraid run": it runs a local script against the
+// configured servers through the same bundling and sandbox pipeline as the
+// execute_code tool, streaming its console output, so scripts can be developed
+// and run in CI without an MCP client
+func runScript(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	cfgFlags := addConfigFlags(flags)
+	serverList := flags.String("server", "", "Comma-separated servers the script may use (default: all)")
+	input := flags.String("input", "", "JSON value passed to the script as its input")
+	timeout := flags.Int("timeout", 0, "Execution timeout in seconds (default: the configured sandbox timeout)")
+	readOnly := flags.Bool("read-only", false, "Only allow tools annotated as read-only")
+	dryRun := flags.Bool("dry-run", false, "Answer tool calls with placeholders and print the planned calls")
+	typeCheck := flags.Bool("type-check", false, "Type-check the script against the generated libraries before running it")
+	addJSONFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid run [flags] script.ts|script.py\n\n")
+		fmt.Fprintf(flags.Output(), "Runs a script against the configured MCP servers as execute_code would, printing\n")
+		fmt.Fprintf(flags.Output(), "its console output as it runs and its return value at the end; with -json,\n")
+		fmt.Fprintf(flags.Output(), "console output goes to stderr and the whole execution result is printed.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("expected one script"))
+	}
+	scriptPath := flags.Arg(0)
+
+	code, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("failed to read script: %w", err))
+	}
+	execArgs := server.ExecuteCodeArgs{
+		Code:      string(code),
+		Timeout:   *timeout,
+		TypeCheck: *typeCheck,
+		DryRun:    *dryRun,
+		NoCache:   true,
+	}
+	if filepath.Ext(scriptPath) == ".py" {
+		execArgs.Language = "python"
+	}
+	if *input != "" {
+		if err := json.Unmarshal([]byte(*input), &execArgs.Input); err != nil {
+			return cli.WithCode(cli.ExitUsage, fmt.Errorf("-input must be JSON: %w", err))
+		}
+	}
+
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		return err
+	}
+	if err := setupExecution(cfg); err != nil {
+		return cli.WithCode(cli.ExitConfig, err)
+	}
+	sessionMgr := session.NewManager(cfg)
+	defer sessionMgr.CloseAll()
+	trail, err := server.NewAuditTrail(cfg)
+	if err != nil {
+		return cli.WithCode(cli.ExitConfig, fmt.Errorf("failed to set up audit trail: %w", err))
+	}
+	if trail != nil {
+		sessionMgr.SetAuditTrail(trail)
+		defer trail.Close()
+	}
+
+	// Interrupting the run stops the script and its tool calls
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := session.Options{ReadOnly: *readOnly}
+	if *serverList != "" {
+		opts.Servers = strings.Split(*serverList, ",")
+	}
+	sessionID := fmt.Sprintf("run-%d", os.Getpid())
+	sessionCtx, err := sessionMgr.GetOrCreateSession(ctx, sessionID, opts)
+	if err != nil {
+		return cli.WithCode(cli.ExitServer, fmt.Errorf("failed to create session: %w", err))
+	}
+	// The session is closed like any other, running its hooks and removing its
+	// bundle directory, before the manager is
+	defer sessionMgr.DeleteSession(sessionID)
+
+	result, err := server.ExecuteCode(ctx, sessionMgr, sessionCtx, execArgs, func(level, message string) {
+		if jsonOutput || level == "warn" || level == "error" {
+			fmt.Fprintln(os.Stderr, message)
+		} else {
+			fmt.Fprintln(os.Stdout, message)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+	} else {
+		printRunResult(result)
+	}
+	if result.IsError {
+		return cli.WithReportedCode(cli.ExitToolError, fmt.Errorf("%s failed", scriptPath))
+	}
+	return nil
+}
+
+// printRunResult prints the text of an execution result: the script's return
+// value to stdout, or its error to stderr
+func printRunResult(result *mcp.CallToolResult) {
+	out := os.Stdout
+	if result.IsError {
+		out = os.Stderr
+	}
+	for _, content := range result.Content {
+		switch c := content.(type) {
+		case *mcp.TextContent:
+			if c.Text != "" {
+				fmt.Fprintln(out, c.Text)
+			}
+		case *mcp.ResourceLink:
+			fmt.Fprintf(os.Stderr, "Output file %s (%s)\n", c.Name, c.URI)
+		}
+	}
+}
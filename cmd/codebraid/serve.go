@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+	"github.com/yousuf/codebraid-mcp/internal/server"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// runServe implements "codebraid serve", also run when codebraid is given no
+// command: it serves the code execution tools over streamable HTTP, stdio or
+// both, sharing the sessions, until interrupted or, serving only stdio, until the
+// client disconnects, draining running executions before it stops
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	cfgFlags := addConfigFlags(flags)
+	portFlag := flags.Int("port", 0, "HTTP server port (overrides config file)")
+	httpAddr := flags.String("http", "", "Address to serve MCP streamable HTTP on, e.g. :8080 or 127.0.0.1:8080 (overrides -port, and serves HTTP whatever the transports)")
+	transportList := flags.String("transport", "", "Comma-separated transports to serve: http, stdio (default: server.transports, or http)")
+	sessions := flags.Bool("sessions", false, "List the sessions of the running instance through its admin API and exit")
+	noReload := flags.Bool("no-reload", false, "Do not reload the config file when it changes or on SIGHUP")
+	refresh := flags.Duration("config-refresh", time.Minute, "How often a config fetched from a URL is fetched again; 0 disables refreshing")
+	flags.Usage = func() {
+		printUsage(flags.Output())
+		fmt.Fprintf(flags.Output(), "\nFlags of serve:\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("unexpected argument %q", flags.Arg(0)))
+	}
+
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		return fmt.Errorf("%w\n\nHint: Specify a config file with -config flag or CODEBRAID_CONFIG env var", err)
+	}
+
+	transports := cfg.GetServerTransports()
+	if *transportList != "" {
+		transports = strings.Split(*transportList, ",")
+	}
+	serveHTTP, serveStdio := *httpAddr != "", false
+	for _, transport := range transports {
+		switch strings.TrimSpace(transport) {
+		case config.TransportHTTP:
+			serveHTTP = true
+		case config.TransportStdio:
+			serveStdio = true
+		default:
+			return cli.WithCode(cli.ExitUsage, fmt.Errorf("unknown transport %q (must be http or stdio)", transport))
+		}
+	}
+
+	if *sessions {
+		if err := printSessions(cfg); err != nil {
+			return fmt.Errorf("failed to list sessions: %w", err)
+		}
+		return nil
+	}
+
+	// A token read from an environment variable may still be missing
+	if addr := cfg.GetAdminAddr(); addr != "" && cfg.GetAdminToken() == "" && !cfg.AdminOnLoopback() {
+		return cli.WithCode(cli.ExitConfig, fmt.Errorf("admin API on %s has no token: set admin.token, or listen on a loopback address such as 127.0.0.1:3001", addr))
+	}
+
+	logDisabledServers(cfg)
+	if cfg.Profile() != "" {
+		log.Printf("Loaded configuration profile %q with %d MCP server(s)", cfg.Profile(), len(cfg.McpServers))
+	} else {
+		log.Printf("Loaded configuration with %d MCP server(s)", len(cfg.McpServers))
+	}
+
+	if err := setupExecution(cfg); err != nil {
+		return cli.WithCode(cli.ExitConfig, fmt.Errorf("failed to set up script execution: %w", err))
+	}
+	log.Println("Bundler initialized successfully")
+
+	// Determine the address to listen on (priority: -http > -port > env > config > default)
+	port := *portFlag
+	if port == 0 {
+		if envPort := os.Getenv("CODEBRAID_PORT"); envPort != "" {
+			fmt.Sscanf(envPort, "%d", &port)
+		}
+	}
+	if port == 0 {
+		port = cfg.GetServerPort()
+	}
+	listenAddr := *httpAddr
+	if listenAddr == "" {
+		listenAddr = fmt.Sprintf(":%d", port)
+	}
+
+	// Create session manager
+	sessionMgr := session.NewManager(cfg)
+
+	// Remove what crashed runs left in the temp directory
+	if sessions := cfg.GetSessions(); !sessions.DisableOrphanCleanup {
+		if removed := sessionMgr.CollectOrphans(time.Duration(sessions.OrphanAge) * time.Second); removed > 0 {
+			log.Printf("Removed %d orphaned temp director(ies)", removed)
+		}
+	}
+
+	// Start pre-warmed runtime processes, if configured
+	pool, err := server.NewSandboxPool(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to start sandbox pool: %w", err)
+	}
+	if pool != nil {
+		sessionMgr.SetSandboxPool(pool)
+		log.Printf("Sandbox pool started with %d warm %s process(es)", cfg.GetSandboxPool().Size, cfg.GetSandboxRuntime())
+	}
+
+	trail, err := server.NewAuditTrail(cfg)
+	if err != nil {
+		return cli.WithCode(cli.ExitConfig, fmt.Errorf("failed to set up audit trail: %w", err))
+	}
+	if trail != nil {
+		sessionMgr.SetAuditTrail(trail)
+		log.Println("Execution audit trail enabled")
+	}
+
+	// Keep blank sessions ready for new clients, if configured
+	if warm := cfg.GetSessions().WarmSessions; warm > 0 {
+		sessionMgr.StartWarmPool(server.WarmBundler(sessionMgr))
+		log.Printf("Warm session pool started with %d session(s)", warm)
+	}
+
+	// Each MCP session is a session of the manager
+	handler := server.NewHTTPHandler(sessionMgr)
+
+	// Setup HTTP server
+	timeout := time.Duration(cfg.GetServerTimeout()) * time.Second
+	var httpServer *http.Server
+	if serveHTTP {
+		httpServer = &http.Server{
+			Addr:         listenAddr,
+			Handler:      handler,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+			IdleTimeout:  timeout * 4,
+		}
+
+		// Start server in a goroutine
+		go func() {
+			log.Printf("CodeBraid MCP server listening on %s", listenAddr)
+			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Server failed: %v", err)
+			}
+		}()
+	}
+
+	// The stdio client is served until it disconnects, or until shutdown once
+	// its session is drained
+	stdioCtx, stopStdio := context.WithCancel(context.Background())
+	defer stopStdio()
+	var stdioDone chan error
+	if serveStdio {
+		stdioDone = make(chan error, 1)
+		go func() {
+			stdioDone <- server.ServeStdio(stdioCtx, sessionMgr)
+		}()
+		log.Println("CodeBraid MCP server serving stdio")
+	}
+
+	// The admin API gets its own listener so it can stay off the public interface
+	var adminServer *http.Server
+	if addr := cfg.GetAdminAddr(); addr != "" {
+		token := cfg.GetAdminToken()
+		if token == "" {
+			log.Printf("Warning: admin API on %s has no token configured", addr)
+		}
+		adminServer = &http.Server{
+			Addr:         addr,
+			Handler:      server.NewAdminHandler(sessionMgr, token),
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+		}
+		go func() {
+			log.Printf("Admin API listening on %s", addr)
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Admin server failed: %v", err)
+			}
+		}()
+	}
+
+	// Apply config file changes to the running server
+	stopWatching := make(chan struct{})
+	if !*noReload {
+		go watchConfig(sessionMgr, cfg.Path(), *refresh, stopWatching)
+	}
+
+	// Wait for interrupt signal, or for the stdio client to leave when it is
+	// the only one served
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	for stopping := false; !stopping; {
+		select {
+		case <-sigChan:
+			stopping = true
+		case err := <-stdioDone:
+			if err != nil {
+				log.Printf("Stdio transport: %v", err)
+			}
+			stdioDone = nil
+			if serveHTTP {
+				log.Println("Stdio client disconnected, still serving HTTP")
+			} else {
+				log.Println("Stdio client disconnected")
+				stopping = true
+			}
+		}
+	}
+	close(stopWatching)
+
+	log.Println("Shutting down server...")
+
+	// Drain running executions and jobs while clients can still collect their
+	// results, then close all sessions
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Duration(cfg.GetShutdownTimeout())*time.Second)
+	defer cancelDrain()
+	if err := sessionMgr.Shutdown(drainCtx); err != nil {
+		log.Printf("Error closing sessions: %v", err)
+	}
+
+	// Graceful shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stopStdio()
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Printf("Server shutdown error: %v", err)
+		}
+	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			log.Printf("Admin server shutdown error: %v", err)
+		}
+	}
+
+	if pool != nil {
+		pool.Close()
+	}
+
+	if trail != nil {
+		if err := trail.Close(); err != nil {
+			log.Printf("Error closing audit trail: %v", err)
+		}
+	}
+
+	log.Println("Server stopped")
+	return nil
+}
+
+// How often the config file is checked for changes, and how long applying a
+// changed config may take to connect servers
+const (
+	configPollInterval  = 2 * time.Second
+	configReloadTimeout = 2 * time.Minute
+)
+
+// watchConfig reloads the config file when it or a file it includes changes, or
+// the process receives SIGHUP, until stop is closed. Configs fetched from URLs are
+// fetched again every refresh and applied when they changed.
+func watchConfig(sessionMgr *session.Manager, path string, refresh time.Duration, stop <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	var refreshes <-chan time.Time
+	if refresh > 0 && sessionMgr.Config().IsRemote() {
+		refreshTicker := time.NewTicker(refresh)
+		defer refreshTicker.Stop()
+		refreshes = refreshTicker.C
+	}
+
+	last := configVersion(sessionMgr.Config().Files())
+	for {
+		onlyIfChanged := false
+		select {
+		case <-stop:
+			return
+		case <-hup:
+			log.Println("Received SIGHUP, reloading configuration")
+		case <-ticker.C:
+			if configVersion(sessionMgr.Config().Files()) == last {
+				continue
+			}
+			log.Printf("Configuration file %s changed, reloading", path)
+		case <-refreshes:
+			onlyIfChanged = true
+		}
+		reloadConfig(sessionMgr, path, onlyIfChanged)
+		last = configVersion(sessionMgr.Config().Files())
+	}
+}
+
+// configVersion identifies the contents of the config files by their modification
+// times and sizes; files that cannot be read count as empty
+func configVersion(files []string) string {
+	var version strings.Builder
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			fmt.Fprintf(&version, "%d-%d", info.ModTime().UnixNano(), info.Size())
+		}
+		version.WriteByte(';')
+	}
+	return version.String()
+}
+
+// reloadConfig loads the config file and applies it to the running server, unless
+// onlyIfChanged is set and its contents did not change. An invalid config is
+// logged and the running one is kept.
+func reloadConfig(sessionMgr *session.Manager, path string, onlyIfChanged bool) {
+	cfg, err := config.LoadWithOptions(config.LoadOptions{
+		ConfigPath:        path,
+		AllowEnvOverrides: true,
+		Profile:           sessionMgr.Config().Profile(),
+		RemoteAuth:        os.Getenv(config.RemoteAuthEnv),
+	})
+	if err != nil {
+		log.Printf("Failed to reload config, keeping the running one: %v", err)
+		return
+	}
+	if onlyIfChanged && cfg.Version() == sessionMgr.Config().Version() {
+		return
+	}
+	if onlyIfChanged {
+		log.Printf("Configuration at %s changed, reloading", path)
+	}
+
+	logDisabledServers(cfg)
+	if restart := config.RestartRequired(sessionMgr.Config(), cfg); len(restart) > 0 {
+		log.Printf("Changes to %s take effect after a restart", strings.Join(restart, ", "))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), configReloadTimeout)
+	defer cancel()
+	if err := sessionMgr.Reload(ctx, cfg); err != nil {
+		log.Printf("Config reloaded with errors: %v", err)
+		return
+	}
+	log.Printf("Reloaded configuration with %d MCP server(s)", len(cfg.McpServers))
+}
+
+// logDisabledServers logs the servers of the config left out on this machine
+func logDisabledServers(cfg *config.Config) {
+	disabled := cfg.DisabledServers()
+	names := make([]string, 0, len(disabled))
+	for name := range disabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Printf("Skipping MCP server %q: %s", name, disabled[name])
+	}
+}
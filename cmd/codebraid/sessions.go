@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/config"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// printSessions lists the sessions of a running instance through its admin API
+func printSessions(cfg *config.Config) error {
+	addr := cfg.GetAdminAddr()
+	if addr == "" {
+		return fmt.Errorf("the admin API is not configured (set admin.addr)")
+	}
+	if host, port, err := net.SplitHostPort(addr); err == nil && (host == "" || host == "0.0.0.0" || host == "::") {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/sessions", nil)
+	if err != nil {
+		return err
+	}
+	if token := cfg.GetAdminToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the admin API response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("admin API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var listing struct {
+		Sessions []session.SessionInfo `json:"sessions"`
+	}
+	if err := json.Unmarshal(body, &listing); err != nil {
+		return fmt.Errorf("failed to decode the admin API response: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAGE\tIDLE\tSERVERS\tBUNDLE\tEXECUTIONS\tFAILED\tBUSY")
+	for _, s := range listing.Sessions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d KB\t%d\t%d\t%t\n",
+			s.ID,
+			time.Duration(s.AgeSeconds)*time.Second,
+			time.Duration(s.IdleSeconds)*time.Second,
+			strings.Join(s.Servers, ","),
+			s.BundleBytes>>10,
+			s.Executions,
+			s.FailedExecutions,
+			s.Busy,
+		)
+	}
+	return tw.Flush()
+}
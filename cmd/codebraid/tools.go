@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// listedTool is a tool as "codebraid list-tools -json" prints it
+type listedTool struct {
+	Server      string               `json:"server"`
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Input       string               `json:"input"`
+	Annotations *mcp.ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// runListTools implements "codebraid list-tools": it connects to the configured
+// servers and prints the tools scripts can call
+func runListTools(args []string) error {
+	flags := flag.NewFlagSet("list-tools", flag.ExitOnError)
+	cfgFlags := addConfigFlags(flags)
+	serverList := flags.String("server", "", "Comma-separated servers to list the tools of (default: all)")
+	match := flags.String("match", "", "Only list tools whose name or description contains this text")
+	readOnly := flags.Bool("read-only", false, "Only list tools annotated as read-only")
+	addJSONFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid list-tools [-server a,b] [-match text] [-read-only] [-json]\n\n")
+		fmt.Fprintf(flags.Output(), "Connects to the configured MCP servers and lists their tools.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	cfg, err := cfgFlags.load()
+	if err != nil {
+		return err
+	}
+	var only []string
+	if *serverList != "" {
+		only = strings.Split(*serverList, ",")
+	}
+	hub, err := connectServers(context.Background(), cfg, only)
+	if err != nil {
+		return err
+	}
+	defer hub.Close()
+	cacheTools(hub.Tools())
+
+	text := strings.ToLower(*match)
+	tools := []listedTool{}
+	for server, serverTools := range hub.Tools() {
+		for _, tool := range serverTools {
+			if *readOnly && (tool.Annotations == nil || !tool.Annotations.ReadOnlyHint) {
+				continue
+			}
+			if text != "" && !strings.Contains(strings.ToLower(tool.Name), text) &&
+				!strings.Contains(strings.ToLower(tool.Description), text) {
+				continue
+			}
+			tools = append(tools, listedTool{
+				Server:      server,
+				Name:        tool.Name,
+				Description: tool.Description,
+				Input:       inputSummary(tool.InputSchema),
+				Annotations: tool.Annotations,
+			})
+		}
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		if tools[i].Server != tools[j].Server {
+			return tools[i].Server < tools[j].Server
+		}
+		return tools[i].Name < tools[j].Name
+	})
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tools)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVER\tTOOL\tINPUT\tANNOTATIONS\tDESCRIPTION")
+	for _, tool := range tools {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			tool.Server,
+			tool.Name,
+			truncate(tool.Input, 60),
+			annotationSummary(tool.Annotations),
+			truncate(firstLine(tool.Description), 80),
+		)
+	}
+	return tw.Flush()
+}
+
+// inputSummary describes the arguments of an input schema in one line, in
+// TypeScript's notation: "title: string, labels?: string[]"
+func inputSummary(inputSchema any) string {
+	schema, _ := inputSchema.(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	if len(properties) == 0 {
+		return "-"
+	}
+	required := make(map[string]bool)
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, name := range list {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		property, _ := properties[name].(map[string]interface{})
+		optional := "?"
+		if required[name] {
+			optional = ""
+		}
+		args = append(args, name+optional+": "+schemaTypeName(property))
+	}
+	return strings.Join(args, ", ")
+}
+
+// schemaTypeName names the type of a property schema
+func schemaTypeName(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		if t == "array" {
+			items, _ := schema["items"].(map[string]interface{})
+			return schemaTypeName(items) + "[]"
+		}
+		if t == "integer" {
+			return "number"
+		}
+		return t
+	case []interface{}:
+		names := make([]string, 0, len(t))
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				names = append(names, schemaTypeName(map[string]interface{}{"type": name}))
+			}
+		}
+		return strings.Join(names, " | ")
+	}
+	if _, ok := schema["enum"]; ok {
+		return "enum"
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return "any"
+}
+
+// annotationSummary lists the hints a tool is annotated with
+func annotationSummary(annotations *mcp.ToolAnnotations) string {
+	if annotations == nil {
+		return "-"
+	}
+	var hints []string
+	if annotations.ReadOnlyHint {
+		hints = append(hints, "read-only")
+	}
+	if annotations.DestructiveHint != nil && *annotations.DestructiveHint {
+		hints = append(hints, "destructive")
+	}
+	if annotations.IdempotentHint {
+		hints = append(hints, "idempotent")
+	}
+	if annotations.OpenWorldHint != nil && *annotations.OpenWorldHint {
+		hints = append(hints, "open-world")
+	}
+	if len(hints) == 0 {
+		return "-"
+	}
+	return strings.Join(hints, ",")
+}
+
+// firstLine returns the first line of text
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
+
+// truncate shortens text to at most n runes, marking the cut with "..."
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/cli"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// urlCheckTimeout bounds checking that one server URL is reachable
+const urlCheckTimeout = 10 * time.Second
+
+// validationError is one problem "codebraid validate" found
+type validationError struct {
+	File    string `json:"file,omitempty"`
+	Path    string `json:"path,omitempty"` // JSON path of the setting, e.g. "mcpServers.github.command"
+	Message string `json:"message"`
+}
+
+func (e validationError) String() string {
+	var parts []string
+	for _, part := range []string{e.File, e.Path, e.Message} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ": ")
+}
+
+// runValidate implements "codebraid validate": it loads the config as the server
+// would, resolving its secrets, and checks that its server commands exist, failing
+// with every problem found, for pre-commit hooks and CI
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	cfgFlags := addConfigFlags(flags)
+	checkURLs := flags.Bool("check-urls", false, "Also check that the URLs of http and sse servers are reachable")
+	addJSONFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: codebraid validate [-config file] [-profile name] [-check-urls] [-json] [file]\n\n")
+		fmt.Fprintf(flags.Output(), "Loads and checks a config, exiting with status 3 when it has problems.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return cli.WithCode(cli.ExitUsage, fmt.Errorf("expected at most one config file"))
+	}
+	if flags.NArg() == 1 {
+		*cfgFlags.path = flags.Arg(0)
+	}
+
+	cfg, err := cfgFlags.load()
+	var problems []validationError
+	if err != nil {
+		problems = loadErrors(err)
+	} else {
+		problems = serverProblems(cfg, *checkURLs)
+	}
+
+	if jsonOutput {
+		result := struct {
+			Valid   bool              `json:"valid"`
+			Config  string            `json:"config,omitempty"`
+			Servers int               `json:"servers"`
+			Errors  []validationError `json:"errors"`
+		}{Valid: len(problems) == 0, Errors: problems}
+		if result.Errors == nil {
+			result.Errors = []validationError{}
+		}
+		if cfg != nil {
+			result.Config, result.Servers = cfg.Path(), len(cfg.McpServers)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) == 0 {
+			fmt.Printf("%s is valid (%d server(s))\n", cfg.Path(), len(cfg.McpServers))
+		}
+	}
+	if len(problems) > 0 {
+		return cli.WithReportedCode(cli.ExitConfig, fmt.Errorf("%d problem(s) found", len(problems)))
+	}
+	return nil
+}
+
+// loadErrors splits an error loading a config into its problems
+func loadErrors(err error) []validationError {
+	var schemaErr *config.SchemaError
+	if !errors.As(err, &schemaErr) {
+		return []validationError{{Message: err.Error()}}
+	}
+	problems := make([]validationError, 0, len(schemaErr.Problems))
+	for _, problem := range schemaErr.Problems {
+		path, message, _ := strings.Cut(problem, ": ")
+		problems = append(problems, validationError{File: schemaErr.File, Path: path, Message: message})
+	}
+	return problems
+}
+
+// serverProblems checks that the commands and working directories of the stdio
+// servers exist and, with checkURLs, that the other servers' URLs are reachable
+func serverProblems(cfg *config.Config, checkURLs bool) []validationError {
+	var (
+		problems []validationError
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+	report := func(name, field, format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		problems = append(problems, validationError{
+			Path:    fmt.Sprintf("mcpServers.%s.%s", name, field),
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	for name, server := range cfg.McpServers {
+		if server.Type == "stdio" {
+			cwd, err := config.ExpandHome(server.Cwd)
+			if err != nil {
+				cwd = server.Cwd
+			}
+			if cwd != "" {
+				if info, err := os.Stat(cwd); err != nil || !info.IsDir() {
+					report(name, "cwd", "%q is not a directory", server.Cwd)
+				}
+			}
+			// A command with a directory in it runs relative to the server's cwd,
+			// a bare name is looked up on PATH
+			command := server.Command
+			if cwd != "" && !filepath.IsAbs(command) && strings.ContainsRune(filepath.ToSlash(command), '/') {
+				command = filepath.Join(cwd, command)
+			}
+			if _, err := exec.LookPath(command); err != nil {
+				if command != server.Command {
+					report(name, "command", "%q not found in %s", server.Command, server.Cwd)
+				} else {
+					report(name, "command", "%q not found on PATH", server.Command)
+				}
+			}
+			continue
+		}
+		if checkURLs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := checkURL(server); err != nil {
+					report(name, "url", "%v", err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Path < problems[j].Path })
+	return problems
+}
+
+// checkURL checks that a server's URL answers; any HTTP response will do, as MCP
+// endpoints commonly refuse plain GET requests
+func checkURL(server config.McpServerConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), urlCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range server.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s is not reachable: %w", server.URL, err)
+	}
+	resp.Body.Close()
+	return nil
+}
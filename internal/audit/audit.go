@@ -0,0 +1,258 @@
+// Package audit records every execution to pluggable sinks, keeping the most
+// recent records in memory so operators can query them.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Execution statuses
+const (
+	StatusOK     = "ok"     // The code ran and returned a result
+	StatusError  = "error"  // The code threw, hit a limit, or its run was rejected (busy, type errors)
+	StatusFailed = "failed" // The code could not be run at all
+	StatusCached = "cached" // A cached result was returned without running the code
+)
+
+// Record describes one execution
+type Record struct {
+	Time          time.Time  `json:"time"`
+	SessionID     string     `json:"sessionId"`
+	Language      string     `json:"language"`
+	CodeHash      string     `json:"codeHash"` // sha256 of the full code
+	Code          string     `json:"code"`     // Truncated to the configured limit
+	CodeTruncated bool       `json:"codeTruncated,omitempty"`
+	DurationMs    int64      `json:"durationMs"`
+	Status        string     `json:"status"`
+	DryRun        bool       `json:"dryRun,omitempty"` // Tool calls were planned, not made
+	Error         string     `json:"error,omitempty"`
+	ToolCalls     []ToolCall `json:"toolCalls"`
+}
+
+// ToolCall is a downstream tool call made during an execution
+type ToolCall struct {
+	Server     string                 `json:"server"`
+	Tool       string                 `json:"tool"`
+	Args       map[string]interface{} `json:"args"` // Redacted
+	DurationMs int64                  `json:"durationMs"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Sink receives every record. Write is called from a single goroutine.
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+	Close() error
+}
+
+// Options configure a Trail
+type Options struct {
+	Sinks        []Sink
+	Retain       int      // Records kept in memory for Query
+	MaxCodeBytes int      // Code kept per record
+	RedactKeys   []string // Argument names redacted in addition to DefaultRedactKeys
+}
+
+// queueSize bounds the records waiting for slow sinks; past it records are
+// only kept in memory
+const queueSize = 1024
+
+// sinkTimeout bounds one delivery to a sink
+const sinkTimeout = 10 * time.Second
+
+// Trail keeps recent records in memory and delivers every record to its sinks
+// in the background, so a slow sink never delays an execution.
+// Trail is safe for concurrent use.
+type Trail struct {
+	opts     Options
+	redactor *Redactor
+
+	mu      sync.Mutex
+	records []Record // Oldest first, at most opts.Retain
+
+	queue chan Record
+	done  chan struct{}
+}
+
+// New creates a trail and starts delivering to its sinks
+func New(opts Options) *Trail {
+	t := &Trail{
+		opts:     opts,
+		redactor: NewRedactor(opts.RedactKeys),
+		queue:    make(chan Record, queueSize),
+		done:     make(chan struct{}),
+	}
+	go t.deliver()
+	return t
+}
+
+// Execution collects the record of one execution while it runs
+type Execution struct {
+	trail  *Trail
+	record Record
+	start  time.Time
+
+	mu        sync.Mutex
+	toolCalls []ToolCall
+}
+
+// Start begins the record of an execution; call Finish once it is done
+func (t *Trail) Start(sessionID, language, code string) *Execution {
+	if language == "" {
+		language = "typescript"
+	}
+	sum := sha256.Sum256([]byte(code))
+	record := Record{
+		SessionID: sessionID,
+		Language:  language,
+		CodeHash:  hex.EncodeToString(sum[:]),
+		Code:      code,
+	}
+	if max := t.opts.MaxCodeBytes; max > 0 && len(code) > max {
+		cut := max
+		for cut > 0 && !utf8.RuneStart(code[cut]) {
+			cut--
+		}
+		record.Code = code[:cut]
+		record.CodeTruncated = true
+	}
+	start := time.Now()
+	record.Time = start.UTC()
+	return &Execution{trail: t, record: record, start: start}
+}
+
+// MarkDryRun records that the execution's tool calls were not sent to the servers
+func (e *Execution) MarkDryRun() {
+	e.record.DryRun = true
+}
+
+// ToolCall adds a downstream call to the record, redacting its arguments
+func (e *Execution) ToolCall(server, tool string, args map[string]interface{}, duration time.Duration, errText string) {
+	call := ToolCall{
+		Server:     server,
+		Tool:       tool,
+		Args:       e.trail.redactor.Redact(args),
+		DurationMs: duration.Milliseconds(),
+		Error:      errText,
+	}
+	e.mu.Lock()
+	e.toolCalls = append(e.toolCalls, call)
+	e.mu.Unlock()
+}
+
+// Finish completes the record with the execution's status and stores it
+func (e *Execution) Finish(status, errText string) {
+	record := e.record
+	record.DurationMs = time.Since(e.start).Milliseconds()
+	record.Status = status
+	record.Error = errText
+
+	e.mu.Lock()
+	record.ToolCalls = append([]ToolCall{}, e.toolCalls...)
+	e.mu.Unlock()
+
+	e.trail.add(record)
+}
+
+// add stores a record and queues it for the sinks
+func (t *Trail) add(record Record) {
+	t.mu.Lock()
+	t.records = append(t.records, record)
+	if over := len(t.records) - t.opts.Retain; over > 0 {
+		t.records = append([]Record(nil), t.records[over:]...)
+	}
+	t.mu.Unlock()
+
+	if len(t.opts.Sinks) == 0 {
+		return
+	}
+	select {
+	case t.queue <- record:
+	default:
+		log.Printf("Audit queue full, record of session %s not delivered to sinks", record.SessionID)
+	}
+}
+
+// deliver writes queued records to the sinks until the trail is closed
+func (t *Trail) deliver() {
+	defer close(t.done)
+	for record := range t.queue {
+		for _, sink := range t.opts.Sinks {
+			ctx, cancel := context.WithTimeout(context.Background(), sinkTimeout)
+			if err := sink.Write(ctx, record); err != nil {
+				log.Printf("Failed to write audit record: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Filter selects records in Query; zero fields match every record
+type Filter struct {
+	SessionID string
+	Status    string
+	CodeHash  string
+	Tool      string // "server" or "server.tool" called during the execution
+	Since     time.Time
+	Limit     int // Most recent records returned
+}
+
+// Query returns the retained records matching filter, oldest first
+func (t *Trail) Query(filter Filter) []Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var matched []Record
+	for _, record := range t.records {
+		if filter.matches(record) {
+			matched = append(matched, record)
+		}
+	}
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[len(matched)-filter.Limit:]
+	}
+	return matched
+}
+
+// matches reports whether record passes the filter
+func (f Filter) matches(record Record) bool {
+	switch {
+	case f.SessionID != "" && record.SessionID != f.SessionID:
+		return false
+	case f.Status != "" && record.Status != f.Status:
+		return false
+	case f.CodeHash != "" && !strings.HasPrefix(record.CodeHash, f.CodeHash):
+		return false
+	case !f.Since.IsZero() && record.Time.Before(f.Since):
+		return false
+	}
+	if f.Tool == "" {
+		return true
+	}
+	for _, call := range record.ToolCalls {
+		if call.Server == f.Tool || call.Server+"."+call.Tool == f.Tool {
+			return true
+		}
+	}
+	return false
+}
+
+// Close delivers the queued records and closes the sinks
+func (t *Trail) Close() error {
+	close(t.queue)
+	<-t.done
+
+	var firstErr error
+	for _, sink := range t.opts.Sinks {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
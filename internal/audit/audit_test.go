@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRedactNestedKeys(t *testing.T) {
+	r := NewRedactor([]string{"ssn"})
+	args := map[string]interface{}{
+		"query": "select 1",
+		"auth": map[string]interface{}{
+			"apiKey": "abc",
+			"user":   "me",
+		},
+		"items":    []interface{}{map[string]interface{}{"SSN": "123"}},
+		"password": "hunter2",
+	}
+
+	redactedArgs := r.Redact(args)
+	if redactedArgs["query"] != "select 1" || redactedArgs["password"] != redacted {
+		t.Fatalf("unexpected top level: %v", redactedArgs)
+	}
+	auth := redactedArgs["auth"].(map[string]interface{})
+	if auth["apiKey"] != redacted || auth["user"] != "me" {
+		t.Fatalf("nested key not redacted: %v", auth)
+	}
+	item := redactedArgs["items"].([]interface{})[0].(map[string]interface{})
+	if item["SSN"] != redacted {
+		t.Fatalf("extra key not redacted: %v", item)
+	}
+	if args["password"] != "hunter2" {
+		t.Fatal("the original arguments were modified")
+	}
+}
+
+func TestCodeTruncatedToLimit(t *testing.T) {
+	trail := New(Options{Retain: 10, MaxCodeBytes: 2})
+	defer trail.Close()
+
+	trail.Start("s1", "", "héllo world").Finish(StatusOK, "")
+	record := trail.Query(Filter{})[0]
+	if record.Code != "h" || !record.CodeTruncated {
+		t.Fatalf("expected the code cut on a character boundary, got %q", record.Code)
+	}
+	if record.Language != "typescript" || len(record.CodeHash) != 64 {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	trail := New(Options{Retain: 2})
+	defer trail.Close()
+
+	trail.Start("s1", "", "a").Finish(StatusOK, "")
+	execution := trail.Start("s2", "", "b")
+	execution.ToolCall("github", "list_repos", nil, time.Millisecond, "")
+	execution.Finish(StatusError, "boom")
+	trail.Start("s1", "", "c").Finish(StatusOK, "")
+
+	if records := trail.Query(Filter{}); len(records) != 2 || records[0].SessionID != "s2" {
+		t.Fatalf("expected the oldest record discarded, got %+v", records)
+	}
+	if records := trail.Query(Filter{Tool: "github.list_repos"}); len(records) != 1 || records[0].Error != "boom" {
+		t.Fatalf("expected the record calling the tool, got %+v", records)
+	}
+	if records := trail.Query(Filter{SessionID: "s1", Status: StatusError}); len(records) != 0 {
+		t.Fatalf("expected no match, got %+v", records)
+	}
+	if records := trail.Query(Filter{Limit: 1}); len(records) != 1 || records[0].SessionID != "s1" {
+		t.Fatalf("expected the most recent record, got %+v", records)
+	}
+}
+
+func TestFileSinkWritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trail := New(Options{Sinks: []Sink{sink}, Retain: 10})
+	trail.Start("s1", "python", "1").Finish(StatusOK, "")
+	trail.Start("s2", "", "2").Finish(StatusFailed, "no runtime")
+	if err := trail.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 || records[0].Language != "python" || records[1].Status != StatusFailed {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+}
@@ -0,0 +1,70 @@
+package audit
+
+import "strings"
+
+// DefaultRedactKeys are the argument names whose values are never recorded.
+// A key matches when its lowercase form contains one of them.
+var DefaultRedactKeys = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "authorization", "credential", "cookie", "privatekey", "private_key"}
+
+// redacted replaces the value of a redacted argument
+const redacted = "[REDACTED]"
+
+// Redactor removes sensitive values from tool call arguments
+type Redactor struct {
+	keys []string
+}
+
+// NewRedactor creates a redactor for DefaultRedactKeys and extra
+func NewRedactor(extra []string) *Redactor {
+	keys := append([]string{}, DefaultRedactKeys...)
+	for _, key := range extra {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return &Redactor{keys: keys}
+}
+
+// Redact returns a copy of args where the values of sensitive keys, at any
+// depth, are replaced by [REDACTED]
+func (r *Redactor) Redact(args map[string]interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	redactedArgs, _ := r.redactValue(args).(map[string]interface{})
+	return redactedArgs
+}
+
+// redactValue copies a decoded JSON value, redacting sensitive keys of its objects
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if r.sensitive(key) {
+				out[key] = redacted
+				continue
+			}
+			out[key] = r.redactValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.redactValue(item)
+		}
+		return out
+	}
+	return value
+}
+
+// sensitive reports whether values of key are redacted
+func (r *Redactor) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range r.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// FileSink appends records to a file as JSON lines
+type FileSink struct {
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it and its directory if needed
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log dir: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Write implements Sink
+func (s *FileSink) Write(ctx context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close implements Sink
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// WebhookSink posts every record as JSON to a URL
+type WebhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhookSink creates a sink posting to url with the given extra headers
+func NewWebhookSink(url string, headers map[string]string) *WebhookSink {
+	return &WebhookSink{url: url, headers: headers, client: &http.Client{}}
+}
+
+// Write implements Sink
+func (s *WebhookSink) Write(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.url, s.headers, body)
+}
+
+// Close implements Sink
+func (s *WebhookSink) Close() error {
+	return nil
+}
+
+// OTLPSink exports records as OpenTelemetry log records over OTLP/HTTP with
+// JSON encoding, e.g. to a collector's http://localhost:4318/v1/logs
+type OTLPSink struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewOTLPSink creates a sink exporting to an OTLP/HTTP logs endpoint
+func NewOTLPSink(endpoint string, headers map[string]string) *OTLPSink {
+	return &OTLPSink{endpoint: endpoint, headers: headers, client: &http.Client{}}
+}
+
+// Write implements Sink
+func (s *OTLPSink) Write(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	severity, severityNumber := "INFO", 9
+	if record.Status != StatusOK && record.Status != StatusCached {
+		severity, severityNumber = "WARN", 13
+	}
+	attributes := []otlpAttribute{
+		stringAttribute("codebraid.session_id", record.SessionID),
+		stringAttribute("codebraid.language", record.Language),
+		stringAttribute("codebraid.code_hash", record.CodeHash),
+		stringAttribute("codebraid.status", record.Status),
+		intAttribute("codebraid.duration_ms", record.DurationMs),
+		intAttribute("codebraid.tool_calls", int64(len(record.ToolCalls))),
+	}
+	export := map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpAttribute{stringAttribute("service.name", "codebraid-mcp")},
+			},
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "codebraid/audit"},
+				"logRecords": []interface{}{map[string]interface{}{
+					"timeUnixNano":   strconv.FormatInt(record.Time.UnixNano(), 10),
+					"severityText":   severity,
+					"severityNumber": severityNumber,
+					"body":           map[string]string{"stringValue": string(body)},
+					"attributes":     attributes,
+				}},
+			}},
+		}},
+	}
+	payload, err := json.Marshal(export)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.endpoint, s.headers, payload)
+}
+
+// Close implements Sink
+func (s *OTLPSink) Close() error {
+	return nil
+}
+
+// otlpAttribute is an OTLP KeyValue in its JSON encoding
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]string{"stringValue": value}}
+}
+
+// intAttribute encodes value as a string, as OTLP/JSON does for 64-bit integers
+func intAttribute(key string, value int64) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]string{"intValue": strconv.FormatInt(value, 10)}}
+}
+
+// post sends a JSON body and fails unless the response is a 2xx
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded %s", url, resp.Status)
+	}
+	return nil
+}
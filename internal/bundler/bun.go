@@ -0,0 +1,73 @@
+package bundler
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// bunToolchain bundles with "bun build".
+// Bun transpiles TypeScript natively, so no swc loader or config file is involved.
+type bunToolchain struct {
+	path string
+}
+
+func (bunToolchain) Name() string { return ToolchainBun }
+
+func (t bunToolchain) Bundle(entry Entry, libDir string, opts BundleOptions) (Output, error) {
+	start := time.Now()
+	w, err := newWorkDir(entry, libDir, opts)
+	if err != nil {
+		return Output{}, err
+	}
+	defer w.remove()
+
+	// Bun resolves aliases through the tsconfig paths of the work directory
+	if len(opts.Transform.Alias) > 0 {
+		tsconfig := fmt.Sprintf(`{"compilerOptions": {"paths": %s}}`, aliasPaths(opts.Transform.Alias))
+		if err := os.WriteFile(filepath.Join(w.dir, "tsconfig.json"), []byte(tsconfig), 0644); err != nil {
+			return Output{}, fmt.Errorf("failed to write tsconfig: %w", err)
+		}
+	}
+
+	sourcemap := "none"
+	if opts.Transform.Sourcemap {
+		sourcemap = "external"
+	}
+	args := []string{"build", w.entry,
+		"--outdir", w.dist(),
+		"--target", "bun",
+		"--format", strings.ToLower(opts.Transform.Format),
+		"--sourcemap=" + sourcemap,
+	}
+	if opts.Transform.Minify {
+		args = append(args, "--minify")
+	}
+	for _, external := range opts.Transform.External {
+		args = append(args, "--external", external)
+	}
+	for _, name := range sortedNames(opts.Transform.Define) {
+		args = append(args, "--define", name+"="+opts.Transform.Define[name])
+	}
+	cmd := exec.Command(t.path, args...)
+
+	var output bytes.Buffer
+	cmd.Dir = w.dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		return Output{}, &BuildError{Stage: "bun build", Diagnostics: parseBunErrors(output.String(), w.dir, libDir), Output: output.String()}
+	}
+
+	out, err := w.output("index.js", opts.Transform.Sourcemap)
+	if err != nil {
+		return Output{}, err
+	}
+	out.Stats = Stats{Duration: time.Since(start), Bytes: len(out.JS)}
+	return out, nil
+}
@@ -1,152 +1,195 @@
 package bundler
 
 import (
-	"bytes"
 	"crypto/rand"
-	_ "embed"
 	"encoding/hex"
 	"fmt"
-	"os"
+	"log"
 	"os/exec"
-	"path/filepath"
 	"sync"
+	"time"
 )
 
 var (
 	globalRspackPath string
-	rspackInitOnce   sync.Once
-	rspackInitError  error
+	globalBunPath    string
+	initOnce         sync.Once
 )
 
-// Bundler handles TypeScript to JavaScript transformation using Rspack/SWC
+// Bundler bundles TypeScript to JavaScript with a Toolchain, adding the bundle
+// cache and npm package allowlist shared by all toolchains
 type Bundler struct {
-	rspackPath string
+	toolchain Toolchain
+	cache     *Cache    // Optional bundle cache, see EnableCache
+	packages  *Packages // Optional npm packages, see EnablePackages
+	transform TransformOptions
+	maxBytes  int // Largest bundle allowed, see SetMaxBundleSize; zero means no limit
 }
 
-// embeddedRspackConfig is the bundler configuration embedded in the binary
-//
-//go:embed rspack.config.ts
-var embeddedRspackConfig string
+var globalMaxBundleBytes int
 
-// Initialize finds and caches the rspack executable path
+// SetMaxBundleSize limits the size of bundles produced by bundlers created afterwards.
+// Zero removes the limit. Should be called once at application startup.
+func SetMaxBundleSize(maxBytes int) {
+	globalMaxBundleBytes = maxBytes
+}
+
+// SizeError reports a bundle larger than the configured limit
+type SizeError struct {
+	Bytes int // Size of the bundle
+	Limit int // Largest bundle allowed
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("bundle is %d KB, over the %d KB limit; import fewer server libraries or packages",
+		(e.Bytes+1023)>>10, e.Limit>>10)
+}
+
+// Initialize finds and caches the rspack and bun executable paths
 // Should be called once at application startup
+// esbuild is built into the binary, so bundling works without either
 func Initialize() error {
-	rspackInitOnce.Do(func() {
-		globalRspackPath, rspackInitError = findRspack()
+	initOnce.Do(func() {
+		if path, err := findRspack(); err == nil {
+			globalRspackPath = path
+		}
+		if path, err := exec.LookPath("bun"); err == nil {
+			globalBunPath = path
+		}
 	})
-	return rspackInitError
+	return nil
 }
 
-// GetRspackPath returns the cached rspack path
+// GetRspackPath returns the cached rspack path ("npx" when it runs through npx)
 func GetRspackPath() (string, error) {
 	if globalRspackPath == "" {
-		return "", fmt.Errorf("rspack not initialized - call Initialize() first")
+		return "", fmt.Errorf("rspack not found - install it with: npm install -g @rspack/cli @rspack/core")
 	}
 	return globalRspackPath, nil
 }
 
-// New creates a new bundler instance with pre-located rspack
-func New() (*Bundler, error) {
-	rspackPath, err := GetRspackPath()
+// GetBunPath returns the cached bun path
+func GetBunPath() (string, error) {
+	if globalBunPath == "" {
+		return "", fmt.Errorf("bun not found - install it from https://bun.sh")
+	}
+	return globalBunPath, nil
+}
+
+// New creates a bundler using the named toolchain (one of the Toolchain* constants)
+func New(toolchain string) (*Bundler, error) {
+	t, err := NewToolchain(toolchain)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Bundler{
-		rspackPath: rspackPath,
+		toolchain: t,
+		cache:     globalCache,
+		packages:  globalPackages,
+		transform: globalTransform,
+		maxBytes:  globalMaxBundleBytes,
 	}, nil
 }
 
-// GetEmbeddedConfig returns the embedded rspack configuration
-func GetEmbeddedConfig() string {
-	return embeddedRspackConfig
-}
-
-// findRspack attempts to locate the rspack executable
-func findRspack() (string, error) {
-	// Try common locations
-	candidates := []string{
-		"rspack", // In PATH
-		"npx",    // Use npx to run @rspack/cli
-		filepath.Join(os.Getenv("HOME"), ".nvm", "versions", "node", "*", "bin", "rspack"),
-	}
-
-	for _, candidate := range candidates {
-		if candidate == "npx" {
-			// Check if npx is available
-			if _, err := exec.LookPath("npx"); err == nil {
-				return "npx", nil
-			}
-		} else {
-			if path, err := exec.LookPath(candidate); err == nil {
-				return path, nil
-			}
-		}
-	}
-
-	return "", fmt.Errorf("rspack executable not found")
+// Toolchain returns the name of the toolchain the bundler uses
+func (b *Bundler) Toolchain() string {
+	return b.toolchain.Name()
 }
 
 // BundleWithSession bundles TypeScript code using a session's bundle directory
 // This allows reuse of server library files across multiple requests in the same session
+// With the bundle cache enabled, code bundled before against the same libraries is returned
+// without running the transform or the bundler.
 func (b *Bundler) BundleWithSession(sessionBundleDir, code string) (js string, sourceMap string, err error) {
-	// Create unique work directory for this request
-	workID, err := generateWorkID()
+	return b.BundleWithFiles(sessionBundleDir, code, nil)
+}
+
+// BundleWithFiles bundles code as the entry point together with helper modules,
+// keyed by relative path (e.g. "lib/math.ts"), which the entry imports relatively
+func (b *Bundler) BundleWithFiles(sessionBundleDir, code string, files map[string]string) (js string, sourceMap string, err error) {
+	out, err := b.Bundle(sessionBundleDir, code, files, false)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate work ID: %w", err)
+		return "", "", err
 	}
+	return out.JS, out.SourceMap, nil
+}
 
-	workDir := filepath.Join(sessionBundleDir, "work", workID)
-	if err := os.MkdirAll(workDir, 0755); err != nil {
-		return "", "", fmt.Errorf("failed to create work dir: %w", err)
+// Bundle bundles like BundleWithFiles and also returns the bundle's stats.
+// With analyze set the bundle cache is not read, so the stats always include
+// the toolchain's per-module report. Bundles over the size limit are rejected
+// with a *SizeError, returned together with the bundle so its stats can be reported.
+func (b *Bundler) Bundle(sessionBundleDir, code string, files map[string]string, analyze bool) (Output, error) {
+	if err := validateFiles(files, entryName(b.transform)); err != nil {
+		return Output{}, err
 	}
-	defer os.RemoveAll(workDir)
 
-	// Symlink to shared servers directory
-	serversSrc := filepath.Join(sessionBundleDir, "servers")
-	serversDst := filepath.Join(workDir, "servers")
-	if err := os.Symlink(serversSrc, serversDst); err != nil {
-		return "", "", fmt.Errorf("failed to create servers symlink: %w", err)
+	// Leave out the libraries of servers the code does not import
+	servers, ok := importedServers(code, files, b.transform)
+	if !ok {
+		servers = nil
 	}
 
-	// Write user code
-	indexPath := filepath.Join(workDir, "index.ts")
-	if err := os.WriteFile(indexPath, []byte(code), 0644); err != nil {
-		return "", "", fmt.Errorf("failed to write user code: %w", err)
+	var cacheKey string
+	if b.cache != nil {
+		settings, err := b.cacheSettings()
+		if err == nil {
+			cacheKey, err = b.cache.key(b.toolchain.Name(), settings, sessionBundleDir, moduleSource(code, files), servers)
+		}
+		if err != nil {
+			log.Printf("Bundle cache disabled for this request: %v", err)
+			cacheKey = ""
+		} else if js, sourceMap, ok := b.cache.Get(cacheKey); ok && !analyze {
+			out := Output{JS: js, SourceMap: sourceMap, Stats: Stats{Bytes: len(js), Cached: true}}
+			return out, b.checkSize(out)
+		}
 	}
 
-	// Use session-level config (absolute path)
-	configPath := filepath.Join(sessionBundleDir, "rspack.config.ts")
-	outputDir := filepath.Join(workDir, "dist")
+	out, err := b.toolchain.Bundle(Entry{Code: code, Files: files, Servers: servers}, sessionBundleDir, BundleOptions{
+		Transform: b.transform,
+		Packages:  b.packages,
+	})
+	if err != nil {
+		return Output{}, err
+	}
+	summary := fmt.Sprintf("%d bytes", out.Stats.Bytes)
+	if out.Stats.Modules > 0 {
+		summary += fmt.Sprintf(", %d modules", out.Stats.Modules)
+	}
+	log.Printf("Bundled with %s in %s (%s)", b.toolchain.Name(), out.Stats.Duration.Round(time.Millisecond), summary)
 
-	// Execute Rspack
-	var cmd *exec.Cmd
-	if b.rspackPath == "npx" {
-		cmd = exec.Command("npx", "-y", "@rspack/cli", "--entry", indexPath, "--config", configPath, "--output-path", outputDir)
-	} else {
-		cmd = exec.Command(b.rspackPath, "--entry", indexPath, "--config", configPath, "--output-path", outputDir)
+	if cacheKey != "" {
+		if err := b.cache.Put(cacheKey, out.JS, out.SourceMap); err != nil {
+			log.Printf("Failed to cache bundle: %v", err)
+		}
 	}
 
-	var stdout bytes.Buffer
-	cmd.Dir = workDir
-	cmd.Stdout = &stdout
+	return out, b.checkSize(out)
+}
 
-	if err := cmd.Run(); err != nil {
-		return "", "", fmt.Errorf("rspack failed: %w\nOutput: %s", err, stdout.String())
+// checkSize rejects a bundle over the size limit
+func (b *Bundler) checkSize(out Output) error {
+	if b.maxBytes > 0 && out.Stats.Bytes > b.maxBytes {
+		return &SizeError{Bytes: out.Stats.Bytes, Limit: b.maxBytes}
 	}
+	return nil
+}
 
-	// Read outputs
-	jsBytes, err := os.ReadFile(filepath.Join(outputDir, "main.js"))
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read bundled JS: %w", err)
+// cacheSettings renders the settings besides the code that bundles depend on,
+// for the bundle cache key
+func (b *Bundler) cacheSettings() (string, error) {
+	settings := "transform:" + b.transform.fingerprint() + "\n"
+	if b.packages != nil {
+		settings += "packages:" + b.packages.Key() + "\n"
 	}
-
-	sourceMapBytes, err := os.ReadFile(filepath.Join(outputDir, "main.js.map"))
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read source map: %w", err)
+	if b.toolchain.Name() == ToolchainRspack {
+		hash, err := rspackTemplateHash()
+		if err != nil {
+			return "", err
+		}
+		settings += "rspack:" + hash + "\n"
 	}
-
-	return string(jsBytes), string(sourceMapBytes), nil
+	return settings, nil
 }
 
 // generateWorkID creates a unique identifier for a work directory
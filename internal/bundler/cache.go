@@ -0,0 +1,307 @@
+package bundler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores bundles on disk keyed by a content hash of everything that affects them.
+// Entries are evicted least recently used first once the cache grows past its size limit;
+// a hit refreshes the entry's modification time, which serves as its last-use time.
+// Cache is safe for concurrent use within a process.
+type Cache struct {
+	dir      string
+	maxBytes int64
+	mu       sync.Mutex
+}
+
+var globalCache *Cache
+
+// EnableCache turns on the bundle cache for bundlers created afterwards.
+// Should be called once at application startup, after Initialize.
+func EnableCache(dir string, maxBytes int64) error {
+	cache, err := NewCache(dir, maxBytes)
+	if err != nil {
+		return err
+	}
+	globalCache = cache
+	return nil
+}
+
+// NewCache creates a bundle cache in dir holding at most maxBytes of bundles
+func NewCache(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bundle cache dir: %w", err)
+	}
+	return &Cache{dir: dir, maxBytes: maxBytes}, nil
+}
+
+// cacheKey hashes the inputs of one bundle: the toolchain, its configuration
+// (including the allowed package set), the session's generated libraries and the user code
+func cacheKey(toolchain, config, libHash, code string) string {
+	h := sha256.New()
+	for _, part := range []string{toolchain, config, libHash, code} {
+		// Length prefixes keep distinct inputs from hashing alike when concatenated
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LibManifestHash hashes the path and content of every file under dir, so it
+// changes whenever a session's generated libraries do
+func LibManifestHash(dir string) (string, error) {
+	hash, err := hashTree(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash server libraries: %w", err)
+	}
+	return hash, nil
+}
+
+// hashTree hashes the path and content of every file under dir
+func hashTree(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			// Links (e.g. node_modules/.bin entries) are hashed by target, not followed
+			target, err := os.Readlink(path)
+			fmt.Fprintf(h, "%s\x00->%s\x00", filepath.ToSlash(rel), target)
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		_, err = io.Copy(h, f)
+		h.Write([]byte{0})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LibManifestFile is the file in a session bundle directory recording the hash
+// of each server's generated libraries; see WriteLibManifest
+const LibManifestFile = "libs.manifest.json"
+
+// WriteLibManifest records the hash of every entry of the session's servers
+// directory, one per server library plus the shared files, so cache keys are
+// derived from one small file instead of reading every library on each request.
+// It must be called whenever the session's libraries are (re)generated.
+func WriteLibManifest(sessionBundleDir string) error {
+	serversDir := filepath.Join(sessionBundleDir, "servers")
+	entries, err := os.ReadDir(serversDir)
+	if err != nil {
+		return fmt.Errorf("failed to read server libraries: %w", err)
+	}
+
+	manifest := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		path := filepath.Join(serversDir, entry.Name())
+		var hash string
+		if entry.IsDir() {
+			if hash, err = LibManifestHash(path); err != nil {
+				return err
+			}
+		} else {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to hash server libraries: %w", err)
+			}
+			sum := sha256.Sum256(data)
+			hash = hex.EncodeToString(sum[:])
+		}
+		manifest[entry.Name()] = hash
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(sessionBundleDir, LibManifestFile), data); err != nil {
+		return fmt.Errorf("failed to write lib manifest: %w", err)
+	}
+	return nil
+}
+
+// SessionLibHash returns the hash of a session's generated TypeScript libraries:
+// that of its lib manifest, or of the libraries themselves when there is none
+func SessionLibHash(sessionBundleDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(sessionBundleDir, LibManifestFile))
+	if os.IsNotExist(err) {
+		return LibManifestHash(filepath.Join(sessionBundleDir, "servers"))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read lib manifest: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// key derives the cache key for bundling code in a session's bundle directory.
+// settings holds the bundler options that change the output, e.g. transform options.
+// With servers set, only the libraries of those servers are part of the key.
+// It fails when the inputs cannot be read, in which case the bundle is not cached.
+func (c *Cache) key(toolchain, settings, sessionBundleDir, code string, servers []string) (string, error) {
+	libHash, err := SessionLibHash(sessionBundleDir)
+	if servers != nil {
+		libHash, err = serverLibHash(sessionBundleDir, servers)
+	}
+	if err != nil {
+		return "", err
+	}
+	return cacheKey(toolchain, settings, libHash, code), nil
+}
+
+// Get returns the cached bundle and source map for key
+func (c *Cache) Get(key string) (js string, sourceMap string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jsPath, mapPath := c.paths(key)
+	jsBytes, err := os.ReadFile(jsPath)
+	if err != nil {
+		return "", "", false
+	}
+	mapBytes, err := os.ReadFile(mapPath)
+	if err != nil {
+		return "", "", false
+	}
+
+	now := time.Now()
+	os.Chtimes(jsPath, now, now)
+	os.Chtimes(mapPath, now, now)
+	return string(jsBytes), string(mapBytes), true
+}
+
+// Put stores a bundle under key and evicts old entries if the cache is over its limit
+func (c *Cache) Put(key, js, sourceMap string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jsPath, mapPath := c.paths(key)
+	// Write the map first: Get treats a bundle without its map as a miss
+	if err := writeFileAtomic(mapPath, []byte(sourceMap)); err != nil {
+		return fmt.Errorf("failed to cache source map: %w", err)
+	}
+	if err := writeFileAtomic(jsPath, []byte(js)); err != nil {
+		return fmt.Errorf("failed to cache bundle: %w", err)
+	}
+
+	c.evict()
+	return nil
+}
+
+// paths returns the files holding the bundle and source map for key
+func (c *Cache) paths(key string) (jsPath, mapPath string) {
+	jsPath = filepath.Join(c.dir, key+".js")
+	return jsPath, jsPath + ".map"
+}
+
+// evict removes least recently used entries until the cache fits in maxBytes
+func (c *Cache) evict() {
+	if c.maxBytes <= 0 {
+		return
+	}
+
+	type entry struct {
+		key     string
+		size    int64
+		lastUse time.Time
+	}
+	entries := map[string]*entry{}
+	var total int64
+
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		log.Printf("Failed to read bundle cache dir: %v", err)
+		return
+	}
+	for _, file := range files {
+		name := file.Name()
+		key, _, found := strings.Cut(name, ".")
+		if !found || key == "" || file.IsDir() {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		e := entries[key]
+		if e == nil {
+			e = &entry{key: key}
+			entries[key] = e
+		}
+		e.size += info.Size()
+		if info.ModTime().After(e.lastUse) {
+			e.lastUse = info.ModTime()
+		}
+		total += info.Size()
+	}
+	if total <= c.maxBytes {
+		return
+	}
+
+	sorted := make([]*entry, 0, len(entries))
+	for _, e := range entries {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].lastUse.Before(sorted[j].lastUse)
+	})
+
+	for _, e := range sorted {
+		if total <= c.maxBytes {
+			break
+		}
+		jsPath, mapPath := c.paths(e.key)
+		os.Remove(jsPath)
+		os.Remove(mapPath)
+		total -= e.size
+	}
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so concurrent readers never see a partially written entry
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,73 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionLibHashUsesManifest(t *testing.T) {
+	newSession := func() string {
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, "servers", "github"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "servers", "github", "index.ts"), []byte("export {};"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "servers", "index.ts"), []byte("export * from './github';"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	first, second := newSession(), newSession()
+	for _, dir := range []string{first, second} {
+		if err := WriteLibManifest(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	firstHash, err := SessionLibHash(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondHash, err := SessionLibHash(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if firstHash != secondHash {
+		t.Fatalf("sessions with the same libraries hash differently: %s and %s", firstHash, secondHash)
+	}
+
+	if err := os.WriteFile(filepath.Join(second, "servers", "github", "index.ts"), []byte("export const x = 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLibManifest(second); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := SessionLibHash(second); changed == firstHash {
+		t.Fatal("expected the hash to change with the libraries")
+	}
+}
+
+func TestSessionLibHashWithoutManifest(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "servers"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "servers", "index.ts"), []byte("export {};"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := SessionLibHash(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := LibManifestHash(filepath.Join(dir, "servers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != want {
+		t.Fatalf("expected the walked hash %s, got %s", want, hash)
+	}
+}
@@ -0,0 +1,143 @@
+package bundler
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// BuildError is a failed transform or bundle, with the toolchain's errors parsed
+// into diagnostics so callers can point at the offending code
+type BuildError struct {
+	Stage       string       // What failed, e.g. "transform" or "esbuild"
+	Diagnostics []Diagnostic // Parsed errors; empty when the output could not be parsed
+	Output      string       // Raw toolchain output, reported when nothing was parsed
+}
+
+func (e *BuildError) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return fmt.Sprintf("%s failed:\n%s", e.Stage, strings.TrimSpace(e.Output))
+	}
+	lines := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		if d.File == "" {
+			lines = append(lines, d.Message)
+		} else {
+			lines = append(lines, fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message))
+		}
+	}
+	return fmt.Sprintf("%s failed:\n%s", e.Stage, strings.Join(lines, "\n"))
+}
+
+// esbuildError converts esbuild messages into a BuildError. Columns become 1-based
+// like tsc's; the suggestion is esbuild's replacement text or else its notes.
+func esbuildError(stage string, messages []api.Message) *BuildError {
+	e := &BuildError{Stage: stage, Diagnostics: make([]Diagnostic, 0, len(messages))}
+	for _, msg := range messages {
+		d := Diagnostic{Code: msg.ID, Message: msg.Text}
+		if msg.Location != nil {
+			d.File = filepath.ToSlash(msg.Location.File)
+			d.Line = msg.Location.Line
+			d.Column = msg.Location.Column + 1
+			if msg.Location.Suggestion != "" {
+				d.Suggestion = fmt.Sprintf("replace with %q", msg.Location.Suggestion)
+			}
+		}
+		if d.Suggestion == "" {
+			notes := make([]string, 0, len(msg.Notes))
+			for _, note := range msg.Notes {
+				notes = append(notes, note.Text)
+			}
+			d.Suggestion = strings.Join(notes, " ")
+		}
+		e.Diagnostics = append(e.Diagnostics, d)
+	}
+	return e
+}
+
+var (
+	// bunErrorLine starts a bun build error; bunLocation follows it
+	bunErrorLine = regexp.MustCompile(`^error: (.*)$`)
+	bunLocation  = regexp.MustCompile(`^\s+at (.+):(\d+):(\d+)$`)
+
+	// rspackErrorLine starts an rspack error: "ERROR in ./index.ts 3:0-35".
+	// The message follows on the next line, prefixed with "×" by rspack's renderer.
+	rspackErrorLine = regexp.MustCompile(`^ERROR in (\S+)(?: (\d+):(\d+)(?:-[\d:]+)?)?`)
+	rspackHelpLine  = regexp.MustCompile(`^\s*help: (.*)$`)
+)
+
+// parseBunErrors extracts the errors from "bun build" output. File paths are made
+// relative to the work directory, or to the session bundle directory for libraries.
+// A trailing "Maybe ..." hint becomes the suggestion.
+func parseBunErrors(output, workDir, libDir string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := bunErrorLine.FindStringSubmatch(line); m != nil {
+			message, suggestion, _ := strings.Cut(m[1], ". Maybe ")
+			if suggestion != "" {
+				suggestion = "Maybe " + suggestion
+			}
+			diagnostics = append(diagnostics, Diagnostic{Message: message, Suggestion: suggestion})
+			continue
+		}
+		if m := bunLocation.FindStringSubmatch(line); m != nil && len(diagnostics) > 0 {
+			d := &diagnostics[len(diagnostics)-1]
+			if d.File == "" {
+				d.File = workspacePath(m[1], workDir, libDir)
+				d.Line, _ = strconv.Atoi(m[2])
+				d.Column, _ = strconv.Atoi(m[3])
+			}
+		}
+	}
+	return diagnostics
+}
+
+// parseRspackErrors extracts the errors from rspack CLI output. Module paths are
+// already relative to the work directory; rspack's 0-based columns become 1-based.
+// "help:" lines become the suggestion.
+func parseRspackErrors(output string) []Diagnostic {
+	var diagnostics []Diagnostic
+	var current *Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := rspackErrorLine.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{File: strings.TrimPrefix(m[1], "./")}
+			if m[2] != "" {
+				d.Line, _ = strconv.Atoi(m[2])
+				column, _ := strconv.Atoi(m[3])
+				d.Column = column + 1
+			}
+			diagnostics = append(diagnostics, d)
+			current = &diagnostics[len(diagnostics)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := rspackHelpLine.FindStringSubmatch(line); m != nil {
+			current.Suggestion = m[1]
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "×"))
+		if current.Message == "" && text != "" {
+			current.Message = strings.TrimPrefix(text, "Error: ")
+		}
+	}
+	return diagnostics
+}
+
+// workspacePath makes a toolchain-reported path relative to the work directory,
+// or to libDir when it is outside it (server libraries resolve through the link)
+func workspacePath(file, workDir, libDir string) string {
+	for _, dir := range []string{workDir, libDir} {
+		if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return file
+}
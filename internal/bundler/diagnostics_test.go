@@ -0,0 +1,107 @@
+package bundler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEsbuildErrorsAreDiagnostics(t *testing.T) {
+	sessionDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sessionDir, "servers"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Bundler{toolchain: esbuildToolchain{}, transform: DefaultTransformOptions()}
+	code := "const a = 1;\nimport { missing } from \"./lib/missing\";\nexport default missing + a;\n"
+	_, _, err := b.BundleWithFiles(sessionDir, code, nil)
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		t.Fatalf("expected a BuildError, got %v", err)
+	}
+	if len(buildErr.Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic, got %+v", buildErr.Diagnostics)
+	}
+	d := buildErr.Diagnostics[0]
+	if d.File != "index.ts" || d.Line != 2 || d.Column != 25 {
+		t.Errorf("expected index.ts:2:25, got %s:%d:%d", d.File, d.Line, d.Column)
+	}
+	if !strings.Contains(d.Message, "Could not resolve") {
+		t.Errorf("unexpected message %q", d.Message)
+	}
+	if !strings.HasPrefix(err.Error(), "esbuild failed:\nindex.ts:2:25: ") {
+		t.Errorf("unexpected error text %q", err.Error())
+	}
+}
+
+func TestTransformErrorsAreDiagnostics(t *testing.T) {
+	opts := DefaultTransformOptions()
+	opts.Sourcefile = "lib/math.ts"
+	_, _, err := Transform("export const x = ;\n", opts)
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) || len(buildErr.Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic, got %v", err)
+	}
+	if d := buildErr.Diagnostics[0]; d.File != "lib/math.ts" || d.Line != 1 || d.Column != 18 {
+		t.Errorf("expected lib/math.ts:1:18, got %+v", d)
+	}
+}
+
+func TestParseBunErrors(t *testing.T) {
+	output := `1 | import { x } from "./missing";
+                      ^
+error: Could not resolve: "./missing". Maybe you need to "bun install"?
+    at /tmp/lib/work/abc/index.ts:1:19
+
+error: No matching export in "servers/github/index.ts" for import "nope"
+    at /tmp/lib/servers/github/index.ts:3:10
+`
+	got := parseBunErrors(output, "/tmp/lib/work/abc", "/tmp/lib")
+	want := []Diagnostic{
+		{File: "index.ts", Line: 1, Column: 19, Message: `Could not resolve: "./missing"`, Suggestion: `Maybe you need to "bun install"?`},
+		{File: "servers/github/index.ts", Line: 3, Column: 10, Message: `No matching export in "servers/github/index.ts" for import "nope"`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diagnostic %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestParseRspackErrors(t *testing.T) {
+	output := `ERROR in ./index.ts 3:0-35
+  × Module not found: Can't resolve './lib/missing' in '/tmp/lib/work/abc'
+   ╭─[3:0]
+ 3 │ import { missing } from "./lib/missing";
+   · ───────────────────────────────────
+   ╰────
+  help: Did you mean './lib/mising.ts'?
+
+Rspack compiled with 1 error
+`
+	got := parseRspackErrors(output)
+	want := Diagnostic{
+		File:       "index.ts",
+		Line:       3,
+		Column:     1,
+		Message:    "Module not found: Can't resolve './lib/missing' in '/tmp/lib/work/abc'",
+		Suggestion: "Did you mean './lib/mising.ts'?",
+	}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestBuildErrorWithoutDiagnosticsReportsOutput(t *testing.T) {
+	err := &BuildError{Stage: "rspack", Output: "  something went wrong\n"}
+	if err.Error() != "rspack failed:\nsomething went wrong" {
+		t.Errorf("unexpected error text %q", err.Error())
+	}
+}
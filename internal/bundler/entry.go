@@ -0,0 +1,279 @@
+package bundler
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// entryFunction wraps user code that uses top-level await
+const entryFunction = "__codebraidMain"
+
+// PrepareEntry turns submitted code into the bundle entry point, which calls exec()
+// so the bundle's completion value is its result.
+//
+// Code using top-level await (or a top-level return) is wrapped in an async function:
+// imports and re-exports are hoisted out of it, export keywords are dropped, and
+// exec() is still called when the code defines one. The wrapper is inserted without
+// adding lines before user code, so source-mapped error lines stay accurate.
+func PrepareEntry(code string) string {
+	if !needsAsyncWrapper(code) {
+		return code + "\nexec();\n"
+	}
+
+	body := []byte(code)
+	var hoisted []string
+	for _, stmt := range scanModuleStatements(code) {
+		if stmt.hoist {
+			hoisted = append(hoisted, code[stmt.start:stmt.end])
+		}
+		blank(body, stmt.start, stmt.end)
+	}
+
+	var b strings.Builder
+	b.WriteString("async function " + entryFunction + "() {")
+	b.Write(body)
+	b.WriteString("\nif (typeof exec === \"function\") return exec();\n}\n")
+	for _, stmt := range hoisted {
+		b.WriteString(stmt)
+		b.WriteString("\n")
+	}
+	b.WriteString(entryFunction + "();\n")
+	return b.String()
+}
+
+// needsAsyncWrapper reports whether code uses await or return outside any function
+func needsAsyncWrapper(code string) bool {
+	loader := api.LoaderTS
+	if globalTransform.TSX {
+		loader = api.LoaderTSX
+	}
+
+	// es2020 has no top-level await, so esbuild reports every use of it.
+	// Top-level return is only reported in ESM, so the module marker forces that.
+	result := api.Transform(code+"\nexport {};", api.TransformOptions{
+		Loader:   loader,
+		Target:   api.ES2020,
+		Format:   api.FormatESModule,
+		LogLevel: api.LogLevelSilent,
+	})
+	for _, msg := range result.Errors {
+		if strings.HasPrefix(msg.Text, "Top-level await") || strings.HasPrefix(msg.Text, "Top-level return") {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleStatement is a span of module syntax that cannot stay inside a function
+type moduleStatement struct {
+	start, end int
+	hoist      bool // Moved to module scope; otherwise the span is only an export keyword to drop
+}
+
+// scanModuleStatements finds top-level import and export syntax in code.
+// It tracks strings, template literals, comments and bracket depth, which is
+// enough to tell module syntax apart from the same words inside expressions.
+func scanModuleStatements(code string) []moduleStatement {
+	var stmts []moduleStatement
+	depth := 0
+	statementStart := true // Whether the next token may begin a statement
+
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == '\n':
+			statementStart = true
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(code[i:], "//"):
+			i = skipLineComment(code, i)
+		case strings.HasPrefix(code[i:], "/*"):
+			i = skipBlockComment(code, i)
+		case c == '"' || c == '\'' || c == '`':
+			i = skipString(code, i)
+			statementStart = false
+		case c == '{' || c == '(' || c == '[':
+			depth++
+			statementStart = c == '{'
+			i++
+		case c == '}' || c == ')' || c == ']':
+			if depth > 0 {
+				depth--
+			}
+			statementStart = c == '}'
+			i++
+		case c == ';':
+			statementStart = true
+			i++
+		case isIdentStart(c):
+			end := i
+			for end < len(code) && isIdentPart(code[end]) {
+				end++
+			}
+			word := code[i:end]
+			if depth == 0 && statementStart {
+				if stmt, ok := moduleStatementAt(code, i, word); ok {
+					stmts = append(stmts, stmt)
+					i = stmt.end
+					statementStart = stmt.hoist
+					continue
+				}
+			}
+			statementStart = false
+			i = end
+		default:
+			statementStart = false
+			i++
+		}
+	}
+	return stmts
+}
+
+// moduleStatementAt parses the import or export statement starting with word at i
+func moduleStatementAt(code string, i int, word string) (moduleStatement, bool) {
+	rest := strings.TrimLeft(code[i+len(word):], " \t")
+	switch word {
+	case "import":
+		// import(...) and import.meta are expressions
+		if strings.HasPrefix(rest, "(") || strings.HasPrefix(rest, ".") {
+			return moduleStatement{}, false
+		}
+		return moduleStatement{start: i, end: specifierEnd(code, i+len(word)), hoist: true}, true
+	case "export":
+		switch {
+		case strings.HasPrefix(rest, "{"), strings.HasPrefix(rest, "*"):
+			// export { a, b }, export { a } from "x", export * from "x"
+			end := closingBrace(code, i)
+			afterList := strings.TrimLeft(code[end:], " \t")
+			if strings.HasPrefix(rest, "*") || strings.HasPrefix(afterList, "from") {
+				return moduleStatement{start: i, end: specifierEnd(code, end), hoist: true}, true
+			}
+			// Local export lists name bindings that now live inside the wrapper
+			return moduleStatement{start: i, end: statementEnd(code, end)}, true
+		case strings.HasPrefix(rest, "default"):
+			keywordEnd := len(code) - len(rest) + len("default")
+			return moduleStatement{start: i, end: keywordEnd}, true
+		default:
+			return moduleStatement{start: i, end: i + len(word)}, true
+		}
+	}
+	return moduleStatement{}, false
+}
+
+// specifierEnd returns the end of the statement whose module specifier is the
+// first string literal at or after i, including a trailing semicolon
+func specifierEnd(code string, i int) int {
+	for i < len(code) {
+		switch code[i] {
+		case '"', '\'':
+			return statementEnd(code, skipString(code, i))
+		case '/':
+			if strings.HasPrefix(code[i:], "//") {
+				i = skipLineComment(code, i)
+				continue
+			}
+			if strings.HasPrefix(code[i:], "/*") {
+				i = skipBlockComment(code, i)
+				continue
+			}
+		}
+		i++
+	}
+	return len(code)
+}
+
+// closingBrace returns the index just past the brace list or "*" following i
+func closingBrace(code string, i int) int {
+	open := strings.IndexAny(code[i:], "{*")
+	if open < 0 || code[i+open] == '*' {
+		return i + open + 1
+	}
+	if end := strings.IndexByte(code[i+open:], '}'); end >= 0 {
+		return i + open + end + 1
+	}
+	return len(code)
+}
+
+// statementEnd extends i over spaces and an optional semicolon
+func statementEnd(code string, i int) int {
+	j := i
+	for j < len(code) && (code[j] == ' ' || code[j] == '\t') {
+		j++
+	}
+	if j < len(code) && code[j] == ';' {
+		return j + 1
+	}
+	return i
+}
+
+// skipString returns the index just past the string or template literal at i.
+// Template substitutions are skipped by brace matching.
+func skipString(code string, i int) int {
+	quote := code[i]
+	for j := i + 1; j < len(code); j++ {
+		switch code[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		case '\n':
+			if quote != '`' {
+				return j
+			}
+		case '$':
+			if quote == '`' && j+1 < len(code) && code[j+1] == '{' {
+				depth := 0
+				for j++; j < len(code); j++ {
+					if code[j] == '{' {
+						depth++
+					} else if code[j] == '}' {
+						depth--
+						if depth == 0 {
+							break
+						}
+					} else if code[j] == '"' || code[j] == '\'' || code[j] == '`' {
+						j = skipString(code, j) - 1
+					}
+				}
+			}
+		}
+	}
+	return len(code)
+}
+
+// skipLineComment returns the index of the newline ending the comment at i
+func skipLineComment(code string, i int) int {
+	if end := strings.IndexByte(code[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(code)
+}
+
+// skipBlockComment returns the index just past the comment at i
+func skipBlockComment(code string, i int) int {
+	if end := strings.Index(code[i+2:], "*/"); end >= 0 {
+		return i + 2 + end + 2
+	}
+	return len(code)
+}
+
+// blank overwrites code[start:end] with spaces, keeping line breaks so positions are preserved
+func blank(code []byte, start, end int) {
+	for i := start; i < end; i++ {
+		if code[i] != '\n' && code[i] != '\r' {
+			code[i] = ' '
+		}
+	}
+}
+
+// isIdentStart reports whether c can begin an identifier; non-ASCII bytes count as letters
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// isIdentPart reports whether c can continue an identifier
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
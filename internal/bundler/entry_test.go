@@ -0,0 +1,204 @@
+package bundler
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+func TestPrepareEntryWithoutTopLevelAwait(t *testing.T) {
+	code := "async function exec() {\n  return await Promise.resolve(1);\n}"
+
+	entry := PrepareEntry(code)
+	if entry != code+"\nexec();\n" {
+		t.Errorf("expected code to be left unwrapped, got:\n%s", entry)
+	}
+}
+
+func TestPrepareEntryTopLevelAwaitFormats(t *testing.T) {
+	code := `import * as github from "./servers/github";
+import { helper } from "./servers/util";
+
+const repos = await github.listRepos({ owner: "me" });
+export const count = repos.length;
+return { count, first: await helper(repos[0]) };
+`
+
+	for _, format := range []string{"esm", "cjs"} {
+		t.Run(format, func(t *testing.T) {
+			opts := DefaultTransformOptions()
+			opts.Format = format
+
+			if format == "cjs" {
+				if _, _, err := Transform(code, opts); err == nil {
+					t.Fatalf("expected raw top-level await to fail the %s transform", format)
+				}
+			}
+
+			if _, _, err := Transform(PrepareEntry(code), opts); err != nil {
+				t.Fatalf("prepared entry failed the %s transform: %v", format, err)
+			}
+		})
+	}
+}
+
+func TestPrepareEntryHoistsModuleSyntax(t *testing.T) {
+	code := `import * as github from "./servers/github";
+import {
+  a,
+  b,
+} from "./servers/util";
+const label = "import x from 'not-an-import'";
+const data = await github.get();
+export { data };
+export * from "./servers/types";
+return data;
+`
+
+	entry := PrepareEntry(code)
+	body, hoisted, ok := strings.Cut(entry, "\n}\n")
+	if !ok {
+		t.Fatalf("expected a wrapper function, got:\n%s", entry)
+	}
+
+	for _, stmt := range []string{
+		`import * as github from "./servers/github";`,
+		"import {\n  a,\n  b,\n} from \"./servers/util\";",
+		`export * from "./servers/types";`,
+	} {
+		if !strings.Contains(hoisted, stmt) {
+			t.Errorf("expected %q to be hoisted out of the wrapper, got:\n%s", stmt, entry)
+		}
+		if strings.Contains(body, stmt) {
+			t.Errorf("expected %q to be removed from the wrapper body, got:\n%s", stmt, entry)
+		}
+	}
+	if strings.Contains(body, "export") {
+		t.Errorf("expected export syntax to be dropped from the wrapper body, got:\n%s", body)
+	}
+	if !strings.Contains(body, `"import x from 'not-an-import'"`) {
+		t.Errorf("expected string contents to be left alone, got:\n%s", body)
+	}
+}
+
+func TestPrepareEntryPreservesLines(t *testing.T) {
+	code := "import * as github from \"./servers/github\";\n\nconst data = await github.get();\nthrow new Error(\"line four\");\n"
+
+	entry := PrepareEntry(code)
+	lines := strings.Split(entry, "\n")
+	if len(lines) < 4 || !strings.Contains(lines[3], `"line four"`) {
+		t.Errorf("expected user code to keep its line numbers, got:\n%s", entry)
+	}
+}
+
+func TestPrepareEntryRunsResult(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			name: "top-level return",
+			code: "const n = await Promise.resolve(41);\nreturn n + 1;",
+			want: "42",
+		},
+		{
+			name: "exec with top-level await",
+			code: "const n = await Promise.resolve(2);\nexport async function exec() {\n  return n * 21;\n}",
+			want: "42",
+		},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{"esm", "cjs"} {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				opts := DefaultTransformOptions()
+				opts.Format = format
+				opts.Sourcemap = false
+
+				js, _, err := Transform(PrepareEntry(tt.code), opts)
+				if err != nil {
+					t.Fatalf("transform failed: %v", err)
+				}
+
+				vm := goja.New()
+				vm.RunString("var module = { exports: {} }; var exports = module.exports;")
+				value, err := vm.RunString(js)
+				if err != nil {
+					t.Fatalf("entry failed to run: %v\n%s", err, js)
+				}
+				promise, ok := value.Export().(*goja.Promise)
+				if !ok {
+					t.Fatalf("expected the entry to complete with a promise, got %v", value)
+				}
+				if promise.State() != goja.PromiseStateFulfilled {
+					t.Fatalf("expected the promise to be fulfilled, got state %v: %v", promise.State(), promise.Result())
+				}
+				if got := promise.Result().String(); got != tt.want {
+					t.Errorf("expected result %s, got %s", tt.want, got)
+				}
+			})
+		}
+	}
+}
+
+// TestSnippetCorpus runs plain JavaScript and TypeScript snippets through the one
+// TypeScript pipeline every execution takes. The JavaScript snippets mention " as "
+// and "type " in strings, comments and names, which substring-based language
+// detection would misread. Each file starts with "// want: <json result>".
+func TestSnippetCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "snippets", "*"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("expected snippet fixtures, got %v", err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, code, _ := strings.Cut(string(data), "\n")
+			want, ok := strings.CutPrefix(header, "// want: ")
+			if !ok {
+				t.Fatalf("expected a want header, got %q", header)
+			}
+
+			opts := DefaultTransformOptions()
+			opts.Format = "cjs"
+			opts.Sourcemap = false
+			js, _, err := Transform(PrepareEntry(code), opts)
+			if err != nil {
+				t.Fatalf("transform failed: %v", err)
+			}
+
+			vm := goja.New()
+			vm.RunString("var module = { exports: {} }; var exports = module.exports;")
+			value, err := vm.RunString(js)
+			if err != nil {
+				t.Fatalf("snippet failed to run: %v\n%s", err, js)
+			}
+			promise, ok := value.Export().(*goja.Promise)
+			if !ok || promise.State() != goja.PromiseStateFulfilled {
+				t.Fatalf("expected a fulfilled promise, got %v", value)
+			}
+			got, err := json.Marshal(promise.Result().Export())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, []byte(want)); err != nil {
+				t.Fatalf("invalid want header: %v", err)
+			}
+			if string(got) != compact.String() {
+				t.Errorf("expected %s, got %s", compact.String(), got)
+			}
+		})
+	}
+}
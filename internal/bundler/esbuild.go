@@ -0,0 +1,268 @@
+package bundler
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// resolveExtensions are tried, in order, for extensionless relative imports
+var resolveExtensions = []string{".ts", ".tsx", ".js", ".mjs", ".cjs", ".json"}
+
+// bundleOutput is the virtual output file; source map paths are relative to its directory
+const bundleOutput = "bundle.js"
+
+// esbuildToolchain bundles in-process with the esbuild API. The entry and helper
+// modules are served from memory, while the generated server libraries and npm
+// packages resolve from disk, so no work directory is written.
+// Source paths in the map are relative to libDir (e.g. "index.ts", "servers/github/index.ts").
+type esbuildToolchain struct{}
+
+func (esbuildToolchain) Name() string { return ToolchainEsbuild }
+
+func (esbuildToolchain) Bundle(entry Entry, libDir string, opts BundleOptions) (Output, error) {
+	start := time.Now()
+	nodePaths, err := esbuildNodePaths(entry, libDir, opts)
+	if err != nil {
+		return Output{}, err
+	}
+
+	modules, err := entryModules(entry, libDir, opts.Transform)
+	if err != nil {
+		return Output{}, err
+	}
+	buildOpts, err := esbuildOptions(libDir, opts.Transform, nodePaths, func() map[string]string { return modules })
+	if err != nil {
+		return Output{}, err
+	}
+	return esbuildOutput(api.Build(buildOpts), start)
+}
+
+// esbuildNodePaths returns the node_modules directory of the allowed npm packages
+// for esbuild to search when the entry imports packages, or none when it imports
+// none. esbuild resolves them there, so nothing is linked into libDir.
+func esbuildNodePaths(entry Entry, libDir string, opts BundleOptions) ([]string, error) {
+	if opts.Packages == nil {
+		return nil, nil
+	}
+	modules, err := opts.Packages.resolve(opts.Transform, sources(entry.Code, entry.Files)...)
+	if err != nil || modules == "" {
+		return nil, err
+	}
+	return []string{modules}, nil
+}
+
+// entryModules keys the entry and its helper modules by their absolute path under libDir.
+// When the entry names the servers it imports, the servers index is replaced by one
+// re-exporting only those, so the other libraries are never loaded.
+func entryModules(entry Entry, libDir string, transform TransformOptions) (map[string]string, error) {
+	modules := make(map[string]string, len(entry.Files)+2)
+	modules[filepath.Join(libDir, entryName(transform))] = entry.Code
+	for name, content := range entry.Files {
+		modules[filepath.Join(libDir, filepath.FromSlash(name))] = content
+	}
+	if entry.Servers != nil {
+		index, ok, err := prunedServerIndex(libDir, entry.Servers)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			modules[filepath.Join(libDir, "servers", "index.ts")] = index
+		}
+	}
+	return modules, nil
+}
+
+// esbuildOptions returns the build options for bundling the entry in libDir.
+// modules is called on every resolve and load, so a build context can be
+// rebuilt with different code.
+func esbuildOptions(libDir string, transform TransformOptions, nodePaths []string, modules func() map[string]string) (api.BuildOptions, error) {
+	target, err := parseTarget(transform.Target)
+	if err != nil {
+		return api.BuildOptions{}, err
+	}
+	format, err := parseFormat(transform.Format)
+	if err != nil {
+		return api.BuildOptions{}, err
+	}
+
+	tsconfig := ""
+	if transform.Decorators {
+		tsconfig = `{"compilerOptions": {"experimentalDecorators": true}}`
+	}
+
+	sourcemap := api.SourceMapNone
+	if transform.Sourcemap {
+		sourcemap = api.SourceMapExternal
+	}
+
+	plugins := []api.Plugin{memoryModules(modules)}
+	if len(transform.Alias) > 0 {
+		plugins = append([]api.Plugin{aliasImports(libDir, transform)}, plugins...)
+	}
+
+	return api.BuildOptions{
+		EntryPoints:       []string{"./" + entryName(transform)},
+		AbsWorkingDir:     libDir,
+		Outfile:           filepath.Join(libDir, bundleOutput),
+		Bundle:            true,
+		Write:             false,
+		Platform:          api.PlatformNode,
+		Target:            target,
+		Format:            format,
+		Sourcemap:         sourcemap,
+		TsconfigRaw:       tsconfig,
+		External:          transform.External,
+		Define:            transform.Define,
+		ResolveExtensions: resolveExtensions,
+		NodePaths:         nodePaths,
+		MinifyWhitespace:  transform.Minify,
+		MinifyIdentifiers: transform.Minify,
+		MinifySyntax:      transform.Minify,
+		Metafile:          true,
+		LogLevel:          api.LogLevelSilent,
+		Plugins:           plugins,
+	}, nil
+}
+
+// esbuildOutput collects the bundle and its stats from a build started at start
+func esbuildOutput(result api.BuildResult, start time.Time) (Output, error) {
+	if len(result.Errors) > 0 {
+		return Output{}, esbuildError("esbuild", result.Errors)
+	}
+
+	var out Output
+	for _, file := range result.OutputFiles {
+		switch filepath.Base(file.Path) {
+		case bundleOutput:
+			out.JS = string(file.Contents)
+		case bundleOutput + ".map":
+			out.SourceMap = string(file.Contents)
+		}
+	}
+	if out.JS == "" {
+		return Output{}, fmt.Errorf("esbuild produced no output")
+	}
+
+	var metafile struct {
+		Inputs  map[string]json.RawMessage `json:"inputs"`
+		Outputs map[string]struct {
+			Inputs map[string]struct {
+				BytesInOutput int `json:"bytesInOutput"`
+			} `json:"inputs"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal([]byte(result.Metafile), &metafile); err == nil {
+		out.Stats.Modules = len(metafile.Inputs)
+		if output, ok := metafile.Outputs[bundleOutput]; ok {
+			out.Stats.ModuleBytes = make(map[string]int, len(output.Inputs))
+			for path, input := range output.Inputs {
+				out.Stats.ModuleBytes[path] = input.BytesInOutput
+			}
+		}
+	}
+	out.Stats.Duration = time.Since(start)
+	out.Stats.Bytes = len(out.JS)
+	return out, nil
+}
+
+// memoryModules serves the modules, keyed by absolute path, from memory. Relative imports
+// that do not name one of them fall through to esbuild's resolution from disk.
+func memoryModules(modules func() map[string]string) api.Plugin {
+	return api.Plugin{
+		Name: "codebraid-modules",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: `^\.\.?(/|$)`},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					base := filepath.Join(args.ResolveDir, filepath.FromSlash(args.Path))
+					if path, ok := lookupModule(modules(), base); ok {
+						return api.OnResolveResult{Path: path}, nil
+					}
+					return api.OnResolveResult{}, nil
+				})
+
+			build.OnLoad(api.OnLoadOptions{Filter: `.*`, Namespace: "file"},
+				func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					content, ok := modules()[args.Path]
+					if !ok {
+						return api.OnLoadResult{}, nil
+					}
+					return api.OnLoadResult{
+						Contents:   &content,
+						ResolveDir: filepath.Dir(args.Path),
+						Loader:     moduleLoader(args.Path),
+					}, nil
+				})
+		},
+	}
+}
+
+// aliasImports rewrites aliased imports to their paths relative to libDir, where the
+// entry lives, and resolves them like the entry's own relative imports.
+// esbuild's Alias option cannot be used: it resolves the rewritten path after
+// plugins run, so it would miss the modules served from memory.
+func aliasImports(libDir string, transform TransformOptions) api.Plugin {
+	prefixes := make([]string, 0, len(transform.Alias))
+	for _, prefix := range sortedNames(transform.Alias) {
+		prefixes = append(prefixes, regexp.QuoteMeta(prefix))
+	}
+	filter := `^(` + strings.Join(prefixes, "|") + `)(/|$)`
+
+	return api.Plugin{
+		Name: "codebraid-alias",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: filter},
+				func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					target, ok := transform.aliasTarget(args.Path)
+					if !ok {
+						return api.OnResolveResult{}, nil
+					}
+					result := build.Resolve(target, api.ResolveOptions{
+						ResolveDir: libDir,
+						Importer:   args.Importer,
+						Kind:       args.Kind,
+					})
+					if len(result.Errors) > 0 {
+						return api.OnResolveResult{Errors: result.Errors}, nil
+					}
+					return api.OnResolveResult{Path: result.Path, Namespace: result.Namespace}, nil
+				})
+		},
+	}
+}
+
+// lookupModule finds the module an import of base names, trying extensions and index files
+func lookupModule(modules map[string]string, base string) (string, bool) {
+	candidates := []string{base}
+	for _, ext := range resolveExtensions {
+		candidates = append(candidates, base+ext)
+	}
+	for _, ext := range resolveExtensions {
+		candidates = append(candidates, filepath.Join(base, "index"+ext))
+	}
+	for _, candidate := range candidates {
+		if _, ok := modules[candidate]; ok {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// moduleLoader picks the esbuild loader for a module served from memory
+func moduleLoader(path string) api.Loader {
+	switch filepath.Ext(path) {
+	case ".tsx":
+		return api.LoaderTSX
+	case ".ts":
+		return api.LoaderTS
+	case ".json":
+		return api.LoaderJSON
+	default:
+		return api.LoaderJS
+	}
+}
@@ -0,0 +1,140 @@
+package bundler
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBundleWithEsbuild(t *testing.T) {
+	sessionDir := t.TempDir()
+	serverDir := filepath.Join(sessionDir, "servers", "github")
+	if err := os.MkdirAll(serverDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	lib := "export async function listRepos(owner: string): Promise<string[]> { return [owner]; }\n"
+	if err := os.WriteFile(filepath.Join(serverDir, "index.ts"), []byte(lib), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Bundler{toolchain: esbuildToolchain{}, transform: DefaultTransformOptions()}
+	code := `import { listRepos } from "./servers/github";
+import { double } from "./lib/math";
+async function exec() { return double((await listRepos("me")).length); }
+exec();
+`
+	files := map[string]string{
+		"lib/math.ts": `import { one } from "./one"; export const double = (n: number): number => (n + one - 1) * 2;`,
+		"lib/one.js":  "export const one = 1;",
+	}
+	js, sourceMap, err := b.BundleWithFiles(sessionDir, code, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(js, "listRepos") || !strings.Contains(js, "double") {
+		t.Errorf("expected the imports to be bundled, got:\n%s", js)
+	}
+	if strings.Contains(js, "import ") {
+		t.Errorf("expected no imports left, got:\n%s", js)
+	}
+
+	var decoded struct {
+		Sources []string `json:"sources"`
+	}
+	if err := json.Unmarshal([]byte(sourceMap), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(decoded.Sources)
+	want := []string{"index.ts", "lib/math.ts", "lib/one.js", "servers/github/index.ts"}
+	if !reflect.DeepEqual(decoded.Sources, want) {
+		t.Errorf("expected sources %v, got %v", want, decoded.Sources)
+	}
+
+	if _, err := os.Stat(filepath.Join(sessionDir, "work")); !os.IsNotExist(err) {
+		t.Errorf("expected no work directory, got %v", err)
+	}
+}
+
+func TestBundleWithEsbuildReportsErrors(t *testing.T) {
+	sessionDir := t.TempDir()
+	b := &Bundler{toolchain: esbuildToolchain{}, transform: DefaultTransformOptions()}
+
+	_, _, err := b.BundleWithFiles(sessionDir, "const x = ;\n", nil)
+	if err == nil || !strings.Contains(err.Error(), "index.ts:1:") {
+		t.Fatalf("expected a located syntax error, got %v", err)
+	}
+
+	_, _, err = b.BundleWithFiles(sessionDir, `import { y } from "./missing"; y();`, nil)
+	if err == nil || !strings.Contains(err.Error(), "./missing") {
+		t.Fatalf("expected an unresolved import error, got %v", err)
+	}
+}
+
+func TestBundleAnalysisAndSizeLimit(t *testing.T) {
+	sessionDir := t.TempDir()
+	b := &Bundler{toolchain: esbuildToolchain{}, transform: DefaultTransformOptions()}
+	code := `import { big } from "./lib/big"; console.log(big);`
+	files := map[string]string{"lib/big.ts": "export const big = `" + strings.Repeat("x", 4096) + "`;"}
+
+	out, err := b.Bundle(sessionDir, code, files, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Stats.ModuleBytes["lib/big.ts"] < 4096 || out.Stats.ModuleBytes["index.ts"] == 0 {
+		t.Errorf("expected per-module bytes for both modules, got %v", out.Stats.ModuleBytes)
+	}
+
+	b.maxBytes = 1024
+	_, err = b.Bundle(sessionDir, code, files, false)
+	var sizeErr *SizeError
+	if !errors.As(err, &sizeErr) || sizeErr.Bytes != out.Stats.Bytes || sizeErr.Limit != 1024 {
+		t.Fatalf("expected a size error, got %v", err)
+	}
+}
+
+func TestBundleWithEsbuildResolvesPackagesFromTheInstall(t *testing.T) {
+	cacheDir := t.TempDir()
+	packages, err := NewPackages(PackageOptions{Allow: []string{"lodash"}, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	installDir := filepath.Join(cacheDir, packages.Key())
+	for name, content := range map[string]string{
+		"lodash/index.js": `module.exports = require("dep");`,
+		"dep/index.js":    `module.exports = { chunk: () => "chunked" };`,
+	} {
+		path := filepath.Join(installDir, "node_modules", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(installDir, ".installed"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sessionDir := t.TempDir()
+	b := &Bundler{toolchain: esbuildToolchain{}, transform: DefaultTransformOptions(), packages: packages}
+	js, _, err := b.BundleWithFiles(sessionDir, `import _ from "lodash"; console.log(_.chunk());`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(js, "chunked") {
+		t.Errorf("expected lodash and its dependency to be bundled, got:\n%s", js)
+	}
+	if _, err := os.Lstat(filepath.Join(sessionDir, "node_modules")); !os.IsNotExist(err) {
+		t.Errorf("expected no node_modules left in the session dir, got %v", err)
+	}
+
+	// The dependency resolves for lodash but is not allowed for the script
+	if _, _, err := b.BundleWithFiles(sessionDir, `import _ from "lodash"; import dep from "dep";`, nil); err == nil {
+		t.Error("expected importing a dependency of an allowed package to fail")
+	}
+}
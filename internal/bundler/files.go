@@ -0,0 +1,119 @@
+package bundler
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxModuleFiles bounds the helper modules submitted with one execution
+const maxModuleFiles = 64
+
+// moduleExtensions lists the helper file types the bundlers resolve
+var moduleExtensions = map[string]bool{
+	".ts": true, ".tsx": true, ".js": true, ".mjs": true, ".cjs": true, ".json": true,
+}
+
+// reservedPaths are work directory entries owned by the bundler
+var reservedPaths = []string{"servers", "node_modules", "dist", "tsconfig.json", "globals.d.ts", "rspack.config.ts"}
+
+// validateFiles checks that helper module names are relative paths the entry can
+// import (e.g. "lib/math.ts" as "./lib/math") that stay inside the work directory.
+// Names are compared case-insensitively, as the work directory may be on a
+// case-insensitive filesystem where "Servers" is "servers".
+func validateFiles(files map[string]string, entry string) error {
+	if len(files) > maxModuleFiles {
+		return fmt.Errorf("too many files: %d, at most %d are allowed", len(files), maxModuleFiles)
+	}
+
+	folded := make(map[string]string, len(files))
+	for _, name := range sortedNames(files) {
+		clean := path.Clean(name)
+		if name == "" || path.IsAbs(name) || strings.Contains(name, `\`) || clean != name || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("invalid file name %q: must be a clean relative path such as \"lib/util.ts\"", name)
+		}
+		if !moduleExtensions[path.Ext(name)] {
+			return fmt.Errorf("invalid file name %q: extension must be one of .ts, .tsx, .js, .mjs, .cjs or .json", name)
+		}
+		if strings.EqualFold(name, entry) {
+			return fmt.Errorf("invalid file name %q: the entry point is the code argument", name)
+		}
+		top, _, _ := strings.Cut(name, "/")
+		for _, reserved := range reservedPaths {
+			if strings.EqualFold(top, reserved) {
+				return fmt.Errorf("invalid file name %q: %s is reserved", name, reserved)
+			}
+		}
+		if other, ok := folded[strings.ToLower(name)]; ok {
+			return fmt.Errorf("invalid file name %q: it differs from %q only in case", name, other)
+		}
+		folded[strings.ToLower(name)] = name
+	}
+	return nil
+}
+
+// writeFiles writes helper modules into the work directory
+func writeFiles(workDir string, files map[string]string) error {
+	for name, content := range files {
+		target := filepath.Join(workDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// checkFiles transforms each helper script in-process so syntax errors are
+// reported with the helper's name before the bundler starts
+func checkFiles(files map[string]string, opts TransformOptions) error {
+	for _, name := range sortedNames(files) {
+		if path.Ext(name) == ".json" {
+			continue
+		}
+		helperOpts := opts
+		helperOpts.Sourcefile = name
+		helperOpts.TSX = opts.TSX && path.Ext(name) == ".tsx"
+		if _, _, err := Transform(files[name], helperOpts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sources returns the entry code and helper contents, in a stable order
+func sources(code string, files map[string]string) []string {
+	all := []string{code}
+	for _, name := range sortedNames(files) {
+		all = append(all, files[name])
+	}
+	return all
+}
+
+// moduleSource flattens code and its helpers into one string for the bundle cache key
+func moduleSource(code string, files map[string]string) string {
+	if len(files) == 0 {
+		return code
+	}
+	var b strings.Builder
+	b.WriteString(code)
+	for _, name := range sortedNames(files) {
+		fmt.Fprintf(&b, "\x00%s\x00%d\x00%s", name, len(files[name]), files[name])
+	}
+	return b.String()
+}
+
+// sortedNames returns the file names in lexical order
+func sortedNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
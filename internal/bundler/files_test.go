@@ -0,0 +1,80 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateFiles(t *testing.T) {
+	valid := map[string]string{
+		"lib/math.ts":     "export const add = (a: number, b: number) => a + b;",
+		"util.js":         "export const one = 1;",
+		"data/items.json": "[1, 2]",
+	}
+	if err := validateFiles(valid, "index.ts"); err != nil {
+		t.Fatalf("expected valid files, got %v", err)
+	}
+
+	for _, name := range []string{
+		"",
+		"/etc/passwd.ts",
+		"../escape.ts",
+		"lib/../../escape.ts",
+		"./lib/math.ts",
+		`lib\math.ts`,
+		"notes.txt",
+		"index.ts",
+		"servers/github/index.ts",
+		"node_modules/left-pad/index.js",
+		// Reserved names in another case are the same entries on case-insensitive filesystems
+		"Index.ts",
+		"Servers/github/index.ts",
+		"NODE_MODULES/left-pad/index.js",
+		"TSConfig.json",
+	} {
+		if err := validateFiles(map[string]string{name: ""}, "index.ts"); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+	if err := validateFiles(map[string]string{"lib/a.ts": "", "Lib/A.ts": ""}, "index.ts"); err == nil {
+		t.Error("expected files differing only in case to be rejected")
+	}
+}
+
+func TestWriteFilesCreatesDirectories(t *testing.T) {
+	workDir := t.TempDir()
+	if err := writeFiles(workDir, map[string]string{"lib/deep/math.ts": "export const two = 2;"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(workDir, "lib", "deep", "math.ts"))
+	if err != nil || string(data) != "export const two = 2;" {
+		t.Errorf("expected the helper to be written, got %q, %v", data, err)
+	}
+}
+
+func TestCheckFilesNamesTheBrokenHelper(t *testing.T) {
+	files := map[string]string{
+		"lib/good.ts":   "export const ok: number = 1;",
+		"lib/broken.ts": "export const = ;",
+		"data.json":     "not checked",
+	}
+	err := checkFiles(files, DefaultTransformOptions())
+	if err == nil || !strings.Contains(err.Error(), "lib/broken.ts") {
+		t.Errorf("expected the syntax error to name lib/broken.ts, got %v", err)
+	}
+}
+
+func TestModuleSourceIsStable(t *testing.T) {
+	files := map[string]string{"a.ts": "1", "b.ts": "2"}
+	if moduleSource("code", files) != moduleSource("code", map[string]string{"b.ts": "2", "a.ts": "1"}) {
+		t.Error("expected the same files to give the same source")
+	}
+	if moduleSource("code", files) == moduleSource("code", map[string]string{"a.ts": "12", "b.ts": ""}) {
+		t.Error("expected moving content between files to change the source")
+	}
+	if moduleSource("code", nil) != "code" {
+		t.Error("expected code without files to be unchanged")
+	}
+}
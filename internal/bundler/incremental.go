@@ -0,0 +1,104 @@
+package bundler
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+var globalIncremental bool
+
+// EnableIncremental makes esbuild bundlers created afterwards keep a build context
+// per session bundle directory and rebuild it for each execution, so the session's
+// server libraries and npm packages are not parsed again when only user code changes.
+// Should be called once at application startup.
+func EnableIncremental() {
+	globalIncremental = true
+}
+
+var (
+	incrementalMu     sync.Mutex
+	incrementalBuilds = make(map[string]*incrementalBuild) // Keyed by session bundle directory
+)
+
+// incrementalBuild is the long-lived esbuild context of one session
+type incrementalBuild struct {
+	mu       sync.Mutex
+	ctx      api.BuildContext
+	settings string            // Options ctx was created with; a change recreates it
+	modules  map[string]string // Entry and helpers of the rebuild in progress
+	released bool              // The session was closed; ReleaseSession disposed ctx
+}
+
+// incrementalToolchain bundles like esbuildToolchain, rebuilding a per-session
+// context instead of starting every build from scratch. Rebuilds of one session
+// run one at a time; the output is the same as a full build.
+type incrementalToolchain struct{}
+
+func (incrementalToolchain) Name() string { return ToolchainEsbuild }
+
+func (incrementalToolchain) Bundle(entry Entry, libDir string, opts BundleOptions) (Output, error) {
+	start := time.Now()
+	nodePaths, err := esbuildNodePaths(entry, libDir, opts)
+	if err != nil {
+		return Output{}, err
+	}
+
+	incrementalMu.Lock()
+	build, ok := incrementalBuilds[libDir]
+	if !ok {
+		build = &incrementalBuild{}
+		incrementalBuilds[libDir] = build
+	}
+	incrementalMu.Unlock()
+
+	build.mu.Lock()
+	defer build.mu.Unlock()
+	if build.released {
+		return esbuildToolchain{}.Bundle(entry, libDir, opts)
+	}
+
+	if build.modules, err = entryModules(entry, libDir, opts.Transform); err != nil {
+		return Output{}, err
+	}
+	settings := opts.Transform.fingerprint() + "\n" + strings.Join(nodePaths, "\n")
+	if build.ctx == nil || build.settings != settings {
+		if build.ctx != nil {
+			build.ctx.Dispose()
+			build.ctx = nil
+		}
+		buildOpts, err := esbuildOptions(libDir, opts.Transform, nodePaths, func() map[string]string { return build.modules })
+		if err != nil {
+			return Output{}, err
+		}
+		ctx, ctxErr := api.Context(buildOpts)
+		if ctxErr != nil {
+			return Output{}, esbuildError("esbuild", ctxErr.Errors)
+		}
+		build.ctx, build.settings = ctx, settings
+	}
+
+	return esbuildOutput(build.ctx.Rebuild(), start)
+}
+
+// ReleaseSession disposes the incremental build context kept for a session bundle
+// directory. Should be called when the session is closed.
+func ReleaseSession(sessionBundleDir string) {
+	incrementalMu.Lock()
+	build, ok := incrementalBuilds[sessionBundleDir]
+	delete(incrementalBuilds, sessionBundleDir)
+	incrementalMu.Unlock()
+	if !ok {
+		return
+	}
+
+	build.mu.Lock()
+	defer build.mu.Unlock()
+	if build.ctx != nil {
+		build.ctx.Dispose()
+		build.ctx = nil
+	}
+	build.released = true
+}
@@ -0,0 +1,54 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncrementalRebuildsPerSession(t *testing.T) {
+	sessionDir := t.TempDir()
+	serverDir := filepath.Join(sessionDir, "servers", "github")
+	if err := os.MkdirAll(serverDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	lib := "export async function listRepos(owner: string): Promise<string[]> { return [owner]; }\n"
+	if err := os.WriteFile(filepath.Join(serverDir, "index.ts"), []byte(lib), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer ReleaseSession(sessionDir)
+
+	b := &Bundler{toolchain: incrementalToolchain{}, transform: DefaultTransformOptions()}
+	first, _, err := b.BundleWithFiles(sessionDir, `import { listRepos } from "./servers/github"; listRepos("first");`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := b.BundleWithFiles(sessionDir, `import { listRepos } from "./servers/github"; listRepos("second");`,
+		map[string]string{"lib/util.ts": "export const unused = 1;"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(first, `"first"`) || !strings.Contains(second, `"second"`) || strings.Contains(second, `"first"`) {
+		t.Errorf("expected each rebuild to bundle its own code, got:\n%s\n---\n%s", first, second)
+	}
+
+	incrementalMu.Lock()
+	build := incrementalBuilds[sessionDir]
+	incrementalMu.Unlock()
+	if build == nil || build.ctx == nil {
+		t.Fatal("expected a build context to be kept for the session")
+	}
+
+	if _, _, err := b.BundleWithFiles(sessionDir, "const x = ;\n", nil); err == nil || !strings.Contains(err.Error(), "index.ts:1:") {
+		t.Fatalf("expected a located syntax error, got %v", err)
+	}
+
+	ReleaseSession(sessionDir)
+	if !build.released || build.ctx != nil {
+		t.Error("expected the build context to be disposed")
+	}
+	if _, _, err := b.BundleWithFiles(sessionDir, `export const ok = 1;`, nil); err != nil {
+		t.Fatalf("expected a released session to still bundle, got %v", err)
+	}
+}
@@ -0,0 +1,425 @@
+package bundler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PackageOptions configures third-party npm packages scripts may import
+type PackageOptions struct {
+	Allow          []string      // Allowed packages, "name" or "name@version" (e.g., "zod@3.23.8")
+	CacheDir       string        // Parent directory of installed package sets
+	InstallTimeout time.Duration // Limit for one install (zero means unbounded)
+	MaxSizeMB      int           // Limit for the installed node_modules (zero means unbounded)
+}
+
+// Packages installs the allowed npm packages once into a shared cache directory
+// and links them into bundling workspaces that import them.
+// The install directory is named after a hash of the allowlist, so changing the
+// list installs a fresh set and identical lists share one install.
+//
+// The allowlist is enforced where modules are resolved, not by reading the
+// code: workspaces only see a node_modules holding links to the allowed
+// packages. Their dependencies resolve from the install the links point into,
+// so they stay out of reach of scripts, whether imported statically, with
+// require() or with a computed import().
+type Packages struct {
+	versions map[string]string // Package name to version range
+	opts     PackageOptions
+	key      string
+
+	mu        sync.Mutex
+	installed bool
+	allowed   string // node_modules holding only the allowed packages, once created
+}
+
+var globalPackages *Packages
+
+// EnablePackages allows scripts to import the configured npm packages in
+// bundlers created afterwards. Should be called once at application startup.
+func EnablePackages(opts PackageOptions) error {
+	packages, err := NewPackages(opts)
+	if err != nil {
+		return err
+	}
+	globalPackages = packages
+	return nil
+}
+
+// NewPackages parses the allowlist
+func NewPackages(opts PackageOptions) (*Packages, error) {
+	versions := make(map[string]string, len(opts.Allow))
+	for _, spec := range opts.Allow {
+		name, version := splitPackageSpec(strings.TrimSpace(spec))
+		if name == "" {
+			return nil, fmt.Errorf("invalid package %q in allowlist", spec)
+		}
+		versions[name] = version
+	}
+
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s@%s\n", name, versions[name])
+	}
+
+	return &Packages{
+		versions: versions,
+		opts:     opts,
+		key:      hex.EncodeToString(h.Sum(nil))[:16],
+	}, nil
+}
+
+// Key identifies the allowed package set; it is part of the bundle cache key
+func (p *Packages) Key() string {
+	return p.key
+}
+
+// resolve checks the packages the sources import or require by name against the
+// allowlist, failing early with a clear error, and, if any are imported, installs
+// the allowed set and returns the node_modules directory holding only them.
+// Imports that transform marks external or aliased are not packages to install.
+// It returns "" when the sources import no packages.
+func (p *Packages) resolve(transform TransformOptions, sources ...string) (string, error) {
+	var imported []string
+	for _, source := range sources {
+		imported = append(imported, importedPackages(source, transform)...)
+	}
+	if len(imported) == 0 {
+		return "", nil
+	}
+
+	for _, name := range imported {
+		if _, ok := p.versions[name]; !ok {
+			return "", fmt.Errorf("package %q is not in the allowed package list", name)
+		}
+	}
+	if _, err := p.install(); err != nil {
+		return "", err
+	}
+	return p.allowedModules()
+}
+
+// allowedModules returns a node_modules directory holding a link to each
+// allowed package in the install and nothing else, creating it once. It is
+// kept beside the install, as installs are shared by allowlists whose packages
+// resolve to the same tree.
+func (p *Packages) allowedModules() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.allowed != "" {
+		return p.allowed, nil
+	}
+
+	dir := filepath.Join(p.opts.CacheDir, "allowed", p.key)
+	modules := filepath.Join(dir, "node_modules")
+	if _, err := os.Stat(filepath.Join(dir, ".linked")); err != nil {
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return "", fmt.Errorf("failed to create allowed packages dir: %w", err)
+		}
+		staging, err := os.MkdirTemp(filepath.Dir(dir), ".link-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create allowed packages dir: %w", err)
+		}
+		defer os.RemoveAll(staging)
+		installed := filepath.Join(p.opts.CacheDir, p.key, "node_modules")
+		for name := range p.versions {
+			link := filepath.Join(staging, "node_modules", filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+				return "", fmt.Errorf("failed to link package %q: %w", name, err)
+			}
+			if err := os.Symlink(filepath.Join(installed, filepath.FromSlash(name)), link); err != nil {
+				return "", fmt.Errorf("failed to link package %q: %w", name, err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(staging, ".linked"), nil, 0644); err != nil {
+			return "", fmt.Errorf("failed to link packages: %w", err)
+		}
+		if err := os.Rename(staging, dir); err != nil {
+			// Another process may have linked them meanwhile
+			if _, statErr := os.Stat(filepath.Join(dir, ".linked")); statErr != nil {
+				return "", fmt.Errorf("failed to link packages: %w", err)
+			}
+		}
+	}
+	p.allowed = modules
+	return modules, nil
+}
+
+// link resolves the packages imported by the sources and links them into dir
+// (a bundle's work directory, removed after the bundle) as node_modules, where
+// toolchains resolving from dir find them. It returns the linked node_modules
+// directory, or "" when the sources import no packages.
+func (p *Packages) link(dir string, transform TransformOptions, sources ...string) (string, error) {
+	modules, err := p.resolve(transform, sources...)
+	if err != nil || modules == "" {
+		return "", err
+	}
+	link := filepath.Join(dir, "node_modules")
+	if target, err := os.Readlink(link); err == nil && target == modules {
+		return modules, nil
+	}
+	if err := replaceSymlink(modules, link); err != nil {
+		return "", fmt.Errorf("failed to link node_modules: %w", err)
+	}
+	return modules, nil
+}
+
+// install installs the allowed packages unless a previous install is complete
+// and returns the node_modules directory.
+// Installs are content-addressed: a set is installed into a staging directory,
+// hashed and moved to store/<hash>, and the allowlist's directory is a link to
+// it. Allowlists resolving to the same packages share one snapshot, and other
+// processes sharing the cache never see a partial install.
+func (p *Packages) install() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	dir := filepath.Join(p.opts.CacheDir, p.key)
+	modules := filepath.Join(dir, "node_modules")
+	marker := filepath.Join(dir, ".installed")
+	if p.installed {
+		return modules, nil
+	}
+	if _, err := os.Stat(marker); err == nil {
+		p.installed = true
+		return modules, nil
+	}
+
+	log.Printf("Installing %d npm package(s) into %s", len(p.versions), dir)
+	if err := os.MkdirAll(p.opts.CacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create package dir: %w", err)
+	}
+	staging, err := os.MkdirTemp(p.opts.CacheDir, ".install-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create package dir: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := json.MarshalIndent(map[string]interface{}{
+		"name":         "codebraid-packages",
+		"private":      true,
+		"dependencies": p.versions,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(staging, "package.json"), manifest, 0644); err != nil {
+		return "", fmt.Errorf("failed to write package.json: %w", err)
+	}
+
+	if err := p.runInstaller(staging); err != nil {
+		return "", err
+	}
+
+	if p.opts.MaxSizeMB > 0 {
+		size, err := dirSize(filepath.Join(staging, "node_modules"))
+		if err != nil {
+			return "", fmt.Errorf("failed to measure installed packages: %w", err)
+		}
+		if size > int64(p.opts.MaxSizeMB)<<20 {
+			return "", fmt.Errorf("installed packages use %d MB, over the %d MB limit", size>>20, p.opts.MaxSizeMB)
+		}
+	}
+
+	snapshot, err := p.store(staging)
+	if err != nil {
+		return "", err
+	}
+
+	// An interrupted install from before snapshots were stored leaves a directory here
+	if info, err := os.Lstat(dir); err == nil && info.IsDir() {
+		os.RemoveAll(dir)
+	}
+	if err := replaceSymlink(snapshot, dir); err != nil {
+		return "", fmt.Errorf("failed to link package snapshot: %w", err)
+	}
+	p.installed = true
+	return modules, nil
+}
+
+// store moves a finished install into the content-addressed store and returns
+// its snapshot directory; an identical snapshot already there is reused
+func (p *Packages) store(staging string) (string, error) {
+	hash, err := hashTree(filepath.Join(staging, "node_modules"))
+	if err != nil {
+		return "", fmt.Errorf("failed to hash installed packages: %w", err)
+	}
+	snapshot := filepath.Join(p.opts.CacheDir, "store", hash[:16])
+	if _, err := os.Stat(filepath.Join(snapshot, ".installed")); err == nil {
+		return snapshot, nil
+	}
+
+	if err := os.WriteFile(filepath.Join(staging, ".installed"), nil, 0644); err != nil {
+		return "", fmt.Errorf("failed to mark packages installed: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(snapshot), 0755); err != nil {
+		return "", fmt.Errorf("failed to create package store: %w", err)
+	}
+	if err := os.Rename(staging, snapshot); err != nil {
+		// Another process may have stored the same snapshot meanwhile
+		if _, statErr := os.Stat(filepath.Join(snapshot, ".installed")); statErr != nil {
+			return "", fmt.Errorf("failed to store package snapshot: %w", err)
+		}
+	}
+	return snapshot, nil
+}
+
+// replaceSymlink points link at target, replacing whatever link was atomically
+func replaceSymlink(target, link string) error {
+	tmp := fmt.Sprintf("%s.tmp-%d", link, time.Now().UnixNano())
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// runInstaller runs npm (or bun when npm is missing) in dir.
+// Install scripts are disabled: packages are only bundled, never built on the host.
+func (p *Packages) runInstaller(dir string) error {
+	ctx := context.Background()
+	if p.opts.InstallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.opts.InstallTimeout)
+		defer cancel()
+	}
+
+	var cmd *exec.Cmd
+	if npm, err := exec.LookPath("npm"); err == nil {
+		cmd = exec.CommandContext(ctx, npm, "install", "--ignore-scripts", "--no-audit", "--no-fund")
+	} else if bun, err := exec.LookPath("bun"); err == nil {
+		cmd = exec.CommandContext(ctx, bun, "install", "--ignore-scripts")
+	} else {
+		return fmt.Errorf("installing packages requires npm or bun in PATH")
+	}
+
+	var output bytes.Buffer
+	cmd.Dir = dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("package install timed out after %s", p.opts.InstallTimeout)
+		}
+		return fmt.Errorf("package install failed: %w\nOutput: %s", err, output.String())
+	}
+	return nil
+}
+
+// requireCall matches require() and import() calls naming a module with a string literal
+var requireCall = regexp.MustCompile(`\b(?:require|import)\s*\(\s*(?:"([^"\n]+)"|'([^'\n]+)')\s*\)`)
+
+// importedPackages returns the npm package names code imports, or requires or
+// imports dynamically by a literal name, to install them and to reject the ones
+// not allowed with a clear error. Modules named at run time are left to the
+// resolution of the allowed packages alone.
+// Relative imports, the generated server libraries and the imports opts marks
+// external or aliased are not packages.
+func importedPackages(code string, opts TransformOptions) []string {
+	var specifiers []string
+	for _, stmt := range scanModuleStatements(code) {
+		if !stmt.hoist {
+			continue
+		}
+		if specifier, ok := moduleSpecifier(code[stmt.start:stmt.end]); ok {
+			specifiers = append(specifiers, specifier)
+		}
+	}
+	for _, match := range requireCall.FindAllStringSubmatch(code, -1) {
+		specifiers = append(specifiers, match[1]+match[2])
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, specifier := range specifiers {
+		if strings.HasPrefix(specifier, ".") || strings.HasPrefix(specifier, "/") || strings.HasPrefix(specifier, "node:") || opts.resolvedElsewhere(specifier) {
+			continue
+		}
+		name := packageName(specifier)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// moduleSpecifier returns the last string literal of an import or export statement
+func moduleSpecifier(stmt string) (string, bool) {
+	stmt = strings.TrimRight(stmt, "; \t")
+	if stmt == "" {
+		return "", false
+	}
+	quote := stmt[len(stmt)-1]
+	if quote != '"' && quote != '\'' {
+		return "", false
+	}
+	start := strings.LastIndexByte(stmt[:len(stmt)-1], quote)
+	if start < 0 {
+		return "", false
+	}
+	return stmt[start+1 : len(stmt)-1], true
+}
+
+// packageName strips the subpath from a bare specifier: "lodash/fp" is "lodash"
+// and "@scope/pkg/sub" is "@scope/pkg"
+func packageName(specifier string) string {
+	parts := strings.SplitN(specifier, "/", 3)
+	if strings.HasPrefix(specifier, "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+// splitPackageSpec splits "name@version" into its parts, defaulting to the latest version
+func splitPackageSpec(spec string) (name, version string) {
+	at := strings.LastIndexByte(spec, '@')
+	if at <= 0 {
+		return spec, "latest"
+	}
+	return spec[:at], spec[at+1:]
+}
+
+// dirSize returns the total size of the files under dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
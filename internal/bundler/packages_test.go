@@ -0,0 +1,132 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportedPackages(t *testing.T) {
+	code := `import * as github from "./servers/github";
+import _ from "lodash";
+import fp from 'lodash/fp';
+import { z } from "zod";
+import { format } from "@date-fns/tz/format";
+export { parse } from "date-fns";
+const text = "import x from 'not-an-import'";
+`
+
+	got := importedPackages(code, DefaultTransformOptions())
+	want := []string{"lodash", "zod", "@date-fns/tz", "date-fns"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestNewPackagesKey(t *testing.T) {
+	a, err := NewPackages(PackageOptions{Allow: []string{"zod@3.23.8", "lodash"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewPackages(PackageOptions{Allow: []string{"lodash", "zod@3.23.8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewPackages(PackageOptions{Allow: []string{"lodash", "zod@3.24.0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Key() != b.Key() {
+		t.Errorf("expected allowlist order not to change the key")
+	}
+	if a.Key() == c.Key() {
+		t.Errorf("expected a version change to change the key")
+	}
+	if a.versions["zod"] != "3.23.8" || a.versions["lodash"] != "latest" {
+		t.Errorf("unexpected versions: %v", a.versions)
+	}
+}
+
+func TestPackagesLink(t *testing.T) {
+	cacheDir := t.TempDir()
+	packages, err := NewPackages(PackageOptions{Allow: []string{"lodash"}, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pretend a previous run installed the set so no installer runs
+	installDir := filepath.Join(cacheDir, packages.Key())
+	for _, name := range []string{"lodash", "dep"} {
+		if err := os.MkdirAll(filepath.Join(installDir, "node_modules", name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(installDir, ".installed"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	workDir := t.TempDir()
+	for _, code := range []string{
+		`import left from "left-pad";`,
+		`const left = require("left-pad");`,
+		`const left = await import('left-pad');`,
+	} {
+		_, err = packages.link(workDir, DefaultTransformOptions(), code)
+		if err == nil || !strings.Contains(err.Error(), `"left-pad" is not in the allowed package list`) {
+			t.Fatalf("expected a disallowed package error for %s, got %v", code, err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := packages.link(workDir, DefaultTransformOptions(), `import _ from "lodash";`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "node_modules", "lodash")); err != nil {
+		t.Errorf("expected lodash to resolve through the linked node_modules: %v", err)
+	}
+	// Dependencies of allowed packages are installed but not resolvable by scripts
+	if _, err := os.Stat(filepath.Join(workDir, "node_modules", "dep")); !os.IsNotExist(err) {
+		t.Errorf("expected only allowed packages in the linked node_modules, got %v", err)
+	}
+}
+
+func TestPackagesStoreIsContentAddressed(t *testing.T) {
+	cacheDir := t.TempDir()
+	packages, err := NewPackages(PackageOptions{Allow: []string{"lodash"}, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stage := func() string {
+		dir, err := os.MkdirTemp(cacheDir, ".install-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(dir, "node_modules", "lodash"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "node_modules", "lodash", "index.js"), []byte("module.exports = {};"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	first, err := packages.store(stage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := packages.store(stage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second || filepath.Dir(first) != filepath.Join(cacheDir, "store") {
+		t.Fatalf("expected identical installs to share one snapshot, got %s and %s", first, second)
+	}
+	if _, err := os.Stat(filepath.Join(first, ".installed")); err != nil {
+		t.Errorf("expected the snapshot to be marked installed: %v", err)
+	}
+}
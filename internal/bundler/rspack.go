@@ -0,0 +1,215 @@
+package bundler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// embeddedRspackConfig is the rspack config template embedded in the binary
+//
+//go:embed rspack.config.ts.tmpl
+var embeddedRspackConfig string
+
+// globalRspackConfigPath is a user-supplied template used instead of the embedded one
+var globalRspackConfigPath string
+
+// SetRspackConfig makes the rspack toolchain render its config from the template
+// at path instead of the embedded one. Empty restores the embedded template.
+// The file is read for every bundle, so edits apply without a restart.
+func SetRspackConfig(path string) {
+	globalRspackConfigPath = path
+}
+
+// rspackToolchain bundles with the rspack CLI, compiling TypeScript with its
+// builtin swc loader. path is "npx" when rspack runs through npx.
+type rspackToolchain struct {
+	path string
+}
+
+func (rspackToolchain) Name() string { return ToolchainRspack }
+
+func (t rspackToolchain) Bundle(entry Entry, libDir string, opts BundleOptions) (Output, error) {
+	start := time.Now()
+	w, err := newWorkDir(entry, libDir, opts)
+	if err != nil {
+		return Output{}, err
+	}
+	defer w.remove()
+
+	// rspack resolves alias targets as absolute paths
+	transform := opts.Transform
+	transform.Alias = make(map[string]string, len(opts.Transform.Alias))
+	for prefix, target := range opts.Transform.Alias {
+		transform.Alias[prefix] = filepath.Join(w.dir, filepath.FromSlash(target))
+	}
+
+	config, err := renderRspackConfig(transform, libDir, filepath.Join(w.dir, "node_modules"))
+	if err != nil {
+		return Output{}, err
+	}
+	configPath := filepath.Join(w.dir, "rspack.config.ts")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		return Output{}, fmt.Errorf("failed to write rspack config: %w", err)
+	}
+
+	args := []string{"--entry", w.entry, "--config", configPath, "--output-path", w.dist()}
+	var cmd *exec.Cmd
+	if t.path == "npx" {
+		cmd = exec.Command("npx", append([]string{"-y", "@rspack/cli"}, args...)...)
+	} else {
+		cmd = exec.Command(t.path, args...)
+	}
+
+	var stdout bytes.Buffer
+	cmd.Dir = w.dir
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return Output{}, &BuildError{Stage: "rspack", Diagnostics: parseRspackErrors(stdout.String()), Output: stdout.String()}
+	}
+
+	out, err := w.output("main.js", opts.Transform.Sourcemap)
+	if err != nil {
+		return Output{}, err
+	}
+	out.Stats = Stats{Duration: time.Since(start), Bytes: len(out.JS)}
+	return out, nil
+}
+
+// RspackConfigData is what rspack config templates are rendered with.
+// Templates can also call json to render a value as JSON, which is valid TypeScript.
+type RspackConfigData struct {
+	Entry       string // Entry module relative to the work directory, e.g. "./index.ts"
+	LibDir      string // Session bundle directory holding the server libraries
+	NodeModules string // Where allowed npm packages are linked for the bundle
+	Target      string // swc target, e.g. "es2020"
+	Module      string // swc module type: "es6" or "commonjs"
+	Minify      bool
+	TSX         bool
+	Decorators  bool
+	Sourcemap   bool
+	External    []string
+	Alias       map[string]string // Import prefixes mapped to absolute paths
+	Define      map[string]string // Identifiers mapped to JavaScript expressions
+	Extensions  []string          // Extensions tried for extensionless imports
+}
+
+// RspackConfig renders the rspack config template for a session bundle directory,
+// so rspack bundles with the same options as the pre-check
+func RspackConfig(opts TransformOptions, libDir string) (string, error) {
+	return renderRspackConfig(opts, libDir, filepath.Join(libDir, "node_modules"))
+}
+
+// renderRspackConfig renders the rspack config template with allowed npm
+// packages resolved from nodeModules
+func renderRspackConfig(opts TransformOptions, libDir, nodeModules string) (string, error) {
+	tmpl, err := rspackTemplate()
+	if err != nil {
+		return "", err
+	}
+
+	target := strings.ToLower(opts.Target)
+	if target == "es6" {
+		target = "es2015" // swc only accepts the year form
+	}
+	module := "es6"
+	if strings.EqualFold(opts.Format, "cjs") {
+		module = "commonjs"
+	}
+
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, RspackConfigData{
+		Entry:       "./" + entryName(opts),
+		LibDir:      libDir,
+		NodeModules: nodeModules,
+		Target:      target,
+		Module:      module,
+		Minify:      opts.Minify,
+		TSX:         opts.TSX,
+		Decorators:  opts.Decorators,
+		Sourcemap:   opts.Sourcemap,
+		External:    opts.External,
+		Alias:       opts.Alias,
+		Define:      opts.Define,
+		Extensions:  resolveExtensions,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render rspack config: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// CheckRspackConfig renders the rspack config template for a session bundle directory
+// with the current transform options and reports whether it is usable
+func CheckRspackConfig(sessionBundleDir string) error {
+	_, err := RspackConfig(globalTransform, sessionBundleDir)
+	return err
+}
+
+// rspackTemplateSource returns the name and text of the configured rspack config
+// template, or of the embedded one
+func rspackTemplateSource() (name, text string, err error) {
+	if globalRspackConfigPath == "" {
+		return "rspack.config.ts.tmpl", embeddedRspackConfig, nil
+	}
+	data, err := os.ReadFile(globalRspackConfigPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read rspack config: %w", err)
+	}
+	return globalRspackConfigPath, string(data), nil
+}
+
+// rspackTemplateHash returns the hash of the rspack config template's text, so
+// bundles cached before the template was edited are not reused
+func rspackTemplateHash() (string, error) {
+	_, text, err := rspackTemplateSource()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// rspackTemplate parses the configured rspack config template, or the embedded one
+func rspackTemplate() (*template.Template, error) {
+	name, text, err := rspackTemplateSource()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"json": rspackJSON}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rspack config: %w", err)
+	}
+	return tmpl, nil
+}
+
+// rspackJSON renders v for a config template
+func rspackJSON(v any) (string, error) {
+	encoded, err := json.Marshal(v)
+	return string(encoded), err
+}
+
+// findRspack attempts to locate the rspack executable, falling back to npx
+func findRspack() (string, error) {
+	if path, err := exec.LookPath("rspack"); err == nil {
+		return path, nil
+	}
+	if matches, _ := filepath.Glob(filepath.Join(os.Getenv("HOME"), ".nvm", "versions", "node", "*", "bin", "rspack")); len(matches) > 0 {
+		return matches[0], nil
+	}
+	if _, err := exec.LookPath("npx"); err == nil {
+		return "npx", nil
+	}
+	return "", fmt.Errorf("rspack executable not found - install it with: npm install -g @rspack/cli @rspack/core")
+}
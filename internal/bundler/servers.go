@@ -0,0 +1,210 @@
+package bundler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// serverExport matches a server's namespace re-export in the generated servers/index.ts
+var serverExport = regexp.MustCompile(`^export \* as (\w+) from '\./(\w+)';$`)
+
+// importedServers finds the server libraries that code and its helper modules import
+// statically, e.g. "./servers/github" or { github } from "./servers". It returns
+// ok false when that cannot be told without running the code (a namespace or
+// side-effect import of the servers index, dynamic import() or require()), in
+// which case every library has to be bundled. Aliased imports are followed.
+func importedServers(code string, files map[string]string, transform TransformOptions) (names []string, ok bool) {
+	seen := map[string]bool{}
+	modules := map[string]string{".": code}
+	for name, content := range files {
+		modules[name] = content
+	}
+
+	for name, content := range modules {
+		if strings.Contains(content, "import(") || strings.Contains(content, "require(") {
+			return nil, false
+		}
+		dir := "."
+		if name != "." {
+			dir = path.Dir(name)
+		}
+		for _, stmt := range scanModuleStatements(content) {
+			if !stmt.hoist {
+				continue
+			}
+			text := content[stmt.start:stmt.end]
+			specifier, found := moduleSpecifier(text)
+			if !found {
+				continue
+			}
+			target := path.Join(dir, specifier)
+			if aliased, ok := transform.aliasTarget(specifier); ok {
+				target = path.Clean(aliased)
+			} else if !strings.HasPrefix(specifier, ".") {
+				continue
+			}
+
+			switch {
+			case target == "servers" || target == "servers/index" || target == "servers/index.ts":
+				bindings, named := importedBindings(text)
+				if !named {
+					return nil, false
+				}
+				for _, binding := range bindings {
+					seen[binding] = true
+				}
+			case strings.HasPrefix(target, "servers/"):
+				server, _, _ := strings.Cut(strings.TrimPrefix(target, "servers/"), "/")
+				server = strings.TrimSuffix(server, path.Ext(server))
+				if server != "mcp-types" {
+					seen[server] = true
+				}
+			}
+		}
+	}
+
+	names = make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// importedBindings returns the names an import or export statement takes from
+// its module's brace list, e.g. ["github", "slack"] for
+// `import { github, slack as s } from "./servers"`. It returns named false for
+// statements that take the whole module.
+func importedBindings(stmt string) (names []string, named bool) {
+	open := strings.IndexByte(stmt, '{')
+	closing := strings.IndexByte(stmt, '}')
+	if open < 0 || closing < open || strings.Contains(stmt[:open], "*") {
+		return nil, false
+	}
+	for _, binding := range strings.Split(stmt[open+1:closing], ",") {
+		fields := strings.Fields(binding)
+		if len(fields) > 0 && fields[0] == "type" && len(fields) > 1 {
+			fields = fields[1:]
+		}
+		if len(fields) > 0 {
+			names = append(names, fields[0])
+		}
+	}
+	return names, true
+}
+
+// prunedServerIndex returns the session's servers/index.ts without the
+// re-exports of servers not in keep, so bundling it does not load their libraries.
+// ok is false when the session has no index.
+func prunedServerIndex(sessionBundleDir string, keep []string) (index string, ok bool, err error) {
+	data, err := os.ReadFile(filepath.Join(sessionBundleDir, "servers", "index.ts"))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read servers index: %w", err)
+	}
+
+	kept := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		kept[name] = true
+	}
+	lines := strings.Split(string(data), "\n")
+	pruned := lines[:0]
+	for _, line := range lines {
+		if m := serverExport.FindStringSubmatch(line); m != nil && !kept[m[1]] {
+			continue
+		}
+		pruned = append(pruned, line)
+	}
+	return strings.Join(pruned, "\n"), true, nil
+}
+
+// serverLibHash hashes the lib manifest entries a bundle importing only the given
+// servers depends on: theirs and the shared files, not the other servers'.
+// Without a manifest it falls back to SessionLibHash.
+func serverLibHash(sessionBundleDir string, servers []string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(sessionBundleDir, LibManifestFile))
+	if os.IsNotExist(err) {
+		return SessionLibHash(sessionBundleDir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read lib manifest: %w", err)
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", fmt.Errorf("failed to read lib manifest: %w", err)
+	}
+
+	used := make(map[string]bool, len(servers))
+	for _, name := range servers {
+		used[name] = true
+	}
+	subset := make(map[string]string)
+	for name, hash := range manifest {
+		// Server libraries are directories; the shared files have extensions
+		if used[name] || path.Ext(name) != "" {
+			subset[name] = hash
+		}
+	}
+	// The index lists every server, so it is hashed as the pruned bundle sees it
+	index, ok, err := prunedServerIndex(sessionBundleDir, servers)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		sum := sha256.Sum256([]byte(index))
+		subset["index.ts"] = hex.EncodeToString(sum[:])
+	}
+
+	encoded, err := json.Marshal(subset)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// linkServers links the servers a bundle imports into workDir/servers, with the
+// shared files and a pruned index, for toolchains that bundle from disk
+func linkServers(workDir, sessionBundleDir string, servers []string) error {
+	src := filepath.Join(sessionBundleDir, "servers")
+	dst := filepath.Join(workDir, "servers")
+	if err := os.Mkdir(dst, 0755); err != nil {
+		return fmt.Errorf("failed to create servers dir: %w", err)
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("failed to read server libraries: %w", err)
+	}
+	used := make(map[string]bool, len(servers))
+	for _, name := range servers {
+		used[name] = true
+	}
+	for _, entry := range entries {
+		if entry.Name() == "index.ts" || (entry.IsDir() && !used[entry.Name()]) {
+			continue
+		}
+		if err := os.Symlink(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return fmt.Errorf("failed to link server library %s: %w", entry.Name(), err)
+		}
+	}
+
+	index, ok, err := prunedServerIndex(sessionBundleDir, servers)
+	if err != nil || !ok {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dst, "index.ts"), []byte(index), 0644); err != nil {
+		return fmt.Errorf("failed to write servers index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportedServers(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		files map[string]string
+		want  []string // nil when every library is needed
+	}{
+		{"none", `console.log(1);`, nil, []string{}},
+		{"direct", `import { listRepos } from "./servers/github";`, nil, []string{"github"}},
+		{"function file", `import { listRepos } from "./servers/github/listRepos.ts";`, nil, []string{"github"}},
+		{"index bindings", `import { github, slack as chat, type TextContent } from "./servers";`, nil, []string{"TextContent", "github", "slack"}},
+		{"types only", `import type { CallToolResult } from "./servers/mcp-types";`, nil, []string{}},
+		{"helper", `import { f } from "./lib/f";`, map[string]string{"lib/f.ts": `export { listRepos as f } from "../servers/github";`}, []string{"github"}},
+		{"namespace", `import * as servers from "./servers";`, nil, nil},
+		{"side effect", `import "./servers";`, nil, nil},
+		{"dynamic", `const m = await import("./servers/" + name);`, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := importedServers(tt.code, tt.files, DefaultTransformOptions())
+			if tt.want == nil {
+				if ok {
+					t.Fatalf("expected every library to be needed, got %v", got)
+				}
+				return
+			}
+			if !ok || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v (ok %v)", tt.want, got, ok)
+			}
+		})
+	}
+}
+
+func TestImportedServersFollowsAliases(t *testing.T) {
+	opts := DefaultTransformOptions()
+	opts.Alias = map[string]string{"@servers": "./servers"}
+	got, ok := importedServers(`import { slack } from "@servers"; import { listRepos } from "@servers/github";`, nil, opts)
+	if !ok || !reflect.DeepEqual(got, []string{"github", "slack"}) {
+		t.Fatalf("expected [github slack], got %v (ok %v)", got, ok)
+	}
+}
+
+// newServersSession writes a session with a github and a slack library
+func newServersSession(t *testing.T) string {
+	dir := t.TempDir()
+	for name, lib := range map[string]string{
+		"github": "export const listRepos = (): string => 'github-lib';\n",
+		"slack":  "export const postMessage = (): string => 'slack-lib';\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, "servers", name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "servers", name, "index.ts"), []byte(lib), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index := "export * as github from './github';\nexport * as slack from './slack';\n\nexport * from './mcp-types';\n"
+	if err := os.WriteFile(filepath.Join(dir, "servers", "index.ts"), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "servers", "mcp-types.ts"), []byte("export type TextContent = { text: string };\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLibManifest(dir); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestBundleLeavesOutUnusedServers(t *testing.T) {
+	sessionDir := newServersSession(t)
+	b := &Bundler{toolchain: esbuildToolchain{}, transform: DefaultTransformOptions()}
+
+	js, _, err := b.BundleWithFiles(sessionDir, `import { github } from "./servers"; console.log(github.listRepos());`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(js, "github-lib") || strings.Contains(js, "slack-lib") {
+		t.Errorf("expected only the github library to be bundled, got:\n%s", js)
+	}
+
+	js, _, err = b.BundleWithFiles(sessionDir, `import * as servers from "./servers"; console.log(servers);`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(js, "github-lib") || !strings.Contains(js, "slack-lib") {
+		t.Errorf("expected a namespace import to bundle every library, got:\n%s", js)
+	}
+}
+
+func TestServerLibHashIgnoresUnusedServers(t *testing.T) {
+	sessionDir := newServersSession(t)
+	before, err := serverLibHash(sessionDir, []string{"github"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(sessionDir, "servers", "slack", "index.ts"), []byte("export {};\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLibManifest(sessionDir); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := serverLibHash(sessionDir, []string{"github"}); after != before {
+		t.Error("expected a change to an unused library to keep the hash")
+	}
+	if after, _ := serverLibHash(sessionDir, []string{"github", "slack"}); after == before {
+		t.Error("expected a change to a used library to change the hash")
+	}
+}
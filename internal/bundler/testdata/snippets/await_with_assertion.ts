@@ -0,0 +1,4 @@
+// want: 42
+const value = (await Promise.resolve("41")) as unknown as string;
+const parsed = <number>Number(value);
+return parsed + 1;
@@ -0,0 +1,5 @@
+// want: 3
+// type Foo = string; interface Bar { x: number }
+/* const value = input as Foo; */
+const total = [1, 2].reduce((sum, n) => sum + n, 0);
+return total;
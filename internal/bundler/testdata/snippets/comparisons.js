@@ -0,0 +1,4 @@
+// want: [true, "lt"]
+const a = 1, b = 2, c = 3;
+const sign = (x) => x < b ? "lt" : "ge";
+return [a < b && c > b, sign(a)];
@@ -0,0 +1,4 @@
+// want: "label:1:2"
+const type = "label";
+const as = { type: 1, as: 2 };
+return [type, as.type, as.as].join(":");
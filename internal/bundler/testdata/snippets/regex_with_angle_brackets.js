@@ -0,0 +1,3 @@
+// want: true
+const generic = /<T>\s*as\s+type/;
+return generic.test("<T> as type");
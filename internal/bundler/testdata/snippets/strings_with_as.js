@@ -0,0 +1,4 @@
+// want: "treat this as text / type alias"
+const message = "treat this as text";
+const kind = 'type alias';
+return message + " / " + kind;
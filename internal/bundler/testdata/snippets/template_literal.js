@@ -0,0 +1,4 @@
+// want: "cast a as b: type"
+const a = "a";
+const b = "b";
+return `cast ${a} as ${b}: ${"type"}`;
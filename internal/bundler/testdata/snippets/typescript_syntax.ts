@@ -0,0 +1,14 @@
+// want: {"id":7,"kind":"Green","names":["a","b"]}
+interface Item { id: number; name?: string }
+enum Color { Red, Green }
+type Pair<T> = [T, T];
+
+function identity<T>(value: T): T {
+  return value;
+}
+
+const item = identity<Item>({ id: 7 });
+const names: Pair<string> = ["a", "b"];
+const config = { retries: 3 } as const satisfies { retries: number };
+const maybe: Item | undefined = item;
+return { id: maybe!.id, kind: Color[Color.Green], names: config.retries > 0 ? names : [] };
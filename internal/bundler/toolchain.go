@@ -0,0 +1,180 @@
+package bundler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Toolchain names for the available bundlers
+const (
+	ToolchainAuto    = ""        // esbuild, or bun when the sandbox runtime is bun
+	ToolchainEsbuild = "esbuild" // In-process esbuild API; no startup cost and no external tools
+	ToolchainRspack  = "rspack"  // rspack CLI with the swc loader, for webpack-compatible output
+	ToolchainBun     = "bun"     // "bun build"; pairs with the bun runtime
+)
+
+// Toolchain bundles an entry module and its helpers against a session's libraries
+type Toolchain interface {
+	// Name identifies the toolchain; it is part of the bundle cache key
+	Name() string
+
+	// Bundle bundles entry, resolving the generated server libraries from libDir
+	// (the session bundle directory)
+	Bundle(entry Entry, libDir string, opts BundleOptions) (Output, error)
+}
+
+// Entry is the code to bundle
+type Entry struct {
+	Code  string            // Entry point source
+	Files map[string]string // Helper modules keyed by relative path, e.g. "lib/math.ts"
+
+	// Servers lists the server libraries the code imports; the others are left
+	// out of the bundle. nil includes every library.
+	Servers []string
+}
+
+// BundleOptions configures one Toolchain.Bundle call
+type BundleOptions struct {
+	Transform TransformOptions
+	Packages  *Packages // npm packages the code may import (optional)
+}
+
+// Output is a bundle produced by a Toolchain
+type Output struct {
+	JS        string
+	SourceMap string
+	Stats     Stats
+}
+
+// Stats describes how a bundle was produced
+type Stats struct {
+	Duration time.Duration // Time spent in the toolchain
+	Modules  int           // Modules in the bundle, zero when the toolchain does not report them
+	Bytes    int           // Size of the bundled JavaScript
+	Cached   bool          // Returned from the bundle cache; only Bytes is set
+
+	// ModuleBytes is what each module, keyed by path, contributes to the bundle,
+	// when the toolchain reports it
+	ModuleBytes map[string]int
+}
+
+// NewToolchain returns the toolchain with the given name.
+// ToolchainAuto selects esbuild; callers pairing bundling with the bun runtime
+// resolve it to ToolchainBun first.
+func NewToolchain(name string) (Toolchain, error) {
+	switch name {
+	case ToolchainAuto, ToolchainEsbuild:
+		if globalIncremental {
+			return incrementalToolchain{}, nil
+		}
+		return esbuildToolchain{}, nil
+	case ToolchainRspack:
+		rspackPath, err := GetRspackPath()
+		if err != nil {
+			return nil, err
+		}
+		return rspackToolchain{path: rspackPath}, nil
+	case ToolchainBun:
+		bunPath, err := GetBunPath()
+		if err != nil {
+			return nil, err
+		}
+		return bunToolchain{path: bunPath}, nil
+	default:
+		return nil, fmt.Errorf("unknown bundler toolchain %q (must be esbuild, rspack, or bun)", name)
+	}
+}
+
+// workDir is a temporary directory laid out for toolchains that bundle from disk:
+// the entry and helper modules and a "servers" link to the session libraries.
+// Allowed packages resolve from a node_modules link in it, removed with it.
+type workDir struct {
+	dir   string
+	entry string // Absolute path of the entry module
+}
+
+// newWorkDir prepares a work directory under libDir. The entry and helpers are
+// transformed in-process first so syntax errors are reported in milliseconds
+// instead of after paying the toolchain's startup cost.
+// The caller removes it with remove.
+func newWorkDir(entry Entry, libDir string, opts BundleOptions) (*workDir, error) {
+	if _, _, err := Transform(entry.Code, opts.Transform); err != nil {
+		return nil, err
+	}
+	if err := checkFiles(entry.Files, opts.Transform); err != nil {
+		return nil, err
+	}
+
+	// Create unique work directory for this request
+	workID, err := generateWorkID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate work ID: %w", err)
+	}
+
+	w := &workDir{dir: filepath.Join(libDir, "work", workID)}
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work dir: %w", err)
+	}
+
+	// Symlink to shared servers directory, or to just the imported libraries
+	if entry.Servers != nil {
+		if err := linkServers(w.dir, libDir, entry.Servers); err != nil {
+			w.remove()
+			return nil, err
+		}
+	} else if err := os.Symlink(filepath.Join(libDir, "servers"), filepath.Join(w.dir, "servers")); err != nil {
+		w.remove()
+		return nil, fmt.Errorf("failed to create servers symlink: %w", err)
+	}
+
+	// Link allowed npm packages the code imports; other bare imports are rejected
+	if opts.Packages != nil {
+		if _, err := opts.Packages.link(w.dir, opts.Transform, sources(entry.Code, entry.Files)...); err != nil {
+			w.remove()
+			return nil, err
+		}
+	}
+
+	if err := writeFiles(w.dir, entry.Files); err != nil {
+		w.remove()
+		return nil, err
+	}
+
+	w.entry = filepath.Join(w.dir, entryName(opts.Transform))
+	if err := os.WriteFile(w.entry, []byte(entry.Code), 0644); err != nil {
+		w.remove()
+		return nil, fmt.Errorf("failed to write user code: %w", err)
+	}
+	return w, nil
+}
+
+// output reads the bundle a toolchain wrote as name (and name.map, when sourcemap
+// is set) to the dist directory
+func (w *workDir) output(name string, sourcemap bool) (Output, error) {
+	jsBytes, err := os.ReadFile(filepath.Join(w.dist(), name))
+	if err != nil {
+		return Output{}, fmt.Errorf("failed to read bundled JS: %w", err)
+	}
+	if !sourcemap {
+		return Output{JS: string(jsBytes)}, nil
+	}
+
+	sourceMapBytes, err := os.ReadFile(filepath.Join(w.dist(), name+".map"))
+	if err != nil {
+		return Output{}, fmt.Errorf("failed to read source map: %w", err)
+	}
+
+	return Output{JS: string(jsBytes), SourceMap: string(sourceMapBytes)}, nil
+}
+
+// dist is the directory toolchains write their output to
+func (w *workDir) dist() string {
+	return filepath.Join(w.dir, "dist")
+}
+
+// remove deletes the work directory
+func (w *workDir) remove() {
+	os.RemoveAll(w.dir)
+}
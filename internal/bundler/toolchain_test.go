@@ -0,0 +1,39 @@
+package bundler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewToolchain(t *testing.T) {
+	for _, name := range []string{ToolchainAuto, ToolchainEsbuild} {
+		toolchain, err := NewToolchain(name)
+		if err != nil {
+			t.Fatalf("toolchain %q: %v", name, err)
+		}
+		if toolchain.Name() != ToolchainEsbuild {
+			t.Errorf("toolchain %q: expected esbuild, got %s", name, toolchain.Name())
+		}
+	}
+
+	if _, err := NewToolchain("webpack"); err == nil || !strings.Contains(err.Error(), "unknown bundler toolchain") {
+		t.Errorf("expected an unknown toolchain error, got %v", err)
+	}
+}
+
+func TestEsbuildToolchainReportsStats(t *testing.T) {
+	entry := Entry{
+		Code:  `import { double } from "./lib/math"; console.log(double(2));`,
+		Files: map[string]string{"lib/math.ts": "export const double = (n: number): number => n * 2;"},
+	}
+	out, err := esbuildToolchain{}.Bundle(entry, t.TempDir(), BundleOptions{Transform: DefaultTransformOptions()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Stats.Modules != 2 {
+		t.Errorf("expected 2 modules, got %d", out.Stats.Modules)
+	}
+	if out.Stats.Bytes != len(out.JS) || out.Stats.Duration <= 0 {
+		t.Errorf("expected size and duration to be reported, got %+v", out.Stats)
+	}
+}
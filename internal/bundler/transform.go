@@ -0,0 +1,245 @@
+package bundler
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// TransformOptions configures in-process TypeScript to JavaScript transforms
+type TransformOptions struct {
+	Target     string // ECMAScript target (e.g., "es2020")
+	Format     string // Module format: "esm" or "cjs"
+	Sourcefile string // File name used in error messages and source maps
+	Sourcemap  bool   // Whether to produce an external source map
+	Decorators bool   // Allow TypeScript experimental (legacy) decorators
+	TSX        bool   // Parse JSX in TypeScript (the entry becomes index.tsx)
+	Minify     bool   // Minify the output
+
+	External []string          // Imports left in the bundle for the runtime to resolve, e.g. "node:fs"
+	Alias    map[string]string // Import prefixes mapped to paths relative to the entry, e.g. "@lib" to "./lib"
+	Define   map[string]string // Global identifiers replaced with JavaScript expressions, e.g. "DEBUG" to "false"
+}
+
+// DefaultTransformOptions returns the default transform and bundle settings
+func DefaultTransformOptions() TransformOptions {
+	return TransformOptions{
+		Target:     "es2020",
+		Format:     "esm",
+		Sourcefile: "index.ts",
+		Sourcemap:  true,
+	}
+}
+
+var globalTransform = DefaultTransformOptions()
+
+// SetTransformOptions sets the transform settings of bundlers created afterwards.
+// Should be called once at application startup.
+func SetTransformOptions(opts TransformOptions) error {
+	if _, err := parseTarget(opts.Target); err != nil {
+		return err
+	}
+	if _, err := parseFormat(opts.Format); err != nil {
+		return err
+	}
+	for prefix, target := range opts.Alias {
+		if prefix == "" || strings.HasPrefix(prefix, ".") || strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("invalid alias %q: must be a bare import prefix such as \"@lib\"", prefix)
+		}
+		clean := path.Clean(target)
+		if !strings.HasPrefix(target, "./") || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("invalid alias %q: target %q must be a clean relative path such as \"./lib\"", prefix, target)
+		}
+	}
+	if len(opts.Define) > 0 {
+		result := api.Transform("", api.TransformOptions{Define: opts.Define, LogLevel: api.LogLevelSilent})
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("invalid define:\n%s", formatMessages(result.Errors))
+		}
+	}
+	opts.Sourcefile = entryName(opts)
+	globalTransform = opts
+	return nil
+}
+
+// GetTransformOptions returns the transform settings set at startup
+func GetTransformOptions() TransformOptions {
+	return globalTransform
+}
+
+// entryName is the file user code is written to, which tells the toolchains whether to parse JSX
+func entryName(opts TransformOptions) string {
+	if opts.TSX {
+		return "index.tsx"
+	}
+	return "index.ts"
+}
+
+// fingerprint identifies the settings that change the bundle, for the bundle cache key
+func (opts TransformOptions) fingerprint() string {
+	external := append([]string(nil), opts.External...)
+	sort.Strings(external)
+	return fmt.Sprintf("target=%s format=%s decorators=%t tsx=%t minify=%t sourcemap=%t external=%q alias=%s define=%s",
+		strings.ToLower(opts.Target), strings.ToLower(opts.Format), opts.Decorators, opts.TSX, opts.Minify,
+		opts.Sourcemap, external, sortedPairs(opts.Alias), sortedPairs(opts.Define))
+}
+
+// sortedPairs renders a map as quoted key=value pairs in key order
+func sortedPairs(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for _, key := range sortedNames(m) {
+		pairs = append(pairs, fmt.Sprintf("%q=%q", key, m[key]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// resolvedElsewhere reports whether an import is left to the runtime (External)
+// or rewritten to a local path (Alias) rather than resolved as an npm package
+func (opts TransformOptions) resolvedElsewhere(specifier string) bool {
+	for _, external := range opts.External {
+		if specifier == external || strings.HasPrefix(specifier, external+"/") {
+			return true
+		}
+	}
+	_, ok := opts.aliasTarget(specifier)
+	return ok
+}
+
+// aliasPaths renders aliases as tsconfig "paths", matching the prefix itself and
+// the modules under it
+func aliasPaths(alias map[string]string) string {
+	paths := make(map[string][]string, 2*len(alias))
+	for prefix, target := range alias {
+		target = strings.TrimSuffix(target, "/")
+		paths[prefix] = []string{target}
+		paths[prefix+"/*"] = []string{target + "/*"}
+	}
+	encoded, _ := json.Marshal(paths)
+	return string(encoded)
+}
+
+// aliasTarget rewrites an aliased import to its path relative to the entry,
+// e.g. "@lib/math" to "./lib/math" for the alias "@lib" to "./lib"
+func (opts TransformOptions) aliasTarget(specifier string) (string, bool) {
+	for prefix, target := range opts.Alias {
+		if specifier == prefix {
+			return target, true
+		}
+		if rest, ok := strings.CutPrefix(specifier, prefix+"/"); ok {
+			return strings.TrimSuffix(target, "/") + "/" + rest, true
+		}
+	}
+	return "", false
+}
+
+// Transform converts TypeScript source to JavaScript in-process using the esbuild API.
+// Unlike bundling, no external process is started, so a transform takes milliseconds.
+func Transform(code string, opts TransformOptions) (js string, sourceMap string, err error) {
+	target, err := parseTarget(opts.Target)
+	if err != nil {
+		return "", "", err
+	}
+
+	format, err := parseFormat(opts.Format)
+	if err != nil {
+		return "", "", err
+	}
+
+	sourcemap := api.SourceMapNone
+	if opts.Sourcemap {
+		sourcemap = api.SourceMapExternal
+	}
+
+	loader := api.LoaderTS
+	if opts.TSX {
+		loader = api.LoaderTSX
+	}
+
+	tsconfig := ""
+	if opts.Decorators {
+		tsconfig = `{"compilerOptions": {"experimentalDecorators": true}}`
+	}
+
+	result := api.Transform(code, api.TransformOptions{
+		Loader:            loader,
+		Target:            target,
+		Format:            format,
+		Sourcefile:        opts.Sourcefile,
+		Sourcemap:         sourcemap,
+		TsconfigRaw:       tsconfig,
+		MinifyWhitespace:  opts.Minify,
+		MinifyIdentifiers: opts.Minify,
+		MinifySyntax:      opts.Minify,
+		LogLevel:          api.LogLevelSilent,
+	})
+
+	if len(result.Errors) > 0 {
+		return "", "", esbuildError("transform", result.Errors)
+	}
+
+	return string(result.Code), string(result.Map), nil
+}
+
+// parseTarget maps an ECMAScript target name to the esbuild target
+func parseTarget(target string) (api.Target, error) {
+	switch strings.ToLower(target) {
+	case "", "es2020":
+		return api.ES2020, nil
+	case "es5":
+		return api.ES5, nil
+	case "es6", "es2015":
+		return api.ES2015, nil
+	case "es2016":
+		return api.ES2016, nil
+	case "es2017":
+		return api.ES2017, nil
+	case "es2018":
+		return api.ES2018, nil
+	case "es2019":
+		return api.ES2019, nil
+	case "es2021":
+		return api.ES2021, nil
+	case "es2022":
+		return api.ES2022, nil
+	case "es2023":
+		return api.ES2023, nil
+	case "es2024":
+		return api.ES2024, nil
+	case "es2025":
+		return api.ES2025, nil
+	case "esnext":
+		return api.ESNext, nil
+	default:
+		return 0, fmt.Errorf("unsupported transform target %q", target)
+	}
+}
+
+// parseFormat maps a module format name to the esbuild format
+func parseFormat(format string) (api.Format, error) {
+	switch strings.ToLower(format) {
+	case "", "esm", "es6":
+		return api.FormatESModule, nil
+	case "cjs", "commonjs":
+		return api.FormatCommonJS, nil
+	default:
+		return 0, fmt.Errorf("unsupported module format %q", format)
+	}
+}
+
+// formatMessages renders esbuild messages as "file:line:column: text" lines
+func formatMessages(messages []api.Message) string {
+	lines := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Location != nil {
+			lines = append(lines, fmt.Sprintf("%s:%d:%d: %s",
+				msg.Location.File, msg.Location.Line, msg.Location.Column+1, msg.Text))
+		} else {
+			lines = append(lines, msg.Text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
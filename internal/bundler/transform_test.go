@@ -0,0 +1,189 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransformDecoratorsAndTSX(t *testing.T) {
+	decorated := "function log(target: any) { return target; }\n@log\nclass Greeter {}\n"
+	if _, _, err := Transform(decorated, DefaultTransformOptions()); err != nil {
+		t.Fatalf("expected standard decorators to transform, got %v", err)
+	}
+
+	opts := DefaultTransformOptions()
+	opts.Decorators = true
+	js, _, err := Transform(decorated, opts)
+	if err != nil {
+		t.Fatalf("expected experimental decorators to transform, got %v", err)
+	}
+	if !strings.Contains(js, "__decorateClass") {
+		t.Errorf("expected legacy decorator helpers, got:\n%s", js)
+	}
+
+	element := "const el = <div id=\"x\">hi</div>;\n"
+	if _, _, err := Transform(element, DefaultTransformOptions()); err == nil {
+		t.Error("expected JSX to be rejected without tsx")
+	}
+	opts = DefaultTransformOptions()
+	opts.TSX = true
+	if _, _, err := Transform(element, opts); err != nil {
+		t.Errorf("expected JSX to transform with tsx, got %v", err)
+	}
+}
+
+func TestSetTransformOptionsRejectsUnknownTarget(t *testing.T) {
+	opts := DefaultTransformOptions()
+	opts.Target = "es1999"
+	if err := SetTransformOptions(opts); err == nil {
+		t.Error("expected an unsupported target to be rejected")
+	}
+	if GetTransformOptions().Target != "es2020" {
+		t.Errorf("expected the invalid options not to be applied, got %+v", GetTransformOptions())
+	}
+}
+
+func TestSetTransformOptionsRejectsInvalidAliasAndDefine(t *testing.T) {
+	for _, alias := range []map[string]string{{"./lib": "./lib"}, {"@lib": "lib"}, {"@lib": "./../outside"}} {
+		opts := DefaultTransformOptions()
+		opts.Alias = alias
+		if err := SetTransformOptions(opts); err == nil {
+			t.Errorf("expected alias %v to be rejected", alias)
+		}
+	}
+
+	opts := DefaultTransformOptions()
+	opts.Define = map[string]string{"DEBUG": "not an expression("}
+	if err := SetTransformOptions(opts); err == nil {
+		t.Error("expected an invalid define to be rejected")
+	}
+}
+
+func TestBundleAppliesExternalAliasAndDefine(t *testing.T) {
+	opts := DefaultTransformOptions()
+	opts.External = []string{"node:fs"}
+	opts.Alias = map[string]string{"@lib": "./lib"}
+	opts.Define = map[string]string{"STAGE": `"prod"`}
+	opts.Sourcemap = false
+	b := &Bundler{toolchain: esbuildToolchain{}, transform: opts}
+
+	code := `import { readFileSync } from "node:fs"; import { double } from "@lib/math"; console.log(readFileSync, double(STAGE));`
+	files := map[string]string{"lib/math.ts": `import { two } from "@lib/two"; export const double = (s: string) => s.repeat(two);`, "lib/two.ts": "export const two = 2;"}
+	js, sourceMap, err := b.BundleWithFiles(t.TempDir(), code, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`from "node:fs"`, "var two = 2", `double("prod")`} {
+		if !strings.Contains(js, want) {
+			t.Errorf("expected the bundle to contain %s, got:\n%s", want, js)
+		}
+	}
+	if sourceMap != "" {
+		t.Errorf("expected no source map, got %s", sourceMap)
+	}
+}
+
+func TestRspackConfigAppliesOptions(t *testing.T) {
+	if config, err := RspackConfig(DefaultTransformOptions(), "/session"); err != nil || config == "" {
+		t.Fatalf("expected a config, got %v", err)
+	}
+
+	config, err := RspackConfig(TransformOptions{Target: "es6", Format: "cjs", Decorators: true, TSX: true, Minify: true}, "/session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`target: ["node", "es2015"]`,
+		`target: "es2015"`,
+		`module: { type: "commonjs" }`,
+		"minimize: true",
+		`test: /\.tsx?$/`,
+		"tsx: true",
+		"decorators: true",
+		"legacyDecorator: true",
+		`entry: "./index.tsx"`,
+		`modules: ["/session/node_modules", "node_modules"]`,
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("expected config to contain %s", want)
+		}
+	}
+	if strings.Contains(config, "es2020") {
+		t.Error("expected the default target to be replaced")
+	}
+
+	config, err = RspackConfig(TransformOptions{External: []string{"node:fs"}, Alias: map[string]string{"@lib": "/work/lib"}, Define: map[string]string{"DEBUG": "false"}}, "/session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`import { rspack } from "@rspack/core";`,
+		`externals: ["node:fs"],`,
+		`plugins: [new rspack.DefinePlugin({"DEBUG":"false"})],`,
+		`alias: {"@lib":"/work/lib"},`,
+		"devtool: false",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("expected config to contain %s", want)
+		}
+	}
+}
+
+func TestRspackConfigOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rspack.config.ts.tmpl")
+	SetRspackConfig(path)
+	defer SetRspackConfig("")
+
+	if err := CheckRspackConfig("/session"); err == nil {
+		t.Error("expected a missing template to be rejected")
+	}
+
+	override := "export default { entry: {{json .Entry}}, target: {{json .Target}}, context: {{json .LibDir}} };\n"
+	if err := os.WriteFile(path, []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := RspackConfig(DefaultTransformOptions(), "/session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `export default { entry: "./index.ts", target: "es2020", context: "/session" };` + "\n"; config != want {
+		t.Errorf("expected %q, got %q", want, config)
+	}
+
+	for _, broken := range []string{"export default {{.Entry", "export default {{.NoSuchField}}"} {
+		if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := CheckRspackConfig("/session"); err == nil {
+			t.Errorf("expected %q to be rejected", broken)
+		}
+	}
+}
+
+func TestRspackTemplateEditChangesCacheSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rspack.config.ts.tmpl")
+	SetRspackConfig(path)
+	defer SetRspackConfig("")
+
+	b := &Bundler{toolchain: rspackToolchain{}, transform: DefaultTransformOptions()}
+	if _, err := b.cacheSettings(); err == nil {
+		t.Error("expected a missing template to fail")
+	}
+
+	var settings []string
+	for _, text := range []string{"export default { target: {{json .Target}} };", "export default { target: \"es5\" };"} {
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		s, err := b.cacheSettings()
+		if err != nil {
+			t.Fatal(err)
+		}
+		settings = append(settings, s)
+	}
+	if settings[0] == settings[1] {
+		t.Error("expected editing the template to change the bundle cache settings")
+	}
+}
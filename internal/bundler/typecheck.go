@@ -0,0 +1,212 @@
+package bundler
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Diagnostic is one error reported by the TypeScript compiler or a bundler
+type Diagnostic struct {
+	File       string `json:"file"` // Relative to the workspace, e.g. "index.ts" or "servers/github/listRepos.ts"
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	Code       string `json:"code"` // e.g. "TS2322"; bundlers may leave it empty
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"` // How to fix it, when the bundler says
+}
+
+// String formats the diagnostic the way tsc prints it
+func (d Diagnostic) String() string {
+	s := fmt.Sprintf("%s:%d:%d - error %s: %s", d.File, d.Line, d.Column, d.Code, d.Message)
+	if d.Code == "" {
+		s = fmt.Sprintf("%s:%d:%d - error: %s", d.File, d.Line, d.Column, d.Message)
+	}
+	if d.Suggestion != "" {
+		s += " (" + d.Suggestion + ")"
+	}
+	return s
+}
+
+// typeCheckConfig checks the entry and whatever it imports without emitting output.
+// Every file is a module so top-level await type-checks; implicit any is allowed
+// because untyped npm packages and quick scripts rely on it.
+// The web worker lib declares the timers, fetch, URL, TextEncoder and console the
+// runtimes provide, without the DOM they don't.
+// The entry name, decorator support and path aliases follow the bundler's transform options.
+const typeCheckConfig = `{
+  "compilerOptions": {
+    "target": "es2022",
+    "module": "esnext",
+    "moduleResolution": "bundler",
+    "moduleDetection": "force",
+    "resolveJsonModule": true,
+    "lib": ["es2022", "webworker", "webworker.iterable"],
+    "types": [],
+    "strict": true,
+    "noImplicitAny": false,
+    "skipLibCheck": true,
+    "noEmit": true,
+    "jsx": "preserve",
+    "experimentalDecorators": %t,
+    "paths": %s
+  },
+  "files": [%q, "globals.d.ts"]
+}
+`
+
+// typeCheckGlobals declares what the sandbox runtimes provide to every script
+// besides the web worker lib, see withPrelude. outputDir is set by the runtimes
+// that return the files written there.
+const typeCheckGlobals = `declare const input: any;
+declare const secrets: {
+  get(name: string): string;
+};
+declare const codebraid: {
+  input(): any;
+  secrets: typeof secrets;
+  outputDir: string;
+  kv: {
+    get(key: string): Promise<any>;
+    set(key: string, value: unknown): Promise<void>;
+    delete(key: string): Promise<void>;
+  };
+};
+declare function callTool(server: string, tool: string, args?: unknown): Promise<any>;
+`
+
+// ignoredDiagnostics are errors for syntax the entry wrapper makes valid (see PrepareEntry)
+var ignoredDiagnostics = map[string]bool{
+	"TS1108": true, // A 'return' statement can only be used within a function body
+}
+
+// diagnosticLine matches tsc's non-pretty output: file(line,col): error TSxxxx: message
+var diagnosticLine = regexp.MustCompile(`^(.+)\((\d+),(\d+)\): error (TS\d+): (.*)$`)
+
+var (
+	tscOnce sync.Once
+	tscPath string
+	tscErr  error
+)
+
+// findTsc locates the TypeScript compiler, falling back to npx
+func findTsc() (string, error) {
+	tscOnce.Do(func() {
+		if path, err := exec.LookPath("tsc"); err == nil {
+			tscPath = path
+		} else if _, err := exec.LookPath("npx"); err == nil {
+			tscPath = "npx"
+		} else {
+			tscErr = fmt.Errorf("type checking requires tsc (npm install -g typescript) or npx in PATH")
+		}
+	})
+	return tscPath, tscErr
+}
+
+// TypeCheck runs tsc against code, its helper files and the session's generated libraries and returns
+// the type errors found; an empty result means the code type-checks.
+// The error result is reserved for failures to run the checker itself.
+func (b *Bundler) TypeCheck(sessionBundleDir, code string, files map[string]string) ([]Diagnostic, error) {
+	if err := validateFiles(files, entryName(b.transform)); err != nil {
+		return nil, err
+	}
+
+	tsc, err := findTsc()
+	if err != nil {
+		return nil, err
+	}
+
+	workID, err := generateWorkID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate work ID: %w", err)
+	}
+
+	workDir := filepath.Join(sessionBundleDir, "work", workID)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := os.Symlink(filepath.Join(sessionBundleDir, "servers"), filepath.Join(workDir, "servers")); err != nil {
+		return nil, fmt.Errorf("failed to create servers symlink: %w", err)
+	}
+	if b.packages != nil {
+		if _, err := b.packages.link(workDir, b.transform, sources(code, files)...); err != nil {
+			return nil, err
+		}
+	}
+
+	workspace := map[string]string{
+		entryName(b.transform): code,
+		"globals.d.ts":         typeCheckGlobals,
+		"tsconfig.json":        fmt.Sprintf(typeCheckConfig, b.transform.Decorators, aliasPaths(b.transform.Alias), entryName(b.transform)),
+	}
+	for name, content := range workspace {
+		if err := os.WriteFile(filepath.Join(workDir, name), []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	if err := writeFiles(workDir, files); err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	if tsc == "npx" {
+		cmd = exec.Command("npx", "-y", "-p", "typescript", "tsc", "--pretty", "false", "-p", "tsconfig.json")
+	} else {
+		cmd = exec.Command(tsc, "--pretty", "false", "-p", "tsconfig.json")
+	}
+
+	var output bytes.Buffer
+	cmd.Dir = workDir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	runErr := cmd.Run()
+	diagnostics, reported := parseDiagnostics(output.String())
+	if runErr != nil && !reported {
+		return nil, fmt.Errorf("tsc failed: %w\nOutput: %s", runErr, output.String())
+	}
+	return diagnostics, nil
+}
+
+// parseDiagnostics extracts errors from tsc output. Indented lines continue the
+// previous message. reported is true when tsc printed any error, including ignored ones.
+func parseDiagnostics(output string) (diagnostics []Diagnostic, reported bool) {
+	var current *Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if m := diagnosticLine.FindStringSubmatch(line); m != nil {
+			reported = true
+			current = nil
+			if ignoredDiagnostics[m[4]] {
+				continue
+			}
+			lineNum, _ := strconv.Atoi(m[2])
+			column, _ := strconv.Atoi(m[3])
+			diagnostics = append(diagnostics, Diagnostic{
+				File:    filepath.ToSlash(m[1]),
+				Line:    lineNum,
+				Column:  column,
+				Code:    m[4],
+				Message: m[5],
+			})
+			current = &diagnostics[len(diagnostics)-1]
+			continue
+		}
+
+		if current != nil && strings.HasPrefix(line, " ") {
+			current.Message += "\n" + strings.TrimSpace(line)
+		} else {
+			current = nil
+		}
+	}
+	return diagnostics, reported
+}
@@ -0,0 +1,64 @@
+package bundler
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	output := `index.ts(3,7): error TS2322: Type 'string' is not assignable to type 'number'.
+index.ts(5,1): error TS1108: A 'return' statement can only be used within a function body.
+servers/github/listRepos.ts(12,3): error TS2345: Argument of type '{ owner: number; }' is not assignable to parameter of type 'ListReposArgs'.
+  Types of property 'owner' are incompatible.
+    Type 'number' is not assignable to type 'string'.
+`
+
+	diagnostics, reported := parseDiagnostics(output)
+	if !reported {
+		t.Fatal("expected errors to be reported")
+	}
+
+	want := []Diagnostic{
+		{File: "index.ts", Line: 3, Column: 7, Code: "TS2322", Message: "Type 'string' is not assignable to type 'number'."},
+		{File: "servers/github/listRepos.ts", Line: 12, Column: 3, Code: "TS2345", Message: "Argument of type '{ owner: number; }' is not assignable to parameter of type 'ListReposArgs'.\nTypes of property 'owner' are incompatible.\nType 'number' is not assignable to type 'string'."},
+	}
+	if !reflect.DeepEqual(diagnostics, want) {
+		t.Errorf("expected %+v, got %+v", want, diagnostics)
+	}
+}
+
+func TestParseDiagnosticsOnlyIgnored(t *testing.T) {
+	diagnostics, reported := parseDiagnostics("index.ts(2,1): error TS1108: A 'return' statement can only be used within a function body.\n")
+	if !reported || len(diagnostics) != 0 {
+		t.Errorf("expected an ignored error to be reported but not returned, got %v (reported %v)", diagnostics, reported)
+	}
+}
+
+func TestTypeCheckRuntimeGlobals(t *testing.T) {
+	if _, err := exec.LookPath("tsc"); err != nil {
+		t.Skip("tsc is not installed")
+	}
+	sessionDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sessionDir, "servers"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Bundler{toolchain: esbuildToolchain{}, transform: DefaultTransformOptions()}
+	code := `await new Promise((resolve) => setTimeout(resolve, 1));
+const res = await fetch(new URL("https://example.com/?q=1"));
+const bytes: Uint8Array = new TextEncoder().encode(await res.text());
+console.log(bytes.length, secrets.get("token"), codebraid.secrets.get("token"));
+const path: string = codebraid.outputDir + "/out.txt";
+await codebraid.kv.set("path", path);
+`
+	diagnostics, err := b.TypeCheck(sessionDir, code, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("expected the runtime globals to type-check, got %v", diagnostics)
+	}
+}
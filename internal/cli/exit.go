@@ -0,0 +1,106 @@
+// Package cli holds what the codebraid and codegen commands share so scripts and
+// CI can consume them: exit codes that tell failures apart, and errors reported
+// as JSON in JSON output mode.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Exit codes of the commands
+const (
+	ExitOK        = 0
+	ExitFailure   = 1 // The command ran and found problems, such as failed checks
+	ExitUsage     = 2 // Invalid flags or arguments
+	ExitConfig    = 3 // The config could not be loaded or is invalid
+	ExitServer    = 4 // An MCP server could not be reached
+	ExitToolError = 5 // A tool or script ran and reported an error
+)
+
+// errorKinds names the exit codes in JSON errors
+var errorKinds = map[int]string{
+	ExitFailure:   "failure",
+	ExitUsage:     "usage",
+	ExitConfig:    "config",
+	ExitServer:    "server",
+	ExitToolError: "tool_error",
+}
+
+// Error is an error with the exit code it causes
+type Error struct {
+	Code int
+	Err  error
+
+	// Reported is set when the command's output already describes the error,
+	// so JSON output mode adds nothing to it
+	Reported bool
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithCode returns err with the exit code it causes; nil stays nil
+func WithCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// WithReportedCode is WithCode for errors the command's output already describes
+func WithReportedCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err, Reported: true}
+}
+
+// ExitCode returns the exit code err causes: the code of the first *Error in its
+// chain, ExitFailure for other errors and ExitOK for nil
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var cliErr *Error
+	if errors.As(err, &cliErr) {
+		return cliErr.Code
+	}
+	return ExitFailure
+}
+
+// Report writes err for the user of command and returns the exit code: as
+// {"error": {"kind", "exitCode", "message"}} when asJSON is set, else as text
+func Report(w io.Writer, command string, err error, asJSON bool) int {
+	code := ExitCode(err)
+	if err == nil {
+		return code
+	}
+	if !asJSON {
+		fmt.Fprintf(w, "%s: %v\n", command, err)
+		return code
+	}
+	var cliErr *Error
+	if errors.As(err, &cliErr) && cliErr.Reported {
+		return code
+	}
+
+	report := map[string]interface{}{
+		"error": map[string]interface{}{
+			"kind":     errorKinds[code],
+			"exitCode": code,
+			"message":  err.Error(),
+		},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	return code
+}
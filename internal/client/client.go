@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"os/exec"
@@ -16,6 +17,7 @@ type McpClient struct {
 	name           string
 	session        *mcp.ClientSession
 	tools          []*mcp.Tool
+	names          toolNames               // Exposed names of the tools
 	onToolsChanged func(serverName string) // Callback when tools change
 }
 
@@ -75,7 +77,7 @@ func NewMcpClient(ctx context.Context, name string, cfg config.McpServerConfig,
 	if err != nil {
 		// If auto-detect HTTP failed, try SSE as fallback
 		if cfg.Type == "" && usedTransport == "http (auto-detected)" {
-			fmt.Printf("HTTP connection failed for %q, trying SSE fallback...\n", name)
+			log.Printf("HTTP connection failed for %q, trying SSE fallback...", name)
 			transport, err = createSSETransport(cfg)
 			if err == nil {
 				session, err = client.Connect(ctx, transport, &mcp.ClientSessionOptions{})
@@ -90,7 +92,7 @@ func NewMcpClient(ctx context.Context, name string, cfg config.McpServerConfig,
 		}
 	}
 
-	fmt.Printf("Connected to %q using %s transport\n", name, usedTransport)
+	log.Printf("Connected to %q using %s transport", name, usedTransport)
 
 	// List available tools
 	toolsResult, err := session.ListTools(ctx, &mcp.ListToolsParams{})
@@ -98,31 +100,58 @@ func NewMcpClient(ctx context.Context, name string, cfg config.McpServerConfig,
 		return nil, fmt.Errorf("failed to list tools: %w", err)
 	}
 
+	names := newToolNames(cfg)
 	mcpClient := &McpClient{
 		name:           name,
 		session:        session,
-		tools:          toolsResult.Tools,
+		tools:          names.apply(name, toolsResult.Tools),
+		names:          names,
 		onToolsChanged: onToolsChanged,
 	}
 
 	return mcpClient, nil
 }
 
+// baseEnv names the variables a stdio server gets when it does not inherit
+// codebraid's environment, which commands commonly need to start at all
+var baseEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "TERM", "TMPDIR", "TZ",
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "TEMP", "TMP",
+	"USERPROFILE", "APPDATA", "LOCALAPPDATA", "PROGRAMDATA", "PROGRAMFILES",
+}
+
 // createStdioTransport creates a stdio transport
 func createStdioTransport(cfg config.McpServerConfig) (mcp.Transport, error) {
 	cmd := exec.Command(cfg.Command, cfg.Args...)
 
 	if cfg.Cwd != "" {
-		cmd.Dir = cfg.Cwd
+		dir, err := config.ExpandHome(cfg.Cwd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve cwd %q: %w", cfg.Cwd, err)
+		}
+		if info, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("invalid cwd: %w", err)
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("invalid cwd: %s is not a directory", dir)
+		}
+		cmd.Dir = dir
 	}
 
-	if len(cfg.Env) > 0 {
+	if !cfg.InheritsEnv() {
+		// Start with the basics only, so the command sees just what it is given
+		cmd.Env = []string{}
+		for _, key := range baseEnv {
+			if value, ok := os.LookupEnv(key); ok {
+				cmd.Env = append(cmd.Env, key+"="+value)
+			}
+		}
+	} else if len(cfg.Env) > 0 {
 		// Start with current environment
 		cmd.Env = os.Environ()
-		// Add/override with config env vars
-		for k, v := range cfg.Env {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
-		}
+	}
+	// Add/override with config env vars
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
 	return &mcp.CommandTransport{Command: cmd}, nil
@@ -168,10 +197,14 @@ func createSSETransport(cfg config.McpServerConfig) (mcp.Transport, error) {
 	}, nil
 }
 
-// CallTool calls a tool on this MCP client
+// CallTool calls a tool on this MCP client by its exposed name
 func (c *McpClient) CallTool(ctx context.Context, toolName string, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	original, ok := c.names.original(toolName)
+	if !ok {
+		return nil, fmt.Errorf("tool %q not found on server %q", toolName, c.name)
+	}
 	return c.session.CallTool(ctx, &mcp.CallToolParams{
-		Name:      toolName,
+		Name:      original,
 		Arguments: args,
 	})
 }
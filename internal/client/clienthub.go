@@ -2,7 +2,11 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -35,7 +39,9 @@ import (
 // - Optional: ClientHub can notify session layer via onToolsRefreshed callback
 type McpClientHub struct {
 	clients          map[string]*McpClient
+	configs          map[string]config.McpServerConfig // How each client was connected, see Reconcile
 	mu               sync.RWMutex
+	reconcileMu      sync.Mutex              // Serializes Reconcile, which connects without holding mu
 	cachedTools      map[string][]*mcp.Tool  // Lazy-cached result of Tools()
 	onToolsRefreshed func(serverName string) // Optional callback for session layer
 }
@@ -44,6 +50,7 @@ type McpClientHub struct {
 func NewMcpClientHub() *McpClientHub {
 	return &McpClientHub{
 		clients: make(map[string]*McpClient),
+		configs: make(map[string]config.McpServerConfig),
 	}
 }
 
@@ -60,11 +67,83 @@ func (ch *McpClientHub) Connect(ctx context.Context, cfg *config.Config) error {
 			return fmt.Errorf("failed to connect to server %q: %w", name, err)
 		}
 		ch.clients[name] = client
+		ch.configs[name] = serverCfg
 	}
 
 	return nil
 }
 
+// Reconcile connects and disconnects servers so the hub is connected to exactly
+// servers, reconnecting those whose config changed. It returns the names of the
+// servers connected, disconnected or reconnected, sorted. Servers that fail to
+// connect are left out and reported in the error; the others are still applied.
+// New servers are connected without holding the hub's lock, so tool calls on the
+// others carry on meanwhile.
+func (ch *McpClientHub) Reconcile(ctx context.Context, servers map[string]config.McpServerConfig) ([]string, error) {
+	ch.reconcileMu.Lock()
+	defer ch.reconcileMu.Unlock()
+
+	changed := make(map[string]bool)
+	ch.mu.RLock()
+	for name := range ch.clients {
+		if serverCfg, keep := servers[name]; !keep || !reflect.DeepEqual(serverCfg, ch.configs[name]) {
+			changed[name] = true
+		}
+	}
+	for name := range servers {
+		if _, connected := ch.clients[name]; !connected {
+			changed[name] = true
+		}
+	}
+	ch.mu.RUnlock()
+
+	var errs []error
+	connected := make(map[string]*McpClient)
+	for name := range changed {
+		serverCfg, wanted := servers[name]
+		if !wanted {
+			continue
+		}
+		client, err := NewMcpClient(ctx, name, serverCfg, ch.handleToolsChanged)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to connect to server %q: %w", name, err))
+			continue
+		}
+		connected[name] = client
+	}
+
+	stale := make(map[string]*McpClient)
+	ch.mu.Lock()
+	for name := range changed {
+		if client, exists := ch.clients[name]; exists {
+			stale[name] = client
+		}
+		delete(ch.clients, name)
+		delete(ch.configs, name)
+		if client, ok := connected[name]; ok {
+			ch.clients[name] = client
+			ch.configs[name] = servers[name]
+		}
+	}
+	if len(changed) > 0 {
+		ch.cachedTools = nil
+	}
+	ch.mu.Unlock()
+
+	for name, client := range stale {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close client %q: %w", name, err))
+		}
+	}
+
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, errors.Join(errs...)
+}
+
 // CallTool calls a tool on a specific MCP server
 func (ch *McpClientHub) CallTool(ctx context.Context, serverName, toolName string, args map[string]interface{}) (*mcp.CallToolResult, error) {
 	ch.mu.RLock()
@@ -162,7 +241,7 @@ func (ch *McpClientHub) RefreshServerTools(ctx context.Context, serverName strin
 	}
 
 	// Update the client's cached tools
-	client.tools = toolsResult.Tools
+	client.tools = client.names.apply(serverName, toolsResult.Tools)
 
 	// Invalidate the hub's cached map
 	ch.cachedTools = nil
@@ -183,7 +262,7 @@ func (ch *McpClientHub) RefreshAllServerTools(ctx context.Context) error {
 			errs = append(errs, fmt.Errorf("server %q: %w", name, err))
 			continue
 		}
-		client.tools = toolsResult.Tools
+		client.tools = client.names.apply(name, toolsResult.Tools)
 	}
 
 	// Invalidate cache
@@ -209,7 +288,7 @@ func (ch *McpClientHub) SetToolsRefreshedCallback(callback func(serverName strin
 func (ch *McpClientHub) handleToolsChanged(serverName string) {
 	// Run in goroutine to avoid blocking the notification callback
 	go func() {
-		fmt.Printf("Tools changed notification received for server %q\n", serverName)
+		log.Printf("Tools changed notification received for server %q", serverName)
 
 		// Create a timeout context for the refresh operation
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -217,11 +296,11 @@ func (ch *McpClientHub) handleToolsChanged(serverName string) {
 
 		// Refresh tools from the server
 		if err := ch.RefreshServerTools(ctx, serverName); err != nil {
-			fmt.Printf("Failed to auto-refresh tools for %q: %v\n", serverName, err)
+			log.Printf("Failed to auto-refresh tools for %q: %v", serverName, err)
 			return
 		}
 
-		fmt.Printf("Successfully auto-refreshed tools for server %q\n", serverName)
+		log.Printf("Successfully auto-refreshed tools for server %q", serverName)
 
 		// Notify session layer if callback is set
 		ch.mu.RLock()
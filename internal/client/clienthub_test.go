@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+func TestReconcileConnectsWithoutBlockingTheHub(t *testing.T) {
+	ch := NewMcpClientHub()
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	// A server that never answers the initialize request
+	servers := map[string]config.McpServerConfig{"slow": {Type: "stdio", Command: "sleep", Args: []string{"3"}}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := ch.Reconcile(ctx, servers)
+		done <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	listed := make(chan struct{})
+	go func() {
+		ch.Servers()
+		ch.Tools()
+		close(listed)
+	}()
+	select {
+	case <-listed:
+	case <-done:
+		t.Fatal("expected Reconcile to still be connecting")
+	case <-time.After(time.Second):
+		t.Fatal("expected the hub readable while a server connects")
+	}
+
+	if err := <-done; err == nil {
+		t.Error("expected the server that never answered to be reported")
+	}
+	if servers := ch.Servers(); len(servers) != 0 {
+		t.Errorf("expected no servers connected, got %v", servers)
+	}
+}
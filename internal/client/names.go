@@ -0,0 +1,75 @@
+package client
+
+import (
+	"log"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// toolNames maps the names a server gives its tools to the names codebraid
+// exposes them under, as configured with the server's prefix and rename. Every
+// tool list and call goes through it, so generated libraries, routing and the
+// tool call history all use the exposed names.
+type toolNames struct {
+	prefix  string            // Added to every tool not renamed
+	rename  map[string]string // Original name to exposed name
+	renamed map[string]string // Exposed name to original name
+}
+
+// newToolNames returns the mapping configured for a server
+func newToolNames(cfg config.McpServerConfig) toolNames {
+	names := toolNames{prefix: cfg.Prefix, rename: cfg.Rename}
+	if len(cfg.Rename) > 0 {
+		names.renamed = make(map[string]string, len(cfg.Rename))
+		for original, exposed := range cfg.Rename {
+			names.renamed[exposed] = original
+		}
+	}
+	return names
+}
+
+// exposed returns the name a tool is exposed under
+func (n toolNames) exposed(original string) string {
+	if exposed, ok := n.rename[original]; ok {
+		return exposed
+	}
+	return n.prefix + original
+}
+
+// original returns the server's name for an exposed tool name, and false when no
+// tool is exposed under it
+func (n toolNames) original(exposed string) (string, bool) {
+	if original, ok := n.renamed[exposed]; ok {
+		return original, true
+	}
+	original, ok := strings.CutPrefix(exposed, n.prefix)
+	if _, renamed := n.rename[original]; !ok || renamed {
+		return "", false
+	}
+	return original, true
+}
+
+// apply returns the tools of a server under their exposed names. The tools are
+// copied when renamed, as they may be shared with the server's session. Of tools
+// exposed under the same name, the first is kept.
+func (n toolNames) apply(serverName string, tools []*mcp.Tool) []*mcp.Tool {
+	if n.prefix == "" && len(n.rename) == 0 {
+		return tools
+	}
+	exposed := make([]*mcp.Tool, 0, len(tools))
+	seen := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		name := n.exposed(tool.Name)
+		if seen[name] {
+			log.Printf("Warning: server %q: tool %q is renamed to %q, which another tool already uses; it is hidden", serverName, tool.Name, name)
+			continue
+		}
+		seen[name] = true
+		renamed := *tool
+		renamed.Name = name
+		exposed = append(exposed, &renamed)
+	}
+	return exposed
+}
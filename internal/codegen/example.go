@@ -0,0 +1,106 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// TypeScriptExample returns a script calling a tool through the library
+// generated for its server. Required arguments get placeholder values from
+// StubValue; optional ones are listed commented out, so the call type-checks
+// as written and shows every argument the tool takes.
+func TypeScriptExample(serverName string, tool *mcp.Tool) string {
+	namespace := namespaceName(serverName)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "import * as %s from './servers/%s';\n\n", namespace, serverName)
+	fmt.Fprintf(&sb, "const result = await %s.%s(", namespace, FunctionName(tool.Name))
+
+	schema, hasArgs := inputArgsSchema(tool)
+	properties, _ := schema["properties"].(map[string]interface{})
+	switch {
+	case hasArgs && len(properties) > 0:
+		required := make(map[string]bool)
+		if list, ok := schema["required"].([]interface{}); ok {
+			for _, name := range list {
+				if name, ok := name.(string); ok {
+					required[name] = true
+				}
+			}
+		}
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		// Required arguments first, each group in name order
+		sort.Slice(names, func(i, j int) bool {
+			if required[names[i]] != required[names[j]] {
+				return required[names[i]]
+			}
+			return names[i] < names[j]
+		})
+
+		sb.WriteString("{\n")
+		for _, name := range names {
+			property, _ := properties[name].(map[string]interface{})
+			value, err := json.Marshal(stubValue(property, schema, 1))
+			if err != nil {
+				value = []byte("null")
+			}
+			line := fmt.Sprintf("  %s: %s,", propertyKey(name), value)
+			if !required[name] {
+				line = "  // " + strings.TrimSpace(line) + " (optional)"
+			}
+			sb.WriteString(line + "\n")
+		}
+		sb.WriteString("}")
+	case hasArgs:
+		// Arguments without declared properties
+		sb.WriteString("{}")
+	}
+	sb.WriteString(");\n")
+
+	if _, ok := outputResultSchema(tool); ok {
+		fmt.Fprintf(&sb, "// result is a %sResult, the tool's structured output\n", toPascalCase(tool.Name))
+	} else {
+		sb.WriteString("// result is a CallToolResult; its text is in result.content\n")
+	}
+	sb.WriteString("console.log(result);\n")
+	return sb.String()
+}
+
+// namespaceName returns the identifier a server's library is imported as,
+// e.g. googleDrive for google-drive
+func namespaceName(serverName string) string {
+	var sb strings.Builder
+	for _, r := range toCamelCase(serverName) {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	name := sb.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "_" + name
+	}
+	return name
+}
+
+// propertyKey returns a property name as an object literal key, quoted unless
+// it is an identifier
+func propertyKey(name string) string {
+	for i, r := range name {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		quoted, _ := json.Marshal(name)
+		return string(quoted)
+	}
+	if name == "" {
+		return `""`
+	}
+	return name
+}
@@ -0,0 +1,47 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestTypeScriptExample(t *testing.T) {
+	tool := &mcp.Tool{
+		Name: "create_issue",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"title":     map[string]interface{}{"type": "string"},
+				"labels":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				"x-trace":   map[string]interface{}{"type": "string"},
+				"assignees": map[string]interface{}{"type": "integer", "minimum": 1.0},
+			},
+			"required": []interface{}{"title", "assignees"},
+		},
+		OutputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "integer"}}},
+	}
+
+	want := `import * as googleDrive from './servers/google-drive';
+
+const result = await googleDrive.createIssue({
+  assignees: 1,
+  title: "",
+  // labels: [""], (optional)
+  // "x-trace": "", (optional)
+});
+// result is a CreateIssueResult, the tool's structured output
+console.log(result);
+`
+	if got := TypeScriptExample("google-drive", tool); got != want {
+		t.Errorf("unexpected example:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTypeScriptExampleWithoutArgs(t *testing.T) {
+	got := TypeScriptExample("2fa", &mcp.Tool{Name: "list", InputSchema: map[string]interface{}{"type": "object"}})
+	if !strings.Contains(got, "import * as _2fa from './servers/2fa';") || !strings.Contains(got, "await _2fa.list();") {
+		t.Errorf("unexpected example:\n%s", got)
+	}
+}
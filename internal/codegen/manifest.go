@@ -0,0 +1,121 @@
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestName is the file recording the libraries generated into an output directory
+const ManifestName = ".codebraid-manifest.json"
+
+// Manifest records the files generated into an output directory, so the files of
+// servers and tools since removed can be pruned without touching files users added
+type Manifest struct {
+	// Servers lists the files of each server's library, relative to its directory
+	Servers map[string][]string `json:"servers"`
+}
+
+// NewManifest returns an empty manifest
+func NewManifest() *Manifest {
+	return &Manifest{Servers: make(map[string][]string)}
+}
+
+// ReadManifest reads the manifest of an output directory; a directory without one
+// has an empty manifest
+func ReadManifest(outputDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, ManifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return NewManifest(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ManifestName, err)
+	}
+
+	m := NewManifest()
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ManifestName, err)
+	}
+	if m.Servers == nil {
+		m.Servers = make(map[string][]string)
+	}
+	// The manifest decides what gets deleted, so it may only name files inside the output directory
+	for server, files := range m.Servers {
+		if !isPlainName(server) {
+			return nil, fmt.Errorf("invalid %s: server %q is not a directory name", ManifestName, server)
+		}
+		for _, file := range files {
+			if !isPlainName(file) {
+				return nil, fmt.Errorf("invalid %s: file %q of server %q is not a file name", ManifestName, file, server)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Write writes the manifest into the output directory
+func (m *Manifest) Write(outputDir string) error {
+	for _, files := range m.Servers {
+		sort.Strings(files)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, ManifestName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ManifestName, err)
+	}
+	return nil
+}
+
+// ServerNames returns the servers of the manifest, sorted
+func (m *Manifest) ServerNames() []string {
+	names := make([]string, 0, len(m.Servers))
+	for name := range m.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Prune removes the files old records that m does not, then the server
+// directories left empty. Files the manifests do not name are never removed.
+// It returns the removed files, relative to outputDir.
+func (m *Manifest) Prune(outputDir string, old *Manifest) ([]string, error) {
+	var removed []string
+	var errs []error
+	for _, server := range old.ServerNames() {
+		keep := make(map[string]bool, len(m.Servers[server]))
+		for _, file := range m.Servers[server] {
+			keep[file] = true
+		}
+
+		serverDir := filepath.Join(outputDir, server)
+		for _, file := range old.Servers[server] {
+			if keep[file] {
+				continue
+			}
+			err := os.Remove(filepath.Join(serverDir, file))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+				continue
+			}
+			if err == nil {
+				removed = append(removed, filepath.Join(server, file))
+			}
+		}
+		if _, kept := m.Servers[server]; !kept {
+			// Fails, leaving the directory, when users added files to it
+			os.Remove(serverDir)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// isPlainName reports whether name is a single path element
+func isPlainName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name && !filepath.IsAbs(name)
+}
@@ -0,0 +1,78 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManifestPrune(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path string) {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{"github/index.ts", "github/listRepos.ts", "github/deleteRepo.ts", "slack/index.ts", "slack/post.ts", "jira/index.ts", "jira/notes.md"} {
+		write(path)
+	}
+
+	old := NewManifest()
+	old.Servers["github"] = []string{"index.ts", "listRepos.ts", "deleteRepo.ts"}
+	old.Servers["slack"] = []string{"index.ts", "post.ts"}
+	old.Servers["jira"] = []string{"index.ts"}
+	if err := old.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deleteRepo was removed from github, slack and jira from the config
+	current := NewManifest()
+	current.Servers["github"] = []string{"index.ts", "listRepos.ts"}
+	removed, err := current.Prune(dir, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join("github", "deleteRepo.ts"), filepath.Join("jira", "index.ts"), filepath.Join("slack", "index.ts"), filepath.Join("slack", "post.ts")}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed %v, want %v", removed, want)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "github", "listRepos.ts")); err != nil {
+		t.Errorf("current file removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "slack")); !os.IsNotExist(err) {
+		t.Errorf("empty server directory kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "jira", "notes.md")); err != nil {
+		t.Errorf("file not in the manifest removed: %v", err)
+	}
+}
+
+func TestReadManifestRejectsPaths(t *testing.T) {
+	dir := t.TempDir()
+	if m, err := ReadManifest(dir); err != nil || len(m.Servers) != 0 {
+		t.Fatalf("missing manifest: got %v, %v", m, err)
+	}
+
+	for _, data := range []string{
+		`{"servers": {"..": ["index.ts"]}}`,
+		`{"servers": {"github": ["../../etc/passwd"]}}`,
+		`{"servers": {"github": ["/etc/passwd"]}}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, ManifestName), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadManifest(dir); err == nil {
+			t.Errorf("manifest %s accepted", data)
+		}
+	}
+}
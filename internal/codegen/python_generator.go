@@ -0,0 +1,417 @@
+package codegen
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// pythonKeywords cannot be used as module, function or parameter names
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true, "def": true,
+	"del": true, "elif": true, "else": true, "except": true, "finally": true, "for": true,
+	"from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+// PythonGenerator generates Python modules from tool definitions.
+// Each server becomes a module of the "servers" package with one function per
+// tool, called with keyword arguments, e.g. github.list_repos(owner="octocat").
+type PythonGenerator struct{}
+
+// NewPythonGenerator creates a new Python generator
+func NewPythonGenerator() *PythonGenerator {
+	return &PythonGenerator{}
+}
+
+// PythonModuleName returns the module name of a server in the servers package
+func PythonModuleName(serverName string) string {
+	return toPythonIdentifier(serverName)
+}
+
+// PythonFunctionName returns the Python function name for a tool
+func PythonFunctionName(toolName string) string {
+	return toPythonIdentifier(toolName)
+}
+
+// WritePythonLibs writes the servers package under outputDir: an __init__.py
+// importing every server and one module per server
+func WritePythonLibs(outputDir string, grouped map[string][]*mcp.Tool) error {
+	packageDir := filepath.Join(outputDir, "servers")
+	if err := os.MkdirAll(packageDir, 0755); err != nil {
+		return fmt.Errorf("failed to create Python package directory %s: %w", packageDir, err)
+	}
+
+	serverNames := make([]string, 0, len(grouped))
+	for name := range grouped {
+		serverNames = append(serverNames, name)
+	}
+	sort.Strings(serverNames)
+
+	generator := NewPythonGenerator()
+	for _, serverName := range serverNames {
+		if err := generator.WriteServerModule(packageDir, serverName, grouped[serverName]); err != nil {
+			return err
+		}
+	}
+	return WritePythonPackageInit(packageDir, grouped)
+}
+
+// WritePythonPackageInit writes the __init__.py of the servers package in
+// packageDir, importing the module of every server in grouped
+func WritePythonPackageInit(packageDir string, grouped map[string][]*mcp.Tool) error {
+	serverNames := make([]string, 0, len(grouped))
+	for name := range grouped {
+		serverNames = append(serverNames, name)
+	}
+	sort.Strings(serverNames)
+
+	initPath := filepath.Join(packageDir, "__init__.py")
+	if err := os.WriteFile(initPath, []byte(NewPythonGenerator().GeneratePackageInit(serverNames)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", initPath, err)
+	}
+	return nil
+}
+
+// WriteServerModule writes the module of one server into packageDir
+func (g *PythonGenerator) WriteServerModule(packageDir, serverName string, tools []*mcp.Tool) error {
+	path := filepath.Join(packageDir, PythonModuleName(serverName)+".py")
+	err := writeFileWith(path, func(w io.Writer) error {
+		return g.WriteModule(w, serverName, tools)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write Python module for %s: %w", serverName, err)
+	}
+	return nil
+}
+
+// GenerateModule generates the Python module for a server's tools
+func (g *PythonGenerator) GenerateModule(serverName string, tools []*mcp.Tool) (string, error) {
+	var sb strings.Builder
+	if err := g.WriteModule(&sb, serverName, tools); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// WriteModule streams the Python module for a server's tools to w
+func (g *PythonGenerator) WriteModule(w io.Writer, serverName string, tools []*mcp.Tool) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\"\"\"%s MCP server tools\n\n", pythonDocstring(serverName))
+	bw.WriteString("This file is auto-generated. Do not edit manually.\n\"\"\"\n\n")
+	bw.WriteString("from __future__ import annotations\n\n")
+	bw.WriteString("from typing import Any, Literal\n\n")
+	bw.WriteString("from codebraid import call_tool as _call_tool\n\n\n")
+	bw.WriteString("def _args(**values: Any) -> dict[str, Any]:\n")
+	bw.WriteString("    return {name: value for name, value in values.items() if value is not None}\n")
+
+	for _, tool := range tools {
+		if tool == nil {
+			return fmt.Errorf("no tool provided for server %q", serverName)
+		}
+		bw.WriteString("\n\n")
+		g.renderFunction(bw, serverName, tool)
+	}
+	return bw.Flush()
+}
+
+// GeneratePackageInit generates the servers package's __init__.py
+func (g *PythonGenerator) GeneratePackageInit(serverNames []string) string {
+	var sb strings.Builder
+	sb.WriteString("\"\"\"All MCP server tools\n\n")
+	sb.WriteString("Import servers as modules and call their tools with keyword arguments:\n\n")
+	sb.WriteString("    from servers import github\n")
+	sb.WriteString("    repos = github.list_repos(owner=\"octocat\")\n\n")
+	sb.WriteString("This file is auto-generated. Do not edit manually.\n\"\"\"\n\n")
+
+	modules := make([]string, len(serverNames))
+	for i, name := range serverNames {
+		modules[i] = PythonModuleName(name)
+	}
+	for _, module := range modules {
+		fmt.Fprintf(&sb, "from . import %s\n", module)
+	}
+
+	quoted := make([]string, len(modules))
+	for i, module := range modules {
+		quoted[i] = fmt.Sprintf("%q", module)
+	}
+	fmt.Fprintf(&sb, "\n__all__ = [%s]\n", strings.Join(quoted, ", "))
+	return sb.String()
+}
+
+// pythonParam is a keyword argument of a generated function
+type pythonParam struct {
+	name        string
+	annotation  string
+	required    bool
+	description string
+}
+
+// renderFunction writes the function calling one tool.
+// Object schemas whose properties are all valid identifiers become keyword-only
+// parameters; any other argument schema is taken as a dict and/or keywords.
+func (g *PythonGenerator) renderFunction(w *bufio.Writer, serverName string, tool *mcp.Tool) {
+	name := PythonFunctionName(tool.Name)
+	params, keywords := pythonParams(tool)
+
+	var signature, argsValue string
+	switch {
+	case keywords && len(params) == 0:
+		signature = "()"
+		argsValue = "{}"
+	case keywords:
+		parts := []string{"*"}
+		names := make([]string, len(params))
+		for i, p := range params {
+			if p.required {
+				parts = append(parts, fmt.Sprintf("%s: %s", p.name, p.annotation))
+			} else {
+				parts = append(parts, fmt.Sprintf("%s: %s | None = None", p.name, p.annotation))
+			}
+			names[i] = fmt.Sprintf("%s=%s", p.name, p.name)
+		}
+		signature = "(" + strings.Join(parts, ", ") + ")"
+		argsValue = "_args(" + strings.Join(names, ", ") + ")"
+	default:
+		signature = "(args: dict[str, Any] | None = None, /, **kwargs: Any)"
+		argsValue = "{**(args or {}), **kwargs}"
+	}
+
+	fmt.Fprintf(w, "def %s%s -> Any:\n", name, signature)
+
+	description := tool.Description
+	if description == "" {
+		description = "Call tool: " + tool.Name
+	}
+	fmt.Fprintf(w, "    \"\"\"%s\n", indentDocstring(pythonDocstring(description)))
+	if len(params) > 0 {
+		w.WriteString("\n    Args:\n")
+		for _, p := range params {
+			if p.description != "" {
+				fmt.Fprintf(w, "        %s: %s\n", p.name, indentDocstring(pythonDocstring(p.description)))
+			} else {
+				fmt.Fprintf(w, "        %s\n", p.name)
+			}
+		}
+	}
+	if _, ok := outputResultSchema(tool); !ok {
+		w.WriteString("\n    Returns the MCP CallToolResult because no outputSchema is defined.\n")
+	}
+	w.WriteString("    \"\"\"\n")
+	fmt.Fprintf(w, "    return _call_tool(%s, %s, %s)\n", pythonString(serverName), pythonString(tool.Name), argsValue)
+}
+
+// pythonParams returns the keyword parameters of a tool, sorted required first,
+// and whether its arguments can be expressed as keywords at all
+func pythonParams(tool *mcp.Tool) ([]pythonParam, bool) {
+	schema, ok := inputArgsSchema(tool)
+	if !ok {
+		return nil, true
+	}
+	if schemaType, hasType := schema["type"]; hasType && schemaType != "object" {
+		return nil, false
+	}
+	for _, key := range []string{"oneOf", "anyOf", "allOf", "patternProperties", "additionalProperties"} {
+		if value, ok := schema[key]; ok && value != false {
+			return nil, false
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	params := make([]pythonParam, 0, len(properties))
+	for name, raw := range properties {
+		// The module's helpers must not be shadowed by a parameter
+		if !isPythonIdentifier(name) || name == "_args" || name == "_call_tool" {
+			return nil, false
+		}
+		property, _ := raw.(map[string]interface{})
+		description, _ := property["description"].(string)
+		params = append(params, pythonParam{
+			name:        name,
+			annotation:  pythonType(property),
+			required:    required[name],
+			description: description,
+		})
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		if params[i].required != params[j].required {
+			return params[i].required
+		}
+		return params[i].name < params[j].name
+	})
+	return params, true
+}
+
+// pythonType maps a JSON schema to a type annotation
+func pythonType(schema map[string]interface{}) string {
+	if values, ok := schema["enum"].([]interface{}); ok && len(values) > 0 {
+		literals := make([]string, 0, len(values))
+		for _, value := range values {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return "Any"
+			}
+			switch value.(type) {
+			case string, float64:
+				literals = append(literals, pythonLiteral(string(encoded)))
+			default:
+				return "Any"
+			}
+		}
+		return "Literal[" + strings.Join(literals, ", ") + "]"
+	}
+
+	switch schemaType := schema["type"].(type) {
+	case string:
+		return pythonTypeName(schemaType, schema)
+	case []interface{}:
+		types := make([]string, 0, len(schemaType))
+		for _, t := range schemaType {
+			if s, ok := t.(string); ok {
+				types = append(types, pythonTypeName(s, schema))
+			}
+		}
+		if len(types) > 0 {
+			return strings.Join(types, " | ")
+		}
+	}
+	return "Any"
+}
+
+// pythonTypeName maps one JSON schema type name to a type annotation
+func pythonTypeName(schemaType string, schema map[string]interface{}) string {
+	switch schemaType {
+	case "string":
+		return "str"
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "null":
+		return "None"
+	case "array":
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			return "list[" + pythonType(items) + "]"
+		}
+		return "list[Any]"
+	case "object":
+		return "dict[str, Any]"
+	}
+	return "Any"
+}
+
+// pythonLiteral converts a JSON string or number to a Python literal
+func pythonLiteral(encoded string) string {
+	if strings.HasPrefix(encoded, `"`) {
+		var s string
+		json.Unmarshal([]byte(encoded), &s)
+		return pythonString(s)
+	}
+	return encoded
+}
+
+// pythonString quotes s as a Python string literal
+func pythonString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&sb, `\x%02x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// pythonDocstring escapes text for a triple-quoted docstring
+func pythonDocstring(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	return strings.ReplaceAll(text, `"""`, `\"\"\"`)
+}
+
+// indentDocstring indents continuation lines to the function body
+func indentDocstring(text string) string {
+	return strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n    ")
+}
+
+// toPythonIdentifier converts a name to snake_case, e.g. listRepos and
+// list-repos both become list_repos. Keywords get a trailing underscore.
+func toPythonIdentifier(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+
+	parts := strings.FieldsFunc(sb.String(), func(r rune) bool { return r == '_' })
+	name := strings.Join(parts, "_")
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "_" + name
+	}
+	if pythonKeywords[name] {
+		name += "_"
+	}
+	return name
+}
+
+// isPythonIdentifier reports whether name can be used as a parameter as is
+func isPythonIdentifier(name string) bool {
+	if name == "" || pythonKeywords[name] {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPythonIdentifier(t *testing.T) {
+	cases := map[string]string{
+		"listRepos":    "list_repos",
+		"list-repos":   "list_repos",
+		"list_repos":   "list_repos",
+		"HTTPGet":      "http_get",
+		"getV2Items":   "get_v2_items",
+		"google drive": "google_drive",
+		"2fa":          "_2fa",
+		"import":       "import_",
+		"":             "_",
+	}
+	for in, want := range cases {
+		if got := toPythonIdentifier(in); got != want {
+			t.Errorf("toPythonIdentifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGeneratePythonModuleZeroArgs(t *testing.T) {
+	tools := loadToolFixture(t, "zero_arg_tools.json")
+
+	content, err := NewPythonGenerator().GenerateModule("test", tools)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	for _, tool := range tools {
+		signature := "def " + PythonFunctionName(tool.Name) + "() -> Any:"
+		if !strings.Contains(content, signature) {
+			t.Errorf("expected parameterless signature %q, got:\n%s", signature, content)
+		}
+		if !strings.Contains(content, `_call_tool("test", "`+tool.Name+`", {})`) {
+			t.Errorf("expected call with empty args for %s, got:\n%s", tool.Name, content)
+		}
+	}
+}
+
+func TestGeneratePythonModuleArgs(t *testing.T) {
+	tools := loadToolFixture(t, "optional_arg_tools.json")
+
+	content, err := NewPythonGenerator().GenerateModule("test", tools)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+
+	expected := []string{
+		`def list_issues(*, limit: int | None = None, state: Literal["open", "closed"] | None = None) -> Any:`,
+		`return _call_tool("test", "list_issues", _args(limit=limit, state=state))`,
+		`def search_code(*, query: str) -> Any:`,
+		// Arbitrary keys cannot be keyword parameters
+		`def set_labels(args: dict[str, Any] | None = None, /, **kwargs: Any) -> Any:`,
+		`return _call_tool("test", "set_labels", {**(args or {}), **kwargs})`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q, got:\n%s", want, content)
+		}
+	}
+}
+
+func TestGeneratePythonModuleEscapesDocstrings(t *testing.T) {
+	tool := &mcp.Tool{
+		Name:        "quote",
+		Description: `Wraps text in """ and \ characters`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"class": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+
+	content, err := NewPythonGenerator().GenerateModule("test", []*mcp.Tool{tool})
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	if !strings.Contains(content, `Wraps text in \"\"\" and \\ characters`) {
+		t.Errorf("expected escaped docstring, got:\n%s", content)
+	}
+	// A keyword cannot be a parameter name, so the tool takes a dict
+	if !strings.Contains(content, "def quote(args: dict[str, Any] | None = None, /, **kwargs: Any)") {
+		t.Errorf("expected dict signature for a keyword property, got:\n%s", content)
+	}
+}
+
+func TestWritePythonLibs(t *testing.T) {
+	dir := t.TempDir()
+	grouped := map[string][]*mcp.Tool{
+		"google-drive": loadToolFixture(t, "optional_arg_tools.json"),
+		"github":       loadToolFixture(t, "zero_arg_tools.json"),
+	}
+	if err := WritePythonLibs(dir, grouped); err != nil {
+		t.Fatalf("WritePythonLibs failed: %v", err)
+	}
+
+	for _, name := range []string{"__init__.py", "github.py", "google_drive.py"} {
+		if _, err := os.Stat(filepath.Join(dir, "servers", name)); err != nil {
+			t.Errorf("expected servers/%s: %v", name, err)
+		}
+	}
+	init, err := os.ReadFile(filepath.Join(dir, "servers", "__init__.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(init), "from . import github\nfrom . import google_drive\n") {
+		t.Errorf("expected sorted module imports, got:\n%s", init)
+	}
+}
@@ -2,6 +2,7 @@ package codegen
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -197,9 +198,17 @@ func (sc *SchemaConverter) convertObject(schema map[string]interface{}, typeName
 		}
 	}
 
+	// Sort property names so generated output is deterministic
+	propNames := make([]string, 0, len(properties))
+	for propName := range properties {
+		propNames = append(propNames, propName)
+	}
+	sort.Strings(propNames)
+
 	tsProperties := make([]TSProperty, 0, len(properties))
 
-	for propName, propSchema := range properties {
+	for _, propName := range propNames {
+		propSchema := properties[propName]
 		propSchemaMap, ok := propSchema.(map[string]interface{})
 		if !ok {
 			continue
@@ -398,9 +407,9 @@ func (sc *SchemaConverter) typeToString(t *TSType) string {
 
 // toPascalCase converts a string to PascalCase
 func toPascalCase(s string) string {
-	// Split by underscore or dash
+	// Split by underscore, dash, or space
 	parts := strings.FieldsFunc(s, func(r rune) bool {
-		return r == '_' || r == '-'
+		return r == '_' || r == '-' || r == ' '
 	})
 
 	for i, part := range parts {
@@ -0,0 +1,142 @@
+package codegen
+
+import "strings"
+
+// maxStubDepth bounds recursion through nested and self-referencing schemas
+const maxStubDepth = 8
+
+// StubValue returns a placeholder value that satisfies schema, for tools that
+// are not really called (dry runs). const, default, examples and enum values
+// are used when present; otherwise objects get every property, arrays one item,
+// numbers their minimum or 0 and strings a value matching their format.
+// $ref is resolved against $defs and definitions of the schema itself.
+func StubValue(schema map[string]interface{}) interface{} {
+	return stubValue(schema, schema, 0)
+}
+
+// stubValue builds the stub of schema; root holds the definitions refs point to
+func stubValue(schema, root map[string]interface{}, depth int) interface{} {
+	if schema == nil || depth > maxStubDepth {
+		return nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		return stubValue(resolveRef(ref, root), root, depth+1)
+	}
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if value, ok := schema["default"]; ok {
+		return value
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if variants, ok := schema[key].([]interface{}); ok && len(variants) > 0 {
+			variant, _ := variants[0].(map[string]interface{})
+			return stubValue(variant, root, depth+1)
+		}
+	}
+	if parts, ok := schema["allOf"].([]interface{}); ok {
+		merged := map[string]interface{}{}
+		for _, part := range parts {
+			partSchema, _ := part.(map[string]interface{})
+			if fields, ok := stubValue(partSchema, root, depth+1).(map[string]interface{}); ok {
+				for key, value := range fields {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	}
+
+	switch stubType(schema) {
+	case "object":
+		object := map[string]interface{}{}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range properties {
+			propertySchema, _ := property.(map[string]interface{})
+			object[name] = stubValue(propertySchema, root, depth+1)
+		}
+		return object
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		if items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{stubValue(items, root, depth+1)}
+	case "string":
+		return stubString(schema)
+	case "integer", "number":
+		if minimum, ok := schema["minimum"].(float64); ok {
+			return minimum
+		}
+		return 0
+	case "boolean":
+		return false
+	}
+	return nil
+}
+
+// stubType returns the schema's type, the first non-null one of a type list,
+// and "object" for untyped schemas with properties
+func stubType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, item := range t {
+			if name, ok := item.(string); ok && name != "null" {
+				return name
+			}
+		}
+		return "null"
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+// stubString returns a string matching the schema's format
+func stubString(schema map[string]interface{}) string {
+	format, _ := schema["format"].(string)
+	switch format {
+	case "date-time":
+		return "1970-01-01T00:00:00Z"
+	case "date":
+		return "1970-01-01"
+	case "time":
+		return "00:00:00Z"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	}
+	return ""
+}
+
+// resolveRef looks up a local reference such as "#/$defs/Repo" in root
+func resolveRef(ref string, root map[string]interface{}) map[string]interface{} {
+	path, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil
+	}
+	var node interface{} = root
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = object[segment]
+	}
+	schema, _ := node.(map[string]interface{})
+	return schema
+}
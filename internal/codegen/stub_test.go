@@ -0,0 +1,62 @@
+package codegen
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStubValue(t *testing.T) {
+	var schema map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"repos": {"type": "array", "items": {"$ref": "#/$defs/Repo"}},
+			"total": {"type": "integer", "minimum": 1},
+			"state": {"type": "string", "enum": ["open", "closed"]},
+			"next": {"type": ["null", "string"], "format": "uri"},
+			"tags": {"type": "array"}
+		},
+		"$defs": {
+			"Repo": {
+				"allOf": [
+					{"properties": {"name": {"type": "string", "default": "example"}}},
+					{"properties": {"private": {"type": "boolean"}, "owner": {"$ref": "#/$defs/Owner"}}}
+				]
+			},
+			"Owner": {"type": "object", "properties": {"login": {"type": "string"}, "repos": {"type": "array", "items": {"$ref": "#/$defs/Repo"}}}}
+		}
+	}`), &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub, ok := StubValue(schema).(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected an object stub, got %#v", StubValue(schema))
+	}
+	if stub["total"] != 1.0 || stub["state"] != "open" || stub["next"] != "https://example.com" {
+		t.Errorf("unexpected scalar stubs: %v", stub)
+	}
+	if !reflect.DeepEqual(stub["tags"], []interface{}{}) {
+		t.Errorf("expected an empty array without items, got %#v", stub["tags"])
+	}
+
+	repos, _ := stub["repos"].([]interface{})
+	if len(repos) != 1 {
+		t.Fatalf("expected one stub item, got %#v", stub["repos"])
+	}
+	repo := repos[0].(map[string]interface{})
+	if repo["name"] != "example" || repo["private"] != false {
+		t.Errorf("allOf parts not merged: %v", repo)
+	}
+	if _, ok := repo["owner"].(map[string]interface{}); !ok {
+		t.Errorf("$ref not resolved: %v", repo)
+	}
+}
+
+func TestStubValueWithoutSchema(t *testing.T) {
+	if stub := StubValue(nil); stub != nil {
+		t.Fatalf("expected nil, got %#v", stub)
+	}
+}
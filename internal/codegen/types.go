@@ -29,6 +29,7 @@ type TSFunction struct {
 	ArgsTypeName string // TypeScript args interface name (or "" if no args)
 	ReturnType   string // TypeScript return type
 	HasArgs      bool   // Whether function takes arguments
+	ArgsOptional bool   // Whether all arguments are optional (args defaults to {})
 }
 
 // TSFile represents a complete TypeScript file to be generated
@@ -1,12 +1,20 @@
 package codegen
 
 import (
+	"bufio"
+	_ "embed"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// mcpTypesTemplate is the shared mcp-types.ts content embedded in the binary
+//
+//go:embed mcp_types.ts.tmpl
+var mcpTypesTemplate string
+
 // TypeScriptGenerator generates TypeScript files from tool definitions
 type TypeScriptGenerator struct {
 	converter *SchemaConverter
@@ -21,55 +29,132 @@ func NewTypeScriptGenerator() *TypeScriptGenerator {
 
 // GenerateFunctionFile generates a single TypeScript file for one function with inline types
 func (g *TypeScriptGenerator) GenerateFunctionFile(serverName string, tool *mcp.Tool) (string, error) {
+	var sb strings.Builder
+	if err := g.WriteFunctionFile(&sb, serverName, tool); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// WriteFunctionFile writes a single TypeScript file for one function with inline types to w
+func (g *TypeScriptGenerator) WriteFunctionFile(w io.Writer, serverName string, tool *mcp.Tool) error {
 	if tool == nil {
-		return "", fmt.Errorf("no tool provided for server %q", serverName)
+		return fmt.Errorf("no tool provided for server %q", serverName)
 	}
 
 	// Reset converter for each file
 	g.converter = NewSchemaConverter()
 
+	function, interfaces, err := g.buildFunction(serverName, tool)
+	if err != nil {
+		return err
+	}
+
 	file := &TSFile{
 		ServerName: serverName,
 		Imports:    []string{},
-		Interfaces: []*TSType{},
-		Functions:  []*TSFunction{},
+		Interfaces: interfaces,
+		Functions:  []*TSFunction{function},
+	}
+
+	// Collect all generated types (including nested ones)
+	g.collectNestedTypes(file)
+
+	// Add imports if needed
+	if function.ReturnType == "CallToolResult" {
+		file.Imports = append(file.Imports, "import type { CallToolResult } from '../mcp-types';")
 	}
 
-	// Track if we need to import MCP types
-	needsMCPTypes := false
+	bw := bufio.NewWriter(w)
+	g.renderFile(bw, file)
+	return bw.Flush()
+}
+
+// GenerateFile generates a complete TypeScript file for a server's tools
+func (g *TypeScriptGenerator) GenerateFile(serverName string, tools []*mcp.Tool) (string, error) {
+	var sb strings.Builder
+	if err := g.WriteFile(&sb, serverName, tools); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// WriteFile streams a complete TypeScript file for a server's tools to w.
+// Each tool's types and function are rendered and flushed through a buffered
+// writer as soon as they are converted, so memory stays bounded by a single
+// tool rather than the whole file for servers exposing thousands of tools.
+func (g *TypeScriptGenerator) WriteFile(w io.Writer, serverName string, tools []*mcp.Tool) error {
+	if len(tools) == 0 {
+		return fmt.Errorf("no tools provided for server %q", serverName)
+	}
+
+	// Reset converter for each file to avoid type name collisions across files
+	g.converter = NewSchemaConverter()
+
+	// Imports must precede all declarations, so check up front whether any
+	// tool falls back to the default MCP result type
+	imports := []string{}
+	for _, tool := range tools {
+		if _, ok := outputResultSchema(tool); !ok {
+			imports = append(imports, "import type { CallToolResult } from './mcp-types';")
+			break
+		}
+	}
 
-	// Generate args interface if inputSchema exists
+	bw := bufio.NewWriter(w)
+	g.renderHeader(bw, serverName, imports)
+
+	// Types already emitted, shared across tools so nested types are written once
+	seen := make(map[string]bool)
+
+	for _, tool := range tools {
+		function, interfaces, err := g.buildFunction(serverName, tool)
+		if err != nil {
+			return err
+		}
+
+		for _, iface := range g.orderTypes(interfaces, seen) {
+			g.renderType(bw, iface)
+			bw.WriteString("\n")
+		}
+
+		g.renderFunction(bw, function)
+		bw.WriteString("\n")
+	}
+
+	return bw.Flush()
+}
+
+// buildFunction converts a tool's schemas and returns its function definition
+// along with the top-level args/result types it references
+func (g *TypeScriptGenerator) buildFunction(serverName string, tool *mcp.Tool) (*TSFunction, []*TSType, error) {
+	interfaces := []*TSType{}
+
+	// Generate args interface if inputSchema declares any arguments
 	argsTypeName := ""
-	if tool.InputSchema != nil {
-		if inputSchema, ok := tool.InputSchema.(map[string]interface{}); ok && len(inputSchema) > 0 {
-			argsTypeName = toPascalCase(tool.Name) + "Args"
-			argsType, err := g.converter.ConvertSchema(inputSchema, argsTypeName)
-			if err != nil {
-				return "", fmt.Errorf("failed to convert input schema for %q: %w", tool.Name, err)
-			}
-			file.Interfaces = append(file.Interfaces, argsType)
+	argsOptional := false
+	if inputSchema, ok := inputArgsSchema(tool); ok {
+		argsTypeName = toPascalCase(tool.Name) + "Args"
+		argsType, err := g.converter.ConvertSchema(inputSchema, argsTypeName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert input schema for %q: %w", tool.Name, err)
 		}
+		interfaces = append(interfaces, argsType)
+		argsOptional = isObjectSchema(inputSchema) && !hasRequiredArgs(inputSchema)
 	}
 
-	// Generate result interface if outputSchema exists
+	// Generate result interface if outputSchema exists, otherwise use default MCP type
 	returnType := "CallToolResult"
-	if tool.OutputSchema != nil {
-		if outputSchema, ok := tool.OutputSchema.(map[string]interface{}); ok && len(outputSchema) > 0 {
-			resultTypeName := toPascalCase(tool.Name) + "Result"
-			resultType, err := g.converter.ConvertSchema(outputSchema, resultTypeName)
-			if err != nil {
-				return "", fmt.Errorf("failed to convert output schema for %q: %w", tool.Name, err)
-			}
-			file.Interfaces = append(file.Interfaces, resultType)
-			returnType = resultTypeName
-		} else {
-			needsMCPTypes = true
+	if outputSchema, ok := outputResultSchema(tool); ok {
+		resultTypeName := toPascalCase(tool.Name) + "Result"
+		resultType, err := g.converter.ConvertSchema(outputSchema, resultTypeName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert output schema for %q: %w", tool.Name, err)
 		}
-	} else {
-		needsMCPTypes = true
+		interfaces = append(interfaces, resultType)
+		returnType = resultTypeName
 	}
 
-	// Generate function
 	function := &TSFunction{
 		Name:         toCamelCase(tool.Name),
 		Description:  tool.Description,
@@ -78,114 +163,95 @@ func (g *TypeScriptGenerator) GenerateFunctionFile(serverName string, tool *mcp.
 		ArgsTypeName: argsTypeName,
 		ReturnType:   returnType,
 		HasArgs:      argsTypeName != "",
+		ArgsOptional: argsOptional,
 	}
-	file.Functions = append(file.Functions, function)
 
-	// Collect all generated types (including nested ones)
-	g.collectNestedTypes(file)
+	return function, interfaces, nil
+}
 
-	// Add imports if needed
-	if needsMCPTypes {
-		file.Imports = append(file.Imports, "import type { CallToolResult } from '../mcp-types';")
+// outputResultSchema returns the tool's output schema if it is a usable, non-empty object
+func outputResultSchema(tool *mcp.Tool) (map[string]interface{}, bool) {
+	schema, ok := tool.OutputSchema.(map[string]interface{})
+	if !ok || len(schema) == 0 {
+		return nil, false
 	}
-
-	return g.renderFile(file), nil
+	return schema, true
 }
 
-// GenerateFile generates a complete TypeScript file for a server's tools
-func (g *TypeScriptGenerator) GenerateFile(serverName string, tools []*mcp.Tool) (string, error) {
-	if len(tools) == 0 {
-		return "", fmt.Errorf("no tools provided for server %q", serverName)
+// inputArgsSchema returns the tool's input schema if it declares any arguments.
+// Missing schemas and empty object schemas (no properties, no additionalProperties,
+// no composition keywords) are treated as zero-argument tools.
+func inputArgsSchema(tool *mcp.Tool) (map[string]interface{}, bool) {
+	schema, ok := tool.InputSchema.(map[string]interface{})
+	if !ok || len(schema) == 0 {
+		return nil, false
 	}
 
-	// Reset converter for each file to avoid type name collisions across files
-	g.converter = NewSchemaConverter()
-
-	file := &TSFile{
-		ServerName: serverName,
-		Imports:    []string{},
-		Interfaces: []*TSType{},
-		Functions:  []*TSFunction{},
+	if schemaType, hasType := schema["type"]; hasType && schemaType != "object" {
+		// Non-object input schemas are unusual but still describe an argument
+		return schema, true
 	}
 
-	// Track if we need to import MCP types
-	needsMCPTypes := false
-
-	// Process each tool
-	for _, tool := range tools {
-		// Generate args interface if inputSchema exists
-		argsTypeName := ""
-		if tool.InputSchema != nil {
-			// Type assert to map[string]interface{} for schema conversion
-			if inputSchema, ok := tool.InputSchema.(map[string]interface{}); ok && len(inputSchema) > 0 {
-				argsTypeName = toPascalCase(tool.Name) + "Args"
-				argsType, err := g.converter.ConvertSchema(inputSchema, argsTypeName)
-				if err != nil {
-					return "", fmt.Errorf("failed to convert input schema for %q: %w", tool.Name, err)
-				}
-				file.Interfaces = append(file.Interfaces, argsType)
-			}
+	for _, key := range []string{"oneOf", "anyOf", "allOf", "patternProperties"} {
+		if _, ok := schema[key]; ok {
+			return schema, true
 		}
+	}
 
-		// Generate result interface if outputSchema exists
-		returnType := "CallToolResult"
-		if tool.OutputSchema != nil {
-			// Type assert to map[string]interface{} for schema conversion
-			if outputSchema, ok := tool.OutputSchema.(map[string]interface{}); ok && len(outputSchema) > 0 {
-				resultTypeName := toPascalCase(tool.Name) + "Result"
-				resultType, err := g.converter.ConvertSchema(outputSchema, resultTypeName)
-				if err != nil {
-					return "", fmt.Errorf("failed to convert output schema for %q: %w", tool.Name, err)
-				}
-				file.Interfaces = append(file.Interfaces, resultType)
-				returnType = resultTypeName
-			} else {
-				// OutputSchema present but not a valid map, use default
-				needsMCPTypes = true
-			}
-		} else {
-			// No outputSchema, use default MCP type
-			needsMCPTypes = true
-		}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok && len(properties) > 0 {
+		return schema, true
+	}
 
-		// Generate function
-		function := &TSFunction{
-			Name:         toCamelCase(tool.Name),
-			Description:  tool.Description,
-			ServerName:   serverName,
-			ToolName:     tool.Name,
-			ArgsTypeName: argsTypeName,
-			ReturnType:   returnType,
-			HasArgs:      argsTypeName != "",
+	switch additionalProps := schema["additionalProperties"].(type) {
+	case bool:
+		if additionalProps {
+			return schema, true
 		}
-		file.Functions = append(file.Functions, function)
+	case map[string]interface{}:
+		return schema, true
 	}
 
-	// Collect all generated types (including nested ones)
-	g.collectNestedTypes(file)
+	return nil, false
+}
 
-	// Add imports if needed
-	if needsMCPTypes {
-		file.Imports = append(file.Imports, "import type { CallToolResult } from './mcp-types';")
-	}
+// hasRequiredArgs reports whether an input schema marks any property as required
+func hasRequiredArgs(schema map[string]interface{}) bool {
+	required, ok := schema["required"].([]interface{})
+	return ok && len(required) > 0
+}
 
-	return g.renderFile(file), nil
+// isObjectSchema reports whether a schema describes a plain object, which {}
+// can stand for when none of its properties are required. Other types and
+// unions of schemas cannot default to {}.
+func isObjectSchema(schema map[string]interface{}) bool {
+	if schemaType, hasType := schema["type"]; hasType {
+		return schemaType == "object"
+	}
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		if _, ok := schema[key]; ok {
+			return false
+		}
+	}
+	return true
 }
 
 // collectNestedTypes collects all nested types and orders them so dependencies come first
 func (g *TypeScriptGenerator) collectNestedTypes(file *TSFile) {
-	// Build a new ordered list of interfaces
-	orderedInterfaces := make([]*TSType, 0, len(file.Interfaces))
-	seen := make(map[string]bool)
+	file.Interfaces = g.orderTypes(file.Interfaces, make(map[string]bool))
+}
+
+// orderTypes returns the given types plus all their nested dependencies,
+// ordered so dependencies come first. Types already in seen are skipped.
+func (g *TypeScriptGenerator) orderTypes(types []*TSType, seen map[string]bool) []*TSType {
+	ordered := make([]*TSType, 0, len(types))
 
 	// Process each top-level interface
-	for _, iface := range file.Interfaces {
+	for _, iface := range types {
 		// Add dependencies first (recursively)
-		g.addTypeWithDependencies(iface, &orderedInterfaces, seen)
+		g.addTypeWithDependencies(iface, &ordered, seen)
 	}
 
-	// Replace with ordered list
-	file.Interfaces = orderedInterfaces
+	return ordered
 }
 
 // addTypeWithDependencies adds a type and all its dependencies in the correct order
@@ -247,119 +313,116 @@ func (g *TypeScriptGenerator) addDependentType(tsType *TSType, result *[]*TSType
 }
 
 // renderFile renders the complete TypeScript file
-func (g *TypeScriptGenerator) renderFile(file *TSFile) string {
-	var sb strings.Builder
-
-	// File header
-	sb.WriteString(fmt.Sprintf("/**\n * Generated MCP tool definitions for: %s\n", file.ServerName))
-	sb.WriteString(" * This file is auto-generated. Do not edit manually.\n")
-	sb.WriteString(" */\n\n")
-
-	// Imports
-	if len(file.Imports) > 0 {
-		for _, imp := range file.Imports {
-			sb.WriteString(imp)
-			sb.WriteString("\n")
-		}
-		sb.WriteString("\n")
-	}
+func (g *TypeScriptGenerator) renderFile(w *bufio.Writer, file *TSFile) {
+	g.renderHeader(w, file.ServerName, file.Imports)
 
 	// Interfaces
 	for _, iface := range file.Interfaces {
-		sb.WriteString(g.renderType(iface))
-		sb.WriteString("\n")
+		g.renderType(w, iface)
+		w.WriteString("\n")
 	}
 
 	// Functions
 	for _, fn := range file.Functions {
-		sb.WriteString(g.renderFunction(fn))
-		sb.WriteString("\n")
+		g.renderFunction(w, fn)
+		w.WriteString("\n")
 	}
+}
 
-	return sb.String()
+// renderHeader renders the file header comment and imports
+func (g *TypeScriptGenerator) renderHeader(w *bufio.Writer, serverName string, imports []string) {
+	// File header
+	fmt.Fprintf(w, "/**\n * Generated MCP tool definitions for: %s\n", serverName)
+	w.WriteString(" * This file is auto-generated. Do not edit manually.\n")
+	w.WriteString(" */\n\n")
+
+	// Imports
+	if len(imports) > 0 {
+		for _, imp := range imports {
+			w.WriteString(imp)
+			w.WriteString("\n")
+		}
+		w.WriteString("\n")
+	}
 }
 
 // renderType renders a TypeScript type/interface
-func (g *TypeScriptGenerator) renderType(t *TSType) string {
-	var sb strings.Builder
-
+func (g *TypeScriptGenerator) renderType(w *bufio.Writer, t *TSType) {
 	// JSDoc comment
 	if t.Description != "" {
-		sb.WriteString("/**\n")
-		sb.WriteString(fmt.Sprintf(" * %s\n", sanitizeComment(t.Description)))
-		sb.WriteString(" */\n")
+		w.WriteString("/**\n")
+		fmt.Fprintf(w, " * %s\n", sanitizeComment(t.Description))
+		w.WriteString(" */\n")
 	}
 
 	switch t.Kind {
 	case "interface":
-		sb.WriteString(fmt.Sprintf("export interface %s {\n", t.Name))
+		fmt.Fprintf(w, "export interface %s {\n", t.Name)
 		for _, prop := range t.Properties {
 			if prop.Description != "" {
-				sb.WriteString(fmt.Sprintf("  /** %s */\n", sanitizeComment(prop.Description)))
+				fmt.Fprintf(w, "  /** %s */\n", sanitizeComment(prop.Description))
 			}
 			optional := ""
 			if prop.IsOptional {
 				optional = "?"
 			}
-			sb.WriteString(fmt.Sprintf("  %s%s: %s;\n", prop.Name, optional, g.converter.typeToString(prop.Type)))
+			fmt.Fprintf(w, "  %s%s: %s;\n", prop.Name, optional, g.converter.typeToString(prop.Type))
 		}
-		sb.WriteString("}\n")
+		w.WriteString("}\n")
 
 	case "type":
-		sb.WriteString(fmt.Sprintf("export type %s = %s;\n", t.Name, t.RawType))
+		fmt.Fprintf(w, "export type %s = %s;\n", t.Name, t.RawType)
 
 	case "union":
 		parts := make([]string, len(t.UnionTypes))
 		for i, ut := range t.UnionTypes {
 			parts[i] = g.converter.typeToString(ut)
 		}
-		sb.WriteString(fmt.Sprintf("export type %s = %s;\n", t.Name, strings.Join(parts, " | ")))
+		fmt.Fprintf(w, "export type %s = %s;\n", t.Name, strings.Join(parts, " | "))
 	}
-
-	return sb.String()
 }
 
 // renderFunction renders a TypeScript function
-func (g *TypeScriptGenerator) renderFunction(fn *TSFunction) string {
-	var sb strings.Builder
-
+func (g *TypeScriptGenerator) renderFunction(w *bufio.Writer, fn *TSFunction) {
 	// JSDoc comment
-	sb.WriteString("/**\n")
+	w.WriteString("/**\n")
 	if fn.Description != "" {
-		sb.WriteString(fmt.Sprintf(" * %s\n", sanitizeComment(fn.Description)))
+		fmt.Fprintf(w, " * %s\n", sanitizeComment(fn.Description))
 	} else {
-		sb.WriteString(fmt.Sprintf(" * Call tool: %s\n", fn.ToolName))
+		fmt.Fprintf(w, " * Call tool: %s\n", fn.ToolName)
 	}
 
 	// Add note if using default MCP type
 	if fn.ReturnType == "CallToolResult" {
-		sb.WriteString(" * \n")
-		sb.WriteString(" * Note: Returns CallToolResult because no outputSchema is defined.\n")
-		sb.WriteString(" * You may need to parse the content to extract the actual result.\n")
+		w.WriteString(" * \n")
+		w.WriteString(" * Note: Returns CallToolResult because no outputSchema is defined.\n")
+		w.WriteString(" * You may need to parse the content to extract the actual result.\n")
 	}
 
-	sb.WriteString(" */\n")
+	w.WriteString(" */\n")
 
-	// Function signature
+	// Zero-argument tools get a parameterless signature; tools whose arguments
+	// are all optional default to an empty object so callers can omit them.
 	params := ""
 	if fn.HasArgs {
 		params = fmt.Sprintf("args: %s", fn.ArgsTypeName)
+		if fn.ArgsOptional {
+			params += " = {}"
+		}
 	}
 
-	sb.WriteString(fmt.Sprintf("export async function %s(%s): Promise<%s> {\n",
-		fn.Name, params, fn.ReturnType))
+	fmt.Fprintf(w, "export async function %s(%s): Promise<%s> {\n",
+		fn.Name, params, fn.ReturnType)
 
 	// Function body
 	argsValue := "{}"
 	if fn.HasArgs {
 		argsValue = "args"
 	}
-	sb.WriteString(fmt.Sprintf("  return await callTool(%q, %q, %s);\n",
-		fn.ServerName, fn.ToolName, argsValue))
+	fmt.Fprintf(w, "  return await callTool(%q, %q, %s);\n",
+		fn.ServerName, fn.ToolName, argsValue)
 
-	sb.WriteString("}\n")
-
-	return sb.String()
+	w.WriteString("}\n")
 }
 
 // GenerateServerIndexFile generates an index.ts for a server directory that re-exports all functions
@@ -411,103 +474,7 @@ func (g *TypeScriptGenerator) GenerateIndexFile(serverNames []string) string {
 
 // GenerateMCPTypesFile generates the mcp-types.ts file
 func (g *TypeScriptGenerator) GenerateMCPTypesFile() string {
-	return `/**
- * MCP Protocol Types
- * 
- * These types represent the standard MCP (Model Context Protocol) response types.
- * They are used as default return types when tools don't specify an outputSchema.
- */
-
-/**
- * Result of a tool call
- */
-export interface CallToolResult {
-  /**
-   * A list of content objects that represent the result of the tool call
-   */
-  content: Content[];
-
-  /**
-   * Optional structured result of the tool call
-   */
-  structuredContent?: any;
-
-  /**
-   * Whether the tool call ended in an error
-   */
-  isError?: boolean;
-}
-
-/**
- * Content types that can be returned by tools
- */
-export type Content = TextContent | ImageContent | ResourceContent;
-
-/**
- * Text content
- */
-export interface TextContent {
-  type: "text";
-  text: string;
-}
-
-/**
- * Image content (base64 encoded)
- */
-export interface ImageContent {
-  type: "image";
-  data: string;
-  mimeType: string;
-}
-
-/**
- * Resource reference content
- */
-export interface ResourceContent {
-  type: "resource";
-  resource: {
-    uri: string;
-    mimeType?: string;
-    text?: string;
-    blob?: string;
-  };
-}
-
-/**
- * Helper function to extract text from CallToolResult
- */
-export function extractText(result: CallToolResult): string {
-  const textContent = result.content.find(c => c.type === "text") as TextContent | undefined;
-  return textContent?.text || "";
-}
-
-/**
- * Helper function to extract JSON from CallToolResult
- */
-export function extractJSON<T = any>(result: CallToolResult): T {
-  if (result.structuredContent) {
-    return result.structuredContent as T;
-  }
-  
-  const text = extractText(result);
-  if (text) {
-    try {
-      return JSON.parse(text) as T;
-    } catch {
-      throw new Error("Failed to parse JSON from text content");
-    }
-  }
-  
-  throw new Error("No structured content or text content found");
-}
-
-/**
- * Helper function to check if result is an error
- */
-export function isErrorResult(result: CallToolResult): boolean {
-  return result.isError === true;
-}
-`
+	return mcpTypesTemplate
 }
 
 // sanitizeComment escapes or removes problematic content from JSDoc comments
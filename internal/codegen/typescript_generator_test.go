@@ -0,0 +1,204 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// loadToolFixture reads a JSON array of tools from testdata
+func loadToolFixture(t *testing.T, name string) []*mcp.Tool {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", name, err)
+	}
+
+	var tools []*mcp.Tool
+	if err := json.Unmarshal(data, &tools); err != nil {
+		t.Fatalf("failed to parse fixture %s: %v", name, err)
+	}
+	return tools
+}
+
+func TestGenerateFunctionFileZeroArgs(t *testing.T) {
+	tools := loadToolFixture(t, "zero_arg_tools.json")
+	generator := NewTypeScriptGenerator()
+
+	for _, tool := range tools {
+		t.Run(tool.Name, func(t *testing.T) {
+			content, err := generator.GenerateFunctionFile("test", tool)
+			if err != nil {
+				t.Fatalf("GenerateFunctionFile failed: %v", err)
+			}
+
+			signature := "export async function " + toCamelCase(tool.Name) + "(): Promise<CallToolResult>"
+			if !strings.Contains(content, signature) {
+				t.Errorf("expected parameterless signature %q, got:\n%s", signature, content)
+			}
+			if strings.Contains(content, "Args") {
+				t.Errorf("expected no args type for zero-argument tool, got:\n%s", content)
+			}
+			if !strings.Contains(content, `callTool("test", "`+tool.Name+`", {})`) {
+				t.Errorf("expected call with empty args object, got:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestGenerateFunctionFileArgs(t *testing.T) {
+	tools := loadToolFixture(t, "optional_arg_tools.json")
+	generator := NewTypeScriptGenerator()
+
+	expected := map[string]string{
+		"list_issues": "listIssues(args: ListIssuesArgs = {})",
+		"search_code": "searchCode(args: SearchCodeArgs)",
+		"set_labels":  "setLabels(args: SetLabelsArgs = {})",
+		"echo_text":   "echoText(args: EchoTextArgs)",
+		"find_item":   "findItem(args: FindItemArgs)",
+	}
+
+	for _, tool := range tools {
+		t.Run(tool.Name, func(t *testing.T) {
+			content, err := generator.GenerateFunctionFile("test", tool)
+			if err != nil {
+				t.Fatalf("GenerateFunctionFile failed: %v", err)
+			}
+
+			if !strings.Contains(content, expected[tool.Name]) {
+				t.Errorf("expected signature %q, got:\n%s", expected[tool.Name], content)
+			}
+			if !strings.Contains(content, `callTool("test", "`+tool.Name+`", args)`) {
+				t.Errorf("expected args to be forwarded, got:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestGenerateFileZeroArgs(t *testing.T) {
+	tools := loadToolFixture(t, "zero_arg_tools.json")
+
+	content, err := NewTypeScriptGenerator().GenerateFile("test", tools)
+	if err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	for _, tool := range tools {
+		signature := "export async function " + toCamelCase(tool.Name) + "()"
+		if !strings.Contains(content, signature) {
+			t.Errorf("expected parameterless signature %q in combined file", signature)
+		}
+	}
+}
+
+// syntheticTools builds n tools with nested input and output schemas
+func syntheticTools(n int) []*mcp.Tool {
+	tools := make([]*mcp.Tool, n)
+	for i := range tools {
+		tools[i] = &mcp.Tool{
+			Name:        fmt.Sprintf("tool_number_%d", i),
+			Description: "Synthetic tool used for benchmarking code generation",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{"type": "string", "description": "Search query"},
+					"limit": map[string]interface{}{"type": "integer"},
+					"filters": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"state":  map[string]interface{}{"type": "string", "enum": []interface{}{"open", "closed"}},
+							"labels": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+						},
+					},
+				},
+				"required": []interface{}{"query"},
+			},
+			OutputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"items": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"id":    map[string]interface{}{"type": "integer"},
+								"title": map[string]interface{}{"type": "string"},
+							},
+						},
+					},
+					"total": map[string]interface{}{"type": "integer"},
+				},
+			},
+		}
+	}
+	return tools
+}
+
+func TestWriteFileMatchesGenerateFile(t *testing.T) {
+	tools := syntheticTools(20)
+
+	generated, err := NewTypeScriptGenerator().GenerateFile("synthetic", tools)
+	if err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewTypeScriptGenerator().WriteFile(&buf, "synthetic", tools); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if buf.String() != generated {
+		t.Errorf("streamed output differs from generated string")
+	}
+	if count := strings.Count(generated, "export interface ToolNumber0ArgsFilters {"); count != 1 {
+		t.Errorf("expected nested type to be emitted once, got %d", count)
+	}
+}
+
+func BenchmarkGenerateFile5000Tools(b *testing.B) {
+	tools := syntheticTools(5000)
+	generator := NewTypeScriptGenerator()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := generator.GenerateFile("synthetic", tools); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteFile5000Tools(b *testing.B) {
+	tools := syntheticTools(5000)
+	generator := NewTypeScriptGenerator()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := generator.WriteFile(io.Discard, "synthetic", tools); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteServerLib5000Tools(b *testing.B) {
+	tools := syntheticTools(5000)
+	generator := NewTypeScriptGenerator()
+	dir := b.TempDir()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := generator.WriteServerLib(filepath.Join(dir, "synthetic"), "synthetic", tools); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// LibOptions configures concurrent library generation
+type LibOptions struct {
+	// Workers is the number of servers generated concurrently (defaults to runtime.NumCPU())
+	Workers int
+
+	// OnServerGenerated is an optional callback invoked after each server's library is written.
+	// Calls are serialized, so the callback does not need its own locking.
+	OnServerGenerated func(serverName string, files []string)
+}
+
+// FunctionName returns the TypeScript function (and file) name for a tool
+func FunctionName(toolName string) string {
+	return toCamelCase(toolName)
+}
+
+// WriteServerLib writes one TypeScript file per tool plus an index.ts into serverDir.
+// Returns the names of the files written, relative to serverDir.
+func (g *TypeScriptGenerator) WriteServerLib(serverDir, serverName string, tools []*mcp.Tool) ([]string, error) {
+	if err := os.MkdirAll(serverDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create server directory %s: %w", serverDir, err)
+	}
+
+	files := make([]string, 0, len(tools)+1)
+
+	// Generate one file per function
+	for _, tool := range tools {
+		fileName := FunctionName(tool.Name) + ".ts"
+
+		functionPath := filepath.Join(serverDir, fileName)
+		err := writeFileWith(functionPath, func(w io.Writer) error {
+			return g.WriteFunctionFile(w, serverName, tool)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write function file for %s.%s: %w", serverName, tool.Name, err)
+		}
+
+		files = append(files, fileName)
+	}
+
+	// Generate server index.ts
+	indexContent := g.GenerateServerIndexFile(serverName, tools)
+	indexPath := filepath.Join(serverDir, "index.ts")
+	if err := os.WriteFile(indexPath, []byte(indexContent), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write server index %s: %w", indexPath, err)
+	}
+	files = append(files, "index.ts")
+
+	return files, nil
+}
+
+// GenerateServerLibs writes a library directory for every server under outputDir,
+// generating servers concurrently with a bounded worker pool.
+// All per-server failures are aggregated into the returned error.
+//
+// Each worker uses its own TypeScriptGenerator because the generator's schema
+// converter is reset per file and is not safe for concurrent use.
+func GenerateServerLibs(outputDir string, grouped map[string][]*mcp.Tool, opts LibOptions) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(grouped) {
+		workers = len(grouped)
+	}
+
+	// Dispatch servers in a stable order
+	serverNames := make([]string, 0, len(grouped))
+	for name := range grouped {
+		serverNames = append(serverNames, name)
+	}
+	sort.Strings(serverNames)
+
+	jobs := make(chan string)
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		errs       []error
+		callbackMu sync.Mutex
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			generator := NewTypeScriptGenerator()
+
+			for serverName := range jobs {
+				serverDir := filepath.Join(outputDir, serverName)
+				files, err := generator.WriteServerLib(serverDir, serverName, grouped[serverName])
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("server %q: %w", serverName, err))
+					mu.Unlock()
+					continue
+				}
+
+				if opts.OnServerGenerated != nil {
+					callbackMu.Lock()
+					opts.OnServerGenerated(serverName, files)
+					callbackMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, name := range serverNames {
+		jobs <- name
+	}
+	close(jobs)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// writeFileWith creates path and streams generated content into it
+func writeFileWith(path string, write func(w io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -1,38 +1,463 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 )
 
 // Config represents the main configuration structure
 type Config struct {
-	Server     *ServerConfig              `json:"server,omitempty"`
-	McpServers map[string]McpServerConfig `json:"mcpServers"`
+	Include     []string                   `json:"include,omitempty"` // Config files merged beneath this one; see include.go
+	Server      *ServerConfig              `json:"server,omitempty"`
+	Sandbox     *SandboxConfig             `json:"sandbox,omitempty"`
+	Bundler     *BundlerConfig             `json:"bundler,omitempty"`
+	Concurrency *ConcurrencyConfig         `json:"concurrency,omitempty"`
+	Jobs        *JobsConfig                `json:"jobs,omitempty"`
+	Sessions    *SessionsConfig            `json:"sessions,omitempty"`
+	ResultCache *ResultCacheConfig         `json:"resultCache,omitempty"`
+	Audit       *AuditConfig               `json:"audit,omitempty"`
+	Admin       *AdminConfig               `json:"admin,omitempty"`
+	Search      *SearchConfig              `json:"search,omitempty"`
+	McpServers  map[string]McpServerConfig `json:"mcpServers"`
+
+	path     string            // File the config was loaded from
+	files    []string          // path and every file it includes
+	profile  string            // Profile applied when loading, if any
+	disabled map[string]string // Servers left out when loading, with why
+	version  string            // Hash of the contents of files
 }
 
 // ServerConfig contains HTTP server settings
 type ServerConfig struct {
 	Port    int `json:"port,omitempty"`
 	Timeout int `json:"timeout,omitempty"` // in seconds
+
+	// ShutdownTimeout is how long shutdown waits, in seconds, for running
+	// executions and background jobs before closing sessions anyway (default 30)
+	ShutdownTimeout int `json:"shutdownTimeout,omitempty"`
+
+	// Transports are what the server is served over: "http", "stdio" or both at
+	// once, sharing the same sessions (default ["http"])
+	Transports []string `json:"transports,omitempty"`
+}
+
+// Transports of the server
+const (
+	TransportHTTP  = "http"
+	TransportStdio = "stdio"
+)
+
+// SandboxConfig contains code execution settings. Executions read them from the
+// current config, so a profile can override any of them and a reload applies to
+// the executions started after it, except the pool and hooks. The npm packages
+// scripts may import are listed in BundlerConfig.Packages.
+type SandboxConfig struct {
+	Runtime   string         `json:"runtime,omitempty"`   // "wasm", "goja", "deno", "bun", "docker", or empty to auto-detect
+	WasmPath  string         `json:"wasmPath,omitempty"`  // Path to the compiled sandbox plugin
+	WasmCache string         `json:"wasmCache,omitempty"` // Directory for persisting compiled wasm (optional)
+	Policy    *SandboxPolicy `json:"policy,omitempty"`    // Resources sandboxed code may access
+	Docker    *DockerConfig  `json:"docker,omitempty"`    // Container settings for the docker runtime
+	Pool      *PoolConfig    `json:"pool,omitempty"`      // Warm process pool for the deno, bun and docker runtimes
+	OSSandbox string         `json:"osSandbox,omitempty"` // Wrap deno and bun in "bwrap", "firejail", "sandbox-exec" or "auto"; empty runs them directly
+
+	// AllowUnsandboxed lets the bun runtime and Python scripts run without an OS
+	// sandbox. Neither has a permission system: unwrapped, scripts can read and
+	// write whatever the server's user can and connect anywhere.
+	AllowUnsandboxed bool `json:"allowUnsandboxed,omitempty"`
+
+	// Secrets are readable by code through secrets.get(name), never as environment
+	// variables, and their values are redacted from output, results and errors
+	Secrets map[string]SecretConfig `json:"secrets,omitempty"`
+
+	Timeout    int `json:"timeout,omitempty"`    // Default execution timeout in seconds
+	MaxTimeout int `json:"maxTimeout,omitempty"` // Upper bound for per-run timeouts in seconds
+
+	// Deterministic makes every run reproducible: Math.random is seeded, the clock is
+	// frozen at the start of the run and network access is disabled
+	Deterministic bool `json:"deterministic,omitempty"`
+
+	// Hooks run before and after every execution, e.g. to seed fixtures or scan outputs
+	Hooks *HooksConfig `json:"hooks,omitempty"`
+
+	// Python, when set, lets execute_code run Python scripts as well as TypeScript
+	Python *PythonConfig `json:"python,omitempty"`
+}
+
+// PythonConfig selects the interpreter Python scripts run with
+type PythonConfig struct {
+	Command  string   `json:"command,omitempty"`  // Interpreter executable (default "python3"), unless venv is set
+	Venv     string   `json:"venv,omitempty"`     // Virtual environment whose interpreter and packages scripts use
+	UV       bool     `json:"uv,omitempty"`       // Install packages with uv into a venv kept in the cache directory
+	Packages []string `json:"packages,omitempty"` // Requirements uv installs for scripts, e.g. "pandas>=2"
+	CacheDir string   `json:"cacheDir,omitempty"` // uv cache and venv directory (default: the user cache directory)
+}
+
+// HooksConfig lists the hooks run around every execution, in order
+type HooksConfig struct {
+	Pre  []HookConfig `json:"pre,omitempty"`  // Before the code runs; a failure aborts the execution
+	Post []HookConfig `json:"post,omitempty"` // After the code ran, with access to its result and outputs
+}
+
+// HookConfig is a command or a Go function registered by name; exactly one is set
+type HookConfig struct {
+	Name         string   `json:"name,omitempty"`         // Used in errors and logs
+	Command      []string `json:"command,omitempty"`      // Program and arguments, run in the scratch directory
+	Func         string   `json:"func,omitempty"`         // Name of a function registered with sandbox.RegisterHook
+	Timeout      int      `json:"timeout,omitempty"`      // Seconds (default 30)
+	IgnoreErrors bool     `json:"ignoreErrors,omitempty"` // Log a failure instead of failing the execution
+}
+
+// SecretConfig says where a secret's value comes from; exactly one field is set
+type SecretConfig struct {
+	Env   string `json:"env,omitempty"`   // Host environment variable holding the value
+	Value string `json:"value,omitempty"` // Literal value
+}
+
+// BundlerConfig contains TypeScript bundling settings
+type BundlerConfig struct {
+	Toolchain    string `json:"toolchain,omitempty"`    // "esbuild", "rspack", "bun", or empty for esbuild (bun with the bun runtime)
+	CacheDir     string `json:"cacheDir,omitempty"`     // Directory for cached bundles
+	CacheSizeMB  int    `json:"cacheSizeMB,omitempty"`  // Cache size limit; least recently used bundles are evicted
+	DisableCache bool   `json:"disableCache,omitempty"` // Always run the bundler
+	TypeCheck    bool   `json:"typeCheck,omitempty"`    // Type-check every execution with tsc before running it
+	Incremental  bool   `json:"incremental,omitempty"`  // Keep an esbuild context per session and rebuild it incrementally
+	MaxBundleKB  int    `json:"maxBundleKB,omitempty"`  // Largest bundle allowed to run; zero means no limit
+
+	// RspackConfig is a config template used by the rspack toolchain instead of the
+	// embedded one; see bundler.RspackConfigData for the fields it is rendered with
+	RspackConfig string `json:"rspackConfig,omitempty"`
+
+	// Packages lists the npm packages scripts may import, as "name" or "name@version".
+	// They are installed once into a shared cache directory keyed by the list.
+	Packages              []string `json:"packages,omitempty"`
+	PackageCacheDir       string   `json:"packageCacheDir,omitempty"`       // Directory for installed packages
+	PackageInstallTimeout int      `json:"packageInstallTimeout,omitempty"` // Install time limit in seconds
+	PackageMaxSizeMB      int      `json:"packageMaxSizeMB,omitempty"`      // Installed size limit
+
+	Transform *TransformConfig `json:"transform,omitempty"` // How user code is compiled
+}
+
+// TransformConfig configures how TypeScript is compiled to JavaScript
+type TransformConfig struct {
+	Target     string `json:"target,omitempty"`     // ECMAScript target, e.g. "es2017" (default "es2020")
+	Module     string `json:"module,omitempty"`     // Module format: "esm" or "cjs" (default "esm")
+	Decorators bool   `json:"decorators,omitempty"` // Allow TypeScript experimental decorators
+	TSX        bool   `json:"tsx,omitempty"`        // Allow JSX in scripts
+	Minify     bool   `json:"minify,omitempty"`     // Minify bundles
+
+	DisableSourcemap bool              `json:"disableSourcemap,omitempty"` // Bundle without source maps; error stacks point into the bundle
+	External         []string          `json:"external,omitempty"`         // Imports left for the runtime to resolve, e.g. "node:fs"
+	Alias            map[string]string `json:"alias,omitempty"`            // Import prefixes mapped to paths relative to the entry, e.g. {"@lib": "./lib"}
+	Define           map[string]string `json:"define,omitempty"`           // Global identifiers replaced with JavaScript expressions, e.g. {"DEBUG": "false"}
+}
+
+// SandboxPolicy lists the resources sandboxed code may access.
+// Network access is denied by default: deno enforces allowNet with permission flags
+// (hosts only) and wasm with the plugin host allowlist. Bun and docker can't enforce
+// it, so allowNet is rejected for them; docker's access is set by docker.network.
+// Tool calls to downstream MCP servers go through the host and are not affected.
+type SandboxPolicy struct {
+	AllowNet   []string `json:"allowNet,omitempty"`   // Hosts ("api.github.com:443", "*.github.com") or CIDRs; empty denies all
+	AllowRead  []string `json:"allowRead,omitempty"`  // Extra paths code may read
+	AllowWrite []string `json:"allowWrite,omitempty"` // Extra paths code may write
+	AllowEnv   []string `json:"allowEnv,omitempty"`   // Host environment variables passed to code ("NAME" or "PREFIX_*"); all others are stripped
+
+	// Env sets fixed environment values for sandboxed code
+	Env map[string]string `json:"env,omitempty"`
+
+	// AllowEnvHeaders lists the variables ("NAME" or "PREFIX_*") clients may set
+	// per session with X-Codebraid-Env-<Name> request headers; none by default.
+	// Variables that load code into or configure the runtimes, such as
+	// LD_PRELOAD, NODE_OPTIONS or PYTHONPATH, can never be set this way.
+	AllowEnvHeaders []string `json:"allowEnvHeaders,omitempty"`
+
+	// ScratchDir is the parent of the private working directory each execution gets.
+	// Code may write there and read the session library; nothing else by default.
+	ScratchDir string `json:"scratchDir,omitempty"`
+
+	MaxMemoryMB   int `json:"maxMemoryMB,omitempty"`   // JavaScript heap limit per execution
+	MaxCPUSeconds int `json:"maxCpuSeconds,omitempty"` // CPU-time limit per execution (process runtimes)
+
+	// Downstream tool call budget per execution; a run making more calls, or
+	// waiting longer for them in total, is stopped. Zero means unbounded.
+	MaxToolCalls   int `json:"maxToolCalls,omitempty"`
+	MaxToolSeconds int `json:"maxToolSeconds,omitempty"`
+
+	// Output caps per execution; console output past MaxOutputKB is dropped and a
+	// result over MaxResultKB is replaced by a truncated preview. Zero uses the defaults.
+	MaxOutputKB int `json:"maxOutputKB,omitempty"`
+	MaxResultKB int `json:"maxResultKB,omitempty"`
+}
+
+// DockerConfig configures the container executor.
+// The image must provide a JavaScript runtime that can run an ES module (node, deno, bun).
+type DockerConfig struct {
+	Binary    string   `json:"binary,omitempty"`    // Container CLI, "docker" or "podman"
+	Image     string   `json:"image,omitempty"`     // Image to run
+	Command   []string `json:"command,omitempty"`   // Runtime command inside the image; the runner path is appended
+	Network   string   `json:"network,omitempty"`   // Docker network mode, e.g. "none" or "bridge"
+	CPUs      float64  `json:"cpus,omitempty"`      // CPU quota, e.g. 0.5 for half a core
+	PidsLimit int      `json:"pidsLimit,omitempty"` // Maximum number of processes in the container
+}
+
+// PoolConfig configures pre-started runtime processes.
+// A zero size disables the pool and every execution starts its own process.
+type PoolConfig struct {
+	Size        int  `json:"size,omitempty"`        // Warm processes kept ready
+	IdleTTL     int  `json:"idleTtl,omitempty"`     // Seconds an idle process is kept before it is stopped
+	PinSessions bool `json:"pinSessions,omitempty"` // Reuse a session's process for its next run
+}
+
+// ConcurrencyConfig bounds simultaneous executions and bundler runs.
+// Requests over a limit wait in a queue of MaxQueue for up to QueueTimeout seconds
+// and are then rejected as busy. Zero values use the defaults.
+type ConcurrencyConfig struct {
+	MaxExecutions        int `json:"maxExecutions,omitempty"`        // Across all sessions
+	MaxSessionExecutions int `json:"maxSessionExecutions,omitempty"` // Within one session
+	MaxBundles           int `json:"maxBundles,omitempty"`           // Transform, bundle and type-check runs
+	MaxQueue             int `json:"maxQueue,omitempty"`             // Waiters per limiter
+	QueueTimeout         int `json:"queueTimeout,omitempty"`         // Seconds a waiter waits for a slot
+}
+
+// JobsConfig bounds the background jobs each session keeps
+type JobsConfig struct {
+	MaxJobs   int `json:"maxJobs,omitempty"`   // Running and finished jobs kept per session; the oldest finished job is discarded first
+	MaxLogKB  int `json:"maxLogKB,omitempty"`  // Console output kept per job; the oldest lines are dropped first
+	Retention int `json:"retention,omitempty"` // Seconds a finished job is kept
 }
 
+// SessionsConfig bounds how long sessions are kept. A session with a request or
+// background job in progress is kept until it finishes. Zero values keep sessions
+// until the client deletes them or the server stops.
+type SessionsConfig struct {
+	IdleTTL int `json:"idleTtl,omitempty"` // Seconds without requests before a session is closed
+	MaxAge  int `json:"maxAge,omitempty"`  // Seconds after creation a session is closed, however active
+
+	// MaxSessions caps concurrent sessions; zero means no limit. When a new session
+	// would exceed it, LimitPolicy "evict" (the default) closes the least recently
+	// used idle session and "reject" refuses to create the new one.
+	MaxSessions int    `json:"maxSessions,omitempty"`
+	LimitPolicy string `json:"limitPolicy,omitempty"`
+
+	// AllowOverrides lets clients pass env and headers for the servers a session
+	// connects to; choosing a subset of the configured servers is always allowed
+	AllowOverrides bool `json:"allowOverrides,omitempty"`
+
+	// WarmSessions is how many blank sessions are kept ready, connected to every
+	// configured server with libraries generated, for new clients that do not
+	// choose servers or pass overrides; zero disables the warm pool
+	WarmSessions int `json:"warmSessions,omitempty"`
+
+	// ReadOnly makes every session read-only: code only sees and may only call the
+	// tools annotated with readOnlyHint. Clients can also ask for a read-only
+	// session, but not for a writable one when this is set.
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// MaxBundleMB caps the disk space of a session's bundle directory. Saving
+	// outputs or snapshots over it first removes the session's oldest outputs, and
+	// fails if that is not enough. Zero means no quota.
+	MaxBundleMB int `json:"maxBundleMB,omitempty"`
+
+	// ArtifactRetention is how many seconds execution outputs are kept before they
+	// are removed; zero keeps them for the life of the session
+	ArtifactRetention int `json:"artifactRetention,omitempty"`
+
+	// OrphanAge is how many seconds bundle and scratch directories left in the temp
+	// directory by crashed servers are kept before they are removed, at startup and
+	// periodically (default 3600). DisableOrphanCleanup keeps them.
+	OrphanAge            int  `json:"orphanAge,omitempty"`
+	DisableOrphanCleanup bool `json:"disableOrphanCleanup,omitempty"`
+
+	// DisableLibSharing generates every session's server libraries in its bundle
+	// directory. By default sessions whose servers expose the same tools hard-link
+	// one read-only copy of the generated files.
+	DisableLibSharing bool `json:"disableLibSharing,omitempty"`
+}
+
+// Session limit policies
+const (
+	SessionLimitEvict  = "evict"
+	SessionLimitReject = "reject"
+)
+
+// ResultCacheConfig enables reusing the results of identical executions.
+// Only enable it when the tools scripts call are idempotent: a cached result is
+// returned without running the code or calling any tool.
+type ResultCacheConfig struct {
+	Enabled    bool `json:"enabled,omitempty"`
+	TTL        int  `json:"ttl,omitempty"`        // Seconds a result is reused
+	MaxEntries int  `json:"maxEntries,omitempty"` // Results kept per session; the oldest is discarded first
+}
+
+// AuditConfig records every execution: its code, session, duration, status and
+// the downstream tools it called, with secrets and sensitive arguments redacted
+type AuditConfig struct {
+	Enabled      bool             `json:"enabled,omitempty"`
+	File         string           `json:"file,omitempty"`         // JSONL file records are appended to
+	Webhook      *AuditHTTPConfig `json:"webhook,omitempty"`      // Receives every record as a JSON POST
+	OTLP         *AuditHTTPConfig `json:"otlp,omitempty"`         // OTLP/HTTP logs endpoint, e.g. http://localhost:4318/v1/logs
+	MaxCodeBytes int              `json:"maxCodeBytes,omitempty"` // Code kept per record
+	Retain       int              `json:"retain,omitempty"`       // Records kept in memory for the admin API
+	RedactKeys   []string         `json:"redactKeys,omitempty"`   // Tool argument names redacted besides the built-in ones
+}
+
+// AuditHTTPConfig is an HTTP endpoint audit records are sent to
+type AuditHTTPConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// AdminConfig enables the admin HTTP API on its own listener, so it can be
+// kept off the network the MCP endpoint is served on
+type AdminConfig struct {
+	Addr  string        `json:"addr,omitempty"`  // Listen address, e.g. "127.0.0.1:3001"; empty disables the API
+	Token *SecretConfig `json:"token,omitempty"` // Bearer token required by every request, and to fork sessions; optional only on loopback
+}
+
+// SearchConfig configures the search_tools tool
+type SearchConfig struct {
+	Semantic *SemanticSearchConfig `json:"semantic,omitempty"` // Also rank tools by meaning; keyword search only when unset
+}
+
+// SemanticSearchConfig ranks tools by the similarity of the embeddings of their
+// names and descriptions to the query's, so a query finds tools sharing no word
+// with it. Tools are embedded once and kept in an on-disk index; only new and
+// changed tools are embedded again.
+type SemanticSearchConfig struct {
+	Embedder string            `json:"embedder,omitempty"` // "api" or "command"
+	URL      string            `json:"url,omitempty"`      // api: OpenAI-compatible embeddings endpoint, e.g. http://localhost:11434/v1/embeddings
+	Model    string            `json:"model,omitempty"`    // api: embedding model, e.g. "text-embedding-3-small"
+	Headers  map[string]string `json:"headers,omitempty"`  // api: sent with every request, e.g. {"Authorization": "Bearer ..."}
+	Command  []string          `json:"command,omitempty"`  // command: program reading a JSON array of texts on stdin and writing a JSON array of vectors
+	IndexDir string            `json:"indexDir,omitempty"` // Directory of the index (default: the user cache directory)
+	MinScore float64           `json:"minScore,omitempty"` // Lowest cosine similarity of a match (default 0.3)
+}
+
+// Semantic search embedders
+const (
+	EmbedderAPI     = "api"
+	EmbedderCommand = "command"
+)
+
 // McpServerConfig is the interface for all MCP server configurations
 type McpServerConfig struct {
 	Type string `json:"type,omitempty"` // Optional: "stdio", "http", or "sse" - will be inferred if omitted
 
 	// Stdio fields
-	Command string            `json:"command,omitempty"`
-	Args    []string          `json:"args,omitempty"`
-	Cwd     string            `json:"cwd,omitempty"`
-	Env     map[string]string `json:"env,omitempty"`
+	Command    string            `json:"command,omitempty"`
+	Args       []string          `json:"args,omitempty"`
+	Cwd        string            `json:"cwd,omitempty"`        // Working directory of the command, may start with ~/ (default: codebraid's)
+	Env        map[string]string `json:"env,omitempty"`        // Added to, or replacing, the inherited environment
+	InheritEnv *bool             `json:"inheritEnv,omitempty"` // Pass codebraid's environment to the command (default true); when false only PATH, HOME and similar basics are passed
+
+	// Package servers are launched by a runner instead of a command; see packages.go
+	Package string `json:"package,omitempty"` // npm or Python package of the server
+	Version string `json:"version,omitempty"` // Version to pin, or "latest"
+	Runner  string `json:"runner,omitempty"`  // "npx" (default) or "uvx"
 
 	// HTTP/SSE fields
 	URL     string            `json:"url,omitempty"`
 	Headers map[string]string `json:"headers,omitempty"`
+
+	// Tools are exposed, in generated libraries and to calls, under names with Prefix
+	// added, e.g. "gh_", or under the name Rename maps them to, e.g.
+	// {"create_issue": "new_issue"}; renamed tools get no prefix
+	Prefix string            `json:"prefix,omitempty"`
+	Rename map[string]string `json:"rename,omitempty"`
+
+	// Disabled servers, and servers whose EnableWhen does not hold on this
+	// machine, are left out of the config when it is loaded
+	Disabled   bool             `json:"disabled,omitempty"`
+	EnableWhen *EnableCondition `json:"enableWhen,omitempty"`
+}
+
+// EnableCondition says where a server is enabled; every field set must hold
+type EnableCondition struct {
+	Env      []string `json:"env,omitempty"`      // Environment variables that must be set and not empty
+	Platform []string `json:"platform,omitempty"` // Operating systems, as Go names them (darwin, linux, windows); one must match
+}
+
+// InheritsEnv reports whether a stdio server's command gets codebraid's environment
+func (s McpServerConfig) InheritsEnv() bool {
+	return s.InheritEnv == nil || *s.InheritEnv
+}
+
+// ExpandHome returns path with a leading ~/ replaced by the user's home directory
+func ExpandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, rest), nil
+}
+
+// ServerOverride changes how one session connects to a configured server
+type ServerOverride struct {
+	Env     map[string]string `json:"env,omitempty"`     // Added to a stdio server's environment
+	Headers map[string]string `json:"headers,omitempty"` // Added to an HTTP or SSE server's requests
+}
+
+// ForSession returns a copy of the config that connects only to servers (all of
+// them when empty), with overrides merged into their env and headers.
+// Naming a server that is not configured, or overriding one not connected, is an error.
+func (c *Config) ForSession(servers []string, overrides map[string]ServerOverride) (*Config, error) {
+	if len(servers) == 0 && len(overrides) == 0 {
+		return c, nil
+	}
+
+	selected := c.McpServers
+	if len(servers) > 0 {
+		selected = make(map[string]McpServerConfig, len(servers))
+		for _, name := range servers {
+			server, ok := c.McpServers[name]
+			if !ok {
+				return nil, fmt.Errorf("server %q is not configured", name)
+			}
+			selected[name] = server
+		}
+	}
+
+	sessionCfg := *c
+	sessionCfg.McpServers = make(map[string]McpServerConfig, len(selected))
+	for name, server := range selected {
+		sessionCfg.McpServers[name] = server
+	}
+	for name, override := range overrides {
+		server, ok := sessionCfg.McpServers[name]
+		if !ok {
+			return nil, fmt.Errorf("cannot override server %q: it is not connected", name)
+		}
+		server.Env = mergeStrings(server.Env, override.Env)
+		server.Headers = mergeStrings(server.Headers, override.Headers)
+		sessionCfg.McpServers[name] = server
+	}
+	return &sessionCfg, nil
+}
+
+// mergeStrings returns a copy of base with extra added, or base when extra is empty
+func mergeStrings(base, extra map[string]string) map[string]string {
+	if len(extra) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(extra))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range extra {
+		merged[key] = value
+	}
+	return merged
 }
 
 // LoadOptions configures how configuration is loaded
@@ -46,6 +471,12 @@ type LoadOptions struct {
 
 	// AllowEnvOverrides enables environment variable overrides
 	AllowEnvOverrides bool
+
+	// Profile names the profile of the config to apply; see profile.go
+	Profile string
+
+	// RemoteAuth is the Authorization header sent when ConfigPath is a URL; see remote.go
+	RemoteAuth string
 }
 
 // DefaultSearchPaths returns common config file locations
@@ -76,10 +507,23 @@ func LoadWithOptions(opts LoadOptions) (*Config, error) {
 		return nil, err
 	}
 
-	// Read the config file
-	data, err := os.ReadFile(configPath)
+	// Read the config file, merged over the files it includes and under the profile
+	loader := &layerLoader{remote: newRemoteSource(configPath, opts.RemoteAuth), version: sha256.New()}
+	merged, err := loader.load(configPath, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %q: %w", configPath, err)
+		return nil, err
+	}
+	if err := applyProfile(merged, opts.Profile); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	dropNulls(merged)
+	secrets, err := resolveSecrets(merged)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge config: %w", err)
 	}
 
 	// Parse the config
@@ -88,14 +532,24 @@ func LoadWithOptions(opts LoadOptions) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	// Expand ${VAR} syntax in config values
+	// Expand ${VAR} syntax in config values, then fill in the secrets, which
+	// must not be expanded
 	expandEnvVars(&config)
+	fillSecrets(&config, secrets)
 
 	// Apply environment variable overrides
 	if opts.AllowEnvOverrides {
 		applyEnvOverrides(&config)
 	}
 
+	// Leave out the servers not enabled on this machine
+	config.disabled = removeDisabled(&config)
+
+	// Build the commands of package servers
+	if err := expandPackages(&config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	// Infer server types if not specified
 	inferServerTypes(&config)
 
@@ -104,12 +558,56 @@ func LoadWithOptions(opts LoadOptions) (*Config, error) {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	config.path = configPath
+	config.files = loader.files
+	config.version = hex.EncodeToString(loader.version.Sum(nil))
+	config.profile = opts.Profile
 	return &config, nil
 }
 
+// Path returns the file the config was loaded from, or "" when it was not loaded from one
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Profile returns the profile applied when loading the config, or "" when none was
+func (c *Config) Profile() string {
+	return c.profile
+}
+
+// DisabledServers returns the servers left out of the config because they are
+// disabled or not enabled on this machine, with the reason for each
+func (c *Config) DisabledServers() map[string]string {
+	return c.disabled
+}
+
+// Files returns the absolute paths, or URLs, of the config file and every file it includes
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// Version identifies the contents the config was loaded from; it changes when any
+// of its files does
+func (c *Config) Version() string {
+	return c.version
+}
+
+// IsRemote reports whether the config or a file it includes was loaded from a URL
+func (c *Config) IsRemote() bool {
+	for _, file := range c.files {
+		if isRemote(file) {
+			return true
+		}
+	}
+	return false
+}
+
 // resolveConfigPath determines which config file to use
 func resolveConfigPath(opts LoadOptions) (string, error) {
 	// If explicit path provided, use it
+	if isRemote(opts.ConfigPath) {
+		return opts.ConfigPath, nil
+	}
 	if opts.ConfigPath != "" {
 		if _, err := os.Stat(opts.ConfigPath); err != nil {
 			return "", fmt.Errorf("config file not found at %q: %w", opts.ConfigPath, err)
@@ -162,6 +660,9 @@ func expandEnvVars(config *Config) {
 //	CODEBRAID_SERVER_<NAME>_COMMAND=node
 //	CODEBRAID_SERVER_<NAME>_ARGS=arg1,arg2
 //	CODEBRAID_SERVER_<NAME>_CWD=/path
+//	CODEBRAID_SERVER_<NAME>_INHERIT_ENV=false
+//	CODEBRAID_SERVER_<NAME>_DISABLED=true
+//	CODEBRAID_SERVER_<NAME>_VERSION=1.2.3
 //	CODEBRAID_SERVER_<NAME>_URL=https://...
 //	CODEBRAID_SERVER_<NAME>_HEADER_<KEY>=value
 //	CODEBRAID_SERVER_<NAME>_ENV_<KEY>=value
@@ -226,6 +727,16 @@ func applyServerOverride(server *McpServerConfig, property, value string) {
 	case property == "CWD":
 		server.Cwd = value
 
+	case property == "VERSION":
+		server.Version = value
+
+	case property == "DISABLED":
+		server.Disabled = value == "true" || value == "1"
+
+	case property == "INHERIT_ENV":
+		inherit := value == "true" || value == "1"
+		server.InheritEnv = &inherit
+
 	case property == "URL":
 		server.URL = value
 
@@ -272,16 +783,175 @@ func inferServerTypes(config *Config) {
 	}
 }
 
+// removeDisabled removes the servers that are disabled or whose enable condition
+// does not hold, and returns why each was removed
+func removeDisabled(config *Config) map[string]string {
+	var disabled map[string]string
+	for name, server := range config.McpServers {
+		reason := ""
+		if server.Disabled {
+			reason = "disabled"
+		} else if server.EnableWhen != nil {
+			reason = server.EnableWhen.unmet()
+		}
+		if reason == "" {
+			continue
+		}
+		if disabled == nil {
+			disabled = make(map[string]string)
+		}
+		disabled[name] = reason
+		delete(config.McpServers, name)
+	}
+	return disabled
+}
+
+// unmet describes the first part of the condition that does not hold, or returns
+// "" when it all holds
+func (c *EnableCondition) unmet() string {
+	for _, key := range c.Env {
+		if os.Getenv(key) == "" {
+			return fmt.Sprintf("environment variable %s is not set", key)
+		}
+	}
+	if len(c.Platform) > 0 {
+		for _, platform := range c.Platform {
+			if platform == runtime.GOOS {
+				return ""
+			}
+		}
+		return fmt.Sprintf("platform %s is not one of %s", runtime.GOOS, strings.Join(c.Platform, ", "))
+	}
+	return ""
+}
+
 // validate checks if the configuration is valid
 func validate(config *Config) error {
 	if len(config.McpServers) == 0 {
+		if len(config.disabled) > 0 {
+			return fmt.Errorf("no MCP servers enabled (%d disabled)", len(config.disabled))
+		}
 		return fmt.Errorf("no MCP servers configured")
 	}
 
+	if config.Server != nil {
+		for _, transport := range config.Server.Transports {
+			if transport != TransportHTTP && transport != TransportStdio {
+				return fmt.Errorf("server: invalid transport %q (must be http or stdio)", transport)
+			}
+		}
+	}
+
+	if config.Admin != nil && config.Admin.Addr != "" && config.Admin.Token == nil && !config.AdminOnLoopback() {
+		return fmt.Errorf("admin: a token is required unless addr is a loopback address such as 127.0.0.1:3001")
+	}
+
+	if config.Sandbox != nil {
+		switch config.Sandbox.Runtime {
+		case "", "wasm", "goja", "deno", "bun", "docker":
+		default:
+			return fmt.Errorf("sandbox: invalid runtime %q (must be wasm, goja, deno, bun, or docker)", config.Sandbox.Runtime)
+		}
+		if config.Sandbox.OSSandbox == "" && !config.Sandbox.AllowUnsandboxed {
+			if config.Sandbox.Runtime == "bun" {
+				return fmt.Errorf("sandbox: bun has no permission system; set osSandbox to wrap it, or allowUnsandboxed to run it with the server's access")
+			}
+			if config.Sandbox.Python != nil {
+				return fmt.Errorf("sandbox: Python has no permission system; set osSandbox to wrap it, or allowUnsandboxed to run it with the server's access")
+			}
+		}
+		if policy := config.Sandbox.Policy; policy != nil {
+			if err := checkAllowNet(config.Sandbox.Runtime, policy.AllowNet); err != nil {
+				return fmt.Errorf("sandbox: %w", err)
+			}
+			if config.Sandbox.Python != nil {
+				if err := checkAllowNet("python", policy.AllowNet); err != nil {
+					return fmt.Errorf("sandbox: %w", err)
+				}
+			}
+		}
+		if config.Sandbox.Timeout < 0 || config.Sandbox.MaxTimeout < 0 {
+			return fmt.Errorf("sandbox: timeouts must not be negative")
+		}
+		for name, secret := range config.Sandbox.Secrets {
+			if (secret.Env == "") == (secret.Value == "") {
+				return fmt.Errorf("sandbox: secret %q must set exactly one of 'env' or 'value'", name)
+			}
+		}
+		if pool := config.Sandbox.Pool; pool != nil && (pool.Size < 0 || pool.IdleTTL < 0) {
+			return fmt.Errorf("sandbox: pool size and idleTtl must not be negative")
+		}
+		if policy := config.Sandbox.Policy; policy != nil && (policy.MaxMemoryMB < 0 || policy.MaxCPUSeconds < 0 || policy.MaxOutputKB < 0 || policy.MaxResultKB < 0 || policy.MaxToolCalls < 0 || policy.MaxToolSeconds < 0) {
+			return fmt.Errorf("sandbox: resource limits must not be negative")
+		}
+	}
+
+	if c := config.Concurrency; c != nil && (c.MaxExecutions < 0 || c.MaxSessionExecutions < 0 || c.MaxBundles < 0 || c.MaxQueue < 0 || c.QueueTimeout < 0) {
+		return fmt.Errorf("concurrency: limits must not be negative")
+	}
+
+	if s := config.Sessions; s != nil && (s.IdleTTL < 0 || s.MaxAge < 0 || s.MaxSessions < 0 || s.WarmSessions < 0 || s.MaxBundleMB < 0 || s.ArtifactRetention < 0 || s.OrphanAge < 0) {
+		return fmt.Errorf("sessions: idleTtl, maxAge, maxSessions, warmSessions, maxBundleMB, artifactRetention and orphanAge must not be negative")
+	}
+	if s := config.Sessions; s != nil {
+		switch s.LimitPolicy {
+		case "", SessionLimitEvict, SessionLimitReject:
+		default:
+			return fmt.Errorf("sessions: invalid limitPolicy %q (must be evict or reject)", s.LimitPolicy)
+		}
+	}
+
+	if config.Search != nil && config.Search.Semantic != nil {
+		semantic := config.Search.Semantic
+		switch semantic.Embedder {
+		case EmbedderAPI:
+			if semantic.URL == "" || semantic.Model == "" {
+				return fmt.Errorf("search: the api embedder requires a url and a model")
+			}
+		case EmbedderCommand:
+			if len(semantic.Command) == 0 {
+				return fmt.Errorf("search: the command embedder requires a command")
+			}
+		default:
+			return fmt.Errorf("search: invalid embedder %q (must be api or command)", semantic.Embedder)
+		}
+		if semantic.MinScore < 0 || semantic.MinScore > 1 {
+			return fmt.Errorf("search: minScore must be between 0 and 1")
+		}
+	}
+
+	if config.Bundler != nil {
+		switch config.Bundler.Toolchain {
+		case "", "esbuild", "rspack", "bun":
+		default:
+			return fmt.Errorf("bundler: invalid toolchain %q (must be esbuild, rspack, or bun)", config.Bundler.Toolchain)
+		}
+		if config.Bundler.RspackConfig != "" && config.Bundler.Toolchain != "rspack" {
+			return fmt.Errorf("bundler: rspackConfig requires the rspack toolchain")
+		}
+	}
+	if config.Bundler != nil && (config.Bundler.CacheSizeMB < 0 || config.Bundler.MaxBundleKB < 0) {
+		return fmt.Errorf("bundler: cacheSizeMB and maxBundleKB must not be negative")
+	}
+	if config.Bundler != nil && (config.Bundler.PackageInstallTimeout < 0 || config.Bundler.PackageMaxSizeMB < 0) {
+		return fmt.Errorf("bundler: package install limits must not be negative")
+	}
+
 	for name, server := range config.McpServers {
 		hasCommand := server.Command != ""
 		hasURL := server.URL != ""
 
+		renamedFrom := make(map[string]string, len(server.Rename))
+		for original, exposed := range server.Rename {
+			if exposed == "" {
+				return fmt.Errorf("server %q: rename of tool %q must not be empty", name, original)
+			}
+			if other, taken := renamedFrom[exposed]; taken {
+				return fmt.Errorf("server %q: tools %q and %q are both renamed to %q", name, other, original, exposed)
+			}
+			renamedFrom[exposed] = original
+		}
+
 		// Check for ambiguous configuration
 		if hasCommand && hasURL {
 			return fmt.Errorf("server %q: cannot specify both 'command' and 'url' (ambiguous server type)", name)
@@ -289,7 +959,7 @@ func validate(config *Config) error {
 
 		// Check that at least one is specified
 		if !hasCommand && !hasURL {
-			return fmt.Errorf("server %q: must specify either 'command' (for stdio) or 'url' (for http/sse)", name)
+			return fmt.Errorf("server %q: must specify 'command' or 'package' (for stdio) or 'url' (for http/sse)", name)
 		}
 
 		// Validate type-specific fields
@@ -327,3 +997,457 @@ func (c *Config) GetServerTimeout() int {
 	}
 	return 30 // Default 30 seconds
 }
+
+// GetServerTransports returns the transports the server is served over
+func (c *Config) GetServerTransports() []string {
+	if c.Server != nil && len(c.Server.Transports) > 0 {
+		return c.Server.Transports
+	}
+	return []string{TransportHTTP}
+}
+
+// GetShutdownTimeout returns how long shutdown drains executions, in seconds
+func (c *Config) GetShutdownTimeout() int {
+	if c.Server != nil && c.Server.ShutdownTimeout > 0 {
+		return c.Server.ShutdownTimeout
+	}
+	return 30 // Default 30 seconds
+}
+
+// GetSandboxRuntime returns the configured sandbox runtime (empty means auto-detect)
+func (c *Config) GetSandboxRuntime() string {
+	if c.Sandbox != nil {
+		return c.Sandbox.Runtime
+	}
+	return ""
+}
+
+// GetSandboxWasmPath returns the configured sandbox plugin path with fallback to default
+func (c *Config) GetSandboxWasmPath() string {
+	if c.Sandbox != nil && c.Sandbox.WasmPath != "" {
+		return c.Sandbox.WasmPath
+	}
+	return "./wasm/dist/sandbox.wasm"
+}
+
+// GetSandboxTimeout returns the default execution timeout in seconds with fallback to default
+func (c *Config) GetSandboxTimeout() int {
+	if c.Sandbox != nil && c.Sandbox.Timeout > 0 {
+		return c.Sandbox.Timeout
+	}
+	return 30 // Default 30 seconds
+}
+
+// GetSandboxMaxTimeout returns the largest per-run timeout a caller may request, in seconds
+func (c *Config) GetSandboxMaxTimeout() int {
+	if c.Sandbox != nil && c.Sandbox.MaxTimeout > 0 {
+		return c.Sandbox.MaxTimeout
+	}
+	return 300 // Default 5 minutes
+}
+
+// checkAllowNet rejects allowNet entries the runtime can't enforce, so a policy
+// never silently allows more than it lists
+func checkAllowNet(runtime string, allowNet []string) error {
+	if len(allowNet) == 0 {
+		return nil
+	}
+	switch runtime {
+	case "bun":
+		return fmt.Errorf("bun can't enforce allowNet: only its HTTP clients use the egress proxy, other connections bypass it (use the deno or wasm runtime)")
+	case "python":
+		return fmt.Errorf("python can't enforce allowNet: only its HTTP clients use the egress proxy, sockets bypass it (use the deno or wasm runtime without sandbox.python)")
+	case "docker":
+		return fmt.Errorf("docker can't enforce allowNet: containers get the access of docker.network")
+	case "deno":
+		for _, entry := range allowNet {
+			if strings.Contains(entry, "*") || strings.Contains(entry, "/") {
+				return fmt.Errorf("deno can't enforce allowNet entry %q: --allow-net takes hosts, not wildcards or CIDR ranges", entry)
+			}
+		}
+	}
+	return nil
+}
+
+// GetSandboxDocker returns the container executor settings with defaults applied
+func (c *Config) GetSandboxDocker() DockerConfig {
+	docker := DockerConfig{}
+	if c.Sandbox != nil && c.Sandbox.Docker != nil {
+		docker = *c.Sandbox.Docker
+	}
+	if docker.Binary == "" {
+		docker.Binary = "docker"
+	}
+	if docker.Image == "" {
+		docker.Image = "node:22-alpine"
+	}
+	if len(docker.Command) == 0 {
+		docker.Command = []string{"node"}
+	}
+	if docker.Network == "" {
+		docker.Network = "none"
+	}
+	if docker.PidsLimit <= 0 {
+		docker.PidsLimit = 128
+	}
+	return docker
+}
+
+// GetSandboxPool returns the warm process pool settings with defaults applied
+func (c *Config) GetSandboxPool() PoolConfig {
+	pool := PoolConfig{}
+	if c.Sandbox != nil && c.Sandbox.Pool != nil {
+		pool = *c.Sandbox.Pool
+	}
+	if pool.IdleTTL <= 0 {
+		pool.IdleTTL = 300 // Default 5 minutes
+	}
+	return pool
+}
+
+// GetConcurrency returns the concurrency limits with defaults applied
+func (c *Config) GetConcurrency() ConcurrencyConfig {
+	limits := ConcurrencyConfig{}
+	if c.Concurrency != nil {
+		limits = *c.Concurrency
+	}
+	if limits.MaxExecutions <= 0 {
+		limits.MaxExecutions = 2 * runtime.NumCPU()
+	}
+	if limits.MaxSessionExecutions <= 0 {
+		limits.MaxSessionExecutions = 4
+	}
+	if limits.MaxBundles <= 0 {
+		limits.MaxBundles = runtime.NumCPU()
+	}
+	if limits.MaxQueue <= 0 {
+		limits.MaxQueue = 32
+	}
+	if limits.QueueTimeout <= 0 {
+		limits.QueueTimeout = 30 // Default 30 seconds
+	}
+	return limits
+}
+
+// GetResultCache returns the result cache settings with defaults applied
+func (c *Config) GetResultCache() ResultCacheConfig {
+	cache := ResultCacheConfig{}
+	if c.ResultCache != nil {
+		cache = *c.ResultCache
+	}
+	if cache.TTL <= 0 {
+		cache.TTL = 300 // Default 5 minutes
+	}
+	if cache.MaxEntries <= 0 {
+		cache.MaxEntries = 64
+	}
+	return cache
+}
+
+// GetAuditEnabled reports whether executions are recorded
+func (c *Config) GetAuditEnabled() bool {
+	return c.Audit != nil && c.Audit.Enabled
+}
+
+// GetAudit returns the audit settings with defaults applied
+func (c *Config) GetAudit() AuditConfig {
+	audit := AuditConfig{}
+	if c.Audit != nil {
+		audit = *c.Audit
+	}
+	if audit.MaxCodeBytes <= 0 {
+		audit.MaxCodeBytes = 4096
+	}
+	if audit.Retain <= 0 {
+		audit.Retain = 1000
+	}
+	return audit
+}
+
+// GetAdminAddr returns the admin API listen address, empty when it is disabled
+func (c *Config) GetAdminAddr() string {
+	if c.Admin != nil {
+		return c.Admin.Addr
+	}
+	return ""
+}
+
+// AdminOnLoopback reports whether the admin API listens on a loopback address
+// only, out of reach of other hosts
+func (c *Config) AdminOnLoopback() bool {
+	host, _, err := net.SplitHostPort(c.GetAdminAddr())
+	return err == nil && isLoopback(host)
+}
+
+// GetAdminToken resolves the bearer token of the admin API, empty when none is set
+func (c *Config) GetAdminToken() string {
+	if c.Admin == nil || c.Admin.Token == nil {
+		return ""
+	}
+	if c.Admin.Token.Env != "" {
+		return os.Getenv(c.Admin.Token.Env)
+	}
+	return c.Admin.Token.Value
+}
+
+// GetSemanticSearchEnabled reports whether search_tools also ranks tools by meaning
+func (c *Config) GetSemanticSearchEnabled() bool {
+	return c.Search != nil && c.Search.Semantic != nil
+}
+
+// GetSemanticSearch returns the semantic search settings with defaults applied
+func (c *Config) GetSemanticSearch() SemanticSearchConfig {
+	semantic := SemanticSearchConfig{}
+	if c.Search != nil && c.Search.Semantic != nil {
+		semantic = *c.Search.Semantic
+	}
+	if semantic.IndexDir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			base = os.TempDir()
+		}
+		semantic.IndexDir = filepath.Join(base, "codebraid", "search-index")
+	}
+	if semantic.MinScore == 0 {
+		semantic.MinScore = 0.3
+	}
+	return semantic
+}
+
+// GetJobs returns the background job limits with defaults applied
+func (c *Config) GetJobs() JobsConfig {
+	jobs := JobsConfig{}
+	if c.Jobs != nil {
+		jobs = *c.Jobs
+	}
+	if jobs.MaxJobs <= 0 {
+		jobs.MaxJobs = 16
+	}
+	if jobs.MaxLogKB <= 0 {
+		jobs.MaxLogKB = 256 // Default 256 KB
+	}
+	if jobs.Retention <= 0 {
+		jobs.Retention = 3600 // Default 1 hour
+	}
+	return jobs
+}
+
+// GetSessions returns the session limits (zero values mean unlimited) with the
+// default limit policy and orphan age applied
+func (c *Config) GetSessions() SessionsConfig {
+	sessions := SessionsConfig{}
+	if c.Sessions != nil {
+		sessions = *c.Sessions
+	}
+	if sessions.LimitPolicy == "" {
+		sessions.LimitPolicy = SessionLimitEvict
+	}
+	if sessions.OrphanAge == 0 {
+		sessions.OrphanAge = 3600
+	}
+	return sessions
+}
+
+// GetSandboxOSSandbox returns the OS sandbox wrapping runtime processes (empty for none)
+func (c *Config) GetSandboxOSSandbox() string {
+	if c.Sandbox != nil {
+		return c.Sandbox.OSSandbox
+	}
+	return ""
+}
+
+// GetSandboxAllowUnsandboxed returns whether bun and Python may run without an OS sandbox
+func (c *Config) GetSandboxAllowUnsandboxed() bool {
+	return c.Sandbox != nil && c.Sandbox.AllowUnsandboxed
+}
+
+// GetSandboxWasmCache returns the directory for persisted wasm compilation (empty keeps it in memory)
+func (c *Config) GetSandboxWasmCache() string {
+	if c.Sandbox != nil {
+		return c.Sandbox.WasmCache
+	}
+	return ""
+}
+
+// GetSandboxPolicy returns the configured sandbox policy (empty denies everything)
+func (c *Config) GetSandboxPolicy() SandboxPolicy {
+	if c.Sandbox != nil && c.Sandbox.Policy != nil {
+		return *c.Sandbox.Policy
+	}
+	return SandboxPolicy{}
+}
+
+// GetSandboxMaxOutputBytes returns the console output cap per execution with fallback to default
+func (c *Config) GetSandboxMaxOutputBytes() int {
+	if policy := c.GetSandboxPolicy(); policy.MaxOutputKB > 0 {
+		return policy.MaxOutputKB << 10
+	}
+	return 1 << 20 // Default 1 MB
+}
+
+// GetSandboxMaxResultBytes returns the result size cap per execution with fallback to default
+func (c *Config) GetSandboxMaxResultBytes() int {
+	if policy := c.GetSandboxPolicy(); policy.MaxResultKB > 0 {
+		return policy.MaxResultKB << 10
+	}
+	return 1 << 20 // Default 1 MB
+}
+
+// GetSandboxSecrets resolves the configured secrets to their values.
+// Secrets read from unset environment variables are left out.
+func (c *Config) GetSandboxSecrets() map[string]string {
+	if c.Sandbox == nil || len(c.Sandbox.Secrets) == 0 {
+		return nil
+	}
+	secrets := make(map[string]string, len(c.Sandbox.Secrets))
+	for name, secret := range c.Sandbox.Secrets {
+		value := secret.Value
+		if secret.Env != "" {
+			value = os.Getenv(secret.Env)
+		}
+		if value != "" {
+			secrets[name] = value
+		}
+	}
+	return secrets
+}
+
+// GetBundlerToolchain returns the configured bundler toolchain (empty means auto-select)
+func (c *Config) GetBundlerToolchain() string {
+	if c.Bundler != nil {
+		return c.Bundler.Toolchain
+	}
+	return ""
+}
+
+// GetBundlerRspackConfig returns the path of the rspack config template (empty means the embedded one)
+func (c *Config) GetBundlerRspackConfig() string {
+	if c.Bundler != nil {
+		return c.Bundler.RspackConfig
+	}
+	return ""
+}
+
+// GetBundleCacheEnabled reports whether bundles are cached between executions
+func (c *Config) GetBundleCacheEnabled() bool {
+	return c.Bundler == nil || !c.Bundler.DisableCache
+}
+
+// GetBundleCacheDir returns the bundle cache directory with fallback to the user cache directory
+func (c *Config) GetBundleCacheDir() string {
+	if c.Bundler != nil && c.Bundler.CacheDir != "" {
+		return c.Bundler.CacheDir
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "codebraid", "bundles")
+}
+
+// GetBundleCacheSizeMB returns the bundle cache size limit in megabytes with fallback to default
+func (c *Config) GetBundleCacheSizeMB() int {
+	if c.Bundler != nil && c.Bundler.CacheSizeMB > 0 {
+		return c.Bundler.CacheSizeMB
+	}
+	return 256 // Default 256 MB
+}
+
+// GetBundlerTypeCheck reports whether every execution is type-checked before it runs
+func (c *Config) GetBundlerTypeCheck() bool {
+	return c.Bundler != nil && c.Bundler.TypeCheck
+}
+
+// GetBundlerIncremental reports whether esbuild keeps a build context per session
+func (c *Config) GetBundlerIncremental() bool {
+	return c.Bundler != nil && c.Bundler.Incremental
+}
+
+// GetMaxBundleKB returns the bundle size limit in kilobytes (zero means no limit)
+func (c *Config) GetMaxBundleKB() int {
+	if c.Bundler != nil {
+		return c.Bundler.MaxBundleKB
+	}
+	return 0
+}
+
+// GetSandboxDeterministic returns whether every run is deterministic by default
+func (c *Config) GetSandboxDeterministic() bool {
+	return c.Sandbox != nil && c.Sandbox.Deterministic
+}
+
+// GetSandboxHooks returns the hooks run around every execution
+func (c *Config) GetSandboxHooks() HooksConfig {
+	if c.Sandbox != nil && c.Sandbox.Hooks != nil {
+		return *c.Sandbox.Hooks
+	}
+	return HooksConfig{}
+}
+
+// GetSandboxPythonEnabled returns whether Python scripts may be executed
+func (c *Config) GetSandboxPythonEnabled() bool {
+	return c.Sandbox != nil && c.Sandbox.Python != nil
+}
+
+// GetSandboxPython returns the Python interpreter settings with defaults applied
+func (c *Config) GetSandboxPython() PythonConfig {
+	var python PythonConfig
+	if c.Sandbox != nil && c.Sandbox.Python != nil {
+		python = *c.Sandbox.Python
+	}
+	if python.Command == "" {
+		python.Command = "python3"
+	}
+	return python
+}
+
+// GetBundlerPackages returns the npm packages scripts may import
+func (c *Config) GetBundlerPackages() []string {
+	if c.Bundler != nil {
+		return c.Bundler.Packages
+	}
+	return nil
+}
+
+// GetPackageCacheDir returns the installed package directory with fallback to the user cache directory
+func (c *Config) GetPackageCacheDir() string {
+	if c.Bundler != nil && c.Bundler.PackageCacheDir != "" {
+		return c.Bundler.PackageCacheDir
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "codebraid", "packages")
+}
+
+// GetPackageInstallTimeout returns the package install time limit in seconds with fallback to default
+func (c *Config) GetPackageInstallTimeout() int {
+	if c.Bundler != nil && c.Bundler.PackageInstallTimeout > 0 {
+		return c.Bundler.PackageInstallTimeout
+	}
+	return 120 // Default 2 minutes
+}
+
+// GetPackageMaxSizeMB returns the installed package size limit in megabytes with fallback to default
+func (c *Config) GetPackageMaxSizeMB() int {
+	if c.Bundler != nil && c.Bundler.PackageMaxSizeMB > 0 {
+		return c.Bundler.PackageMaxSizeMB
+	}
+	return 200 // Default 200 MB
+}
+
+// GetTransform returns the transform settings with defaults applied
+func (c *Config) GetTransform() TransformConfig {
+	var transform TransformConfig
+	if c.Bundler != nil && c.Bundler.Transform != nil {
+		transform = *c.Bundler.Transform
+	}
+	if transform.Target == "" {
+		transform.Target = "es2020"
+	}
+	if transform.Module == "" {
+		transform.Module = "esm"
+	}
+	return transform
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// String values of a config can be stored encrypted, so configs holding tokens
+// can be committed: "ENC[v1,...]" values are decrypted with AES-256-GCM when the
+// config is loaded, like $secret references (see secrets.go). Each value is
+// bound to the path of its field in its file, such as mcpServers.github.env.TOKEN
+// or mcpServers.db.args[2], so it cannot be moved to another field. The key is found
+// in CODEBRAID_CONFIG_KEY (base64), in the file CODEBRAID_CONFIG_KEY_FILE names,
+// or in config.key under the user config directory; "codebraid config keygen"
+// creates one, and "codebraid config encrypt" and "decrypt" rewrite a file.
+
+// Environment variables locating the key of encrypted values
+const (
+	ConfigKeyEnv     = "CODEBRAID_CONFIG_KEY"
+	ConfigKeyFileEnv = "CODEBRAID_CONFIG_KEY_FILE"
+)
+
+// DefaultEncryptKeys matches the keys whose values EncryptFile encrypts by default
+const DefaultEncryptKeys = `(?i)(token|secret|password|passwd|api[_-]?key|authorization|credential)`
+
+// encryptedPrefix and encryptedSuffix wrap an encrypted value
+const (
+	encryptedPrefix = "ENC[v1,"
+	encryptedSuffix = "]"
+)
+
+// isEncrypted reports whether a config string is an encrypted value
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// DefaultKeyPath returns where the key of encrypted values is kept when neither
+// environment variable is set
+func DefaultKeyPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "codebraid", "config.key")
+}
+
+// LoadKey finds the key of encrypted values
+func LoadKey() ([]byte, error) {
+	encoded := os.Getenv(ConfigKeyEnv)
+	source := ConfigKeyEnv
+	if encoded == "" {
+		path := os.Getenv(ConfigKeyFileEnv)
+		if path == "" {
+			path = DefaultKeyPath()
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("no key for encrypted config values: set %s or %s, or create %s with \"codebraid config keygen\"",
+				ConfigKeyEnv, ConfigKeyFileEnv, DefaultKeyPath())
+		}
+		encoded, source = strings.TrimSpace(string(data)), path
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid key in %s: it must be 32 bytes, base64 encoded", source)
+	}
+	return key, nil
+}
+
+// NewKey returns a new random key, base64 encoded
+func NewKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// encryptValue encrypts a config string, bound to the path of its field
+func encryptValue(key []byte, plaintext, path string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(path))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + encryptedSuffix, nil
+}
+
+// decryptValue decrypts an encrypted config string found at path
+func decryptValue(key []byte, value, path string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), encryptedSuffix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: wrong key, corrupted value or value moved from another field")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptedValue is an encrypted string of a config file, marked with the path
+// of its field in that file when the file is read, as merging includes and
+// profiles can move it to another path
+type encryptedValue struct {
+	value string
+	path  string
+}
+
+// markEncrypted returns a config value with its encrypted strings replaced by
+// encryptedValues; path locates it in its file
+func markEncrypted(value interface{}, path string) interface{} {
+	switch value := value.(type) {
+	case string:
+		if isEncrypted(value) {
+			return encryptedValue{value: value, path: path}
+		}
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = markEncrypted(item, joinPath(path, key))
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = markEncrypted(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+	return value
+}
+
+// joinPath returns the path of the field key of the object at path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// EncryptFile returns a config file with the string values of keys matching
+// pattern encrypted, keeping the order of its fields. Values already encrypted
+// and secret references are left as they are.
+func EncryptFile(data, key []byte, pattern string) ([]byte, int, error) {
+	keys, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid key pattern: %w", err)
+	}
+	count := 0
+	out, err := rewriteStrings(data, func(field, path, value string) (string, error) {
+		if field == "$secret" || isEncrypted(value) || !keys.MatchString(field) {
+			return value, nil
+		}
+		count++
+		return encryptValue(key, value, path)
+	})
+	return out, count, err
+}
+
+// DecryptFile returns a config file with its encrypted values decrypted, keeping
+// the order of its fields
+func DecryptFile(data, key []byte) ([]byte, int, error) {
+	count := 0
+	out, err := rewriteStrings(data, func(_, path, value string) (string, error) {
+		if !isEncrypted(value) {
+			return value, nil
+		}
+		count++
+		return decryptValue(key, value, path)
+	})
+	return out, count, err
+}
+
+// rewriteStrings re-encodes a JSON document with two-space indentation, passing
+// every string value through rewrite with the name of the field holding it (the
+// enclosing field for values in lists) and its path
+func rewriteStrings(data []byte, rewrite func(field, path, value string) (string, error)) ([]byte, error) {
+	type frame struct {
+		object bool   // Whether the container is an object, or a list
+		field  string // The current field of an object, or the field holding a list
+		path   string // Of the container
+		count  int    // Values written so far
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out bytes.Buffer
+	var stack []*frame
+	expectKey := false
+
+	newline := func() {
+		out.WriteByte('\n')
+		out.WriteString(strings.Repeat("  ", len(stack)))
+	}
+	writeJSON := func(v interface{}) {
+		encoded, _ := json.Marshal(v)
+		out.Write(encoded)
+	}
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+
+		// Closing a container ends the line of its last value
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if top.count > 0 {
+				newline()
+			}
+			out.WriteRune(rune(delim))
+			expectKey = len(stack) > 0 && stack[len(stack)-1].object
+			continue
+		}
+
+		// Every other token starts a key or a value
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+			if !top.object || expectKey {
+				if top.count > 0 {
+					out.WriteByte(',')
+				}
+				top.count++
+				newline()
+			}
+		}
+		if top != nil && top.object && expectKey {
+			top.field = token.(string)
+			writeJSON(top.field)
+			out.WriteString(": ")
+			expectKey = false
+			continue
+		}
+
+		field, path := "", ""
+		if top != nil {
+			field = top.field
+			if top.object {
+				path = joinPath(top.path, field)
+			} else {
+				path = fmt.Sprintf("%s[%d]", top.path, top.count-1)
+			}
+		}
+		switch token := token.(type) {
+		case json.Delim:
+			out.WriteRune(rune(token))
+			stack = append(stack, &frame{object: token == '{', field: field, path: path})
+			expectKey = token == '{'
+			continue
+		case string:
+			rewritten, err := rewrite(field, path, token)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			writeJSON(rewritten)
+		case json.Number:
+			out.WriteString(token.String())
+		default:
+			writeJSON(token)
+		}
+		expectKey = top != nil && top.object
+	}
+	if len(stack) > 0 {
+		return nil, errors.New("failed to parse config: unexpected end of JSON input")
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	encoded, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := base64.StdEncoding.DecodeString(encoded)
+	return key
+}
+
+func TestEncryptFileRoundTrip(t *testing.T) {
+	key := testKey(t)
+	encrypted, err := encryptValue(key, "kept", "mcpServers.b.env.OLD_TOKEN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `{
+  "mcpServers": {
+    "z": {
+      "command": "z-server",
+      "args": ["--token", "x"],
+      "env": {"API_KEY": "k1", "PLAIN": "p"}
+    },
+    "b": {
+      "url": "https://example.com",
+      "headers": {"Authorization": {"$secret": "env://AUTH"}},
+      "env": {"OLD_TOKEN": "` + encrypted + `"},
+      "empty": {},
+      "none": []
+    }
+  },
+  "tokens": [["t1", "t2"], {"password": "pw"}],
+  "count": 1.50
+}
+`
+	out, count, err := EncryptFile([]byte(input), key, DefaultEncryptKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("expected 4 values encrypted (API_KEY, t1, t2, password), got %d:\n%s", count, out)
+	}
+	for _, plain := range []string{`"k1"`, `"t1"`, `"t2"`, `"pw"`} {
+		if strings.Contains(string(out), plain) {
+			t.Errorf("expected %s encrypted:\n%s", plain, out)
+		}
+	}
+	for _, kept := range []string{`"PLAIN": "p"`, `"$secret": "env://AUTH"`, encrypted, `"empty": {}`, `"none": []`, `"count": 1.50`} {
+		if !strings.Contains(string(out), kept) {
+			t.Errorf("expected %s kept as it was:\n%s", kept, out)
+		}
+	}
+	if strings.Index(string(out), `"z"`) > strings.Index(string(out), `"b"`) {
+		t.Errorf("expected the order of fields kept:\n%s", out)
+	}
+
+	decrypted, count, err := DecryptFile(out, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Errorf("expected 5 values decrypted, got %d", count)
+	}
+	again, _, err := DecryptFile([]byte(input), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != string(again) {
+		t.Errorf("round trip changed the file:\n%s\nexpected:\n%s", decrypted, again)
+	}
+	if !strings.Contains(string(decrypted), `"OLD_TOKEN": "kept"`) {
+		t.Errorf("expected the value encrypted beforehand decrypted:\n%s", decrypted)
+	}
+}
+
+func TestDecryptFileWrongKey(t *testing.T) {
+	out, _, err := EncryptFile([]byte(`{"env": {"TOKEN": "t"}}`), testKey(t), DefaultEncryptKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := DecryptFile(out, testKey(t)); err == nil || !strings.Contains(err.Error(), "env.TOKEN") {
+		t.Errorf("expected decrypting with another key to fail at env.TOKEN, got %v", err)
+	}
+}
+
+func TestDecryptFileMovedValue(t *testing.T) {
+	key := testKey(t)
+	encrypted, err := encryptValue(key, "t", "mcpServers.a.env.TOKEN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := `{"mcpServers": {"b": {"env": {"TOKEN": "` + encrypted + `"}}}}`
+	if _, _, err := DecryptFile([]byte(moved), key); err == nil {
+		t.Error("expected a value moved to another field to fail to decrypt")
+	}
+	listed := `{"mcpServers": {"a": {"env": {"TOKEN": "` + encrypted + `"}, "args": ["` + encrypted + `"]}}}`
+	if _, _, err := DecryptFile([]byte(listed), key); err == nil || !strings.Contains(err.Error(), "mcpServers.a.args[0]") {
+		t.Errorf("expected a value copied into a list to fail at mcpServers.a.args[0], got %v", err)
+	}
+}
+
+func TestLoadDecryptsProfileValuesByTheirPathInTheFile(t *testing.T) {
+	key := testKey(t)
+	t.Setenv(ConfigKeyEnv, base64.StdEncoding.EncodeToString(key))
+	input := `{
+  "mcpServers": {"github": {"command": "gh-server", "env": {"GITHUB_TOKEN": "base"}}},
+  "profiles": {"prod": {"mcpServers": {"github": {"env": {"GITHUB_TOKEN": "prod"}}}}}
+}`
+	out, _, err := EncryptFile([]byte(input), key, DefaultEncryptKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "codebraid.json")
+	if err := os.WriteFile(path, out, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for profile, want := range map[string]string{"": "base", "prod": "prod"} {
+		cfg, err := LoadWithOptions(LoadOptions{ConfigPath: path, Profile: profile})
+		if err != nil {
+			t.Fatalf("profile %q: %v", profile, err)
+		}
+		if got := cfg.McpServers["github"].Env["GITHUB_TOKEN"]; got != want {
+			t.Errorf("profile %q: expected %q, got %q", profile, want, got)
+		}
+	}
+}
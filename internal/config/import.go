@@ -0,0 +1,141 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+)
+
+// importedServer is a server entry of the mcpServers format shared by Claude
+// Desktop (claude_desktop_config.json) and Cursor (.cursor/mcp.json)
+type importedServer struct {
+	Type     string            `json:"type"`
+	Command  string            `json:"command"`
+	Args     []string          `json:"args"`
+	Cwd      string            `json:"cwd"`
+	Env      map[string]string `json:"env"`
+	EnvFile  string            `json:"envFile"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers"`
+	Disabled bool              `json:"disabled"`
+}
+
+// cursorVariable matches the ${env:NAME} and ${userHome} variables of Cursor configs
+var cursorVariable = regexp.MustCompile(`\$\{(env:[A-Za-z_][A-Za-z0-9_]*|userHome)\}`)
+
+// DefaultImportPaths returns where Claude Desktop and Cursor keep their server
+// configs on this platform: Claude Desktop's config, then Cursor's project and
+// global configs
+func DefaultImportPaths() []string {
+	homeDir, _ := os.UserHomeDir()
+	var claudeDir string
+	switch runtime.GOOS {
+	case "darwin":
+		claudeDir = filepath.Join(homeDir, "Library", "Application Support", "Claude")
+	case "windows":
+		claudeDir = filepath.Join(os.Getenv("APPDATA"), "Claude")
+	default:
+		claudeDir = filepath.Join(homeDir, ".config", "Claude")
+	}
+	return []string{
+		filepath.Join(claudeDir, "claude_desktop_config.json"),
+		filepath.Join(".cursor", "mcp.json"),
+		filepath.Join(homeDir, ".cursor", "mcp.json"),
+	}
+}
+
+// ImportServers reads the servers of a Claude Desktop or Cursor config file and
+// converts them to codebraid servers. Servers that cannot be converted are left
+// out and described in the returned warnings.
+func ImportServers(path string) (map[string]McpServerConfig, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	var file struct {
+		McpServers map[string]importedServer `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %q: %w", path, err)
+	}
+	if file.McpServers == nil {
+		return nil, nil, fmt.Errorf("%q has no mcpServers", path)
+	}
+
+	names := make([]string, 0, len(file.McpServers))
+	for name := range file.McpServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	servers := make(map[string]McpServerConfig, len(names))
+	var warnings []string
+	for _, name := range names {
+		server, err := convertServer(file.McpServers[name])
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped server %q: %v", name, err))
+			continue
+		}
+		if file.McpServers[name].EnvFile != "" {
+			warnings = append(warnings, fmt.Sprintf("server %q: envFile is not supported, set its variables in env", name))
+		}
+		servers[name] = server
+	}
+	return servers, warnings, nil
+}
+
+// convertServer converts one imported server entry
+func convertServer(imported importedServer) (McpServerConfig, error) {
+	if (imported.Command == "") == (imported.URL == "") {
+		return McpServerConfig{}, fmt.Errorf("it must have exactly one of 'command' or 'url'")
+	}
+
+	server := McpServerConfig{
+		Command:  convertVariables(imported.Command),
+		Cwd:      convertVariables(imported.Cwd),
+		URL:      convertVariables(imported.URL),
+		Disabled: imported.Disabled,
+	}
+	switch imported.Type {
+	case "", "stdio", "http", "sse":
+		server.Type = imported.Type
+	case "streamable-http", "streamableHttp":
+		server.Type = "http"
+	default:
+		return McpServerConfig{}, fmt.Errorf("unsupported type %q", imported.Type)
+	}
+	for _, arg := range imported.Args {
+		server.Args = append(server.Args, convertVariables(arg))
+	}
+	server.Env = convertMap(imported.Env)
+	server.Headers = convertMap(imported.Headers)
+	return server, nil
+}
+
+// convertVariables rewrites Cursor's ${env:NAME} and ${userHome} as the ${NAME}
+// and ${HOME} codebraid expands
+func convertVariables(value string) string {
+	return cursorVariable.ReplaceAllStringFunc(value, func(variable string) string {
+		name := variable[2 : len(variable)-1]
+		if name == "userHome" {
+			return "${HOME}"
+		}
+		return "${" + name[len("env:"):] + "}"
+	})
+}
+
+// convertMap applies convertVariables to the values of env or headers
+func convertMap(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	converted := make(map[string]string, len(values))
+	for key, value := range values {
+		converted[key] = convertVariables(value)
+	}
+	return converted
+}
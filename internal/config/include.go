@@ -0,0 +1,170 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config files can pull in other config files with an "include" list, so a team
+// can share server definitions in a base file that personal configs build on:
+//
+//	{"include": ["../team/codebraid.json"], "mcpServers": {"github": {"env": {"GITHUB_TOKEN": "${MY_TOKEN}"}}}}
+//
+// Precedence, lowest first: the included files in the order listed, each after
+// its own includes, then the including file itself. Objects are merged key by
+// key at every level, so an overlay can change one field of a server defined in
+// the base; any other value, including arrays such as args, replaces the value
+// beneath it, and null removes it (e.g. "command": null before setting "url").
+// Include paths are relative to the file listing them and may use ${VAR}; they
+// may be https URLs, and the includes of a remote config are URLs relative to
+// its own (see remote.go). A file
+// may be included more than once, but not by itself through its own includes.
+
+// layerLoader reads a config file and the files it includes
+type layerLoader struct {
+	remote  remoteSource // How remote config files are fetched
+	files   []string     // Every file read, in order
+	version hash.Hash    // Of the contents of every file read
+}
+
+// load reads a config file as JSON and merges its includes beneath it. stack holds
+// the files including this one.
+func (l *layerLoader) load(path string, stack []string) (map[string]interface{}, error) {
+	abs := path
+	if !isRemote(path) {
+		var err error
+		if abs, err = filepath.Abs(path); err != nil {
+			return nil, fmt.Errorf("failed to resolve config file %q: %w", path, err)
+		}
+	}
+	for i, including := range stack {
+		if including == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], abs), " -> "))
+		}
+	}
+
+	var data []byte
+	var err error
+	if isRemote(abs) {
+		data, err = l.remote.fetch(abs)
+	} else {
+		data, err = os.ReadFile(abs)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %q: %w", path, err)
+	}
+	l.files = append(l.files, abs)
+	fmt.Fprintf(l.version, "%s\x00%d\x00", abs, len(data))
+	l.version.Write(data)
+
+	var layer map[string]interface{}
+	if err := json.Unmarshal(data, &layer); err != nil {
+		return nil, fmt.Errorf("failed to parse config %q: %w", path, err)
+	}
+	if err := checkLayer(layer, path); err != nil {
+		return nil, err
+	}
+	includes, err := includeList(layer)
+	if err != nil {
+		return nil, fmt.Errorf("config %q: %w", path, err)
+	}
+	markEncrypted(layer, "")
+
+	merged := make(map[string]interface{})
+	for _, include := range includes {
+		include, err := resolveInclude(abs, os.ExpandEnv(include))
+		if err != nil {
+			return nil, fmt.Errorf("config %q: %w", path, err)
+		}
+		base, err := l.load(include, append(stack, abs))
+		if err != nil {
+			return nil, err
+		}
+		mergeLayer(merged, base)
+	}
+	mergeLayer(merged, layer)
+	return merged, nil
+}
+
+// resolveInclude resolves an include relative to the file listing it; the
+// includes of a remote config are resolved relative to its URL
+func resolveInclude(including, include string) (string, error) {
+	if isRemote(including) {
+		base, err := url.Parse(including)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(filepath.ToSlash(include))
+		if err != nil {
+			return "", fmt.Errorf("invalid include %q: %w", include, err)
+		}
+		resolved := base.ResolveReference(ref).String()
+		if !isRemote(resolved) {
+			return "", fmt.Errorf("include %q of a remote config must be an https URL", include)
+		}
+		return resolved, nil
+	}
+	if isRemote(include) || filepath.IsAbs(include) {
+		return include, nil
+	}
+	return filepath.Join(filepath.Dir(including), include), nil
+}
+
+// includeList returns the files a config layer includes
+func includeList(layer map[string]interface{}) ([]string, error) {
+	value, ok := layer["include"]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("include must be a list of file paths")
+	}
+	includes := make([]string, 0, len(list))
+	for _, item := range list {
+		path, ok := item.(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("include must be a list of file paths")
+		}
+		includes = append(includes, path)
+	}
+	return includes, nil
+}
+
+// mergeLayer merges a config layer over dst: objects are merged recursively,
+// null removes a key and any other value replaces the one in dst
+func mergeLayer(dst, layer map[string]interface{}) {
+	for key, value := range layer {
+		if value == nil {
+			delete(dst, key)
+			continue
+		}
+		overlay, isObject := value.(map[string]interface{})
+		base, baseIsObject := dst[key].(map[string]interface{})
+		if isObject && baseIsObject {
+			mergeLayer(base, overlay)
+			continue
+		}
+		// Nulls nested in a new object are kept until dropNulls, as profiles still
+		// need them to remove keys when merged
+		dst[key] = value
+	}
+}
+
+// dropNulls removes the null keys left in a merged config, which had nothing
+// beneath them to remove
+func dropNulls(merged map[string]interface{}) {
+	for key, value := range merged {
+		switch value := value.(type) {
+		case nil:
+			delete(merged, key)
+		case map[string]interface{}:
+			dropNulls(value)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Runners that launch package servers
+const (
+	RunnerNpx = "npx" // npm packages
+	RunnerUvx = "uvx" // Python packages, with uv
+)
+
+// A stdio server can be given as a package instead of a command, and codebraid
+// builds the command that runs it, pinned to a version:
+//
+//	"github": {"package": "@modelcontextprotocol/server-github", "version": "2025.4.8"}
+//	"git":    {"package": "mcp-server-git", "version": "0.6.2", "runner": "uvx", "args": ["--repository", "."]}
+//
+// npx runs "npx -y PACKAGE@VERSION ARGS..." and uvx runs "uvx PACKAGE@VERSION
+// ARGS...". Without a version the latest release runs, which can change under
+// a running fleet; "latest" says so explicitly.
+
+// Valid package names and versions. Package names follow npm's grammar, scoped
+// or not, and PEP 508's for uvx; none can start with "-", which the runner would
+// take for an option.
+var (
+	npmPackageName  = regexp.MustCompile(`^(@[a-zA-Z0-9~][a-zA-Z0-9._~-]*/)?[a-zA-Z0-9~][a-zA-Z0-9._~-]*$`)
+	pypiPackageName = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]*[a-zA-Z0-9])?$`)
+	packageVersion  = regexp.MustCompile(`^[a-zA-Z0-9^~<>=*][a-zA-Z0-9.+_~^<>=*-]*$`)
+)
+
+// maxNpmPackageName is the longest name npm accepts
+const maxNpmPackageName = 214
+
+// expandPackages sets the command and arguments of every package server
+func expandPackages(config *Config) error {
+	for name, server := range config.McpServers {
+		if server.Package == "" {
+			if server.Version != "" || server.Runner != "" {
+				return fmt.Errorf("server %q: version and runner require package", name)
+			}
+			continue
+		}
+		if server.Command != "" || server.URL != "" {
+			return fmt.Errorf("server %q: set either 'package' or 'command'/'url', not both", name)
+		}
+		if server.Version != "" && !packageVersion.MatchString(server.Version) {
+			return fmt.Errorf("server %q: invalid version %q", name, server.Version)
+		}
+
+		spec := server.Package
+		if server.Version != "" {
+			spec += "@" + server.Version
+		}
+		switch server.Runner {
+		case "", RunnerNpx:
+			if !npmPackageName.MatchString(server.Package) || len(server.Package) > maxNpmPackageName {
+				return fmt.Errorf("server %q: invalid npm package name %q", name, server.Package)
+			}
+			server.Command = RunnerNpx
+			server.Args = append([]string{"-y", spec}, server.Args...)
+		case RunnerUvx:
+			if !pypiPackageName.MatchString(server.Package) {
+				return fmt.Errorf("server %q: invalid Python package name %q", name, server.Package)
+			}
+			server.Command = RunnerUvx
+			server.Args = append([]string{spec}, server.Args...)
+		default:
+			return fmt.Errorf("server %q: invalid runner %q (must be npx or uvx)", name, server.Runner)
+		}
+		config.McpServers[name] = server
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandPackages(t *testing.T) {
+	cases := []struct {
+		server  McpServerConfig
+		command string
+		args    []string
+		wantErr bool
+	}{
+		{server: McpServerConfig{Package: "@modelcontextprotocol/server-github", Version: "2025.4.8"},
+			command: "npx", args: []string{"-y", "@modelcontextprotocol/server-github@2025.4.8"}},
+		{server: McpServerConfig{Package: "mcp-server-git", Version: "0.6.2", Runner: "uvx", Args: []string{"--repository", "."}},
+			command: "uvx", args: []string{"mcp-server-git@0.6.2", "--repository", "."}},
+		{server: McpServerConfig{Package: "server", Version: "latest"}, command: "npx", args: []string{"-y", "server@latest"}},
+		{server: McpServerConfig{Package: "server", Version: "^1.2.0"}, command: "npx", args: []string{"-y", "server@^1.2.0"}},
+		{server: McpServerConfig{Package: "server"}, command: "npx", args: []string{"-y", "server"}},
+		{server: McpServerConfig{Package: "mcp_server.time", Runner: "uvx"}, command: "uvx", args: []string{"mcp_server.time"}},
+
+		// Options passed to the runner as packages
+		{server: McpServerConfig{Package: "--registry=https://evil.example"}, wantErr: true},
+		{server: McpServerConfig{Package: "-y"}, wantErr: true},
+		{server: McpServerConfig{Package: "--with=evil", Runner: "uvx"}, wantErr: true},
+		{server: McpServerConfig{Package: "server", Version: "--registry=x"}, wantErr: true},
+		// Not package names
+		{server: McpServerConfig{Package: "server x"}, wantErr: true},
+		{server: McpServerConfig{Package: ".hidden"}, wantErr: true},
+		{server: McpServerConfig{Package: "@scope/"}, wantErr: true},
+		{server: McpServerConfig{Package: "../local"}, wantErr: true},
+		{server: McpServerConfig{Package: "git+https://example.com/x.git"}, wantErr: true},
+		{server: McpServerConfig{Package: "@scope/server", Runner: "uvx"}, wantErr: true},
+		{server: McpServerConfig{Package: "server-", Runner: "uvx"}, wantErr: true},
+		{server: McpServerConfig{Package: "server", Version: "1.0 || 2.0"}, wantErr: true},
+		{server: McpServerConfig{Package: "server", Version: "1@2"}, wantErr: true},
+		// Other settings
+		{server: McpServerConfig{Package: "server", Runner: "pipx"}, wantErr: true},
+		{server: McpServerConfig{Package: "server", Command: "node"}, wantErr: true},
+		{server: McpServerConfig{Command: "node", Version: "1.0"}, wantErr: true},
+	}
+	for _, c := range cases {
+		cfg := &Config{McpServers: map[string]McpServerConfig{"s": c.server}}
+		err := expandPackages(cfg)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%+v: expected an error", c.server)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: %v", c.server, err)
+			continue
+		}
+		server := cfg.McpServers["s"]
+		if server.Command != c.command || !reflect.DeepEqual(server.Args, c.args) {
+			t.Errorf("%+v: got %s %q, want %s %q", c.server, server.Command, server.Args, c.command, c.args)
+		}
+	}
+}
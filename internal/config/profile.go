@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProfileEnv selects a profile when none is given explicitly
+const ProfileEnv = "CODEBRAID_PROFILE"
+
+// A config file can define named profiles, such as dev, staging and prod, each a
+// partial config merged over the rest of the file when it is selected:
+//
+//	{"mcpServers": {...}, "profiles": {"prod": {"mcpServers": {"scratch-db": null}, "sandbox": {"policy": {"maxMemoryMB": 256}}}}}
+//
+// A profile merges like an overlay file (see include.go), so it can change any
+// setting, add servers or remove them with null. It is applied after includes,
+// so a base file can define profiles too, and before CODEBRAID_SERVER_*
+// environment overrides. Profiles cannot include files or define profiles.
+
+// applyProfile merges the named profile of a merged config over it and removes the
+// profiles from it. No profile is applied when name is empty.
+func applyProfile(merged map[string]interface{}, name string) error {
+	value, ok := merged["profiles"]
+	delete(merged, "profiles")
+	if !ok || value == nil {
+		if name != "" {
+			return fmt.Errorf("profile %q not found: the config defines no profiles", name)
+		}
+		return nil
+	}
+	profiles, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("profiles must be an object of named profiles")
+	}
+	for profileName, profile := range profiles {
+		layer, ok := profile.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("profile %q must be an object", profileName)
+		}
+		for _, key := range []string{"include", "profiles"} {
+			if _, ok := layer[key]; ok {
+				return fmt.Errorf("profile %q: %s is not allowed in a profile", profileName, key)
+			}
+		}
+	}
+	if name == "" {
+		return nil
+	}
+
+	profile, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for profileName := range profiles {
+			names = append(names, profileName)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(names, ", "))
+	}
+	mergeLayer(merged, profile.(map[string]interface{}))
+	return nil
+}
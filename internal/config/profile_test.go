@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProfileOverridesSandboxSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "codebraid.json")
+	data := `{
+  "mcpServers": {"github": {"command": "gh-server"}},
+  "sandbox": {
+    "runtime": "deno",
+    "timeout": 60,
+    "policy": {"allowNet": ["api.github.com:443"], "allowEnv": ["GITHUB_*"], "maxMemoryMB": 512, "maxOutputKB": 2048}
+  },
+  "bundler": {"packages": ["zod@3.23.8"]},
+  "profiles": {
+    "prod": {
+      "sandbox": {"timeout": 10, "policy": {"allowNet": null, "maxMemoryMB": 128, "maxCpuSeconds": 5}},
+      "bundler": {"packages": null}
+    }
+  }
+}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadWithOptions(LoadOptions{ConfigPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GetSandboxTimeout() != 60 || cfg.GetSandboxPolicy().MaxMemoryMB != 512 || len(cfg.GetSandboxPolicy().AllowNet) != 1 {
+		t.Errorf("expected the base sandbox settings without a profile, got %+v", cfg.Sandbox)
+	}
+
+	cfg, err = LoadWithOptions(LoadOptions{ConfigPath: path, Profile: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := cfg.GetSandboxPolicy()
+	if cfg.GetSandboxRuntime() != "deno" || cfg.GetSandboxTimeout() != 10 {
+		t.Errorf("expected the runtime kept and the timeout overridden, got %q and %d", cfg.GetSandboxRuntime(), cfg.GetSandboxTimeout())
+	}
+	if policy.AllowNet != nil || policy.MaxMemoryMB != 128 || policy.MaxCPUSeconds != 5 {
+		t.Errorf("expected the profile's limits and no network, got %+v", policy)
+	}
+	if !reflect.DeepEqual(policy.AllowEnv, []string{"GITHUB_*"}) || cfg.GetSandboxMaxOutputBytes() != 2048<<10 {
+		t.Errorf("expected the settings the profile leaves alone kept, got %+v", policy)
+	}
+	if cfg.Bundler != nil && cfg.Bundler.Packages != nil {
+		t.Errorf("expected the profile to remove the package allowlist, got %+v", cfg.Bundler.Packages)
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import "reflect"
+
+// RestartRequired lists the settings that differ between old and new but are only
+// read at startup, so a reloaded config does not apply them until a restart.
+// MCP servers, limits, timeouts and the other settings read per session or per
+// execution are not listed.
+func RestartRequired(old, new *Config) []string {
+	var changed []string
+	check := func(name string, a, b any) {
+		if !reflect.DeepEqual(a, b) {
+			changed = append(changed, name)
+		}
+	}
+
+	check("server", old.Server, new.Server)
+	check("admin", old.Admin, new.Admin)
+	check("audit", old.Audit, new.Audit)
+	check("bundler", startupBundler(old), startupBundler(new))
+	check("sandbox.pool", old.GetSandboxPool(), new.GetSandboxPool())
+	check("sandbox.hooks", old.GetSandboxHooks(), new.GetSandboxHooks())
+
+	oldSessions, newSessions := old.GetSessions(), new.GetSessions()
+	check("sessions.warmSessions", oldSessions.WarmSessions, newSessions.WarmSessions)
+	check("sessions.readOnly", oldSessions.ReadOnly, newSessions.ReadOnly)
+	check("sessions.artifactRetention", oldSessions.ArtifactRetention, newSessions.ArtifactRetention)
+	check("sessions.orphanAge", oldSessions.OrphanAge, newSessions.OrphanAge)
+	check("sessions.disableOrphanCleanup", oldSessions.DisableOrphanCleanup, newSessions.DisableOrphanCleanup)
+	check("sessions.disableLibSharing", oldSessions.DisableLibSharing, newSessions.DisableLibSharing)
+	return changed
+}
+
+// startupBundler returns the bundler settings read at startup; the toolchain and
+// type checking are chosen per execution
+func startupBundler(c *Config) BundlerConfig {
+	var bundler BundlerConfig
+	if c.Bundler != nil {
+		bundler = *c.Bundler
+	}
+	bundler.Toolchain = ""
+	bundler.TypeCheck = false
+	return bundler
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The config path may be an https URL, so a fleet of instances can share one
+// centrally managed config. Fetched configs are cached with their ETag under the
+// user cache directory: later loads revalidate the cached copy, and use it when
+// the server cannot be reached, so an instance still starts during an outage.
+// RemoteAuthEnv sets the Authorization header sent with requests to the config's
+// host, and only to it, redirects included. Plain http is accepted only for
+// loopback hosts, for redirect targets too.
+
+// RemoteAuthEnv holds the Authorization header value sent when fetching a remote config
+const RemoteAuthEnv = "CODEBRAID_CONFIG_AUTH"
+
+// maxRemoteConfigBytes bounds the size of a fetched config file
+const maxRemoteConfigBytes = 10 << 20
+
+// remoteClient fetches remote config files
+var remoteClient = &http.Client{Timeout: 30 * time.Second, CheckRedirect: checkRemoteRedirect}
+
+// checkRemoteRedirect refuses redirects to plain http, except on loopback, and
+// drops the Authorization header on redirects to another host
+func checkRemoteRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after 10 redirects")
+	}
+	if req.URL.Scheme != "https" && !(req.URL.Scheme == "http" && isLoopback(req.URL.Hostname())) {
+		return fmt.Errorf("refusing redirect to %s: remote configs must use https", req.URL.Redacted())
+	}
+	if req.URL.Host != via[0].URL.Host {
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
+
+// isRemote reports whether a config path is a URL
+func isRemote(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// remoteSource fetches the remote config files of one load
+type remoteSource struct {
+	auth     string // Authorization header value
+	authHost string // The only host auth is sent to: the host of the config
+}
+
+// newRemoteSource returns how to fetch the remote files of the config at path
+func newRemoteSource(path, auth string) remoteSource {
+	source := remoteSource{auth: auth}
+	if u, err := url.Parse(path); err == nil && isRemote(path) {
+		source.authHost = u.Host
+	}
+	return source
+}
+
+// fetch returns the contents of a remote config file, revalidating the cached copy
+func (r remoteSource) fetch(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "http" && !isLoopback(u.Hostname()) {
+		return nil, fmt.Errorf("remote configs must use https")
+	}
+
+	cachePath := remoteCachePath(rawURL)
+	cached, cacheErr := os.ReadFile(cachePath)
+	etag, _ := os.ReadFile(cachePath + ".etag")
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if cacheErr == nil && len(etag) > 0 {
+		req.Header.Set("If-None-Match", string(etag))
+	}
+	if r.auth != "" && u.Host == r.authHost {
+		req.Header.Set("Authorization", r.auth)
+	}
+	resp, err := remoteClient.Do(req)
+	if err != nil {
+		if cacheErr == nil {
+			log.Printf("Warning: failed to fetch config %s, using the cached copy: %v", rawURL, err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cacheErr == nil:
+		return cached, nil
+	case resp.StatusCode != http.StatusOK && cacheErr == nil:
+		log.Printf("Warning: failed to fetch config %s, using the cached copy: server returned %s", rawURL, resp.Status)
+		return cached, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRemoteConfigBytes {
+		return nil, fmt.Errorf("config is larger than %d MB", maxRemoteConfigBytes>>20)
+	}
+	storeRemoteCache(cachePath, data, resp.Header.Get("ETag"))
+	return data, nil
+}
+
+// remoteCachePath returns where the fetched copy of a remote config is cached
+func remoteCachePath(rawURL string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, "codebraid", "remote-config", hex.EncodeToString(sum[:16])+".json")
+}
+
+// storeRemoteCache caches a fetched config and its ETag. The copy is private to
+// the user, as configs may hold credentials. Failures only cost the cache.
+func storeRemoteCache(cachePath string, data []byte, etag string) {
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err != nil {
+		return
+	}
+	if err := os.WriteFile(cachePath, data, 0600); err != nil {
+		return
+	}
+	if etag != "" {
+		os.WriteFile(cachePath+".etag", []byte(etag), 0600)
+	} else {
+		os.Remove(cachePath + ".etag")
+	}
+}
+
+// isLoopback reports whether host is localhost or a loopback address
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRemoteFetchDropsAuthOnCrossHostRedirect(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var leaked string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Get("Authorization")
+		w.Write([]byte(`{}`))
+	}))
+	defer other.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+"/config.json", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	source := newRemoteSource(origin.URL+"/config.json", "Bearer secret")
+	if _, err := source.fetch(origin.URL + "/config.json"); err != nil {
+		t.Fatal(err)
+	}
+	if leaked != "" {
+		t.Errorf("expected no Authorization header on another host, got %q", leaked)
+	}
+}
+
+func TestRemoteFetchRefusesRedirectToHTTP(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/config.json", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	if _, err := newRemoteSource(origin.URL, "").fetch(origin.URL); err == nil {
+		t.Error("expected a redirect to plain http to be refused")
+	}
+}
+
+func TestRemoteFetchFallsBackToCacheOnErrorStatus(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"mcpServers": {}}`))
+	}))
+	defer srv.Close()
+
+	source := newRemoteSource(srv.URL, "")
+	if _, err := source.fetch(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	status = http.StatusBadGateway
+	data, err := source.fetch(srv.URL)
+	if err != nil {
+		t.Fatalf("expected the cached copy, got %v", err)
+	}
+	if string(data) != `{"mcpServers": {}}` {
+		t.Errorf("unexpected cached copy %q", data)
+	}
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/yousuf/codebraid-mcp/internal/fuzzy"
+)
+
+// maxSchemaErrors bounds the schema errors reported for one config file
+const maxSchemaErrors = 20
+
+// checkLayer checks one config file against the schema of Config before it is
+// merged, so that misspelled or misplaced settings fail the load instead of being
+// silently ignored. Errors give the JSON path of the setting, what was expected
+// there and, for unknown fields, the field that was probably meant.
+func checkLayer(layer map[string]interface{}, file string) error {
+	var errs []string
+	configType := reflect.TypeOf(Config{})
+	for key, value := range layer {
+		switch key {
+		case "$schema": // Editors use it to find a JSON schema
+		case "profiles":
+			profiles, ok := value.(map[string]interface{})
+			if value != nil && !ok {
+				errs = append(errs, fmt.Sprintf("profiles: expected an object of named profiles, got %s", jsonType(value)))
+				continue
+			}
+			for name, profile := range profiles {
+				checkValue(profile, configType, "profiles."+name, &errs)
+			}
+		default:
+			checkField(key, value, configType, "", &errs)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Strings(errs)
+	return &SchemaError{File: file, Problems: errs}
+}
+
+// SchemaError lists the settings of a config file that do not match the schema
+type SchemaError struct {
+	File     string   // The config file
+	Problems []string // "path: problem", sorted by path
+}
+
+func (e *SchemaError) Error() string {
+	problems := e.Problems
+	if len(problems) > maxSchemaErrors {
+		problems = append(problems[:maxSchemaErrors:maxSchemaErrors], fmt.Sprintf("and %d more errors", len(problems)-maxSchemaErrors))
+	}
+	return fmt.Sprintf("invalid config %q:\n  %s", e.File, strings.Join(problems, "\n  "))
+}
+
+// checkValue checks a JSON value against the Go type it decodes into. Null is
+// accepted everywhere, as it removes a setting of an included file, and so is a
+// secret reference where a string is expected.
+func checkValue(value interface{}, t reflect.Type, path string, errs *[]string) {
+	if value == nil {
+		return
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	mismatch := func(expected string) {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", path, expected, jsonType(value)))
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if object, ok := value.(map[string]interface{}); ok {
+			if _, isRef := object["$secret"]; isRef {
+				return
+			}
+		}
+		if _, ok := value.(string); !ok {
+			mismatch("a string")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			mismatch("true or false")
+		}
+	case reflect.Int, reflect.Int64:
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			mismatch("an integer")
+		}
+	case reflect.Float64:
+		if _, ok := value.(float64); !ok {
+			mismatch("a number")
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			mismatch("a list")
+			return
+		}
+		for i, item := range items {
+			checkValue(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch("an object")
+			return
+		}
+		for key, item := range object {
+			checkValue(item, t.Elem(), path+"."+key, errs)
+		}
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch("an object")
+			return
+		}
+		for key, item := range object {
+			checkField(key, item, t, path, errs)
+		}
+	}
+}
+
+// checkField checks the field key of an object decoding into the struct t
+func checkField(key string, value interface{}, t reflect.Type, parent string, errs *[]string) {
+	path := key
+	if parent != "" {
+		path = parent + "." + key
+	}
+	fields := jsonFields(t)
+	field, ok := fields[key]
+	if !ok {
+		msg := fmt.Sprintf("%s: unknown field %q", path, key)
+		if suggestion := closestField(key, fields); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+		}
+		*errs = append(*errs, msg)
+		return
+	}
+	checkValue(value, field.Type, path, errs)
+}
+
+// jsonFields returns the fields of a struct by their JSON names
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// closestField returns the field name key was most likely meant to be, or "" when
+// none is close
+func closestField(key string, fields map[string]reflect.StructField) string {
+	best, bestDistance := "", 0
+	for name := range fields {
+		distance := fuzzy.Distance(strings.ToLower(key), strings.ToLower(name))
+		if distance > max(1, len(name)/3) {
+			continue
+		}
+		if best == "" || distance < bestDistance || (distance == bestDistance && name < best) {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// jsonType names the JSON type of a decoded value for errors
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return "null"
+}
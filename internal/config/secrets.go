@@ -0,0 +1,225 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Any string in a config can be a reference to a secret kept elsewhere, resolved
+// when the config is loaded so the secret never has to be written into it:
+//
+//	"env": {"GITHUB_TOKEN": {"$secret": "vault://secret/data/github#token"}}
+//
+// The scheme of the reference picks the provider that resolves it:
+//
+//	env://NAME                  the host environment variable NAME
+//	file://PATH                 the contents of a file, without trailing newlines
+//	keychain://SERVICE/ACCOUNT  the OS keychain (macOS Keychain, or libsecret's secret-tool)
+//	vault://PATH#FIELD          a HashiCorp Vault secret read with VAULT_ADDR and VAULT_TOKEN
+//	aws-sm://ID#FIELD           an AWS Secrets Manager secret read with the aws CLI
+//
+// #FIELD picks one field of a secret holding several; it may be left out when
+// the secret holds a single value. Other schemes can be added with
+// RegisterSecretProvider. A reference that cannot be resolved fails the load.
+
+// SecretProvider resolves the references of one scheme; ref is the reference
+// without its "scheme://"
+type SecretProvider func(ctx context.Context, ref string) (string, error)
+
+// secretTimeout bounds resolving one reference
+const secretTimeout = 30 * time.Second
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{
+		"env":      envSecret,
+		"file":     fileSecret,
+		"keychain": keychainSecret,
+		"vault":    vaultSecret,
+		"aws-sm":   awsSecret,
+	}
+)
+
+// RegisterSecretProvider makes provider resolve the references with scheme,
+// replacing any provider of that scheme. Should be called before loading configs.
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[scheme] = provider
+}
+
+// secretPlaceholder stands in for the secret at index i until fillSecrets. It has
+// no '$', so expanding ${VAR} in server settings leaves it, and the secret, alone.
+func secretPlaceholder(i int) string {
+	return "\x00secret" + strconv.Itoa(i) + "\x00"
+}
+
+// resolveSecrets replaces the secret references and encrypted values in a merged
+// config with their values. Secrets in server settings are replaced with placeholders instead,
+// and their values returned for fillSecrets.
+func resolveSecrets(merged map[string]interface{}) ([]string, error) {
+	r := &secretResolver{resolved: make(map[string]string)}
+	for key, value := range merged {
+		resolved, err := r.walk(value, key, key == "mcpServers")
+		if err != nil {
+			return nil, err
+		}
+		merged[key] = resolved
+	}
+	return r.placeholders, nil
+}
+
+// secretResolver resolves the references of one config, each only once, and
+// decrypts its encrypted values
+type secretResolver struct {
+	resolved     map[string]string
+	placeholders []string
+	key          []byte // Of encrypted values, loaded when the first one is found
+}
+
+// walk returns value with the secret references in it resolved and encrypted
+// values decrypted; path locates it in the config for errors
+func (r *secretResolver) walk(value interface{}, path string, placeholder bool) (interface{}, error) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if ref, isRef := value["$secret"]; isRef {
+			secret, err := r.resolve(ref, len(value))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			return r.place(secret, placeholder), nil
+		}
+		for key, item := range value {
+			resolved, err := r.walk(item, path+"."+key, placeholder)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = resolved
+		}
+	case encryptedValue:
+		if r.key == nil {
+			key, err := LoadKey()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			r.key = key
+		}
+		secret, err := decryptValue(r.key, value.value, value.path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return r.place(secret, placeholder), nil
+	case []interface{}:
+		for i, item := range value {
+			resolved, err := r.walk(item, fmt.Sprintf("%s[%d]", path, i), placeholder)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = resolved
+		}
+	}
+	return value, nil
+}
+
+// place returns a secret, or its placeholder when it is to be filled in later
+func (r *secretResolver) place(secret string, placeholder bool) string {
+	if !placeholder {
+		return secret
+	}
+	r.placeholders = append(r.placeholders, secret)
+	return secretPlaceholder(len(r.placeholders) - 1)
+}
+
+// resolve returns the value of a reference; keys is how many keys the object
+// holding it has
+func (r *secretResolver) resolve(ref interface{}, keys int) (string, error) {
+	reference, ok := ref.(string)
+	if !ok || keys != 1 {
+		return "", fmt.Errorf(`a secret reference must be {"$secret": "scheme://..."}`)
+	}
+	if secret, ok := r.resolved[reference]; ok {
+		return secret, nil
+	}
+
+	scheme, rest, ok := strings.Cut(reference, "://")
+	if !ok {
+		return "", fmt.Errorf("secret reference %q has no scheme", reference)
+	}
+	secretProvidersMu.RLock()
+	provider, ok := secretProviders[scheme]
+	schemes := make([]string, 0, len(secretProviders))
+	for name := range secretProviders {
+		schemes = append(schemes, name)
+	}
+	secretProvidersMu.RUnlock()
+	if !ok {
+		sort.Strings(schemes)
+		return "", fmt.Errorf("secret reference %q: unknown scheme %q (known: %s)", reference, scheme, strings.Join(schemes, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	secret, err := provider(ctx, rest)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %q: %w", reference, err)
+	}
+	r.resolved[reference] = secret
+	return secret, nil
+}
+
+// fillSecrets puts the secrets back in place of their placeholders in the
+// server settings
+func fillSecrets(config *Config, secrets []string) {
+	if len(secrets) == 0 {
+		return
+	}
+	pairs := make([]string, 0, 2*len(secrets))
+	for i, secret := range secrets {
+		pairs = append(pairs, secretPlaceholder(i), secret)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	for name, server := range config.McpServers {
+		server.Command = replacer.Replace(server.Command)
+		server.URL = replacer.Replace(server.URL)
+		server.Cwd = replacer.Replace(server.Cwd)
+		for i, arg := range server.Args {
+			server.Args[i] = replacer.Replace(arg)
+		}
+		for key, val := range server.Env {
+			server.Env[key] = replacer.Replace(val)
+		}
+		for key, val := range server.Headers {
+			server.Headers[key] = replacer.Replace(val)
+		}
+		config.McpServers[name] = server
+	}
+}
+
+// envSecret resolves env://NAME
+func envSecret(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// fileSecret resolves file://PATH, where PATH may start with ~/
+func fileSecret(_ context.Context, path string) (string, error) {
+	path, err := ExpandHome(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// keychainSecret resolves keychain://SERVICE/ACCOUNT from the macOS Keychain or,
+// elsewhere, the Secret Service through libsecret's secret-tool
+func keychainSecret(ctx context.Context, ref string) (string, error) {
+	service, account, _ := strings.Cut(ref, "/")
+	if service == "" {
+		return "", fmt.Errorf("keychain references need a service: keychain://SERVICE/ACCOUNT")
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		args := []string{"find-generic-password", "-s", service, "-w"}
+		if account != "" {
+			args = append(args, "-a", account)
+		}
+		cmd = exec.CommandContext(ctx, "security", args...)
+	case "windows":
+		return "", fmt.Errorf("the keychain provider is not supported on Windows")
+	default:
+		args := []string{"lookup", "service", service}
+		if account != "" {
+			args = append(args, "account", account)
+		}
+		cmd = exec.CommandContext(ctx, "secret-tool", args...)
+	}
+	return runSecretCommand(cmd)
+}
+
+// vaultSecret resolves vault://PATH#FIELD by reading PATH from the Vault HTTP API
+// at VAULT_ADDR with VAULT_TOKEN, or the token left by "vault login". KV version 2
+// paths include data/, e.g. vault://secret/data/github#token.
+func vaultSecret(ctx context.Context, ref string) (string, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	addr := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(homeDir, ".vault-token")); err == nil {
+				token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("VAULT_TOKEN is not set and there is no ~/.vault-token")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	fields := secret.Data
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		if _, ok := fields["metadata"]; ok {
+			fields = nested // KV version 2
+		}
+	}
+	return secretField(fields, field)
+}
+
+// awsSecret resolves aws-sm://ID#FIELD with the aws CLI, which finds credentials
+// and the region as it always does (environment, profiles, instance roles)
+func awsSecret(ctx context.Context, ref string) (string, error) {
+	id, field, _ := strings.Cut(ref, "#")
+	if id == "" {
+		return "", fmt.Errorf("aws-sm references need a secret ID: aws-sm://ID#FIELD")
+	}
+	value, err := runSecretCommand(exec.CommandContext(ctx, "aws", "secretsmanager", "get-secret-value",
+		"--secret-id", id, "--query", "SecretString", "--output", "text"))
+	if err != nil || field == "" {
+		return value, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so it has no field %q", field)
+	}
+	return secretField(fields, field)
+}
+
+// secretField returns one field of a secret holding several, or its only field
+// when field is empty
+func secretField(fields map[string]interface{}, field string) (string, error) {
+	if field == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secret has %d fields; pick one with #FIELD", len(fields))
+		}
+		for name := range fields {
+			field = name
+		}
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// runSecretCommand runs a command printing a secret and returns its output
+// without the trailing newline
+func runSecretCommand(cmd *exec.Cmd) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", filepath.Base(cmd.Path), err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", filepath.Base(cmd.Path), err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
@@ -0,0 +1,29 @@
+package fuzzy
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"issue", "issue", 0},
+		{"", "abc", 3},
+		{"issue", "isue", 1},   // Deletion
+		{"issue", "isssue", 1}, // Insertion
+		{"issue", "iszue", 1},  // Substitution
+		{"issue", "isuse", 1},  // Swap
+		{"command", "comamnd", 1},
+		{"timeout", "tiemuot", 2},
+		{"café", "cafe", 1},
+		{"kitten", "sitting", 3},
+	} {
+		if got := Distance(tc.a, tc.b); got != tc.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+		if got := Distance(tc.b, tc.a); got != tc.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package limiter bounds how many operations of a kind run at once.
+// Callers over the limit wait in a bounded queue for a bounded time, so a burst
+// of requests degrades into fast, explicit "busy" errors instead of piling up
+// processes on the host.
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Limiter is a counting semaphore with a bounded wait queue.
+// A nil *Limiter imposes no limit.
+type Limiter struct {
+	name    string
+	slots   chan struct{}
+	queue   chan struct{}
+	timeout time.Duration
+}
+
+// New creates a limiter allowing concurrency simultaneous holders and at most
+// queue waiters, each waiting up to timeout (zero waits until ctx is done).
+// It returns nil, which imposes no limit, when concurrency is not positive.
+func New(name string, concurrency, queue int, timeout time.Duration) *Limiter {
+	if concurrency <= 0 {
+		return nil
+	}
+	return &Limiter{
+		name:    name,
+		slots:   make(chan struct{}, concurrency),
+		queue:   make(chan struct{}, max(queue, 0)),
+		timeout: timeout,
+	}
+}
+
+// BusyError reports an operation rejected because its limiter was saturated
+type BusyError struct {
+	Limiter string // Name of the limiter, e.g. "executions"
+	Reason  string
+}
+
+// Error implements the error interface
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("too many concurrent %s: %s", e.Limiter, e.Reason)
+}
+
+// Acquire takes a slot, waiting in the queue if none is free.
+// It fails with a BusyError when the queue is full or the wait times out,
+// and with ctx's error when ctx is done first. Call release exactly once.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	// Fast path: a free slot needs no queue position
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	default:
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return nil, &BusyError{Limiter: l.name, Reason: fmt.Sprintf("%d running and the wait queue is full", cap(l.slots))}
+	}
+	defer func() { <-l.queue }()
+
+	var expired <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	case <-expired:
+		return nil, &BusyError{Limiter: l.name, Reason: fmt.Sprintf("no slot freed up within %s", l.timeout)}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// release frees a slot taken by Acquire
+func (l *Limiter) release() {
+	<-l.slots
+}
@@ -0,0 +1,83 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNilLimiterDoesNotLimit(t *testing.T) {
+	l := New("executions", 0, 0, 0)
+	for i := 0; i < 3; i++ {
+		if _, err := l.Acquire(context.Background()); err != nil {
+			t.Fatalf("expected no limit, got %v", err)
+		}
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	l := New("executions", 1, 0, time.Second)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	var busy *BusyError
+	if _, err := l.Acquire(context.Background()); !errors.As(err, &busy) {
+		t.Fatalf("expected a busy error with no queue, got %v", err)
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	l := New("bundles", 1, 1, 20*time.Millisecond)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	var busy *BusyError
+	if _, err := l.Acquire(context.Background()); !errors.As(err, &busy) || busy.Limiter != "bundles" {
+		t.Fatalf("expected a queue timeout, got %v", err)
+	}
+}
+
+func TestQueuedWaiterGetsReleasedSlot(t *testing.T) {
+	l := New("executions", 1, 1, time.Second)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := l.Acquire(context.Background())
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	release()
+	if err := <-acquired; err != nil {
+		t.Fatalf("expected the waiter to get the slot, got %v", err)
+	}
+}
+
+func TestAcquireCancelled(t *testing.T) {
+	l := New("executions", 1, 1, 0)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
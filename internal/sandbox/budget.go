@@ -0,0 +1,106 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// toolBudget bounds the downstream tool calls of one run: how many it makes and
+// how long it spends waiting for them in total. The first call over budget
+// stops the run.
+type toolBudget struct {
+	maxCalls int
+	maxTime  time.Duration
+	stop     context.CancelFunc
+
+	mu       sync.Mutex
+	calls    int
+	used     time.Duration
+	exceeded *LimitError
+}
+
+// toolBudgetExecutor enforces Limits.MaxToolCalls and Limits.MaxToolTime
+type toolBudgetExecutor struct {
+	Executor
+	maxCalls int
+	maxTime  time.Duration
+}
+
+// withToolBudget wraps an executor so runs exceeding their tool call budget
+// fail with a LimitError
+func withToolBudget(executor Executor, maxCalls int, maxTime time.Duration) Executor {
+	return &toolBudgetExecutor{Executor: executor, maxCalls: maxCalls, maxTime: maxTime}
+}
+
+// ExecuteCode implements Executor
+func (e *toolBudgetExecutor) ExecuteCode(ctx context.Context, run Run) (Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	budget := &toolBudget{maxCalls: e.maxCalls, maxTime: e.maxTime, stop: cancel}
+	ctx = context.WithValue(ctx, toolBudgetKey{}, budget)
+
+	result, err := e.Executor.ExecuteCode(ctx, run)
+	if exceeded := budget.err(); exceeded != nil {
+		return Result{}, exceeded
+	}
+	return result, err
+}
+
+// toolBudgetKey carries the run's tool budget in its context
+type toolBudgetKey struct{}
+
+// startToolCall takes one call from the budget of the run ctx belongs to.
+// It returns the context the call runs with, bounded by the tool time left,
+// and an error once the budget is used up.
+func startToolCall(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	budget, ok := ctx.Value(toolBudgetKey{}).(*toolBudget)
+	if !ok {
+		return ctx, func() {}, nil
+	}
+
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	if budget.exceeded != nil {
+		return nil, nil, budget.exceeded
+	}
+	if budget.maxCalls > 0 && budget.calls >= budget.maxCalls {
+		return nil, nil, budget.exceed(fmt.Sprintf("tool call budget exceeded: more than %d calls", budget.maxCalls))
+	}
+	budget.calls++
+	if budget.maxTime <= 0 {
+		return ctx, func() {}, nil
+	}
+	callCtx, cancel := context.WithTimeout(ctx, budget.maxTime-budget.used)
+	return callCtx, cancel, nil
+}
+
+// finishToolCall charges a call's duration to the budget of the run ctx belongs to
+func finishToolCall(ctx context.Context, duration time.Duration) {
+	budget, ok := ctx.Value(toolBudgetKey{}).(*toolBudget)
+	if !ok || budget.maxTime <= 0 {
+		return
+	}
+
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	budget.used += duration
+	if budget.used >= budget.maxTime && budget.exceeded == nil {
+		budget.exceed(fmt.Sprintf("tool call budget exceeded: %s spent waiting for tools", budget.maxTime))
+	}
+}
+
+// exceed records why the budget ran out and stops the run. Called with mu held.
+func (b *toolBudget) exceed(message string) *LimitError {
+	b.exceeded = &LimitError{Type: LimitToolBudget, Message: message}
+	b.stop()
+	return b.exceeded
+}
+
+// err returns the budget violation, if any
+func (b *toolBudget) err() *LimitError {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exceeded
+}
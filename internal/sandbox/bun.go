@@ -0,0 +1,45 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+)
+
+// NewBunSandbox creates a sandbox that runs code with Bun.
+// Auto-install is disabled so scripts cannot pull packages from the registry.
+// Bun has no permission system: its HTTP clients go through the egress proxy, which
+// denies everything since allowNet can't be enforced for other connections, and
+// the filesystem is only isolated by running in a scratch directory with HOME redirected,
+// unless an OS sandbox is configured. Without one it only runs when opts.Unsandboxed
+// says so.
+func NewBunSandbox(ctx context.Context, opts Options) (*ProcessSandbox, error) {
+	if opts.OSSandbox == OSSandboxNone && !opts.Unsandboxed {
+		return nil, fmt.Errorf("bun is not sandboxed without an OS sandbox (set sandbox.osSandbox, or sandbox.allowUnsandboxed)")
+	}
+	if len(opts.Policy.AllowNet) > 0 {
+		return nil, fmt.Errorf("bun can't enforce allowNet: only its HTTP clients use the egress proxy")
+	}
+	egress, err := ParseEgressPolicy(opts.Policy.AllowNet)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapper, err := newOSSandbox(opts.OSSandbox, opts.Policy, opts.LibDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProcessSandbox(ctx, ProcessOptions{
+		Name:        RuntimeBun,
+		Command:     "bun",
+		Args:        []string{"run", "--no-install"},
+		Limits:      opts.Limits,
+		Env:         sandboxEnv(opts.Policy.AllowEnv, opts.Env),
+		Persistent:  opts.Persistent,
+		Pool:        opts.Pool,
+		SessionID:   opts.SessionID,
+		Egress:      egress,
+		ScratchRoot: opts.Policy.ScratchDir,
+		Wrap:        wrapper.wrapFunc(),
+	}, opts.ClientHub)
+}
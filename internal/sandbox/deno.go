@@ -0,0 +1,94 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// NewDenoSandbox creates a sandbox that runs code with Deno.
+// Deno denies all I/O by default, so the policy is translated into explicit
+// --allow-* flags and anything not listed is blocked by the runtime itself.
+func NewDenoSandbox(ctx context.Context, opts Options) (*ProcessSandbox, error) {
+	egress, err := ParseEgressPolicy(opts.Policy.AllowNet)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range egress.Hosts() {
+		if strings.Contains(host, "*") {
+			return nil, fmt.Errorf("deno --allow-net does not support wildcards such as %q in allowNet", host)
+		}
+	}
+	if egress.HasCIDRs() {
+		return nil, fmt.Errorf("deno --allow-net does not support CIDR ranges in allowNet")
+	}
+
+	wrapper, err := newOSSandbox(opts.OSSandbox, opts.Policy, opts.LibDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProcessSandbox(ctx, ProcessOptions{
+		Name:        RuntimeDeno,
+		Command:     "deno",
+		Args:        denoArgs(opts.Policy, egress, opts.Limits, sortedKeys(opts.Env)),
+		Limits:      opts.Limits,
+		Env:         sandboxEnv(opts.Policy.AllowEnv, opts.Env),
+		Persistent:  opts.Persistent,
+		Pool:        opts.Pool,
+		SessionID:   opts.SessionID,
+		ScratchRoot: opts.Policy.ScratchDir,
+		PermissionArgs: func(scratchDir string) []string {
+			return denoFSArgs(opts.Policy, scratchDir, opts.LibDir)
+		},
+		Wrap: wrapper.wrapFunc(),
+	}, opts.ClientHub)
+}
+
+// denoArgs builds the deno command line for a sandbox policy.
+// injected names the variables set by the host, which code may read as well.
+func denoArgs(policy config.SandboxPolicy, egress *EgressPolicy, limits Limits, injected []string) []string {
+	args := []string{
+		"run",
+		"--quiet",
+		"--no-prompt", // Fail on missing permissions instead of asking on a TTY
+		"--no-config", // Ignore any deno.json in the working directory
+		"--no-lock",
+	}
+
+	args = appendPermission(args, "--allow-net", egress.Hosts())
+	args = appendPermission(args, "--allow-env", append(append([]string{}, policy.AllowEnv...), injected...))
+
+	// Cap the V8 heap so allocation failures surface as a clean out-of-memory abort
+	if limits.MemoryMB > 0 {
+		args = append(args, fmt.Sprintf("--v8-flags=--max-old-space-size=%d", limits.MemoryMB))
+	}
+
+	return args
+}
+
+// denoFSArgs grants read/write on the execution's scratch directory, read-only
+// access to the session library, and whatever extra paths the policy lists
+func denoFSArgs(policy config.SandboxPolicy, scratchDir, libDir string) []string {
+	read := []string{scratchDir}
+	if libDir != "" {
+		read = append(read, libDir)
+	}
+	read = append(read, policy.AllowRead...)
+	write := append([]string{scratchDir}, policy.AllowWrite...)
+
+	var args []string
+	args = appendPermission(args, "--allow-read", read)
+	args = appendPermission(args, "--allow-write", write)
+	return args
+}
+
+// appendPermission adds "flag=a,b" when values is non-empty
+func appendPermission(args []string, flag string, values []string) []string {
+	if len(values) == 0 {
+		return args
+	}
+	return append(args, flag+"="+strings.Join(values, ","))
+}
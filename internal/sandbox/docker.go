@@ -0,0 +1,116 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// NewDockerSandbox creates a sandbox that runs each execution in a throwaway container.
+// Only the runner script, the execution's scratch directory and (read-only) the session
+// library are mounted; memory, CPU and network settings come from the sandbox config.
+func NewDockerSandbox(ctx context.Context, opts Options) (*ProcessSandbox, error) {
+	docker := opts.Docker
+	if len(opts.Policy.AllowNet) > 0 {
+		return nil, fmt.Errorf("docker can't enforce allowNet; container network access is set by docker.network")
+	}
+
+	runner, err := ensureRunner()
+	if err != nil {
+		return nil, err
+	}
+
+	// The client itself needs the host environment; the container only gets the sandbox env
+	env := sandboxEnv(opts.Policy.AllowEnv, opts.Env)
+
+	return NewProcessSandbox(ctx, ProcessOptions{
+		Name:        RuntimeDocker,
+		Command:     docker.Binary,
+		Args:        []string{"run", "--rm", "--interactive"},
+		Limits:      opts.Limits,
+		Env:         env,
+		HostEnv:     true,
+		Persistent:  opts.Persistent,
+		Pool:        opts.Pool,
+		SessionID:   opts.SessionID,
+		SkipRlimits: true,
+		ScratchRoot: opts.Policy.ScratchDir,
+		PermissionArgs: func(scratchDir string) []string {
+			return dockerRunArgs(docker, opts.Limits, runner, scratchDir, opts.LibDir, envNames(env))
+		},
+		Terminate: func(scratchDir string) {
+			removeContainer(docker.Binary, containerName(scratchDir))
+		},
+	}, opts.ClientHub)
+}
+
+// dockerRunArgs builds the container options, image and runtime command.
+// The runner and scratch directory are mounted at their host paths so the
+// runner path appended by ProcessSandbox resolves inside the container too.
+// Variables in env are passed by name so their values stay off the command line.
+func dockerRunArgs(docker config.DockerConfig, limits Limits, runner, scratchDir, libDir string, env []string) []string {
+	args := []string{
+		"--name", containerName(scratchDir),
+		"--network", docker.Network,
+		"--read-only",
+		"--tmpfs", "/tmp",
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--pids-limit", strconv.Itoa(docker.PidsLimit),
+		"--volume", runner + ":" + runner + ":ro",
+		"--volume", scratchDir + ":" + scratchDir,
+		"--workdir", scratchDir,
+		"--env", "HOME=" + scratchDir,
+	}
+
+	// Run as the host user so the scratch directory (mode 0700) stays writable
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 && gid >= 0 {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", uid, gid))
+	}
+
+	if libDir != "" {
+		args = append(args, "--volume", libDir+":"+libDir+":ro")
+	}
+
+	for _, name := range env {
+		args = append(args, "--env", name)
+	}
+
+	if limits.MemoryMB > 0 {
+		// Equal memory and swap limits disable swap for the container
+		memory := fmt.Sprintf("%dm", limits.MemoryMB)
+		args = append(args, "--memory", memory, "--memory-swap", memory)
+	}
+	if limits.CPUTime > 0 {
+		seconds := int64((limits.CPUTime + time.Second - 1) / time.Second)
+		args = append(args, "--ulimit", fmt.Sprintf("cpu=%d:%d", seconds, seconds+1))
+	}
+	if docker.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(docker.CPUs, 'f', -1, 64))
+	}
+
+	args = append(args, docker.Image)
+	return append(args, docker.Command...)
+}
+
+// containerName derives a unique container name from the execution's scratch directory
+func containerName(scratchDir string) string {
+	return filepath.Base(scratchDir)
+}
+
+// removeContainer force-removes a container left running by a killed or timed-out client
+func removeContainer(binary, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if output, err := exec.CommandContext(ctx, binary, "rm", "--force", name).CombinedOutput(); err != nil {
+		log.Printf("Failed to remove container %s: %v (%s)", name, err, output)
+	}
+}
@@ -0,0 +1,45 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/client"
+	"github.com/yousuf/codebraid-mcp/internal/codegen"
+)
+
+// dryRunKey marks the context of a dry run, whose tool calls are not sent
+type dryRunKey struct{}
+
+// isDryRun reports whether ctx belongs to a dry run
+func isDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// stubToolResult answers a dry-run tool call without calling the server.
+// Tools with an output schema return a value derived from it as structured
+// content; others return a text block saying the call was skipped.
+// Unknown servers and tools fail as they would in a real run.
+func stubToolResult(clientHub *client.McpClientHub, serverName, toolName string) (*mcp.CallToolResult, error) {
+	tools, ok := clientHub.ServerTools(serverName)
+	if !ok {
+		return nil, fmt.Errorf("server %q not found", serverName)
+	}
+	for _, tool := range tools {
+		if tool.Name != toolName {
+			continue
+		}
+		result := &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("dry run: %s.%s was not called", serverName, toolName)},
+			},
+		}
+		if schema, ok := tool.OutputSchema.(map[string]interface{}); ok && len(schema) > 0 {
+			result.StructuredContent = codegen.StubValue(schema)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("tool %q not found on server %q", toolName, serverName)
+}
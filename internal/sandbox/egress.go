@@ -0,0 +1,295 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errEgressDenied is returned when a destination is not covered by the policy
+var errEgressDenied = errors.New("network access denied by sandbox policy")
+
+// egressRule is one parsed allowNet entry
+type egressRule struct {
+	host    string     // Hostname, IP, or "*.example.com" wildcard
+	port    string     // Empty matches any port
+	network *net.IPNet // Set for CIDR entries
+}
+
+// EgressPolicy decides which network destinations sandboxed code may reach.
+// Entries are hosts ("api.github.com"), wildcards ("*.github.com"), either
+// optionally with a port ("api.github.com:443"), or CIDR ranges ("10.0.0.0/8").
+// An empty policy denies all network access.
+type EgressPolicy struct {
+	rules []egressRule
+}
+
+// ParseEgressPolicy parses allowNet entries into a policy
+func ParseEgressPolicy(entries []string) (*EgressPolicy, error) {
+	policy := &EgressPolicy{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q in allowNet: %w", entry, err)
+			}
+			policy.rules = append(policy.rules, egressRule{network: network})
+			continue
+		}
+
+		rule := egressRule{host: entry}
+		if host, port, err := net.SplitHostPort(entry); err == nil {
+			rule.host, rule.port = host, port
+		}
+		rule.host = strings.ToLower(strings.Trim(rule.host, "[]"))
+		policy.rules = append(policy.rules, rule)
+	}
+	return policy, nil
+}
+
+// Empty reports whether the policy denies all network access
+func (p *EgressPolicy) Empty() bool {
+	return p == nil || len(p.rules) == 0
+}
+
+// Hosts returns the host entries (with ports) in the form runtime permission flags expect.
+// CIDR entries have no flag equivalent and are only enforced by the egress proxy.
+func (p *EgressPolicy) Hosts() []string {
+	if p == nil {
+		return nil
+	}
+	var hosts []string
+	for _, rule := range p.rules {
+		if rule.network != nil {
+			continue
+		}
+		if rule.port != "" {
+			hosts = append(hosts, net.JoinHostPort(rule.host, rule.port))
+		} else {
+			hosts = append(hosts, rule.host)
+		}
+	}
+	return hosts
+}
+
+// HasCIDRs reports whether the policy contains CIDR entries
+func (p *EgressPolicy) HasCIDRs() bool {
+	if p == nil {
+		return false
+	}
+	for _, rule := range p.rules {
+		if rule.network != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHost reports whether a hostname (or literal IP) matches a host rule
+func (p *EgressPolicy) allowsHost(host, port string) bool {
+	host = strings.ToLower(host)
+	for _, rule := range p.rules {
+		if rule.network != nil || (rule.port != "" && rule.port != port) {
+			continue
+		}
+		if rule.host == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(rule.host, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsIP reports whether an address falls inside a CIDR rule
+func (p *EgressPolicy) allowsIP(ip net.IP) bool {
+	for _, rule := range p.rules {
+		if rule.network != nil && rule.network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// egressProxy is a filtering HTTP/HTTPS forward proxy for runtimes without a
+// permission system. It resolves destinations itself and dials only addresses
+// the policy allows, so CIDR rules cannot be bypassed with a hostname.
+type egressProxy struct {
+	policy    *EgressPolicy
+	listener  net.Listener
+	server    *http.Server
+	transport *http.Transport
+	dialer    net.Dialer
+	wg        sync.WaitGroup
+}
+
+// startEgressProxy starts a proxy for policy on a loopback port
+func startEgressProxy(policy *EgressPolicy) (*egressProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start egress proxy: %w", err)
+	}
+
+	p := &egressProxy{
+		policy:   policy,
+		listener: listener,
+		dialer:   net.Dialer{Timeout: 10 * time.Second},
+	}
+	p.transport = &http.Transport{
+		DialContext: p.dial,
+		Proxy:       nil,
+	}
+	p.server = &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Egress proxy stopped: %v", err)
+		}
+	}()
+
+	return p, nil
+}
+
+// URL returns the proxy address for HTTP_PROXY/HTTPS_PROXY
+func (p *egressProxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// Env returns the environment variables that route runtime traffic through the proxy
+func (p *egressProxy) Env() []string {
+	url := p.URL()
+	return []string{
+		"HTTP_PROXY=" + url,
+		"HTTPS_PROXY=" + url,
+		"http_proxy=" + url,
+		"https_proxy=" + url,
+		"NO_PROXY=",
+		"no_proxy=",
+	}
+}
+
+// Close stops the proxy and closes open tunnels
+func (p *egressProxy) Close() {
+	p.server.Close()
+	p.transport.CloseIdleConnections()
+	p.wg.Wait()
+}
+
+// dial connects to addr only if the host or one of its resolved addresses is allowed
+func (p *egressProxy) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	hostAllowed := p.policy.allowsHost(host, port)
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		if !hostAllowed && !p.policy.allowsIP(ip) {
+			continue
+		}
+		return p.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+	}
+
+	return nil, fmt.Errorf("%w: %s", errEgressDenied, addr)
+}
+
+// ServeHTTP handles CONNECT tunnels and absolute-URI proxy requests
+func (p *egressProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+
+	if !r.URL.IsAbs() {
+		http.Error(w, "egress proxy only accepts proxy requests", http.StatusBadRequest)
+		return
+	}
+
+	outReq := r.Clone(r.Context())
+	outReq.RequestURI = ""
+	outReq.Header.Del("Proxy-Connection")
+	outReq.Header.Del("Proxy-Authorization")
+
+	resp, err := p.transport.RoundTrip(outReq)
+	if err != nil {
+		p.reject(w, r.URL.Host, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// tunnel opens a raw TCP tunnel for HTTPS traffic
+func (p *egressProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := p.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		p.reject(w, r.Host, err)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	go func() {
+		io.Copy(upstream, client)
+		upstream.Close()
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+}
+
+// reject answers a request the proxy could not or would not forward
+func (p *egressProxy) reject(w http.ResponseWriter, host string, err error) {
+	if errors.Is(err, errEgressDenied) {
+		log.Printf("[sandbox] Blocked network access to %s", host)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
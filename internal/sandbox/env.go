@@ -0,0 +1,63 @@
+package sandbox
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// sandboxEnv builds the environment visible to sandboxed code: only host variables
+// matched by allow, then inject on top. Nothing else from the host is passed on,
+// so credentials in the server's environment do not leak into generated code.
+func sandboxEnv(allow []string, inject map[string]string) []string {
+	var env []string
+	for _, entry := range os.Environ() {
+		name, _, ok := strings.Cut(entry, "=")
+		if !ok || !EnvAllowed(name, allow) {
+			continue
+		}
+		if _, overridden := inject[name]; overridden {
+			continue
+		}
+		env = append(env, entry)
+	}
+	for _, name := range sortedKeys(inject) {
+		env = append(env, name+"="+inject[name])
+	}
+	return env
+}
+
+// EnvAllowed reports whether name matches an allowlist entry.
+// Entries are exact names or prefixes ending in "*", e.g. "AWS_*".
+func EnvAllowed(name string, allow []string) bool {
+	for _, pattern := range allow {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// envNames returns the variable names in env
+func envNames(env []string) []string {
+	names := make([]string, 0, len(env))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		names = append(names, name)
+	}
+	return names
+}
+
+// sortedKeys returns the keys of m in order, for deterministic command lines
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,170 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/client"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// Runtime names for the available execution backends
+const (
+	RuntimeAuto   = ""       // Use wasm when the plugin is available, otherwise goja
+	RuntimeWasm   = "wasm"   // QuickJS compiled to WebAssembly, run with extism
+	RuntimeGoja   = "goja"   // Pure-Go JavaScript interpreter, no external files required
+	RuntimeDeno   = "deno"   // Deno subprocess with permissions enforced by the runtime
+	RuntimeBun    = "bun"    // Bun subprocess; pairs with Bun's bundler
+	RuntimeDocker = "docker" // Throwaway container per execution, the strongest isolation
+	RuntimePython = "python" // Python interpreter subprocess running scripts instead of bundles
+)
+
+// Executor runs bundled JavaScript code with access to downstream MCP tools
+type Executor interface {
+	// ExecuteCode runs the bundle and returns its result or the error it threw.
+	// The error return is reserved for failures of the executor and limit violations.
+	ExecuteCode(ctx context.Context, run Run) (Result, error)
+
+	// Close frees resources held by the executor
+	Close()
+}
+
+// Run is one execution request for an Executor
+type Run struct {
+	Code      string        // Bundled JavaScript
+	SourceMap string        // Source map for Code, used to map error stacks (optional)
+	OnOutput  OutputFunc    // Receives console output live (optional, defaults to the server log)
+	Timeout   time.Duration // Overrides Limits.Timeout for this run when non-zero
+
+	// OnToolCall receives the downstream tool calls the code makes (optional)
+	OnToolCall ToolCallFunc
+
+	// Input is a JSON value exposed to the code as the global "input" and
+	// returned by codebraid.input() (optional)
+	Input json.RawMessage
+
+	// Secrets are readable by the code through secrets.get(name) (optional)
+	Secrets Secrets
+
+	// KV is the session's key-value store, behind codebraid.kv (optional)
+	KV KVStore
+
+	// Deterministic, when set, seeds Math.random and freezes the clock (optional)
+	Deterministic *Determinism
+
+	// Restore is a snapshot extracted into the working directory before the run,
+	// and Snapshot asks for the directory to be archived into Result.Snapshot
+	// afterwards (runtimes with a filesystem only)
+	Restore  []byte
+	Snapshot bool
+
+	// DryRun answers tool calls with stubs derived from the tools' output schemas
+	// instead of calling the servers; OnToolCall still sees every call
+	DryRun bool
+
+	// ReadOnly refuses calls to tools not annotated with readOnlyHint, so code
+	// cannot modify what the servers reach; refused calls fail like unknown tools
+	ReadOnly bool
+}
+
+// Determinism fixes the sources of nondeterminism a run can observe, so repeated
+// runs of the same code against the same tool results produce the same result
+type Determinism struct {
+	Seed uint32    // Seed of the Math.random generator
+	Now  time.Time // Returned by Date.now() and new Date() for the whole run
+}
+
+// Result is the outcome of one execution.
+// The value returned by exec() (or a top-level return) is the result of the script.
+type Result struct {
+	Output string // JSON-encoded return value (empty for undefined), or error output when Failed
+	Failed bool   // The code threw; Output holds {"error", "stack", "location"}
+
+	// Artifacts are the files the run wrote to codebraid.outputDir
+	// (runtimes without a filesystem have none)
+	Artifacts []Artifact
+
+	// UsedKV is set when the code called codebraid.kv, so the result depends on,
+	// or changed, the session's key-value store
+	UsedKV bool
+
+	// Snapshot is the gzipped tarball of the working directory, without its
+	// outputs, when the run asked for one
+	Snapshot []byte
+
+	Usage Usage // Resources the run consumed
+}
+
+// Options configures which executor is created and how
+type Options struct {
+	Runtime      string               // One of the Runtime* constants
+	WasmPath     string               // Path to the compiled sandbox plugin (wasm runtime only)
+	WasmCacheDir string               // Directory persisting compiled wasm between restarts (optional)
+	Policy       config.SandboxPolicy // Resources sandboxed code may access
+	Limits       Limits               // Per-execution resource limits
+	LibDir       string               // Session bundle directory, readable but not writable by code
+	Docker       config.DockerConfig  // Container settings (docker runtime only)
+	Env          map[string]string    // Variables injected into the sandbox environment, on top of Policy.AllowEnv
+	Persistent   bool                 // Keep runtime state alive across executions (process runtimes)
+	Pool         *Pool                // Warm processes to run in (process runtimes, optional)
+	SessionID    string               // Session the executor runs for, used to pin pooled processes
+	Secrets      Secrets              // Readable via secrets.get(name) and redacted from everything the run reports
+	OSSandbox    string               // One of the OSSandbox* constants, wrapping deno, bun and python processes
+	Unsandboxed  bool                 // Run bun and python without an OS sandbox, see config.SandboxConfig.AllowUnsandboxed
+	Python       config.PythonConfig  // Interpreter settings (python runtime only)
+	ClientHub    *client.McpClientHub
+}
+
+// New creates an executor for the requested runtime.
+// With RuntimeAuto, the wasm plugin is used when present at WasmPath and goja otherwise,
+// so simple scripts still run on hosts where the plugin has not been built.
+func New(ctx context.Context, opts Options) (Executor, error) {
+	runtime := opts.Runtime
+	if runtime == RuntimeAuto {
+		runtime = RuntimeGoja
+		if _, err := os.Stat(opts.WasmPath); err == nil {
+			runtime = RuntimeWasm
+		} else {
+			log.Printf("Sandbox plugin not found at %s, falling back to goja runtime", opts.WasmPath)
+		}
+	}
+
+	var executor Executor
+	var err error
+	switch runtime {
+	case RuntimeWasm:
+		executor, err = NewSandbox(ctx, opts)
+	case RuntimeGoja:
+		executor, err = NewGojaSandbox(ctx, opts)
+	case RuntimeDeno:
+		executor, err = NewDenoSandbox(ctx, opts)
+	case RuntimeBun:
+		executor, err = NewBunSandbox(ctx, opts)
+	case RuntimeDocker:
+		executor, err = NewDockerSandbox(ctx, opts)
+	case RuntimePython:
+		executor, err = NewPythonSandbox(ctx, opts)
+	default:
+		return nil, fmt.Errorf("unsupported sandbox runtime %q", opts.Runtime)
+	}
+	if err != nil {
+		return nil, err
+	}
+	executor = withToolCalls(executor)
+	if opts.Limits.MaxToolCalls > 0 || opts.Limits.MaxToolTime > 0 {
+		executor = withToolBudget(executor, opts.Limits.MaxToolCalls, opts.Limits.MaxToolTime)
+	}
+
+	// Redact before truncating, so a cut can never leave part of a secret behind
+	if len(opts.Secrets) > 0 {
+		executor = withSecrets(executor, opts.Secrets)
+	}
+	if opts.Limits.OutputBytes > 0 || opts.Limits.ResultBytes > 0 {
+		executor = withTruncation(executor, opts.Limits.OutputBytes, opts.Limits.ResultBytes)
+	}
+	return withUsage(executor), nil
+}
@@ -0,0 +1,243 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/yousuf/codebraid-mcp/internal/client"
+)
+
+// gojaProgramCounter matches the bytecode offset goja appends to stack positions,
+// e.g. "main.js:3:9(12)", so stacks can be parsed like V8/QuickJS ones
+var gojaProgramCounter = regexp.MustCompile(`:(\d+):(\d+)\(\d+\)`)
+
+// GojaSandbox executes user code with the goja pure-Go JavaScript interpreter.
+// It is slower than the WASM sandbox but needs no plugin build or external runtime.
+// There is no event loop: promises settle, but timers and async I/O are unavailable.
+type GojaSandbox struct {
+	vm        *goja.Runtime
+	limits    Limits
+	sessionID string
+	output    OutputFunc // Console sink of the execution in progress
+	clientHub *client.McpClientHub
+	ctx       context.Context
+	runCtx    context.Context // Context of the execution in progress, used for tool calls
+}
+
+// NewGojaSandbox creates a new goja-backed sandbox instance
+// The interpreter exposes no filesystem or network APIs, so the policy needs no enforcement.
+func NewGojaSandbox(ctx context.Context, opts Options) (*GojaSandbox, error) {
+	sb := &GojaSandbox{
+		vm:        goja.New(),
+		limits:    opts.Limits,
+		sessionID: opts.SessionID,
+		clientHub: opts.ClientHub,
+		ctx:       ctx,
+		runCtx:    ctx,
+	}
+
+	if err := sb.installGlobals(); err != nil {
+		return nil, fmt.Errorf("failed to set up goja runtime: %w", err)
+	}
+
+	return sb, nil
+}
+
+// installGlobals exposes callTool, console and CommonJS shims to user code
+func (s *GojaSandbox) installGlobals() error {
+	if err := s.vm.Set("callTool", s.callTool); err != nil {
+		return err
+	}
+
+	console := s.vm.NewObject()
+	for _, level := range []string{"log", "info", "warn", "error", "debug"} {
+		level := level
+		if err := console.Set(level, func(call goja.FunctionCall) goja.Value {
+			parts := make([]string, len(call.Arguments))
+			for i, arg := range call.Arguments {
+				parts[i] = arg.String()
+			}
+			s.output.emit(level, strings.Join(parts, " "))
+			return goja.Undefined()
+		}); err != nil {
+			return err
+		}
+	}
+	if err := s.vm.Set("console", console); err != nil {
+		return err
+	}
+
+	// Bundles use CommonJS chunk format and may touch module/exports
+	_, err := s.vm.RunString("var module = { exports: {} }; var exports = module.exports;")
+	return err
+}
+
+// callTool is the goja equivalent of the WASM callMcpTool host function
+func (s *GojaSandbox) callTool(call goja.FunctionCall) goja.Value {
+	toolCall := McpToolCall{
+		ServerName: call.Argument(0).String(),
+		ToolName:   call.Argument(1).String(),
+		Args:       map[string]interface{}{},
+	}
+	if args, ok := call.Argument(2).Export().(map[string]interface{}); ok {
+		toolCall.Args = args
+	}
+
+	response := callMcpTool(s.runCtx, s.clientHub, toolCall)
+	if !response.Success {
+		msg := response.Error
+		if msg == "" {
+			msg = "MCP call failed"
+		}
+		panic(s.vm.NewGoError(errors.New(msg)))
+	}
+
+	// Round-trip through JSON so scripts see plain objects, as in the WASM sandbox
+	value, err := s.fromJSON(response.Result)
+	if err != nil {
+		panic(s.vm.NewGoError(fmt.Errorf("failed to decode tool result: %w", err)))
+	}
+	return value
+}
+
+// ExecuteCode executes bundled JavaScript code in the goja runtime.
+// The runtime is reused, so globals set by one run are visible to the next.
+func (s *GojaSandbox) ExecuteCode(ctx context.Context, run Run) (Result, error) {
+	run, err := run.withPrelude("")
+	if err != nil {
+		return Result{}, err
+	}
+
+	if run.Restore != nil || run.Snapshot {
+		return Result{}, fmt.Errorf("snapshots are not supported by the goja runtime, which has no filesystem")
+	}
+
+	// Hooks have timeouts of their own and don't count against the run's.
+	// There is no scratch directory to give them.
+	hookCtx := ctx
+	if err := runHooks(hookCtx, HookPre, s.sessionID, "", nil); err != nil {
+		return Result{}, err
+	}
+
+	limits := s.limits.forRun(run)
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+
+	// Interrupt the interpreter when the execution is cancelled or times out
+	s.runCtx = ctx
+	s.output = run.OnOutput
+	stop := context.AfterFunc(ctx, func() {
+		s.vm.Interrupt(ctx.Err())
+	})
+	defer func() {
+		stop()
+		s.vm.ClearInterrupt()
+		s.runCtx = s.ctx
+		s.output = nil
+	}()
+
+	var result Result
+	cpuTime := onThread(func() {
+		result, err = s.execute(run.Code, run.SourceMap)
+	})
+	if err != nil {
+		return Result{}, limits.checkContext(ctx, err)
+	}
+	if err := runHooks(hookCtx, HookPost, s.sessionID, "", &result); err != nil {
+		return Result{}, err
+	}
+	result.Usage.CPUTime = cpuTime
+	return result, nil
+}
+
+// execute runs the bundle and converts its completion value to JSON
+func (s *GojaSandbox) execute(bundledCode, sourceMap string) (Result, error) {
+	value, err := s.vm.RunScript("main.js", bundledCode)
+	if err != nil {
+		var exception *goja.Exception
+		if errors.As(err, &exception) {
+			return s.errorOutput(exception.Value(), sourceMap)
+		}
+		return Result{}, fmt.Errorf("script execution failed: %w", err)
+	}
+
+	// exec() is usually async, unwrap the promise it returns
+	if promise, ok := value.Export().(*goja.Promise); ok {
+		switch promise.State() {
+		case goja.PromiseStateFulfilled:
+			value = promise.Result()
+		case goja.PromiseStateRejected:
+			return s.errorOutput(promise.Result(), sourceMap)
+		default:
+			return Result{}, fmt.Errorf("execution did not complete: the goja runtime has no event loop for timers or async I/O")
+		}
+	}
+
+	if value == nil || goja.IsUndefined(value) {
+		return Result{}, nil
+	}
+
+	output, err := s.toJSON(value)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Output: output}, nil
+}
+
+// errorOutput renders a thrown JavaScript value in the same shape as the WASM sandbox
+func (s *GojaSandbox) errorOutput(thrown goja.Value, sourceMap string) (Result, error) {
+	message := thrown.String()
+	stack := ""
+
+	if obj, ok := thrown.(*goja.Object); ok {
+		if msg := obj.Get("message"); msg != nil && !goja.IsUndefined(msg) {
+			message = msg.String()
+		}
+		if st := obj.Get("stack"); st != nil && !goja.IsUndefined(st) {
+			stack = gojaProgramCounter.ReplaceAllString(st.String(), ":$1:$2")
+		}
+	}
+
+	return formatExecutionError(message, stack, sourceMap)
+}
+
+// toJSON serializes a value with the runtime's own JSON.stringify
+func (s *GojaSandbox) toJSON(value goja.Value) (string, error) {
+	stringify, ok := goja.AssertFunction(s.vm.Get("JSON").ToObject(s.vm).Get("stringify"))
+	if !ok {
+		return "", fmt.Errorf("JSON.stringify is not available")
+	}
+
+	result, err := stringify(goja.Undefined(), value)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize result: %w", err)
+	}
+	if goja.IsUndefined(result) {
+		return "", nil
+	}
+	return result.String(), nil
+}
+
+// fromJSON converts a Go value into a plain JavaScript value via JSON.parse
+func (s *GojaSandbox) fromJSON(v interface{}) (goja.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	parse, ok := goja.AssertFunction(s.vm.Get("JSON").ToObject(s.vm).Get("parse"))
+	if !ok {
+		return nil, fmt.Errorf("JSON.parse is not available")
+	}
+	return parse(goja.Undefined(), s.vm.ToValue(string(data)))
+}
+
+// Close releases the runtime
+func (s *GojaSandbox) Close() {
+	s.vm = nil
+}
@@ -0,0 +1,215 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hook phases
+const (
+	HookPre  = "pre"  // Before the code runs; a failure aborts the execution
+	HookPost = "post" // After the code ran and before its outputs are collected
+)
+
+// defaultHookTimeout bounds a hook that sets no timeout of its own
+const defaultHookTimeout = 30 * time.Second
+
+// HookEvent is what a hook sees of the execution it runs around
+type HookEvent struct {
+	Phase     string
+	SessionID string
+
+	// ScratchDir is the run's working directory, which the code sees as /tmp
+	// under the wasm runtime. It is empty for goja, which has no filesystem.
+	ScratchDir string
+
+	// OutputsDir holds the files the code wrote to codebraid.outputDir.
+	// They are collected as artifacts after the post hooks, so a post hook can
+	// scan, rewrite or upload them.
+	OutputsDir string
+
+	// Result is the outcome of the run, for post hooks only. Go hooks may modify it.
+	Result *Result
+}
+
+// HookFunc is a hook implemented in Go and registered with RegisterHook
+type HookFunc func(ctx context.Context, event *HookEvent) error
+
+// Hook runs around every execution: a command or a registered HookFunc
+type Hook struct {
+	Name         string        // Used in errors and logs; defaults to the command or function name
+	Command      []string      // Program and arguments, run in the scratch directory, or a temporary one for runtimes without
+	Func         string        // Name of a function registered with RegisterHook
+	Timeout      time.Duration // Defaults to 30 seconds
+	IgnoreErrors bool          // Log a failure instead of failing the execution
+}
+
+// Hooks are the hooks run before and after every execution
+type Hooks struct {
+	Pre  []Hook
+	Post []Hook
+}
+
+var (
+	hookFuncsMu sync.RWMutex
+	hookFuncs   = map[string]HookFunc{}
+
+	globalHooks Hooks
+)
+
+// RegisterHook makes fn available to hooks configured with Func set to name.
+// Should be called before SetHooks.
+func RegisterHook(name string, fn HookFunc) {
+	hookFuncsMu.Lock()
+	defer hookFuncsMu.Unlock()
+	hookFuncs[name] = fn
+}
+
+// SetHooks sets the hooks run around executions started afterwards.
+// Should be called once at application startup.
+func SetHooks(hooks Hooks) error {
+	for _, phase := range [][]Hook{hooks.Pre, hooks.Post} {
+		for i := range phase {
+			hook := &phase[i]
+			switch {
+			case len(hook.Command) > 0 && hook.Func != "":
+				return fmt.Errorf("hook %s: set either a command or a function, not both", hook.name())
+			case len(hook.Command) > 0:
+			case hook.Func != "":
+				if _, ok := lookupHookFunc(hook.Func); !ok {
+					return fmt.Errorf("hook %s: no function registered as %q", hook.name(), hook.Func)
+				}
+			default:
+				return fmt.Errorf("hook %s: a command or a function is required", hook.name())
+			}
+		}
+	}
+	globalHooks = hooks
+	return nil
+}
+
+// lookupHookFunc returns the function registered under name
+func lookupHookFunc(name string) (HookFunc, bool) {
+	hookFuncsMu.RLock()
+	defer hookFuncsMu.RUnlock()
+	fn, ok := hookFuncs[name]
+	return fn, ok
+}
+
+// runHooks runs the hooks of a phase in order, stopping at the first failure
+// that is not ignored. scratch is empty for runtimes without a filesystem.
+func runHooks(ctx context.Context, phase, sessionID, scratch string, result *Result) error {
+	hooks := globalHooks.Pre
+	if phase == HookPost {
+		hooks = globalHooks.Post
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	event := &HookEvent{Phase: phase, SessionID: sessionID, ScratchDir: scratch, Result: result}
+	if scratch != "" {
+		event.OutputsDir = filepath.Join(scratch, outputsDirName)
+	}
+	for _, hook := range hooks {
+		if err := hook.run(ctx, event); err != nil {
+			if hook.IgnoreErrors {
+				log.Printf("Session %s: %s-execution hook %s failed: %v", sessionID, phase, hook.name(), err)
+				continue
+			}
+			return fmt.Errorf("%s-execution hook %s failed: %w", phase, hook.name(), err)
+		}
+	}
+	return nil
+}
+
+// name identifies the hook in errors and logs
+func (h *Hook) name() string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case len(h.Command) > 0:
+		return filepath.Base(h.Command[0])
+	case h.Func != "":
+		return h.Func
+	}
+	return "(unnamed)"
+}
+
+// run calls the hook with its timeout
+func (h *Hook) run(ctx context.Context, event *HookEvent) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if h.Func != "" {
+		fn, ok := lookupHookFunc(h.Func)
+		if !ok {
+			return fmt.Errorf("no function registered as %q", h.Func)
+		}
+		return fn(ctx, event)
+	}
+	return h.runCommand(ctx, event)
+}
+
+// runCommand runs a command hook in the scratch directory. The event is passed
+// in CODEBRAID_* variables, and a post hook reads the result as JSON on stdin:
+// {"output": "<JSON-encoded return value>", "failed": false}.
+// Runtimes without a scratch directory get the hook a temporary one, removed
+// once it exits, so it never writes into the server's working directory.
+func (h *Hook) runCommand(ctx context.Context, event *HookEvent) error {
+	dir := event.ScratchDir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "codebraid-hook-*")
+		if err != nil {
+			return fmt.Errorf("failed to create working directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"CODEBRAID_HOOK="+event.Phase,
+		"CODEBRAID_SESSION_ID="+event.SessionID,
+		"CODEBRAID_SCRATCH_DIR="+event.ScratchDir,
+		"CODEBRAID_OUTPUTS_DIR="+event.OutputsDir,
+	)
+	if event.Result != nil {
+		input, err := json.Marshal(map[string]interface{}{
+			"output": event.Result.Output,
+			"failed": event.Result.Failed,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		cmd.Stdin = bytes.NewReader(input)
+	}
+
+	output := cappedBuffer{max: 4 << 10}
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errors.New("timed out")
+		}
+		if text := strings.TrimSpace(output.String()); text != "" {
+			return fmt.Errorf("%w: %s", err, text)
+		}
+		return err
+	}
+	return nil
+}
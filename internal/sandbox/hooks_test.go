@@ -0,0 +1,50 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCommandHookWithoutScratchDirLeavesCwdUntouched(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadDir(cwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook := Hook{Command: []string{"sh", "-c", "echo hooked > hook-output.txt && mkdir -p outputs && pwd > outputs/pre.txt"}}
+	if err := SetHooks(Hooks{Pre: []Hook{hook}, Post: []Hook{hook}}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetHooks(Hooks{})
+
+	sb, err := NewGojaSandbox(context.Background(), Options{Runtime: RuntimeGoja, SessionID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sb.Close()
+	result, err := sb.ExecuteCode(context.Background(), Run{Code: "1 + 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed || result.Output != "2" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	after, err := os.ReadDir(cwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		for _, entry := range after {
+			if entry.Name() == "hook-output.txt" || entry.Name() == "outputs" {
+				os.RemoveAll(entry.Name())
+			}
+		}
+		t.Fatalf("hook wrote into the working directory: %d entries before, %d after", len(before), len(after))
+	}
+}
@@ -3,8 +3,12 @@ package sandbox
 import (
 	"context"
 	"encoding/json"
+	"log"
+	"time"
 
 	extism "github.com/extism/go-sdk"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/client"
 )
 
 // McpToolCall represents a call to an MCP tool from the sandbox
@@ -21,7 +25,8 @@ type McpToolResponse struct {
 	Error   string      `json:"error,omitempty"`
 }
 
-// createCallMcpToolHostFunc creates the host function for calling MCP tools
+// createCallMcpToolHostFunc creates the host function for calling MCP tools.
+// It logs to the server log rather than the plugin logger, which carries only console output.
 func createCallMcpToolHostFunc(sb *Sandbox) extism.HostFunction {
 	return extism.NewHostFunctionWithStack(
 		"callMcpTool",
@@ -30,7 +35,7 @@ func createCallMcpToolHostFunc(sb *Sandbox) extism.HostFunction {
 			offset := stack[0]
 			inputData, err := plugin.ReadBytes(offset)
 			if err != nil {
-				plugin.Logf(extism.LogLevelError, "Failed to read input: %v", err)
+				log.Printf("[sandbox:wasm] Failed to read input: %v", err)
 				stack[0] = 0
 				return
 			}
@@ -38,54 +43,25 @@ func createCallMcpToolHostFunc(sb *Sandbox) extism.HostFunction {
 			// Parse tool call request
 			var toolCall McpToolCall
 			if err := json.Unmarshal(inputData, &toolCall); err != nil {
-				plugin.Logf(extism.LogLevelError, "Failed to parse tool call: %v", err)
+				log.Printf("[sandbox:wasm] Failed to parse tool call: %v", err)
 				writeErrorResponse(plugin, stack, "Invalid tool call format")
 				return
 			}
 
-			plugin.Logf(extism.LogLevelInfo, "Calling MCP tool: %s.%s", toolCall.ServerName, toolCall.ToolName)
+			log.Printf("[sandbox:wasm] Calling MCP tool: %s.%s", toolCall.ServerName, toolCall.ToolName)
 
 			// Make synchronous MCP call
-			result, err := sb.clientHub.CallTool(sb.ctx, toolCall.ServerName, toolCall.ToolName, toolCall.Args)
-			if err != nil {
-				plugin.Logf(extism.LogLevelError, "Failed to call MCP tool: %v", err)
-
-				errResp := map[string]string{"error": err.Error()}
-				responseData, _ := json.Marshal(errResp)
-				responseOffset, err := plugin.WriteBytes(responseData)
-				if err != nil {
-					plugin.Logf(extism.LogLevelError, "Failed to write error response: %v", err)
-					stack[0] = 0
-					return
-				}
-
-				stack[0] = responseOffset
-				return
-			}
-
-			// Prepare response
-			response := McpToolResponse{
-				Success: err == nil,
-			}
-
-			if err != nil {
-				response.Error = err.Error()
-				plugin.Logf(extism.LogLevelError, "MCP call failed: %v", err)
-			} else {
-				if result.StructuredContent == nil {
-					response.Result = result
-				} else {
-					response.Result = result.StructuredContent
-				}
-
-				plugin.Log(extism.LogLevelInfo, "MCP call succeeded")
+			// ctx is the per-execution context passed to CallWithContext
+			response := callMcpTool(ctx, sb.clientHub, toolCall)
+			if !response.Success {
+				log.Printf("[sandbox:wasm] MCP call failed: %s", response.Error)
 			}
 
 			// Write response back to plugin memory
 			responseData, _ := json.Marshal(response)
 			responseOffset, err := plugin.WriteBytes(responseData)
 			if err != nil {
-				plugin.Logf(extism.LogLevelError, "Failed to write response: %v", err)
+				log.Printf("[sandbox:wasm] Failed to write response: %v", err)
 				stack[0] = 0
 				return
 			}
@@ -98,6 +74,74 @@ func createCallMcpToolHostFunc(sb *Sandbox) extism.HostFunction {
 	)
 }
 
+// callMcpTool performs a tool call on behalf of sandboxed code, or answers a call
+// to a host binding. Shared by all execution backends so they return identical responses.
+func callMcpTool(ctx context.Context, clientHub *client.McpClientHub, toolCall McpToolCall) McpToolResponse {
+	if toolCall.ServerName == hostServer {
+		return callHost(ctx, toolCall)
+	}
+
+	// A read-only session's calls to other tools are refused before reaching the server
+	if isReadOnly(ctx) {
+		if err := checkReadOnly(clientHub, toolCall.ServerName, toolCall.ToolName); err != nil {
+			trackToolCall(ctx, ToolCall{
+				ServerName: toolCall.ServerName,
+				ToolName:   toolCall.ToolName,
+				Args:       toolCall.Args,
+				Error:      err.Error(),
+			})
+			return McpToolResponse{
+				Success: false,
+				Error:   err.Error(),
+			}
+		}
+	}
+
+	callCtx, cancel, err := startToolCall(ctx)
+	if err != nil {
+		return McpToolResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+	defer cancel()
+
+	start := time.Now()
+	var result *mcp.CallToolResult
+	if isDryRun(ctx) {
+		result, err = stubToolResult(clientHub, toolCall.ServerName, toolCall.ToolName)
+	} else {
+		result, err = clientHub.CallTool(callCtx, toolCall.ServerName, toolCall.ToolName, toolCall.Args)
+	}
+	finishToolCall(ctx, time.Since(start))
+	call := ToolCall{
+		ServerName: toolCall.ServerName,
+		ToolName:   toolCall.ToolName,
+		Args:       toolCall.Args,
+		Duration:   time.Since(start),
+	}
+	if err != nil {
+		call.Error = err.Error()
+	} else if result.IsError {
+		call.Error = "tool returned an error result"
+	}
+	trackToolCall(ctx, call)
+	if err != nil {
+		return McpToolResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
+	}
+
+	response := McpToolResponse{Success: true}
+	if result.StructuredContent == nil {
+		response.Result = result
+	} else {
+		response.Result = result.StructuredContent
+	}
+	return response
+}
+
 // writeErrorResponse writes an error response to the plugin
 func writeErrorResponse(plugin *extism.CurrentPlugin, stack []uint64, errorMsg string) {
 	response := McpToolResponse{
@@ -0,0 +1,136 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// withPrelude prepends a line to the bundle that binds the run's input as the global
+// "input" and as codebraid.input(), its secrets behind secrets.get(name) and the
+// session's key-value store as codebraid.kv, and
+// shifts the source map down by that line. The bindings are set on every run so
+// persistent runtimes never see a stale input or secret.
+// outputDir, when the runtime has one, is exposed as codebraid.outputDir.
+// Math.random and Date are replaced for deterministic runs and restored otherwise.
+func (r Run) withPrelude(outputDir string) (Run, error) {
+	input := r.Input
+	if len(input) == 0 {
+		input = json.RawMessage("undefined")
+	} else if !json.Valid(input) {
+		return r, fmt.Errorf("input is not valid JSON")
+	}
+
+	secrets := r.Secrets
+	if secrets == nil {
+		secrets = Secrets{}
+	}
+	secretValues, err := json.Marshal(secrets)
+	if err != nil {
+		return r, err
+	}
+
+	bindings := "input: () => globalThis.input, secrets: globalThis.secrets, kv: " + kvBinding
+	if outputDir != "" {
+		dir, err := json.Marshal(outputDir)
+		if err != nil {
+			return r, err
+		}
+		bindings += ", outputDir: " + string(dir)
+	}
+	// The values are only reachable through get(), so logging the secrets object shows none of them
+	secretsBinding := fmt.Sprintf(`globalThis.secrets = ((values) => Object.freeze({ get(name) { if (!Object.prototype.hasOwnProperty.call(values, name)) throw new Error("secret " + JSON.stringify(name) + " is not configured"); return values[name]; } }))(%s);`, secretValues)
+	determinism := "null"
+	if d := r.Deterministic; d != nil {
+		determinism = fmt.Sprintf("{ seed: %d, now: %d }", d.Seed, d.Now.UnixMilli())
+	}
+	prelude := fmt.Sprintf("globalThis.input = %s; %s globalThis.codebraid = Object.assign(globalThis.codebraid || {}, { %s }); %s(%s);\n", input, secretsBinding, bindings, determinismBinding, determinism)
+
+	sourceMap, err := shiftSourceMap(r.SourceMap, 1)
+	if err != nil {
+		return r, err
+	}
+
+	r.Code = prelude + r.Code
+	r.SourceMap = sourceMap
+	return r, nil
+}
+
+// withPythonPrelude is withPrelude for runner.py: the first line of the code
+// binds the input as codebraid.input(), the secrets behind codebraid.secrets.get(name)
+// and the output directory as codebraid.output_dir. The runner executes that line
+// apart from the script, so tracebacks keep the script's own line numbers.
+func (r Run) withPythonPrelude(outputDir string) (Run, error) {
+	if r.Deterministic != nil {
+		return r, fmt.Errorf("deterministic runs are not supported for Python")
+	}
+	if len(r.Input) > 0 && !json.Valid(r.Input) {
+		return r, fmt.Errorf("input is not valid JSON")
+	}
+
+	secrets := r.Secrets
+	if secrets == nil {
+		secrets = Secrets{}
+	}
+	secretValues, err := json.Marshal(secrets)
+	if err != nil {
+		return r, err
+	}
+
+	// JSON string literals are valid Python string literals, so each value is
+	// passed as the JSON text it decodes
+	var args []string
+	for _, value := range []string{string(r.Input), string(secretValues), outputDir} {
+		quoted, err := json.Marshal(value)
+		if err != nil {
+			return r, err
+		}
+		args = append(args, string(quoted))
+	}
+
+	r.Code = fmt.Sprintf("__import__(\"codebraid\")._bind(%s)\n", strings.Join(args, ", ")) + r.Code
+	return r, nil
+}
+
+// kvBinding exposes the session's key-value store through callTool, see callHost.
+// Its methods return promises in every runtime, and get resolves to undefined for
+// unset keys whether the runtime decodes a missing result as null or undefined.
+const kvBinding = `Object.freeze({ get: async (key) => (await callTool("` + hostServer + `", "kv.get", { key })) ?? undefined, set: async (key, value) => { await callTool("` + hostServer + `", "kv.set", { key, value }); }, delete: async (key) => { await callTool("` + hostServer + `", "kv.delete", { key }); } })`
+
+// determinismBinding takes { seed, now } or null. The originals are kept on the first
+// run, so a persistent runtime gets them back after a deterministic run. Math.random
+// becomes a mulberry32 generator and Date a wrapper sharing Date.prototype (so instanceof
+// still works) whose now() and argument-less constructor return the frozen time.
+const determinismBinding = `((d) => { const real = globalThis.__codebraidReal = globalThis.__codebraidReal || { random: Math.random, Date: globalThis.Date }; if (!d) { Math.random = real.random; globalThis.Date = real.Date; return; } let state = d.seed >>> 0; Math.random = () => { state = (state + 0x6D2B79F5) >>> 0; let t = state; t = Math.imul(t ^ (t >>> 15), t | 1); t ^= t + Math.imul(t ^ (t >>> 7), t | 61); return ((t ^ (t >>> 14)) >>> 0) / 4294967296; }; const RealDate = real.Date; const FrozenDate = function (...args) { if (!new.target) return new RealDate(d.now).toString(); return args.length ? new RealDate(...args) : new RealDate(d.now); }; FrozenDate.prototype = RealDate.prototype; FrozenDate.now = () => d.now; FrozenDate.parse = RealDate.parse; FrozenDate.UTC = RealDate.UTC; globalThis.Date = FrozenDate; })`
+
+// shiftSourceMap offsets every generated line in sourceMap by lines.
+// Each ";" in the mappings starts a new generated line, so prefixing them is enough.
+func shiftSourceMap(sourceMap string, lines int) (string, error) {
+	if strings.TrimSpace(sourceMap) == "" {
+		return sourceMap, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(sourceMap), &fields); err != nil {
+		return "", fmt.Errorf("failed to parse source map: %w", err)
+	}
+
+	var mappings string
+	if raw, ok := fields["mappings"]; ok {
+		if err := json.Unmarshal(raw, &mappings); err != nil {
+			return "", fmt.Errorf("failed to parse source map mappings: %w", err)
+		}
+	}
+
+	shifted, err := json.Marshal(strings.Repeat(";", lines) + mappings)
+	if err != nil {
+		return "", err
+	}
+	fields["mappings"] = shifted
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode source map: %w", err)
+	}
+	return string(out), nil
+}
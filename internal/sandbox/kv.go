@@ -0,0 +1,87 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// KVStore is the key-value store of a session, which code reads and writes with
+// codebraid.kv.get(key), codebraid.kv.set(key, value) and codebraid.kv.delete(key)
+// to keep state between executions. Values are JSON.
+type KVStore interface {
+	// Get returns the value stored under key, or false when there is none
+	Get(key string) (json.RawMessage, bool, error)
+
+	// Set stores value under key; a nil value deletes the key
+	Set(key string, value json.RawMessage) error
+}
+
+// hostServer is the server name host bindings such as codebraid.kv call through
+// callTool, so they work in every runtime. Calls to it are not tool calls.
+const hostServer = "__codebraid"
+
+// kvStoreKey carries the run's KVStore in its context
+type kvStoreKey struct{}
+
+// trackedKV is a run's KVStore, recording whether the run used it
+type trackedKV struct {
+	KVStore
+	used *atomic.Bool
+}
+
+// Get implements KVStore
+func (kv trackedKV) Get(key string) (json.RawMessage, bool, error) {
+	kv.used.Store(true)
+	return kv.KVStore.Get(key)
+}
+
+// Set implements KVStore
+func (kv trackedKV) Set(key string, value json.RawMessage) error {
+	kv.used.Store(true)
+	return kv.KVStore.Set(key, value)
+}
+
+// callHost answers a call to a host binding
+func callHost(ctx context.Context, call McpToolCall) McpToolResponse {
+	store, _ := ctx.Value(kvStoreKey{}).(KVStore)
+	if store == nil {
+		return McpToolResponse{Error: "codebraid.kv is not available in this session"}
+	}
+	key, _ := call.Args["key"].(string)
+	if key == "" {
+		return McpToolResponse{Error: "codebraid.kv: key must be a non-empty string"}
+	}
+
+	switch call.ToolName {
+	case "kv.get":
+		value, ok, err := store.Get(key)
+		if err != nil {
+			return McpToolResponse{Error: fmt.Sprintf("codebraid.kv.get: %v", err)}
+		}
+		if !ok {
+			return McpToolResponse{Success: true}
+		}
+		return McpToolResponse{Success: true, Result: value}
+
+	case "kv.set", "kv.delete":
+		// Dry runs leave the store as it was
+		if isDryRun(ctx) {
+			return McpToolResponse{Success: true}
+		}
+		var value json.RawMessage
+		if v, ok := call.Args["value"]; ok && v != nil && call.ToolName == "kv.set" {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return McpToolResponse{Error: fmt.Sprintf("codebraid.kv.set: value is not JSON: %v", err)}
+			}
+			value = data
+		}
+		if err := store.Set(key, value); err != nil {
+			return McpToolResponse{Error: fmt.Sprintf("codebraid.%s: %v", call.ToolName, err)}
+		}
+		return McpToolResponse{Success: true}
+	}
+	return McpToolResponse{Error: fmt.Sprintf("unknown host binding %q", call.ToolName)}
+}
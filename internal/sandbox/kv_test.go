@@ -0,0 +1,51 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// memoryKV is a KVStore in memory
+type memoryKV map[string]json.RawMessage
+
+func (kv memoryKV) Get(key string) (json.RawMessage, bool, error) {
+	value, ok := kv[key]
+	return value, ok, nil
+}
+
+func (kv memoryKV) Set(key string, value json.RawMessage) error {
+	if value == nil {
+		delete(kv, key)
+	} else {
+		kv[key] = value
+	}
+	return nil
+}
+
+func TestResultReportsKVUse(t *testing.T) {
+	sb, err := New(context.Background(), Options{Runtime: RuntimeGoja, SessionID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sb.Close()
+
+	for code, want := range map[string]bool{
+		`1 + 1`: false,
+		// Mentioning the store is not using it
+		`"codebraid.kv"`: false,
+		`(async () => { await codebraid.kv.set("a", 1) })()`:                          true,
+		`(async () => { const { kv } = codebraid; return await kv.get("a") ?? 0 })()`: true,
+	} {
+		result, err := sb.ExecuteCode(context.Background(), Run{Code: code, KV: memoryKV{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Failed {
+			t.Fatalf("%s: %s", code, result.Output)
+		}
+		if result.UsedKV != want {
+			t.Errorf("%s: UsedKV = %v, want %v", code, result.UsedKV, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Error types reported in structured limit failures
+const (
+	LimitTimeout    = "timeout"
+	LimitResource   = "resource_limit"
+	LimitToolBudget = "tool_budget" // More downstream tool calls, or time waiting for them, than allowed
+	LimitCancelled  = "cancelled"   // The caller cancelled the request; not a limit, but reported alike
+)
+
+// Limits bounds the resources a single execution may use.
+// Memory and CPU limits are enforced by the wasm and process runtimes;
+// the goja interpreter has no resource accounting and only honours Timeout.
+// A persistent runtime process accumulates CPU time across all of its runs.
+type Limits struct {
+	Timeout  time.Duration // Wall-clock limit per ExecuteCode call (zero means unbounded)
+	MemoryMB int           // JavaScript heap limit in megabytes (zero means unbounded)
+	CPUTime  time.Duration // CPU-time limit for runtime processes (zero means unbounded)
+
+	OutputBytes int // Console output kept per run; the rest is dropped (zero means unbounded)
+	ResultBytes int // Result size past which it is replaced by a preview (zero means unbounded)
+
+	MaxToolCalls int           // Downstream tool calls per run (zero means unbounded)
+	MaxToolTime  time.Duration // Total time a run may wait for tool calls (zero means unbounded)
+}
+
+// messageBytes bounds a single runner protocol message. Console lines and results
+// under their limits are truncated by the executor; this backstop only stops a
+// runner sending more than the host should buffer, allowing for JSON escaping.
+func (l Limits) messageBytes() int {
+	if l.OutputBytes == 0 || l.ResultBytes == 0 {
+		return 0
+	}
+	return 4*max(l.OutputBytes, l.ResultBytes) + 64<<10
+}
+
+// LimitError reports an execution that was stopped by a sandbox limit or cancelled.
+// Callers should return Output() to the client rather than failing the tool call,
+// so the model can tell a runaway script apart from an infrastructure error.
+type LimitError struct {
+	Type    string // One of the Limit* constants
+	Message string
+}
+
+// Error implements the error interface
+func (e *LimitError) Error() string {
+	return e.Message
+}
+
+// Output renders the error in the JSON shape used for execution errors
+func (e *LimitError) Output() string {
+	output, _ := json.Marshal(map[string]interface{}{
+		"error": e.Message,
+		"type":  e.Type,
+	})
+	return string(output)
+}
+
+// memoryError reports an execution that ran out of its memory allowance
+func (l Limits) memoryError() *LimitError {
+	return &LimitError{
+		Type:    LimitResource,
+		Message: fmt.Sprintf("resource limit exceeded: memory (%d MB)", l.MemoryMB),
+	}
+}
+
+// cpuError reports an execution that used up its CPU-time allowance
+func (l Limits) cpuError() *LimitError {
+	return &LimitError{
+		Type:    LimitResource,
+		Message: fmt.Sprintf("resource limit exceeded: CPU time (%s)", l.CPUTime),
+	}
+}
+
+// processViolation identifies a runtime process that was stopped by a memory or CPU limit.
+// Runtimes either abort with an out-of-memory report on stderr or are killed by the
+// kernel's OOM killer with SIGKILL; runs the sandbox stopped itself never get here.
+// Container clients report a signalled container as exit status 128+n instead.
+func (l Limits) processViolation(state *os.ProcessState, stderr string) *LimitError {
+	if state == nil {
+		return nil
+	}
+	exitCode := state.ExitCode()
+	if l.CPUTime > 0 && (killedByCPULimit(state) || exitCode == 128+24 || state.UserTime()+state.SystemTime() >= l.CPUTime) {
+		return l.cpuError()
+	}
+	if l.MemoryMB > 0 && (isOutOfMemory(stderr) || killedByOOM(state) || exitCode == 128+9) {
+		return l.memoryError()
+	}
+	return nil
+}
+
+// isOutOfMemory reports whether a runtime message describes heap exhaustion
+func isOutOfMemory(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "out of memory") || strings.Contains(message, "reached heap limit")
+}
+
+// forRun returns the limits that apply to one run
+func (l Limits) forRun(run Run) Limits {
+	if run.Timeout > 0 {
+		l.Timeout = run.Timeout
+	}
+	return l
+}
+
+// withTimeout derives the context for one execution
+func (l Limits) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, l.Timeout)
+}
+
+// checkContext converts the error of a run whose context ended into a LimitError:
+// a timeout when the deadline passed, or a cancellation by the caller
+func (l Limits) checkContext(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &LimitError{
+			Type:    LimitTimeout,
+			Message: fmt.Sprintf("execution timed out after %s", l.Timeout),
+		}
+	case errors.Is(ctx.Err(), context.Canceled):
+		return CancelledError()
+	}
+	return err
+}
+
+// CancelledError reports an execution stopped because its request was cancelled
+func CancelledError() *LimitError {
+	return &LimitError{Type: LimitCancelled, Message: "execution cancelled"}
+}
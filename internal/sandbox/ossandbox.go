@@ -0,0 +1,256 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// OS sandboxes that can wrap the deno and bun runtime processes
+const (
+	OSSandboxNone        = ""             // Run the runtime directly
+	OSSandboxAuto        = "auto"         // bubblewrap or firejail on Linux, sandbox-exec on macOS
+	OSSandboxBubblewrap  = "bwrap"        // Linux namespaces: only system directories, the library and scratch are visible
+	OSSandboxFirejail    = "firejail"     // Linux: non-system directories hidden except the runtime's paths, read-only except scratch, seccomp
+	OSSandboxSandboxExec = "sandbox-exec" // macOS Seatbelt: reads confined to system directories and the runtime's paths, writes to scratch
+)
+
+// systemDirs are mounted read-only by bubblewrap, and left visible by firejail,
+// so the runtime can load its libraries
+var systemDirs = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc", "/opt", "/nix"}
+
+// firejailKeptDirs are top-level directories firejail never hides: the kernel
+// and device filesystems it sets up itself
+var firejailKeptDirs = []string{"/proc", "/sys", "/dev", "/run", "/tmp"}
+
+// seatbeltSystemDirs are readable under sandbox-exec so the runtime can load
+// the system frameworks and its libraries
+var seatbeltSystemDirs = []string{"/usr", "/bin", "/sbin", "/System", "/Library/Frameworks", "/private/etc", "/private/var/db/timezone", "/dev", "/opt", "/nix"}
+
+// osSandbox wraps runtime commands in a platform sandbox driven by the sandbox policy.
+// It is a middle ground between a raw process, which can read anything the server
+// can, and a container: no image is needed, but the filesystem and network the
+// runtime sees are still restricted by the kernel.
+type osSandbox struct {
+	kind   string
+	path   string
+	policy config.SandboxPolicy
+	libDir string
+}
+
+// newOSSandbox resolves the configured OS sandbox, or returns nil for none
+func newOSSandbox(kind string, policy config.SandboxPolicy, libDir string) (*osSandbox, error) {
+	if kind == OSSandboxNone {
+		return nil, nil
+	}
+
+	if kind == OSSandboxAuto {
+		switch runtime.GOOS {
+		case "linux":
+			kind = OSSandboxBubblewrap
+			if _, err := exec.LookPath(OSSandboxBubblewrap); err != nil {
+				kind = OSSandboxFirejail
+			}
+		case "darwin":
+			kind = OSSandboxSandboxExec
+		default:
+			return nil, fmt.Errorf("no OS sandbox is supported on %s; use the docker runtime for isolation", runtime.GOOS)
+		}
+	}
+
+	wantOS := map[string]string{
+		OSSandboxBubblewrap:  "linux",
+		OSSandboxFirejail:    "linux",
+		OSSandboxSandboxExec: "darwin",
+	}[kind]
+	if wantOS == "" {
+		return nil, fmt.Errorf("unsupported OS sandbox %q", kind)
+	}
+	if wantOS != runtime.GOOS {
+		return nil, fmt.Errorf("OS sandbox %q is not available on %s", kind, runtime.GOOS)
+	}
+
+	path, err := exec.LookPath(kind)
+	if err != nil {
+		return nil, fmt.Errorf("%s executable not found: %w", kind, err)
+	}
+	return &osSandbox{kind: kind, path: path, policy: policy, libDir: libDir}, nil
+}
+
+// wrapFunc returns wrap as a ProcessOptions.Wrap hook, or nil when there is no sandbox
+func (w *osSandbox) wrapFunc() func(command string, args []string, scratchDir string) (string, []string) {
+	if w == nil {
+		return nil
+	}
+	return w.wrap
+}
+
+// wrap returns the command line running command inside the sandbox
+func (w *osSandbox) wrap(command string, args []string, scratchDir string) (string, []string) {
+	readOnly := append(w.readOnlyPaths(command), w.policy.AllowRead...)
+	writable := append([]string{scratchDir}, w.policy.AllowWrite...)
+	network := len(w.policy.AllowNet) > 0
+
+	var wrapperArgs []string
+	switch w.kind {
+	case OSSandboxBubblewrap:
+		wrapperArgs = bubblewrapArgs(readOnly, writable, scratchDir, network)
+	case OSSandboxFirejail:
+		wrapperArgs = firejailArgs(readOnly, writable, topLevelDirs(), network)
+	case OSSandboxSandboxExec:
+		wrapperArgs = []string{"-p", seatbeltProfile(readOnly, writable, network)}
+	}
+	return w.path, append(append(wrapperArgs, command), args...)
+}
+
+// readOnlyPaths lists what the runtime must read besides system directories:
+// its own installation, the runner scripts and the session library
+func (w *osSandbox) readOnlyPaths(command string) []string {
+	var paths []string
+	if resolved, err := filepath.EvalSymlinks(command); err == nil {
+		paths = append(paths, filepath.Dir(resolved))
+	}
+	paths = append(paths, filepath.Dir(command))
+	for _, runner := range []*runnerFile{jsRunner, pythonRunner} {
+		if path, err := runner.ensure(); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	if w.libDir != "" {
+		paths = append(paths, w.libDir)
+	}
+	return paths
+}
+
+// bubblewrapArgs builds a new mount namespace holding only the given paths,
+// with fresh /proc, /dev and /tmp, and no network unless the policy allows some
+// (the bun egress proxy listens on the host's loopback)
+func bubblewrapArgs(readOnly, writable []string, scratchDir string, network bool) []string {
+	args := []string{"--die-with-parent", "--unshare-all"}
+	if network {
+		args = append(args, "--share-net")
+	}
+	for _, dir := range systemDirs {
+		args = append(args, "--ro-bind-try", dir, dir)
+	}
+	args = append(args, "--proc", "/proc", "--dev", "/dev", "--tmpfs", "/tmp")
+	for _, path := range readOnly {
+		args = append(args, "--ro-bind-try", path, path)
+	}
+	for _, path := range writable {
+		flag := "--bind-try"
+		if path == scratchDir {
+			flag = "--bind"
+		}
+		args = append(args, flag, path, path)
+	}
+	return append(args, "--chdir", scratchDir, "--")
+}
+
+// firejailArgs whitelists the paths the runtime needs, which hides the rest of
+// the top-level directories holding them, and hides every other top-level
+// directory outside the system ones (home directories, /srv, /var, ...). The
+// filesystem is read-only except the writable paths, capabilities are dropped
+// and dangerous syscalls filtered.
+func firejailArgs(readOnly, writable, roots []string, network bool) []string {
+	args := []string{"--quiet", "--noprofile", "--nonewprivs", "--caps.drop=all", "--seccomp"}
+
+	whitelisted := map[string]bool{}
+	for _, path := range append(append([]string{}, readOnly...), writable...) {
+		if withinAny(path, systemDirs) {
+			continue
+		}
+		args = append(args, "--whitelist="+path)
+		whitelisted[topLevelDir(path)] = true
+	}
+	for _, root := range roots {
+		switch {
+		case whitelisted[root], withinAny(root, systemDirs):
+		case root == "/tmp":
+			args = append(args, "--private-tmp")
+		case !withinAny(root, firejailKeptDirs):
+			args = append(args, "--blacklist="+root)
+		}
+	}
+
+	args = append(args, "--read-only=/")
+	for _, path := range writable {
+		args = append(args, "--read-write="+path)
+	}
+	if !network {
+		args = append(args, "--net=none")
+	}
+	return append(args, "--")
+}
+
+// seatbeltProfile allows reads only from system directories and the given
+// paths, writes only to the writable paths and, unless the policy allows some,
+// denies all network access. Metadata stays readable everywhere so the runtime
+// can resolve paths through directories it cannot list.
+func seatbeltProfile(readOnly, writable []string, network bool) string {
+	var b strings.Builder
+	b.WriteString("(version 1)\n(allow default)\n(deny file-read* file-write*)\n(allow file-read-metadata)\n")
+	b.WriteString(`(allow file-read* (literal "/")`)
+	for _, path := range seatbeltSystemDirs {
+		fmt.Fprintf(&b, " (subpath %q)", path)
+	}
+	for _, path := range append(append([]string{}, readOnly...), writable...) {
+		fmt.Fprintf(&b, " (subpath %q)", realPath(path))
+	}
+	b.WriteString(")\n")
+	b.WriteString(`(allow file-write* (literal "/dev/null") (regex #"^/dev/tty")`)
+	for _, path := range writable {
+		fmt.Fprintf(&b, " (subpath %q)", realPath(path))
+	}
+	b.WriteString(")\n")
+	if !network {
+		b.WriteString("(deny network*)\n")
+	}
+	return b.String()
+}
+
+// realPath resolves symlinks in path when it exists. Seatbelt matches real
+// paths, e.g. /private/var/... for a temp directory.
+func realPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// topLevelDirs lists the directories in /
+func topLevelDirs() []string {
+	entries, err := os.ReadDir("/")
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, "/"+entry.Name())
+		}
+	}
+	return dirs
+}
+
+// topLevelDir returns the directory in / that holds path
+func topLevelDir(path string) string {
+	first, _, _ := strings.Cut(strings.TrimPrefix(filepath.Clean(path), "/"), "/")
+	return "/" + first
+}
+
+// withinAny reports whether path is one of dirs or inside one of them
+func withinAny(path string, dirs []string) bool {
+	path = filepath.Clean(path)
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
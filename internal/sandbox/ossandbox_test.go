@@ -0,0 +1,69 @@
+package sandbox
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrappedCommandMountsRuntimeDirWithLimits(t *testing.T) {
+	runtimeDir := filepath.Join(t.TempDir(), ".deno", "bin")
+	wrapper := &osSandbox{kind: OSSandboxBubblewrap, path: "/usr/bin/bwrap"}
+	s := &ProcessSandbox{
+		command: filepath.Join(runtimeDir, "deno"),
+		args:    []string{"run"},
+		limits:  Limits{CPUTime: 5 * time.Second, MemoryMB: 128},
+		wrap:    wrapper.wrap,
+	}
+
+	scratch := t.TempDir()
+	_, args := s.commandLine(scratch, "runner.mjs")
+	line := strings.Join(args, " ")
+	if bind := "--ro-bind-try " + runtimeDir + " " + runtimeDir; !strings.Contains(line, bind) {
+		t.Errorf("expected the runtime's directory mounted (%s):\n%s", bind, line)
+	}
+	if !strings.HasSuffix(line, "-- "+s.command+" run runner.mjs") {
+		t.Errorf("expected the sandbox to run the runtime itself:\n%s", line)
+	}
+}
+
+func TestFirejailArgsHideNonSystemDirectories(t *testing.T) {
+	readOnly := []string{"/root/.deno/bin", "/usr/local/lib/codebraid"}
+	writable := []string{"/tmp/codebraid-scratch"}
+	roots := []string{"/bin", "/etc", "/home", "/proc", "/root", "/srv", "/tmp", "/usr", "/var"}
+	args := firejailArgs(readOnly, writable, roots, false)
+
+	for _, want := range []string{"--whitelist=/root/.deno/bin", "--whitelist=/tmp/codebraid-scratch", "--blacklist=/home", "--blacklist=/srv", "--blacklist=/var", "--read-write=/tmp/codebraid-scratch", "--net=none"} {
+		if !slices.Contains(args, want) {
+			t.Errorf("expected %s in %v", want, args)
+		}
+	}
+	for _, unwanted := range []string{"--whitelist=/usr/local/lib/codebraid", "--blacklist=/root", "--blacklist=/tmp", "--private-tmp", "--blacklist=/proc", "--blacklist=/usr"} {
+		if slices.Contains(args, unwanted) {
+			t.Errorf("expected no %s in %v", unwanted, args)
+		}
+	}
+
+	if args := firejailArgs(readOnly, []string{"/srv/scratch"}, roots, false); !slices.Contains(args, "--private-tmp") {
+		t.Errorf("expected a private /tmp when nothing in it is needed, got %v", args)
+	}
+}
+
+func TestSeatbeltProfileConfinesReads(t *testing.T) {
+	profile := seatbeltProfile([]string{"/opt/homebrew/bin", "/Users/dev/.deno/bin"}, []string{"/private/tmp/scratch"}, true)
+	for _, want := range []string{
+		"(deny file-read* file-write*)",
+		`(allow file-read* (literal "/") (subpath "/usr")`,
+		`(subpath "/Users/dev/.deno/bin") (subpath "/private/tmp/scratch"))`,
+		`(allow file-write* (literal "/dev/null") (regex #"^/dev/tty") (subpath "/private/tmp/scratch"))`,
+	} {
+		if !strings.Contains(profile, want) {
+			t.Errorf("expected %s in the profile:\n%s", want, profile)
+		}
+	}
+	if strings.Contains(profile, "(deny network*)") {
+		t.Errorf("expected network allowed:\n%s", profile)
+	}
+}
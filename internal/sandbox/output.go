@@ -0,0 +1,33 @@
+package sandbox
+
+import (
+	"log"
+	"time"
+)
+
+// OutputFunc receives console output from sandboxed code as it is produced.
+// level is the console method that was called ("log", "info", "warn", "error", "debug").
+// It is invoked synchronously from the executing goroutine.
+type OutputFunc func(level, message string)
+
+// emit forwards console output to fn, or to the server log when no callback is set
+func (fn OutputFunc) emit(level, message string) {
+	if fn == nil {
+		log.Printf("[sandbox:%s] %s", level, message)
+		return
+	}
+	fn(level, message)
+}
+
+// ToolCall is a downstream MCP tool call made by sandboxed code
+type ToolCall struct {
+	ServerName string
+	ToolName   string
+	Args       map[string]interface{} // With secret values redacted
+	Duration   time.Duration
+	Error      string // Why the call failed, empty when it succeeded
+}
+
+// ToolCallFunc receives every tool call of a run once it has finished.
+// Calls made concurrently by the code are reported concurrently.
+type ToolCallFunc func(call ToolCall)
@@ -0,0 +1,83 @@
+package sandbox
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// outputsDirName is the directory under the scratch directory collected after each run
+const outputsDirName = "outputs"
+
+// Limits on what one run may hand back through its outputs directory
+const (
+	maxArtifacts     = 64
+	maxArtifactBytes = 32 << 20 // Total size of a run's artifacts
+)
+
+// Artifact is a file a run wrote to its outputs directory
+type Artifact struct {
+	Name string // Slash-separated path relative to the outputs directory
+	Data []byte
+}
+
+// resetOutputs empties the outputs directory under scratch so that a reused
+// process starts every run without the previous run's files
+func resetOutputs(scratch string) error {
+	dir := filepath.Join(scratch, outputsDirName)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear outputs directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create outputs directory: %w", err)
+	}
+	return nil
+}
+
+// collectOutputs reads the regular files written under scratch/outputs and
+// resets the directory. Symlinks are skipped so a script cannot point outside it.
+func collectOutputs(scratch string) ([]Artifact, error) {
+	dir := filepath.Join(scratch, outputsDirName)
+
+	var artifacts []Artifact
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		if len(artifacts) == maxArtifacts || total > maxArtifactBytes {
+			return fmt.Errorf("outputs exceed the limit of %d files or %d MB", maxArtifacts, maxArtifactBytes>>20)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, Artifact{Name: filepath.ToSlash(rel), Data: data})
+		return nil
+	})
+	if resetErr := resetOutputs(scratch); err == nil {
+		err = resetErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect outputs: %w", err)
+	}
+	return artifacts, nil
+}
@@ -0,0 +1,241 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// PoolOptions configures a warm process pool
+type PoolOptions struct {
+	Size        int           // Started processes kept ready for new executions
+	IdleTTL     time.Duration // Idle processes older than this are stopped (zero keeps them)
+	PinSessions bool          // Keep the process a session used for that session's next run
+}
+
+// Pool keeps runtime processes started ahead of time so executions skip interpreter startup.
+// A warm process serves one execution; afterwards it is stopped, or with PinSessions kept
+// for later runs of the same session only, so no state crosses sessions.
+//
+// Warm processes are started before any session exists, so they get no access to a
+// session's library directory; bundles are self-contained and do not need it.
+type Pool struct {
+	template *ProcessSandbox
+	opts     PoolOptions
+
+	mu     sync.Mutex
+	warm   []*pooledProcess
+	pinned map[string]*pooledProcess // Keyed by session ID
+	closed bool
+
+	refill chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// pooledProcess is an idle process and when it became idle
+type pooledProcess struct {
+	proc      *runnerProcess
+	idleSince time.Time
+}
+
+// NewPool creates a pool of processes for a process runtime (deno, bun or docker).
+// opts.LibDir and opts.ClientHub are ignored; executors supply them per run.
+func NewPool(opts Options, poolOpts PoolOptions) (*Pool, error) {
+	opts.LibDir = ""
+	opts.Persistent = false
+
+	var template *ProcessSandbox
+	var err error
+	switch opts.Runtime {
+	case RuntimeDeno:
+		template, err = NewDenoSandbox(context.Background(), opts)
+	case RuntimeBun:
+		template, err = NewBunSandbox(context.Background(), opts)
+	case RuntimeDocker:
+		template, err = NewDockerSandbox(context.Background(), opts)
+	default:
+		return nil, fmt.Errorf("sandbox runtime %q does not run in processes and cannot be pooled", opts.Runtime)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pool{
+		template: template,
+		opts:     poolOpts,
+		pinned:   map[string]*pooledProcess{},
+		refill:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.maintain()
+	p.signalRefill()
+	return p, nil
+}
+
+// acquire returns the process pinned to a session, a warm process, or a newly started one
+func (p *Pool) acquire(sessionID string) (*runnerProcess, error) {
+	p.mu.Lock()
+	if pinned, ok := p.pinned[sessionID]; ok && sessionID != "" {
+		delete(p.pinned, sessionID)
+		p.mu.Unlock()
+		return pinned.proc, nil
+	}
+	var warm *pooledProcess
+	if n := len(p.warm); n > 0 {
+		warm = p.warm[n-1]
+		p.warm = p.warm[:n-1]
+	}
+	p.mu.Unlock()
+
+	p.signalRefill()
+	if warm != nil {
+		return warm.proc, nil
+	}
+	return p.template.start()
+}
+
+// release hands back a process after a successful run.
+// It is kept for the session's next run when pinning is on, and stopped otherwise.
+func (p *Pool) release(sessionID string, proc *runnerProcess) {
+	p.mu.Lock()
+	if !p.opts.PinSessions || sessionID == "" || p.closed {
+		p.mu.Unlock()
+		proc.stop()
+		return
+	}
+	previous := p.pinned[sessionID]
+	p.pinned[sessionID] = &pooledProcess{proc: proc, idleSince: time.Now()}
+	p.mu.Unlock()
+
+	if previous != nil {
+		previous.proc.stop()
+	}
+}
+
+// ReleaseSession stops the process pinned to a session, if any
+func (p *Pool) ReleaseSession(sessionID string) {
+	p.mu.Lock()
+	pinned := p.pinned[sessionID]
+	delete(p.pinned, sessionID)
+	p.mu.Unlock()
+
+	if pinned != nil {
+		pinned.proc.stop()
+	}
+}
+
+// Close stops every pooled process
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.warm
+	for _, pinned := range p.pinned {
+		idle = append(idle, pinned)
+	}
+	p.warm, p.pinned = nil, map[string]*pooledProcess{}
+	p.mu.Unlock()
+
+	close(p.done)
+	p.wg.Wait()
+	for _, pp := range idle {
+		pp.proc.stop()
+	}
+}
+
+// signalRefill asks the maintenance loop to top the pool up
+func (p *Pool) signalRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// maintain keeps Size warm processes ready and stops processes idle past IdleTTL
+func (p *Pool) maintain() {
+	defer p.wg.Done()
+
+	interval := time.Minute
+	if p.opts.IdleTTL > 0 && p.opts.IdleTTL/2 < interval {
+		interval = max(p.opts.IdleTTL/2, time.Second)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.expire()
+		case <-p.refill:
+		}
+		p.fill()
+	}
+}
+
+// expire stops idle processes older than IdleTTL
+func (p *Pool) expire() {
+	if p.opts.IdleTTL <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-p.opts.IdleTTL)
+
+	var expired []*pooledProcess
+	p.mu.Lock()
+	fresh := p.warm[:0]
+	for _, pp := range p.warm {
+		if pp.idleSince.Before(cutoff) {
+			expired = append(expired, pp)
+		} else {
+			fresh = append(fresh, pp)
+		}
+	}
+	p.warm = fresh
+	for sessionID, pp := range p.pinned {
+		if pp.idleSince.Before(cutoff) {
+			expired = append(expired, pp)
+			delete(p.pinned, sessionID)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, pp := range expired {
+		pp.proc.stop()
+	}
+}
+
+// fill starts processes until Size are warm
+func (p *Pool) fill() {
+	for {
+		p.mu.Lock()
+		need := !p.closed && len(p.warm) < p.opts.Size
+		p.mu.Unlock()
+		if !need {
+			return
+		}
+
+		proc, err := p.template.start()
+		if err != nil {
+			log.Printf("Sandbox pool: failed to start %s process: %v", p.template.name, err)
+			return
+		}
+
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			proc.stop()
+			return
+		}
+		p.warm = append(p.warm, &pooledProcess{proc: proc, idleSince: time.Now()})
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !unix
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+)
+
+// configureProcessTree is a no-op where process groups are unavailable;
+// killProcessTree stops the direct child only
+func configureProcessTree(cmd *exec.Cmd) {}
+
+// killProcessTree kills the runtime process
+func killProcessTree(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// killedByCPULimit is always false where RLIMIT_CPU is unavailable
+func killedByCPULimit(state *os.ProcessState) bool {
+	return false
+}
+
+// killedByOOM is always false where signals are unavailable
+func killedByOOM(state *os.ProcessState) bool {
+	return false
+}
+
+// ProcessAlive cannot tell where signals are unavailable, so it assumes the
+// process is running
+func ProcessAlive(pid int) bool {
+	return true
+}
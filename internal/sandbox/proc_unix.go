@@ -0,0 +1,42 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// configureProcessTree starts the runtime in its own process group so that
+// killProcessTree stops every process it spawned, not just the direct child
+func configureProcessTree(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessTree sends SIGKILL to the runtime's whole process group
+func killProcessTree(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// killedByCPULimit reports whether the kernel stopped the process for exceeding RLIMIT_CPU
+func killedByCPULimit(state *os.ProcessState) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGXCPU
+}
+
+// killedByOOM reports whether the process was killed with SIGKILL, which the
+// kernel sends when memory runs out, as the runtimes never get it otherwise
+func killedByOOM(state *os.ProcessState) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
+
+// ProcessAlive reports whether a process with the given ID is running
+func ProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,580 @@
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/client"
+)
+
+// Languages of the scripts a runner executes
+const (
+	LanguageJavaScript = "javascript" // Bundles run by runner.mjs
+	LanguagePython     = "python"     // Scripts run by runner.py
+)
+
+var (
+	//go:embed runner.mjs
+	runnerScript string
+
+	//go:embed runner.py
+	pythonRunnerScript string
+)
+
+// runnerFile is an embedded runner script, written to a content-addressed file
+// in a private directory once per process
+type runnerFile struct {
+	script string
+	ext    string
+
+	once sync.Once
+	path string
+	err  error
+}
+
+var (
+	jsRunner     = &runnerFile{script: runnerScript, ext: ".mjs"}
+	pythonRunner = &runnerFile{script: pythonRunnerScript, ext: ".py"}
+)
+
+// ensureRunner writes the JavaScript runner script
+func ensureRunner() (string, error) {
+	return jsRunner.ensure()
+}
+
+// ensure writes the script unless an identical copy exists and returns its path.
+// The directory is private to the user and the file is replaced by a rename,
+// never written through, so other users cannot plant or redirect the runner
+// every execution runs.
+func (r *runnerFile) ensure() (string, error) {
+	r.once.Do(func() {
+		dir, err := runnerDir()
+		if err != nil {
+			r.err = fmt.Errorf("failed to create runner directory: %w", err)
+			return
+		}
+		sum := sha256.Sum256([]byte(r.script))
+		path := filepath.Join(dir, "runner-"+hex.EncodeToString(sum[:8])+r.ext)
+		if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
+			if existing, err := os.ReadFile(path); err == nil && string(existing) == r.script {
+				r.path = path
+				return
+			}
+		}
+
+		// Created exclusively under a random name, then moved into place
+		tmp, err := os.CreateTemp(dir, "runner-*.tmp")
+		if err != nil {
+			r.err = fmt.Errorf("failed to write runner script: %w", err)
+			return
+		}
+		defer os.Remove(tmp.Name())
+		_, err = tmp.WriteString(r.script)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			// Readable by the container user of the docker runtime
+			err = os.Chmod(tmp.Name(), 0o644)
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			r.err = fmt.Errorf("failed to write runner script: %w", err)
+			return
+		}
+		r.path = path
+	})
+	return r.path, r.err
+}
+
+// runnerDir returns the directory the runner scripts are written to: a 0700
+// directory in the user cache directory, or a fresh temporary one without it
+func runnerDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return os.MkdirTemp("", "codebraid-runner-*")
+	}
+	dir := filepath.Join(base, "codebraid", "runners")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		if err := os.Chmod(dir, 0o700); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// runnerMessage is a line of the runner's stdio protocol (see runner.mjs)
+type runnerMessage struct {
+	Type    string       `json:"type"`
+	ID      int          `json:"id,omitempty"`
+	Call    *McpToolCall `json:"call,omitempty"`
+	Level   string       `json:"level,omitempty"`
+	Message string       `json:"message,omitempty"`
+	Output  string       `json:"output,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Stack   string       `json:"stack,omitempty"`
+}
+
+// runnerResponse answers a runner "call" message
+type runnerResponse struct {
+	ID int `json:"id"`
+	McpToolResponse
+}
+
+// ProcessOptions configures a process-backed sandbox
+type ProcessOptions struct {
+	Name    string   // Runtime name used in logs and errors
+	Command string   // Runtime executable, resolved via PATH
+	Args    []string // Arguments placed before the runner script path
+	Limits  Limits
+
+	// Language selects the runner script and the prelude binding each run's
+	// input and secrets (LanguageJavaScript when empty)
+	Language string
+
+	// SkipRlimits leaves memory and CPU enforcement to the runtime itself
+	// (e.g., a container engine) instead of applying rlimits to the child
+	SkipRlimits bool
+
+	// ScratchRoot is the parent of the per-execution working directories
+	ScratchRoot string
+
+	// PermissionArgs, when set, returns extra arguments for an execution's
+	// scratch directory (e.g., runtime permission flags that must name it)
+	PermissionArgs func(scratchDir string) []string
+
+	// Wrap, when set, returns the command line running the runtime inside an
+	// OS sandbox. Resource limits are set around the wrapper, which passes them
+	// on to the runtime it starts.
+	Wrap func(command string, args []string, scratchDir string) (string, []string)
+
+	// Env is the environment of the runtime process, on top of the scratch settings.
+	// HostEnv passes the full host environment instead, for container clients
+	// that need it themselves and hand Env on to the container.
+	Env     []string
+	HostEnv bool
+
+	// Persistent keeps one runtime process alive across ExecuteCode calls,
+	// so globals and module state survive between runs
+	Persistent bool
+
+	// Pool supplies started processes; SessionID identifies the owner for pinning
+	Pool      *Pool
+	SessionID string
+
+	// Terminate, when set, is called after a run that did not exit on its own,
+	// for runtimes whose work outlives the client process (e.g., containers)
+	Terminate func(scratchDir string)
+
+	// Egress, when set, routes the runtime's HTTP(S) traffic through a filtering
+	// proxy. Used for runtimes that cannot enforce network permissions themselves.
+	Egress *EgressPolicy
+}
+
+// ProcessSandbox executes user code in an external JavaScript runtime process.
+// By default each ExecuteCode call starts a fresh process running the embedded
+// runner script, which receives the bundle on stdin and proxies tool calls back
+// over stdout. With a Pool, processes are taken from it instead of started per call.
+// In persistent mode one process serves every run until it fails.
+// A ProcessSandbox runs one execution at a time.
+type ProcessSandbox struct {
+	name        string
+	command     string
+	args        []string
+	limits      Limits
+	runner      *runnerFile
+	prelude     func(run Run, outputDir string) (Run, error)
+	skipRlimits bool
+	egress      *EgressPolicy
+	scratchRoot string
+	permissions func(scratchDir string) []string
+	wrap        func(command string, args []string, scratchDir string) (string, []string)
+	terminate   func(scratchDir string)
+	env         []string
+	hostEnv     bool
+	persistent  bool
+	pool        *Pool
+	sessionID   string
+	clientHub   *client.McpClientHub
+
+	proc *runnerProcess // Live process in persistent mode
+}
+
+// runnerProcess is a started runtime process speaking the runner protocol
+type runnerProcess struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	encoder   *json.Encoder
+	reader    *bufio.Reader
+	stderr    cappedBuffer
+	scratch   string
+	proxy     *egressProxy
+	terminate func(scratchDir string)
+}
+
+// NewProcessSandbox creates a sandbox that runs "command args... runner.mjs" (or runner.py)
+func NewProcessSandbox(ctx context.Context, opts ProcessOptions, clientHub *client.McpClientHub) (*ProcessSandbox, error) {
+	path, err := exec.LookPath(opts.Command)
+	if err != nil {
+		return nil, fmt.Errorf("%s executable not found: %w", opts.Name, err)
+	}
+
+	runner, prelude := jsRunner, Run.withPrelude
+	switch opts.Language {
+	case "", LanguageJavaScript:
+	case LanguagePython:
+		runner, prelude = pythonRunner, Run.withPythonPrelude
+	default:
+		return nil, fmt.Errorf("unsupported runner language %q", opts.Language)
+	}
+
+	return &ProcessSandbox{
+		name:        opts.Name,
+		command:     path,
+		args:        opts.Args,
+		limits:      opts.Limits,
+		runner:      runner,
+		prelude:     prelude,
+		skipRlimits: opts.SkipRlimits,
+		egress:      opts.Egress,
+		scratchRoot: opts.ScratchRoot,
+		permissions: opts.PermissionArgs,
+		wrap:        opts.Wrap,
+		terminate:   opts.Terminate,
+		env:         opts.Env,
+		hostEnv:     opts.HostEnv,
+		persistent:  opts.Persistent,
+		pool:        opts.Pool,
+		sessionID:   opts.SessionID,
+		clientHub:   clientHub,
+	}, nil
+}
+
+// ExecuteCode executes bundled JavaScript code in the runtime process.
+// The process runs in a private scratch directory that is deleted with it,
+// and on timeout the runtime's whole process tree is killed.
+func (s *ProcessSandbox) ExecuteCode(ctx context.Context, run Run) (Result, error) {
+	run, err := s.prelude(run, outputsDirName)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Hooks have timeouts of their own and don't count against the run's
+	hookCtx := ctx
+	limits := s.limits.forRun(run)
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+
+	proc := s.proc
+	if proc == nil {
+		var err error
+		if s.pool != nil {
+			proc, err = s.pool.acquire(s.sessionID)
+		} else {
+			proc, err = s.start()
+		}
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	if run.Restore != nil {
+		err = restoreScratch(proc.scratch, run.Restore)
+	}
+	if err == nil {
+		err = runHooks(hookCtx, HookPre, s.sessionID, proc.scratch, nil)
+	}
+	if err != nil {
+		if proc != s.proc {
+			proc.stop()
+		}
+		return Result{}, err
+	}
+
+	// Kill the process tree as soon as the run is cancelled or times out;
+	// pending reads then see EOF and the run loop returns
+	stop := context.AfterFunc(ctx, func() {
+		killProcessTree(proc.cmd)
+	})
+
+	// The docker runtime's process is the docker client and a wrapped runtime's is
+	// the OS sandbox, whose usage says nothing about the code
+	measure := !s.skipRlimits && s.wrap == nil
+	var cpuStart time.Duration
+	if measure {
+		resetPeakRSS(proc.cmd.Process.Pid)
+		cpuStart = processCPUTime(proc.cmd.Process.Pid)
+	}
+	result, err := s.run(ctx, proc, run)
+	if err == nil && measure {
+		result.Usage.CPUTime = processCPUTime(proc.cmd.Process.Pid) - cpuStart
+		result.Usage.PeakRSS = processPeakRSS(proc.cmd.Process.Pid)
+	}
+	stop()
+	if err == nil {
+		err = runHooks(hookCtx, HookPost, s.sessionID, proc.scratch, &result)
+	}
+	if err == nil && run.Snapshot {
+		result.Snapshot, err = snapshotScratch(proc.scratch)
+	}
+	if err == nil {
+		result.Artifacts, err = collectOutputs(proc.scratch)
+	}
+
+	s.proc = nil
+	if err == nil && ctx.Err() == nil {
+		switch {
+		case s.persistent:
+			s.proc = proc
+		case s.pool != nil:
+			s.pool.release(s.sessionID, proc)
+		default:
+			proc.stop()
+		}
+		return result, nil
+	}
+
+	proc.stop()
+	if err != nil {
+		return Result{}, limits.checkContext(ctx, err)
+	}
+	return result, nil
+}
+
+// SnapshotScratch implements ScratchState for the persistent process
+func (s *ProcessSandbox) SnapshotScratch() ([]byte, error) {
+	if s.proc == nil {
+		return nil, nil
+	}
+	return snapshotScratch(s.proc.scratch)
+}
+
+// ScratchDir implements ScratchState
+func (s *ProcessSandbox) ScratchDir() string {
+	if s.proc == nil {
+		return ""
+	}
+	return s.proc.scratch
+}
+
+// RestoreScratch implements ScratchState, starting the persistent process if
+// it is not running yet
+func (s *ProcessSandbox) RestoreScratch(snapshot []byte) error {
+	if !s.persistent {
+		return fmt.Errorf("%s sandbox is not persistent", s.name)
+	}
+	if s.proc == nil {
+		var proc *runnerProcess
+		var err error
+		if s.pool != nil {
+			proc, err = s.pool.acquire(s.sessionID)
+		} else {
+			proc, err = s.start()
+		}
+		if err != nil {
+			return err
+		}
+		s.proc = proc
+	}
+	return restoreScratch(s.proc.scratch, snapshot)
+}
+
+// commandLine returns the command line running the runner script in scratch.
+// The OS sandbox wraps the runtime itself, so it mounts the runtime's own
+// directory; the limits are then set by a shell that execs the sandbox, and the
+// runtime inherits them from its first instruction.
+func (s *ProcessSandbox) commandLine(scratch, runner string) (string, []string) {
+	args := append([]string{}, s.args...)
+	if s.permissions != nil {
+		args = append(args, s.permissions(scratch)...)
+	}
+	args = append(args, runner)
+
+	command := s.command
+	if s.wrap != nil {
+		command, args = s.wrap(command, args, scratch)
+	}
+	if !s.skipRlimits {
+		command, args = rlimitCommand(s.limits, command, args)
+	}
+	return command, args
+}
+
+// start launches a runtime process in a new scratch directory
+func (s *ProcessSandbox) start() (*runnerProcess, error) {
+	runner, err := s.runner.ensure()
+	if err != nil {
+		return nil, err
+	}
+
+	scratch, err := newScratchDir(s.scratchRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resetOutputs(scratch); err != nil {
+		os.RemoveAll(scratch)
+		return nil, err
+	}
+
+	proc := &runnerProcess{scratch: scratch, terminate: s.terminate, stderr: cappedBuffer{max: 64 << 10}}
+
+	command, args := s.commandLine(scratch, runner)
+	cmd := exec.Command(command, args...)
+	cmd.Dir = scratch
+	cmd.Env = append([]string{}, s.env...)
+	if s.hostEnv {
+		cmd.Env = append(os.Environ(), s.env...)
+	}
+	cmd.Env = append(cmd.Env, scratchEnv(scratch)...)
+	configureProcessTree(cmd)
+	cmd.WaitDelay = time.Second // Don't block on pipes held open by orphaned grandchildren
+	cmd.Stderr = &proc.stderr
+	proc.cmd = cmd
+
+	if s.egress != nil {
+		if proc.proxy, err = startEgressProxy(s.egress); err != nil {
+			os.RemoveAll(scratch)
+			return nil, err
+		}
+		cmd.Env = append(cmd.Env, proc.proxy.Env()...)
+	}
+
+	if proc.stdin, err = cmd.StdinPipe(); err != nil {
+		proc.stop()
+		return nil, fmt.Errorf("failed to open %s stdin: %w", s.name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		proc.stop()
+		return nil, fmt.Errorf("failed to open %s stdout: %w", s.name, err)
+	}
+	proc.encoder = json.NewEncoder(proc.stdin)
+	proc.reader = bufio.NewReader(stdout)
+
+	if err := cmd.Start(); err != nil {
+		proc.stop()
+		return nil, fmt.Errorf("failed to start %s: %w", s.name, err)
+	}
+
+	return proc, nil
+}
+
+// run sends one bundle to the runner and services the protocol until its result arrives
+func (s *ProcessSandbox) run(ctx context.Context, proc *runnerProcess, run Run) (Result, error) {
+	if err := proc.encoder.Encode(map[string]string{"type": "start", "code": run.Code}); err != nil {
+		return Result{}, fmt.Errorf("failed to send code to %s: %w", s.name, err)
+	}
+
+	for {
+		line, readErr := readMessage(proc.reader, s.limits.messageBytes())
+		if len(bytes.TrimSpace(line)) > 0 {
+			var msg runnerMessage
+			if err := json.Unmarshal(line, &msg); err != nil {
+				return Result{}, fmt.Errorf("invalid message from %s runner: %w", s.name, err)
+			}
+
+			switch msg.Type {
+			case "call":
+				response := McpToolResponse{Success: false, Error: "Invalid tool call format"}
+				if msg.Call != nil {
+					log.Printf("[sandbox:%s] Calling MCP tool: %s.%s", s.name, msg.Call.ServerName, msg.Call.ToolName)
+					if msg.Call.Args == nil {
+						msg.Call.Args = map[string]interface{}{}
+					}
+					response = callMcpTool(ctx, s.clientHub, *msg.Call)
+				}
+				if err := proc.encoder.Encode(runnerResponse{ID: msg.ID, McpToolResponse: response}); err != nil {
+					return Result{}, fmt.Errorf("failed to send tool result to %s: %w", s.name, err)
+				}
+			case "log":
+				run.OnOutput.emit(msg.Level, msg.Message)
+			case "result":
+				return Result{Output: msg.Output}, nil
+			case "error":
+				return formatExecutionError(msg.Error, msg.Stack, run.SourceMap)
+			}
+		}
+
+		if readErr != nil {
+			var limitErr *LimitError
+			if errors.As(readErr, &limitErr) {
+				return Result{}, limitErr
+			}
+			if !errors.Is(readErr, io.EOF) {
+				return Result{}, fmt.Errorf("failed to read from %s: %w", s.name, readErr)
+			}
+			break
+		}
+	}
+
+	// The runner exited without reporting a result
+	waitErr := proc.cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return Result{}, fmt.Errorf("%s execution aborted: %w", s.name, ctxErr)
+	}
+	detail := strings.TrimSpace(proc.stderr.String())
+	if limitErr := s.limits.processViolation(proc.cmd.ProcessState, detail); limitErr != nil {
+		return Result{}, limitErr
+	}
+	if waitErr != nil {
+		return Result{}, fmt.Errorf("%s exited without a result: %w\n%s", s.name, waitErr, detail)
+	}
+	return Result{}, fmt.Errorf("%s exited without a result\n%s", s.name, detail)
+}
+
+// stop kills the process tree if it is still running and removes its scratch directory
+func (p *runnerProcess) stop() {
+	if p.stdin != nil {
+		p.stdin.Close()
+	}
+
+	exited := p.cmd.ProcessState != nil && p.cmd.ProcessState.Exited()
+	if p.cmd.Process != nil && p.cmd.ProcessState == nil {
+		killProcessTree(p.cmd)
+		p.cmd.Wait()
+	}
+	if !exited && p.terminate != nil {
+		p.terminate(p.scratch)
+	}
+
+	if p.proxy != nil {
+		p.proxy.Close()
+	}
+	os.RemoveAll(p.scratch)
+}
+
+// Close stops the persistent runtime process, if any
+func (s *ProcessSandbox) Close() {
+	if s.proc != nil {
+		s.proc.stop()
+		s.proc = nil
+	}
+}
@@ -0,0 +1,208 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// PythonLibDir is the directory of the session bundle holding the generated
+// Python "servers" package; it is put on the scripts' PYTHONPATH
+const PythonLibDir = "python"
+
+// NewPythonSandbox creates a sandbox that runs Python scripts with runner.py.
+// Python has no permission system, so as with bun its HTTP clients go through
+// the egress proxy (honoured by urllib, requests and httpx), which denies
+// everything since allowNet can't be enforced for raw sockets, and the
+// filesystem is only isolated by the scratch directory and HOME redirection,
+// unless an OS sandbox is configured. Without one it only runs when
+// opts.Unsandboxed says so.
+func NewPythonSandbox(ctx context.Context, opts Options) (*ProcessSandbox, error) {
+	if opts.OSSandbox == OSSandboxNone && !opts.Unsandboxed {
+		return nil, fmt.Errorf("python is not sandboxed without an OS sandbox (set sandbox.osSandbox, or sandbox.allowUnsandboxed)")
+	}
+	if len(opts.Policy.AllowNet) > 0 {
+		return nil, fmt.Errorf("python can't enforce allowNet: only its HTTP clients use the egress proxy")
+	}
+	egress, err := ParseEgressPolicy(opts.Policy.AllowNet)
+	if err != nil {
+		return nil, err
+	}
+
+	python := opts.Python
+	if python.UV && len(python.Packages) > 0 {
+		// Installed on the host: the sandboxed script has no network and can't
+		// write to the uv cache
+		if python.Venv, err = installPythonPackages(ctx, python); err != nil {
+			return nil, err
+		}
+	}
+	command, args, err := pythonCommand(python)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := opts.Policy
+	if prefix := pythonPrefix(command); prefix != "" {
+		// The standard library lives beside the interpreter's bin directory
+		policy.AllowRead = append(append([]string{}, policy.AllowRead...), prefix)
+	}
+	env := sandboxEnv(opts.Policy.AllowEnv, opts.Env)
+	env = append(env,
+		"PYTHONDONTWRITEBYTECODE=1",
+		"PYTHONNOUSERSITE=1",
+		"PYTHONIOENCODING=utf-8",
+	)
+	if opts.LibDir != "" {
+		env = append(env, "PYTHONPATH="+filepath.Join(opts.LibDir, PythonLibDir))
+	}
+	if venv := python.Venv; venv != "" {
+		env = append(env, "VIRTUAL_ENV="+venv)
+		policy.AllowRead = append(append([]string{}, policy.AllowRead...), venv)
+	}
+
+	wrapper, err := newOSSandbox(opts.OSSandbox, policy, opts.LibDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProcessSandbox(ctx, ProcessOptions{
+		Name:        RuntimePython,
+		Command:     command,
+		Args:        args,
+		Language:    LanguagePython,
+		Limits:      opts.Limits,
+		Env:         env,
+		Persistent:  opts.Persistent,
+		SessionID:   opts.SessionID,
+		Egress:      egress,
+		ScratchRoot: opts.Policy.ScratchDir,
+		Wrap:        wrapper.wrapFunc(),
+	}, opts.ClientHub)
+}
+
+// pythonCommand returns the command line running a script with the configured
+// interpreter: the venv's, or Command
+func pythonCommand(cfg config.PythonConfig) (string, []string, error) {
+	interpreter, err := pythonInterpreter(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	// Skip the user's site-packages and keep console output unbuffered
+	return interpreter, []string{"-s", "-u"}, nil
+}
+
+// pythonInterpreter returns the venv's interpreter, or Command
+func pythonInterpreter(cfg config.PythonConfig) (string, error) {
+	if cfg.Venv == "" {
+		return cfg.Command, nil
+	}
+	interpreter := venvPython(cfg.Venv)
+	if _, err := os.Stat(interpreter); err != nil {
+		return "", fmt.Errorf("python venv %s has no interpreter: %w", cfg.Venv, err)
+	}
+	return interpreter, nil
+}
+
+// venvPython returns the path of a venv's interpreter
+func venvPython(venv string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(venv, "Scripts", "python.exe")
+	}
+	return filepath.Join(venv, "bin", "python")
+}
+
+// pythonPrefix returns the installation prefix of interpreter, the parent of
+// the bin directory its symlinks resolve to, or "" when it can't be found
+func pythonPrefix(interpreter string) string {
+	path, err := exec.LookPath(interpreter)
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return filepath.Dir(filepath.Dir(path))
+}
+
+// pythonVenvMu serializes installs, so sessions starting together share one
+var pythonVenvMu sync.Mutex
+
+// installPythonPackages installs cfg.Packages with uv into a venv in the cache
+// directory and returns it. The venv is kept for the interpreter and package
+// list, so later sessions and restarts reuse it. It is built under a temporary
+// name and moved into place when complete; scripts only run its interpreter,
+// which finds its packages relative to the venv wherever it is.
+func installPythonPackages(ctx context.Context, cfg config.PythonConfig) (string, error) {
+	uv, err := exec.LookPath("uv")
+	if err != nil {
+		return "", fmt.Errorf("uv executable not found: %w", err)
+	}
+	interpreter, err := pythonInterpreter(cfg)
+	if err != nil {
+		return "", err
+	}
+	if interpreter, err = exec.LookPath(interpreter); err != nil {
+		return "", fmt.Errorf("python interpreter not found: %w", err)
+	}
+
+	cacheDir := pythonCacheDir(cfg)
+	sum := sha256.Sum256([]byte(interpreter + "\n" + strings.Join(cfg.Packages, "\n")))
+	venv := filepath.Join(cacheDir, "venvs", hex.EncodeToString(sum[:8]))
+
+	pythonVenvMu.Lock()
+	defer pythonVenvMu.Unlock()
+	if _, err := os.Stat(filepath.Join(venv, "pyvenv.cfg")); err == nil {
+		return venv, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(venv), 0755); err != nil {
+		return "", fmt.Errorf("failed to create python venv directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(filepath.Dir(venv), "venv-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create python venv directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "venv")
+	steps := [][]string{
+		{"venv", "--quiet", "--python", interpreter, tmp},
+		append([]string{"pip", "install", "--quiet", "--python", venvPython(tmp)}, cfg.Packages...),
+	}
+	for _, step := range steps {
+		cmd := exec.CommandContext(ctx, uv, step...)
+		cmd.Env = append(os.Environ(), "UV_CACHE_DIR="+cacheDir)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("uv %s failed: %w: %s", step[0], err, strings.TrimSpace(string(output)))
+		}
+	}
+	if err := os.Rename(tmp, venv); err != nil {
+		// Another server may have installed the same venv meanwhile
+		if _, statErr := os.Stat(filepath.Join(venv, "pyvenv.cfg")); statErr != nil {
+			return "", fmt.Errorf("failed to install python venv: %w", err)
+		}
+	}
+	return venv, nil
+}
+
+// pythonCacheDir returns the uv cache directory, which also holds the venvs
+// installPythonPackages builds
+func pythonCacheDir(cfg config.PythonConfig) string {
+	if cfg.CacheDir != "" {
+		return cfg.CacheDir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "codebraid", "uv")
+	}
+	return filepath.Join(os.TempDir(), "codebraid-uv")
+}
@@ -0,0 +1,94 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+func TestPythonRejectsAllowNet(t *testing.T) {
+	opts := Options{Unsandboxed: true, Policy: config.SandboxPolicy{AllowNet: []string{"api.github.com:443"}}}
+	if _, err := NewPythonSandbox(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "allowNet") {
+		t.Errorf("expected allowNet refused for python, got %v", err)
+	}
+}
+
+// writeScript writes an executable shell script to dir/name
+func writeScript(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPythonInstallsUVPackagesOutsideTheOSSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the fake bubblewrap needs linux")
+	}
+	out, err := exec.Command("python3", "-c", "import sys; print(sys.executable)").Output()
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	python := strings.TrimSpace(string(out))
+
+	// The fake uv makes a real venv, then installs each package as a module
+	// defining NAME; the fake bubblewrap records its arguments and runs the command
+	bin, logs := t.TempDir(), t.TempDir()
+	writeScript(t, bin, "uv", `echo "$@" >> "$UV_LOG"
+case "$1" in
+venv) "$4" -m venv --without-pip "$5" ;;
+pip) site=$("$5" -c 'import sysconfig; print(sysconfig.get_path("purelib"))')
+     shift 5; for pkg; do echo "NAME = '$pkg'" > "$site/$pkg.py"; done ;;
+esac
+`)
+	// The runtime gets none of the host's environment, so the log is named inline
+	writeScript(t, bin, "bwrap", `echo "$@" > `+filepath.Join(logs, "bwrap")+`
+while [ "$1" != "--" ]; do shift; done
+shift
+exec "$@"
+`)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("UV_LOG", filepath.Join(logs, "uv"))
+
+	cacheDir := t.TempDir()
+	opts := Options{
+		OSSandbox: OSSandboxBubblewrap,
+		Python:    config.PythonConfig{Command: python, UV: true, Packages: []string{"fakepkg"}, CacheDir: cacheDir},
+	}
+	for i := 0; i < 2; i++ {
+		sb, err := NewPythonSandbox(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := sb.ExecuteCode(context.Background(), Run{Code: "import fakepkg\nfakepkg.NAME"})
+		sb.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Failed || !strings.Contains(result.Output, "fakepkg") {
+			t.Fatalf("expected the installed package imported, got %+v", result)
+		}
+	}
+
+	uvLog, _ := os.ReadFile(filepath.Join(logs, "uv"))
+	if installs := strings.Count(string(uvLog), "pip install"); installs != 1 {
+		t.Errorf("expected the packages installed once and reused, got %d installs:\n%s", installs, uvLog)
+	}
+	bwrapLog, _ := os.ReadFile(filepath.Join(logs, "bwrap"))
+	venvs, _ := filepath.Glob(filepath.Join(cacheDir, "venvs", "*"))
+	if len(venvs) != 1 {
+		t.Fatalf("expected one venv in the cache, got %v", venvs)
+	}
+	if bind := "--ro-bind-try " + venvs[0] + " " + venvs[0]; !strings.Contains(string(bwrapLog), bind) {
+		t.Errorf("expected the venv mounted read-only (%s):\n%s", bind, bwrapLog)
+	}
+	if strings.Contains(string(bwrapLog), "uv ") || strings.Contains(string(bwrapLog), "--share-net") {
+		t.Errorf("expected the sandbox to run the venv's python without network:\n%s", bwrapLog)
+	}
+}
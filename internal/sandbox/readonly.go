@@ -0,0 +1,53 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/client"
+)
+
+// readOnlyKey marks the context of a run that may only call read-only tools
+type readOnlyKey struct{}
+
+// isReadOnly reports whether ctx belongs to a read-only run
+func isReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
+
+// IsReadOnlyTool reports whether a tool is annotated as not modifying its
+// environment. Tools without annotations may modify it, as the MCP spec defaults
+// readOnlyHint to false.
+func IsReadOnlyTool(tool *mcp.Tool) bool {
+	return tool.Annotations != nil && tool.Annotations.ReadOnlyHint
+}
+
+// ReadOnlyTools returns the read-only tools of tools
+func ReadOnlyTools(tools []*mcp.Tool) []*mcp.Tool {
+	readOnly := make([]*mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if IsReadOnlyTool(tool) {
+			readOnly = append(readOnly, tool)
+		}
+	}
+	return readOnly
+}
+
+// checkReadOnly refuses a call to a tool that is not read-only
+func checkReadOnly(clientHub *client.McpClientHub, serverName, toolName string) error {
+	tools, ok := clientHub.ServerTools(serverName)
+	if !ok {
+		return fmt.Errorf("server %q not found", serverName)
+	}
+	for _, tool := range tools {
+		if tool.Name == toolName {
+			if IsReadOnlyTool(tool) {
+				return nil
+			}
+			return fmt.Errorf("tool %s.%s is not read-only and this session is read-only", serverName, toolName)
+		}
+	}
+	return fmt.Errorf("tool %q not found on server %q", toolName, serverName)
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rlimitDataHeadroom is added to the heap limit when capping the data segment,
+// leaving room for the runtime's own allocations outside the JavaScript heap
+const rlimitDataHeadroom = 256 << 20
+
+// rlimitCommand runs command through a shell that caps its CPU time and data
+// segment and then execs it. The soft CPU limit delivers SIGXCPU; the hard limit
+// a second later is a SIGKILL backstop.
+func rlimitCommand(limits Limits, command string, args []string) (string, []string) {
+	var steps []string
+	if limits.CPUTime > 0 {
+		seconds := (limits.CPUTime + 999_999_999) / 1_000_000_000
+		// The soft limit goes first, as a hard limit below the current soft one is refused
+		steps = append(steps, fmt.Sprintf("ulimit -S -t %d", seconds), fmt.Sprintf("ulimit -H -t %d", seconds+1))
+	}
+	if limits.MemoryMB > 0 {
+		kb := (uint64(limits.MemoryMB)<<20 + rlimitDataHeadroom) >> 10
+		steps = append(steps, fmt.Sprintf("ulimit -d %d", kb))
+	}
+	if len(steps) == 0 {
+		return command, args
+	}
+
+	script := strings.Join(append(steps, `exec "$0" "$@"`), " && ")
+	return "/bin/sh", append([]string{"-c", script, command}, args...)
+}
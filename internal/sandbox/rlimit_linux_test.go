@@ -0,0 +1,41 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRlimitCommandSetsLimitsBeforeExec(t *testing.T) {
+	command, args := rlimitCommand(Limits{CPUTime: 2500 * time.Millisecond, MemoryMB: 64}, "/bin/sh", []string{"-c", "ulimit -S -t; ulimit -H -t; ulimit -d"})
+	out, err := exec.Command(command, args...).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Fields(string(out)), []string{"3", "4", "327680"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expected limits %v in the runtime, got %v", want, got)
+	}
+}
+
+func TestProcessViolationOnlyReportsMemoryForSIGKILL(t *testing.T) {
+	limits := Limits{MemoryMB: 64}
+	for script, want := range map[string]string{
+		"kill -KILL $$": "resource limit exceeded: memory (64 MB)",
+		"kill -SEGV $$": "",
+		"kill -TERM $$": "",
+		"exit 1":        "",
+	} {
+		cmd := exec.Command("/bin/sh", "-c", script)
+		cmd.Run()
+		got := ""
+		if limitErr := limits.processViolation(cmd.ProcessState, ""); limitErr != nil {
+			got = limitErr.Message
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", script, got, want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sandbox
+
+// rlimitCommand leaves the command unchanged outside Linux; heap flags and
+// timeouts still apply
+func rlimitCommand(limits Limits, command string, args []string) (string, []string) {
+	return command, args
+}
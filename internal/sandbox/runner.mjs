@@ -0,0 +1,114 @@
+/**
+ * CodeBraid process runner
+ *
+ * Executes bundles received on stdin inside an external JavaScript runtime
+ * (Deno, Node, Bun) and proxies callTool requests back to the host. A runner
+ * may serve several runs in sequence when the host keeps it alive.
+ *
+ * Protocol: newline-delimited JSON in both directions.
+ *   host   -> runner: {"type":"start","code":"..."}
+ *   runner -> host:   {"type":"call","id":1,"call":{"serverName","toolName","args"}}
+ *   host   -> runner: {"id":1,"success":true,"result":...}
+ *   runner -> host:   {"type":"log","level":"log","message":"..."}
+ *   runner -> host:   {"type":"result","output":"..."} | {"type":"error","error":"...","stack":"..."}
+ */
+
+const isDeno = typeof Deno !== "undefined";
+const encoder = new TextEncoder();
+const decoder = new TextDecoder();
+
+function send(message) {
+    const line = JSON.stringify(message) + "\n";
+    if (isDeno) {
+        const data = encoder.encode(line);
+        let written = 0;
+        while (written < data.length) {
+            written += Deno.stdout.writeSync(data.subarray(written));
+        }
+    } else {
+        process.stdout.write(line);
+    }
+}
+
+async function* readMessages() {
+    const stream = isDeno ? Deno.stdin.readable : process.stdin;
+    let buffer = "";
+    for await (const chunk of stream) {
+        buffer += typeof chunk === "string" ? chunk : decoder.decode(chunk, { stream: true });
+        let index;
+        while ((index = buffer.indexOf("\n")) >= 0) {
+            const line = buffer.slice(0, index);
+            buffer = buffer.slice(index + 1);
+            if (line.trim() !== "") {
+                yield JSON.parse(line);
+            }
+        }
+    }
+}
+
+function formatArg(arg) {
+    if (typeof arg === "string") {
+        return arg;
+    }
+    try {
+        return JSON.stringify(arg);
+    } catch {
+        return String(arg);
+    }
+}
+
+for (const level of ["log", "info", "warn", "error", "debug"]) {
+    console[level] = (...args) => send({ type: "log", level, message: args.map(formatArg).join(" ") });
+}
+
+const pending = new Map();
+let nextCallId = 0;
+
+/**
+ * Call an MCP tool on a downstream server via the host
+ */
+globalThis.callTool = (serverName, toolName, args) => new Promise((resolve, reject) => {
+    const id = ++nextCallId;
+    pending.set(id, { resolve, reject });
+    send({ type: "call", id, call: { serverName, toolName, args: args || {} } });
+});
+
+/**
+ * Run one bundle and report its result; globals it sets stay visible to later runs
+ */
+async function run(code) {
+    try {
+        // Indirect eval runs the bundle in global scope; sourceURL names it in stack traces
+        const result = await (0, eval)(code + "\n//# sourceURL=main.js");
+        send({ type: "result", output: result !== undefined ? JSON.stringify(result) : "" });
+    } catch (error) {
+        send({
+            type: "error",
+            error: error && error.message !== undefined ? error.message : String(error),
+            // Drop the runner's own frames, they only add noise to user stack traces
+            stack: error && error.stack
+                ? error.stack.split("\n").filter((line) => !line.includes(import.meta.url)).join("\n")
+                : "",
+        });
+    }
+}
+
+// Start runs and dispatch tool call responses until the host closes stdin.
+// The host sends the next start only after the previous run reported its result.
+for await (const message of readMessages()) {
+    if (message.type === "start") {
+        run(message.code);
+        continue;
+    }
+
+    const call = pending.get(message.id);
+    if (!call) {
+        continue;
+    }
+    pending.delete(message.id);
+    if (message.success) {
+        call.resolve(message.result);
+    } else {
+        call.reject(new Error(message.error || "MCP call failed"));
+    }
+}
@@ -0,0 +1,205 @@
+"""
+CodeBraid Python runner
+
+Executes scripts received on stdin and proxies call_tool requests back to the
+host. It speaks the protocol of runner.mjs, so the host treats both alike:
+
+  host   -> runner: {"type":"start","code":"..."}
+  runner -> host:   {"type":"call","id":1,"call":{"serverName","toolName","args"}}
+  host   -> runner: {"id":1,"success":true,"result":...}
+  runner -> host:   {"type":"log","level":"log","message":"..."}
+  runner -> host:   {"type":"result","output":"..."} | {"type":"error","error":"...","stack":"..."}
+
+The first line of the code is the host's prelude binding the run's input and
+secrets; the rest is the script, whose result is the value of its last
+expression statement. Tool calls block until the host answers them.
+"""
+
+import ast
+import io
+import json
+import linecache
+import sys
+import traceback
+import types
+
+_protocol_in = sys.stdin
+_protocol_out = sys.stdout
+
+
+def _send(message):
+    _protocol_out.write(json.dumps(message) + "\n")
+    _protocol_out.flush()
+
+
+class _Console(io.TextIOBase):
+    """Sends complete lines written to it as console output of one level"""
+
+    def __init__(self, level):
+        self._level = level
+        self._buffer = ""
+
+    def writable(self):
+        return True
+
+    def write(self, text):
+        self._buffer += text
+        *lines, self._buffer = self._buffer.split("\n")
+        for line in lines:
+            _send({"type": "log", "level": self._level, "message": line})
+        return len(text)
+
+    def flush(self):
+        if self._buffer:
+            _send({"type": "log", "level": self._level, "message": self._buffer})
+            self._buffer = ""
+
+
+sys.stdout = _Console("log")
+sys.stderr = _Console("error")
+sys.stdin = io.StringIO()
+
+
+class ToolError(Exception):
+    """Raised when a downstream MCP tool call fails"""
+
+
+_next_call_id = 0
+
+
+def call_tool(server_name, tool_name, args=None):
+    """Call an MCP tool on a downstream server via the host"""
+    global _next_call_id
+    _next_call_id += 1
+    call_id = _next_call_id
+    sys.stdout.flush()
+    _send({"type": "call", "id": call_id, "call": {"serverName": server_name, "toolName": tool_name, "args": args or {}}})
+
+    while True:
+        line = _protocol_in.readline()
+        if not line:
+            raise ToolError("host closed the connection")
+        if not line.strip():
+            continue
+        message = json.loads(line)
+        if message.get("id") != call_id:
+            continue
+        if message.get("success"):
+            return message.get("result")
+        raise ToolError(message.get("error") or "MCP call failed")
+
+
+class _Secrets:
+    """Secret values are only reachable through get(), so printing shows none of them"""
+
+    def __init__(self, values):
+        self.__values = values
+
+    def get(self, name):
+        if name not in self.__values:
+            raise KeyError("secret %s is not configured" % json.dumps(name))
+        return self.__values[name]
+
+    def __repr__(self):
+        return "<secrets>"
+
+
+class _KV:
+    """The session's key-value store; values are JSON and kept between runs"""
+
+    def get(self, key, default=None):
+        value = call_tool("__codebraid", "kv.get", {"key": key})
+        return default if value is None else value
+
+    def set(self, key, value):
+        call_tool("__codebraid", "kv.set", {"key": key, "value": value})
+
+    def delete(self, key):
+        call_tool("__codebraid", "kv.delete", {"key": key})
+
+
+codebraid = types.ModuleType("codebraid", "Host bindings of the CodeBraid runner")
+codebraid.call_tool = call_tool
+codebraid.ToolError = ToolError
+codebraid.kv = _KV()
+sys.modules["codebraid"] = codebraid
+
+
+def _bind(input_json, secrets_json, output_dir):
+    value = json.loads(input_json) if input_json else None
+    codebraid.input = lambda: value
+    codebraid.secrets = _Secrets(json.loads(secrets_json))
+    codebraid.output_dir = output_dir or None
+
+
+codebraid._bind = _bind
+
+# Globals persist across runs when the host keeps the runner alive
+_globals = {"__name__": "__main__", "__builtins__": __builtins__}
+
+
+def _to_json(value):
+    """Falls back to to_dict() (e.g. pandas), lists for sets and tuples, and str()"""
+
+    def default(obj):
+        to_dict = getattr(obj, "to_dict", None)
+        if callable(to_dict):
+            try:
+                return to_dict(orient="records")
+            except TypeError:
+                return to_dict()
+        if isinstance(obj, (set, frozenset, tuple)):
+            return list(obj)
+        return str(obj)
+
+    return json.dumps(value, default=default)
+
+
+def _run(code):
+    prelude, _, script = code.partition("\n")
+    try:
+        exec(prelude, {})
+        _globals["input"] = codebraid.input()
+        _globals["secrets"] = codebraid.secrets
+
+        # Registered so tracebacks can show the script's source lines
+        linecache.cache["main.py"] = (len(script), None, script.splitlines(True), "main.py")
+        tree = ast.parse(script, "main.py")
+        last = None
+        if tree.body and isinstance(tree.body[-1], ast.Expr):
+            last = ast.Expression(tree.body.pop().value)
+        exec(compile(tree, "main.py", "exec"), _globals)
+        result = eval(compile(last, "main.py", "eval"), _globals) if last is not None else None
+
+        sys.stdout.flush()
+        sys.stderr.flush()
+        _send({"type": "result", "output": _to_json(result) if result is not None else ""})
+    except SystemExit as error:
+        if error.code in (None, 0):
+            sys.stdout.flush()
+            sys.stderr.flush()
+            _send({"type": "result", "output": ""})
+        else:
+            _report(error)
+    except BaseException as error:
+        _report(error)
+
+
+def _report(error):
+    sys.stdout.flush()
+    sys.stderr.flush()
+    # Drop the runner's own frames, they only add noise to user tracebacks
+    frames = [frame for frame in traceback.extract_tb(error.__traceback__) if frame.filename != __file__]
+    stack = "Traceback (most recent call last):\n" + "".join(traceback.format_list(frames))
+    stack += "".join(traceback.format_exception_only(type(error), error))
+    _send({"type": "error", "error": "%s: %s" % (type(error).__name__, error), "stack": stack.rstrip("\n")})
+
+
+# Start runs until the host closes stdin.
+# The host sends the next start only after the previous run reported its result.
+for _line in _protocol_in:
+    if not _line.strip():
+        continue
+    _message = json.loads(_line)
+    if _message.get("type") == "start":
+        _run(_message["code"])
@@ -4,35 +4,76 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
 
 	extism "github.com/extism/go-sdk"
+	"github.com/tetratelabs/wazero"
 	"github.com/yousuf/codebraid-mcp/internal/client"
+	"github.com/yousuf/codebraid-mcp/internal/config"
 	"github.com/yousuf/codebraid-mcp/internal/sourcemap"
 )
 
-// Sandbox provides a WebAssembly execution environment for user code
+// Sandbox provides a WebAssembly execution environment for user code.
+// The plugin is QuickJS compiled to WASM (see wasm/) running under extism/wazero.
 type Sandbox struct {
 	plugin    *extism.Plugin
+	limits    Limits
+	sessionID string
+	scratch   string     // Private directory mounted at /tmp, removed on Close
+	output    OutputFunc // Console sink of the execution in progress
 	clientHub *client.McpClientHub
 	ctx       context.Context
 }
 
 // NewSandbox creates a new sandbox instance
-func NewSandbox(ctx context.Context, wasmPath string, clientHub *client.McpClientHub) (*Sandbox, error) {
+func NewSandbox(ctx context.Context, opts Options) (*Sandbox, error) {
+	limits := opts.Limits
+	egress, err := ParseEgressPolicy(opts.Policy.AllowNet)
+	if err != nil {
+		return nil, err
+	}
+
+	scratch, err := newScratchDir(opts.Policy.ScratchDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := resetOutputs(scratch); err != nil {
+		os.RemoveAll(scratch)
+		return nil, err
+	}
+
 	manifest := extism.Manifest{
 		Wasm: []extism.Wasm{
 			extism.WasmFile{
-				Path: wasmPath,
+				Path: opts.WasmPath,
 			},
 		},
+		// extism denies HTTP requests to any host not listed; ports and CIDRs are not supported
+		AllowedHosts: wasmAllowedHosts(egress),
+		// WASI sees only these directories; nothing else on the host is reachable
+		AllowedPaths: wasmAllowedPaths(scratch, opts.LibDir, opts.Policy),
+	}
+	if limits.MemoryMB > 0 {
+		// Wasm memory grows in 64 KiB pages
+		manifest.Memory = &extism.ManifestMemory{MaxPages: uint32(limits.MemoryMB) * 16}
 	}
 
 	config := extism.PluginConfig{
 		EnableWasi: true,
+		// Abort running code when the execution context is done, which enforces the timeout
+		RuntimeConfig: wazero.NewRuntimeConfig().
+			WithCompilationCache(wasmCompilationCache(opts.WasmCacheDir)).
+			WithCloseOnContextDone(true),
 	}
 
 	sb := &Sandbox{
-		clientHub: clientHub,
+		limits:    limits,
+		scratch:   scratch,
+		sessionID: opts.SessionID,
+		clientHub: opts.ClientHub,
 		ctx:       ctx,
 	}
 
@@ -43,50 +84,148 @@ func NewSandbox(ctx context.Context, wasmPath string, clientHub *client.McpClien
 
 	plugin, err := extism.NewPlugin(ctx, manifest, config, hostFunctions)
 	if err != nil {
+		os.RemoveAll(scratch)
 		return nil, fmt.Errorf("failed to create plugin: %w", err)
 	}
 
+	// console.* in the plugin is delivered through the extism logger
+	enablePluginLogs()
+	plugin.SetLogger(func(level extism.LogLevel, message string) {
+		sb.output.emit(consoleLevel(level), message)
+	})
+
 	sb.plugin = plugin
 	return sb, nil
 }
 
-// ExecuteCode executes bundled JavaScript code in the sandbox
-func (s *Sandbox) ExecuteCode(bundledCode, sourceMap string) (string, error) {
+// ExecuteCode executes bundled JavaScript code in the sandbox.
+// The plugin instance is reused, so QuickJS globals persist between runs.
+func (s *Sandbox) ExecuteCode(ctx context.Context, run Run) (Result, error) {
+	run, err := run.withPrelude("/tmp/" + outputsDirName)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Hooks have timeouts of their own and don't count against the run's
+	hookCtx := ctx
+	if run.Restore != nil {
+		if err := restoreScratch(s.scratch, run.Restore); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := runHooks(hookCtx, HookPre, s.sessionID, s.scratch, nil); err != nil {
+		return Result{}, err
+	}
+
+	limits := s.limits.forRun(run)
+	ctx, cancel := limits.withTimeout(ctx)
+	defer cancel()
+
+	s.output = run.OnOutput
+	defer func() { s.output = nil }()
+
 	// Call the executeCode function exported by the JavaScript plugin
-	exit, output, err := s.plugin.Call("executeCode", []byte(bundledCode))
+	var exit uint32
+	var output []byte
+	cpuTime := onThread(func() {
+		exit, output, err = s.plugin.CallWithContext(ctx, "executeCode", []byte(run.Code))
+	})
 	if err != nil {
-		return "", fmt.Errorf("plugin execution failed: %w", err)
+		if limits.MemoryMB > 0 && isOutOfMemory(err.Error()) {
+			return Result{}, limits.memoryError()
+		}
+		return Result{}, limits.checkContext(ctx, fmt.Errorf("plugin execution failed: %w", err))
 	}
 	if exit != 0 {
-		return "", fmt.Errorf("plugin exited with code %d", exit)
+		return Result{}, fmt.Errorf("plugin exited with code %d", exit)
 	}
 
 	var outputMap map[string]interface{}
 	err = json.Unmarshal(output, &outputMap)
 	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal output: %w", err)
+		return Result{}, fmt.Errorf("failed to unmarshal output: %w", err)
 	}
 
-	if errVal, ok := outputMap["error"]; ok && errVal != nil && errVal.(string) != "" {
-		if stackVal, ok := outputMap["stack"]; ok && stackVal != nil && stackVal.(string) != "" {
-			mappedStack, err := sourcemap.Map(sourceMap, stackVal.(string), true)
-			if err != nil {
-				return "", fmt.Errorf("failed to map error stack trace: %w", err)
+	var result Result
+	if errVal, ok := outputMap["error"].(string); ok && errVal != "" {
+		// QuickJS reports a failed memory grow as an "out of memory" exception
+		if limits.MemoryMB > 0 && isOutOfMemory(errVal) {
+			return Result{}, limits.memoryError()
+		}
+		if stackVal, ok := outputMap["stack"].(string); ok && stackVal != "" {
+			if result, err = formatExecutionError(errVal, stackVal, run.SourceMap); err != nil {
+				return Result{}, err
 			}
+		} else {
+			result = Result{Output: string(output), Failed: true}
+		}
+	} else {
+		result = Result{Output: string(output)}
+	}
 
-			errorOutput := map[string]interface{}{
-				"error": errVal.(string),
-				"stack": mappedStack,
-			}
-			errorOutputJson, err := json.Marshal(errorOutput)
-			if err != nil {
-				return "", fmt.Errorf("failed to marshal error output: %w", err)
-			}
-			return string(errorOutputJson), err
+	if err := runHooks(hookCtx, HookPost, s.sessionID, s.scratch, &result); err != nil {
+		return Result{}, err
+	}
+	if run.Snapshot {
+		if result.Snapshot, err = snapshotScratch(s.scratch); err != nil {
+			return Result{}, err
 		}
 	}
+	if result.Artifacts, err = collectOutputs(s.scratch); err != nil {
+		return Result{}, err
+	}
+	result.Usage.CPUTime = cpuTime
+	return result, nil
+}
+
+var pluginLogsOnce sync.Once
 
-	return string(output), nil
+// enablePluginLogs raises extism's global log level, which defaults to off
+func enablePluginLogs() {
+	pluginLogsOnce.Do(func() {
+		extism.SetLogLevel(extism.LogLevelDebug)
+	})
+}
+
+// consoleLevel maps an extism log level to the console method that produced it
+func consoleLevel(level extism.LogLevel) string {
+	switch level {
+	case extism.LogLevelError:
+		return "error"
+	case extism.LogLevelWarn:
+		return "warn"
+	case extism.LogLevelDebug, extism.LogLevelTrace:
+		return "debug"
+	default:
+		return "log"
+	}
+}
+
+// wasmAllowedHosts converts the egress policy to extism host patterns
+func wasmAllowedHosts(egress *EgressPolicy) []string {
+	hosts := egress.Hosts()
+	for i, host := range hosts {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			hosts[i] = h
+		}
+	}
+	return hosts
+}
+
+// wasmAllowedPaths maps host directories into the WASI filesystem.
+// The scratch directory is writable at /tmp; the session library is read-only at /lib.
+func wasmAllowedPaths(scratch, libDir string, policy config.SandboxPolicy) map[string]string {
+	paths := map[string]string{scratch: "/tmp"}
+	if libDir != "" {
+		paths["ro:"+libDir] = "/lib"
+	}
+	for _, dir := range policy.AllowRead {
+		paths["ro:"+dir] = dir
+	}
+	for _, dir := range policy.AllowWrite {
+		paths[dir] = dir
+	}
+	return paths
 }
 
 // Close closes the sandbox and frees resources
@@ -94,4 +233,47 @@ func (s *Sandbox) Close() {
 	if s.plugin != nil {
 		s.plugin.Close(s.ctx)
 	}
+	if s.scratch != "" {
+		os.RemoveAll(s.scratch)
+	}
+}
+
+// formatExecutionError maps an error stack trace back to the original sources
+// and renders it as the JSON error output returned to callers.
+// "location" points at the failing line of user TypeScript when it can be resolved.
+func formatExecutionError(message, stack, sourceMap string) (Result, error) {
+	if stack == "" {
+		return errorResult(message)
+	}
+
+	errorOutput := map[string]interface{}{
+		"error": message,
+		"stack": stack,
+	}
+
+	resolved, err := sourcemap.Resolve(sourceMap, stack)
+	if err != nil {
+		// A broken source map should not hide the error itself
+		log.Printf("Failed to map error stack trace: %v", err)
+	} else {
+		errorOutput["stack"] = resolved.Stack
+		if resolved.Location != nil {
+			errorOutput["location"] = resolved.Location
+		}
+	}
+
+	errorOutputJson, err := json.Marshal(errorOutput)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to marshal error output: %w", err)
+	}
+	return Result{Output: string(errorOutputJson), Failed: true}, nil
+}
+
+// errorResult renders an error without a stack trace
+func errorResult(message string) (Result, error) {
+	output, err := json.Marshal(map[string]interface{}{"error": message})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to marshal error output: %w", err)
+	}
+	return Result{Output: string(output), Failed: true}, nil
 }
@@ -0,0 +1,39 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+)
+
+// ScratchPrefix starts the name of every scratch directory. The ID of the process
+// that created it follows, so orphaned directories can be told from live ones.
+const ScratchPrefix = "codebraid-run-"
+
+// newScratchDir creates a private working directory for one execution under root
+// (the system temp directory when root is empty)
+func newScratchDir(root string) (string, error) {
+	if root == "" {
+		root = os.TempDir()
+	}
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return "", fmt.Errorf("failed to create scratch root %s: %w", root, err)
+	}
+
+	dir, err := os.MkdirTemp(root, fmt.Sprintf("%s%d-", ScratchPrefix, os.Getpid()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	return dir, nil
+}
+
+// scratchEnv points home and temp directories at the scratch directory so
+// runtimes and libraries don't read or write the user's real home
+func scratchEnv(dir string) []string {
+	return []string{
+		"HOME=" + dir,
+		"USERPROFILE=" + dir,
+		"TMPDIR=" + dir,
+		"TMP=" + dir,
+		"TEMP=" + dir,
+	}
+}
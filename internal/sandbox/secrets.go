@@ -0,0 +1,119 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Secrets maps secret names to values. Code reads them with secrets.get(name);
+// they are never placed in the runtime's environment.
+type Secrets map[string]string
+
+// minRedactLength keeps very short values from being redacted out of ordinary text
+const minRedactLength = 4
+
+// Redact replaces every secret value in text with [REDACTED:name]
+func (s Secrets) Redact(text string) string {
+	if len(s) == 0 || text == "" {
+		return text
+	}
+	return s.replacer().Replace(text)
+}
+
+// replacer matches each value raw and JSON-escaped, since results and error
+// output are JSON. Longer values go first so one secret containing another is
+// redacted whole.
+func (s Secrets) replacer() *strings.Replacer {
+	type pattern struct{ old, new string }
+	var patterns []pattern
+	for name, value := range s {
+		if len(value) < minRedactLength {
+			continue
+		}
+		marker := "[REDACTED:" + name + "]"
+		patterns = append(patterns, pattern{value, marker})
+		if encoded, err := json.Marshal(value); err == nil {
+			if escaped := string(encoded[1 : len(encoded)-1]); escaped != value {
+				patterns = append(patterns, pattern{escaped, marker})
+			}
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		return len(patterns[i].old) > len(patterns[j].old)
+	})
+
+	args := make([]string, 0, len(patterns)*2)
+	for _, p := range patterns {
+		args = append(args, p.old, p.new)
+	}
+	return strings.NewReplacer(args...)
+}
+
+// secretsExecutor binds secrets into every run and redacts their values from
+// the console output, result and errors the run reports
+type secretsExecutor struct {
+	Executor
+	secrets  Secrets
+	replacer *strings.Replacer
+}
+
+// withSecrets wraps an executor so its runs can read secrets
+func withSecrets(executor Executor, secrets Secrets) Executor {
+	return &secretsExecutor{Executor: executor, secrets: secrets, replacer: secrets.replacer()}
+}
+
+// ExecuteCode implements Executor
+func (e *secretsExecutor) ExecuteCode(ctx context.Context, run Run) (Result, error) {
+	run.Secrets = e.secrets
+	onOutput := run.OnOutput
+	run.OnOutput = func(level, message string) {
+		onOutput.emit(level, e.replacer.Replace(message))
+	}
+	if onToolCall := run.OnToolCall; onToolCall != nil {
+		run.OnToolCall = func(call ToolCall) {
+			call.Args, _ = e.redactValue(call.Args).(map[string]interface{})
+			call.Error = e.replacer.Replace(call.Error)
+			onToolCall(call)
+		}
+	}
+
+	result, err := e.Executor.ExecuteCode(ctx, run)
+	if err != nil {
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			return Result{}, &LimitError{Type: limitErr.Type, Message: e.replacer.Replace(limitErr.Message)}
+		}
+		return Result{}, errors.New(e.replacer.Replace(err.Error()))
+	}
+
+	result.Output = e.replacer.Replace(result.Output)
+	return result, nil
+}
+
+// redactValue returns a copy of a decoded JSON value with secret values
+// redacted from its strings, leaving the value itself untouched
+func (e *secretsExecutor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return e.replacer.Replace(v)
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = e.redactValue(item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = e.redactValue(item)
+		}
+		return redacted
+	}
+	return value
+}
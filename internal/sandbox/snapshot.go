@@ -0,0 +1,210 @@
+package sandbox
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxSnapshotBytes bounds the file contents of a scratch directory snapshot
+const maxSnapshotBytes = 256 << 20
+
+// snapshotScratch archives the scratch directory, except the outputs directory,
+// as a gzipped tarball. Entries are in lexical order with their modification
+// times cleared, so identical files always give identical bytes.
+// Only directories and regular files are kept; symlinks are skipped.
+func snapshotScratch(scratch string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	var total int64
+	err := filepath.WalkDir(scratch, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(scratch, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if rel == outputsDirName && d.IsDir() {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{
+			Name:    filepath.ToSlash(rel),
+			Mode:    int64(info.Mode().Perm()),
+			ModTime: time.Unix(0, 0),
+		}
+		if d.IsDir() {
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+			return tw.WriteHeader(header)
+		}
+
+		total += info.Size()
+		if total > maxSnapshotBytes {
+			return fmt.Errorf("working directory exceeds the snapshot limit of %d MB", maxSnapshotBytes>>20)
+		}
+		header.Typeflag = tar.TypeReg
+		header.Size = info.Size()
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.CopyN(tw, f, info.Size())
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot working directory: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// restoreScratch extracts a snapshot into the scratch directory, replacing
+// files of the same name. Writes go through an os.Root, so neither entries nor
+// symlinks the code left in the directory can reach outside it.
+func restoreScratch(scratch string, snapshot []byte) error {
+	root, err := os.OpenRoot(scratch)
+	if err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	defer root.Close()
+	if err := extractSnapshot(root, snapshot); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	return nil
+}
+
+// extractSnapshot writes the directories and regular files of a snapshot under root
+func extractSnapshot(root *os.Root, snapshot []byte) error {
+	gz, err := gzip.NewReader(bytes.NewReader(snapshot))
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+
+	var total int64
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid entry %q", header.Name)
+		}
+		mode := fs.FileMode(header.Mode).Perm() | 0600
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := mkdirAllRoot(root, name); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			total += header.Size
+			if total > maxSnapshotBytes {
+				return fmt.Errorf("snapshot exceeds the limit of %d MB", maxSnapshotBytes>>20)
+			}
+			if parent := filepath.Dir(name); parent != "." {
+				if err := mkdirAllRoot(root, parent); err != nil {
+					return err
+				}
+			}
+			f, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.CopyN(f, tr, header.Size)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported entry %q", header.Name)
+		}
+	}
+}
+
+// mkdirAllRoot creates dir and its missing parents inside root
+func mkdirAllRoot(root *os.Root, dir string) error {
+	var path string
+	for _, part := range strings.Split(dir, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+		if err := root.Mkdir(path, 0700); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScratchState is implemented by executors whose working directory outlives a
+// run, so it can be carried over to another executor of the same kind
+type ScratchState interface {
+	// SnapshotScratch archives the working directory like Run.Snapshot;
+	// nil when there is none yet
+	SnapshotScratch() ([]byte, error)
+
+	// RestoreScratch extracts a snapshot into the working directory like Run.Restore
+	RestoreScratch(snapshot []byte) error
+
+	// ScratchDir returns the working directory; empty when there is none yet
+	ScratchDir() string
+}
+
+// AsScratchState returns the ScratchState behind an executor's wrappers, if any
+func AsScratchState(executor Executor) (ScratchState, bool) {
+	for {
+		if state, ok := executor.(ScratchState); ok {
+			return state, true
+		}
+		switch e := executor.(type) {
+		case *usageExecutor:
+			executor = e.Executor
+		case *truncatingExecutor:
+			executor = e.Executor
+		case *secretsExecutor:
+			executor = e.Executor
+		case *toolBudgetExecutor:
+			executor = e.Executor
+		case *toolCallExecutor:
+			executor = e.Executor
+		default:
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// LimitOutput reports a runner message too large to read, see readMessage
+const LimitOutput = "output_limit"
+
+// truncatingExecutor caps the console output and result size of every run.
+// Console output past the limit is dropped and summarised by a final warning;
+// an oversized result is replaced by a preview with its byte counts.
+type truncatingExecutor struct {
+	Executor
+	outputBytes int
+	resultBytes int
+}
+
+// withTruncation wraps an executor so its runs report at most the given number of bytes
+func withTruncation(executor Executor, outputBytes, resultBytes int) Executor {
+	return &truncatingExecutor{Executor: executor, outputBytes: outputBytes, resultBytes: resultBytes}
+}
+
+// ExecuteCode implements Executor
+func (e *truncatingExecutor) ExecuteCode(ctx context.Context, run Run) (Result, error) {
+	onOutput := run.OnOutput
+	var emitted, dropped int
+	if e.outputBytes > 0 {
+		run.OnOutput = func(level, message string) {
+			remaining := e.outputBytes - emitted
+			if remaining <= 0 {
+				dropped += len(message)
+				return
+			}
+			kept := truncateUTF8(message, remaining)
+			dropped += len(message) - len(kept)
+			emitted += len(kept)
+			onOutput.emit(level, kept)
+		}
+	}
+
+	result, err := e.Executor.ExecuteCode(ctx, run)
+	if dropped > 0 {
+		onOutput.emit("warn", fmt.Sprintf("[output truncated: %d of %d bytes dropped, limit is %d bytes]", dropped, emitted+dropped, e.outputBytes))
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if e.resultBytes > 0 && len(result.Output) > e.resultBytes {
+		result.Output = truncatedResult(result.Output, e.resultBytes)
+	}
+	return result, nil
+}
+
+// truncatedResult replaces an oversized result with a JSON object holding its
+// first limit bytes as a string preview and the byte counts
+func truncatedResult(output string, limit int) string {
+	truncated, _ := json.Marshal(map[string]interface{}{
+		"truncated":  true,
+		"totalBytes": len(output),
+		"limitBytes": limit,
+		"preview":    truncateUTF8(output, limit),
+	})
+	return string(truncated)
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a multi-byte character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// readMessage reads one newline-terminated runner message of at most max bytes.
+// Longer lines are an output limit violation; they are not buffered in full.
+func readMessage(reader *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if max > 0 && len(line)+len(chunk) > max {
+			return nil, &LimitError{
+				Type:    LimitOutput,
+				Message: fmt.Sprintf("output limit exceeded: a single message of the run is over %d bytes", max),
+			}
+		}
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the rest,
+// so a runtime flooding stderr cannot exhaust host memory
+type cappedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+// Write implements io.Writer; it never fails so the writer is not blocked
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// String returns the retained output
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
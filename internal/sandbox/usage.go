@@ -0,0 +1,118 @@
+package sandbox
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Usage reports the resources one execution consumed.
+// CPUTime and PeakRSS are zero when the runtime cannot measure them: the docker
+// runtime's process is the docker client, and the in-process runtimes (wasm, goja)
+// share the server's memory, so only their CPU time is measured.
+type Usage struct {
+	WallTime    time.Duration // From the start of the run to its result, including waits for tool calls
+	CPUTime     time.Duration // User and system time spent running the code
+	PeakRSS     int64         // Peak resident memory of the runtime process in bytes
+	ToolCalls   int           // Downstream MCP tool calls made by the code
+	OutputBytes int           // Console output delivered, after truncation
+	ResultBytes int           // Size of the result, after truncation
+}
+
+// usageExecutor records the wall time, tool calls and output sizes of every run
+// on top of the CPU and memory figures the runtime itself reports
+type usageExecutor struct {
+	Executor
+}
+
+// withUsage wraps an executor so its results report resource usage.
+// It is the outermost wrapper, so sizes are those the caller receives.
+func withUsage(executor Executor) Executor {
+	return &usageExecutor{Executor: executor}
+}
+
+// ExecuteCode implements Executor
+func (e *usageExecutor) ExecuteCode(ctx context.Context, run Run) (Result, error) {
+	var toolCalls atomic.Int64
+	onToolCall := run.OnToolCall
+	run.OnToolCall = func(call ToolCall) {
+		toolCalls.Add(1)
+		if onToolCall != nil {
+			onToolCall(call)
+		}
+	}
+
+	onOutput := run.OnOutput
+	var outputBytes int
+	run.OnOutput = func(level, message string) {
+		outputBytes += len(message)
+		onOutput.emit(level, message)
+	}
+
+	start := time.Now()
+	result, err := e.Executor.ExecuteCode(ctx, run)
+	if err != nil {
+		return result, err
+	}
+
+	result.Usage.WallTime = time.Since(start)
+	result.Usage.ToolCalls = int(toolCalls.Load())
+	result.Usage.OutputBytes = outputBytes
+	result.Usage.ResultBytes = len(result.Output)
+	return result, nil
+}
+
+// toolCallExecutor hands the run's OnToolCall, dry-run and read-only modes and key-value store
+// to the tool calls the runtime makes, which only see the run's context
+type toolCallExecutor struct {
+	Executor
+}
+
+// withToolCalls wraps a runtime so its tool calls are reported to OnToolCall.
+// It is the innermost wrapper, so calls are reported as the other wrappers
+// (e.g. secret redaction) shape them.
+func withToolCalls(executor Executor) Executor {
+	return &toolCallExecutor{Executor: executor}
+}
+
+// ExecuteCode implements Executor
+func (e *toolCallExecutor) ExecuteCode(ctx context.Context, run Run) (Result, error) {
+	if run.OnToolCall != nil {
+		ctx = context.WithValue(ctx, toolCallFuncKey{}, run.OnToolCall)
+	}
+	if run.DryRun {
+		ctx = context.WithValue(ctx, dryRunKey{}, true)
+	}
+	if run.ReadOnly {
+		ctx = context.WithValue(ctx, readOnlyKey{}, true)
+	}
+	var usedKV atomic.Bool
+	if run.KV != nil {
+		ctx = context.WithValue(ctx, kvStoreKey{}, trackedKV{KVStore: run.KV, used: &usedKV})
+	}
+	result, err := e.Executor.ExecuteCode(ctx, run)
+	result.UsedKV = usedKV.Load()
+	return result, err
+}
+
+// toolCallFuncKey carries the run's OnToolCall in its context
+type toolCallFuncKey struct{}
+
+// trackToolCall reports a finished tool call to the run ctx belongs to
+func trackToolCall(ctx context.Context, call ToolCall) {
+	if onToolCall, ok := ctx.Value(toolCallFuncKey{}).(ToolCallFunc); ok {
+		onToolCall(call)
+	}
+}
+
+// onThread runs fn pinned to one OS thread and returns the CPU time the thread
+// spent, which is the cost of an in-process runtime executing the code
+func onThread(fn func()) time.Duration {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	start := threadCPUTime()
+	fn()
+	return threadCPUTime() - start
+}
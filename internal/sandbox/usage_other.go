@@ -0,0 +1,23 @@
+//go:build !linux
+
+package sandbox
+
+import "time"
+
+// threadCPUTime is not measured outside Linux
+func threadCPUTime() time.Duration {
+	return 0
+}
+
+// processCPUTime is not measured outside Linux
+func processCPUTime(pid int) time.Duration {
+	return 0
+}
+
+// processPeakRSS is not measured outside Linux
+func processPeakRSS(pid int) int64 {
+	return 0
+}
+
+// resetPeakRSS is a no-op outside Linux
+func resetPeakRSS(pid int) {}
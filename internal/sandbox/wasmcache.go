@@ -0,0 +1,32 @@
+package sandbox
+
+import (
+	"log"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+)
+
+var (
+	compilationCache     wazero.CompilationCache
+	compilationCacheOnce sync.Once
+)
+
+// wasmCompilationCache returns the process-wide wazero compilation cache.
+// Compiling the QuickJS module dominates sandbox start-up, so it is shared by
+// every plugin instance and, when dir is set, persisted across restarts.
+// The directory is only honoured on first use.
+func wasmCompilationCache(dir string) wazero.CompilationCache {
+	compilationCacheOnce.Do(func() {
+		if dir != "" {
+			cache, err := wazero.NewCompilationCacheWithDir(dir)
+			if err == nil {
+				compilationCache = cache
+				return
+			}
+			log.Printf("Warning: failed to open wasm compilation cache at %s: %v", dir, err)
+		}
+		compilationCache = wazero.NewCompilationCache()
+	})
+	return compilationCache
+}
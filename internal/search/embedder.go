@@ -0,0 +1,151 @@
+// Package search ranks documents, such as the tools of MCP servers, by how
+// close their meaning is to a query, using embeddings from a pluggable embedder
+// and an on-disk index of the documents' embeddings.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects how close
+// their meanings are
+type Embedder interface {
+	// ID identifies the embedder and its model; vectors of embedders with
+	// different IDs are not comparable
+	ID() string
+	// Embed returns the vector of each text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// APIEmbedder embeds texts with an OpenAI-compatible embeddings endpoint, as
+// served by OpenAI, Ollama, llama.cpp or LM Studio
+type APIEmbedder struct {
+	url     string
+	model   string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewAPIEmbedder creates an embedder posting to the endpoint url, e.g.
+// https://api.openai.com/v1/embeddings, with the given model and extra headers
+func NewAPIEmbedder(url, model string, headers map[string]string) *APIEmbedder {
+	return &APIEmbedder{url: url, model: model, headers: headers, client: &http.Client{}}
+}
+
+// ID implements Embedder
+func (e *APIEmbedder) ID() string {
+	return "api " + e.url + " " + e.model
+}
+
+// Embed implements Embedder
+func (e *APIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 256<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s responded %s: %s", e.url, resp.Status, strings.TrimSpace(string(data[:min(len(data), 512)])))
+	}
+
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", e.url, err)
+	}
+	vectors := make([][]float32, len(texts))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("invalid response from %s: embedding index %d out of range", e.url, item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return checkVectors(vectors, e.url)
+}
+
+// CommandEmbedder embeds texts with a local program, such as a script running a
+// sentence-transformers model. The program reads a JSON array of texts from
+// stdin and writes a JSON array of their vectors to stdout.
+type CommandEmbedder struct {
+	command []string
+}
+
+// NewCommandEmbedder creates an embedder running command, a program and its
+// arguments, for every batch of texts
+func NewCommandEmbedder(command []string) *CommandEmbedder {
+	return &CommandEmbedder{command: command}
+}
+
+// ID implements Embedder
+func (e *CommandEmbedder) ID() string {
+	return "command " + strings.Join(e.command, " ")
+}
+
+// Embed implements Embedder
+func (e *CommandEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	input, err := json.Marshal(texts)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s failed: %w: %s", e.command[0], err, message)
+		}
+		return nil, fmt.Errorf("%s failed: %w", e.command[0], err)
+	}
+
+	var vectors [][]float32
+	if err := json.Unmarshal(output, &vectors); err != nil {
+		return nil, fmt.Errorf("invalid output from %s: %w", e.command[0], err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("invalid output from %s: %d vectors for %d texts", e.command[0], len(vectors), len(texts))
+	}
+	return checkVectors(vectors, e.command[0])
+}
+
+// checkVectors fails when a text got no vector or vectors differ in length
+func checkVectors(vectors [][]float32, source string) ([][]float32, error) {
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("invalid response from %s: no embedding for text %d", source, i)
+		}
+		if len(vector) != len(vectors[0]) {
+			return nil, fmt.Errorf("invalid response from %s: embeddings differ in length", source)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,244 @@
+package search
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// batchSize bounds the texts embedded in one request
+const batchSize = 64
+
+// maxUnusedAge is how long an embedding no search needed is kept in the index
+const maxUnusedAge = 30 * 24 * time.Hour
+
+// Document is a text to rank, identified by ID
+type Document struct {
+	ID   string
+	Text string
+}
+
+// Result is a document ranked against a query
+type Result struct {
+	ID    string
+	Score float64 // Cosine similarity of the document and the query, at most 1
+}
+
+// Index ranks documents by the cosine similarity of their embeddings to the
+// query's. The embeddings of documents are kept, by the hash of their text, in
+// a file of the index directory, so each document is embedded once: when the
+// documents change, only new and changed ones are embedded again.
+// Index is safe for concurrent use.
+type Index struct {
+	path string
+
+	embedderMu sync.Mutex
+	embedder   Embedder
+
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]*indexEntry // By text hash
+}
+
+// indexEntry is the embedding of one document text
+type indexEntry struct {
+	Vector []float32 `json:"vector"`
+	Used   time.Time `json:"used"` // When a search last needed it
+}
+
+// indexFile is the on-disk form of an index
+type indexFile struct {
+	Embedder string                 `json:"embedder"`
+	Entries  map[string]*indexEntry `json:"entries"`
+}
+
+// NewIndex creates an index of the embeddings of embedder, kept in dir. Every
+// embedder has its own file there, so switching models never mixes vectors.
+func NewIndex(embedder Embedder, dir string) *Index {
+	sum := sha256.Sum256([]byte(embedder.ID()))
+	return &Index{
+		embedder: embedder,
+		path:     filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"),
+		entries:  make(map[string]*indexEntry),
+	}
+}
+
+// Path returns the file the index is kept in
+func (ix *Index) Path() string {
+	return ix.path
+}
+
+// SetEmbedder replaces the embedder of the index with one of the same ID, such
+// as the same model with a rotated API key; the vectors of the index are kept
+func (ix *Index) SetEmbedder(embedder Embedder) {
+	ix.embedderMu.Lock()
+	defer ix.embedderMu.Unlock()
+	ix.embedder = embedder
+}
+
+// currentEmbedder returns the embedder last set
+func (ix *Index) currentEmbedder() Embedder {
+	ix.embedderMu.Lock()
+	defer ix.embedderMu.Unlock()
+	return ix.embedder
+}
+
+// Search ranks docs against query, returning those scoring at least minScore,
+// best first. Documents not yet in the index are embedded and the index saved
+// before ranking.
+func (ix *Index) Search(ctx context.Context, docs []Document, query string, minScore float64) ([]Result, error) {
+	vectors, err := ix.vectors(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	queryVectors, err := ix.currentEmbedder().Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(queryVectors) != 1 {
+		return nil, fmt.Errorf("failed to embed query: got %d vectors", len(queryVectors))
+	}
+
+	results := make([]Result, 0, len(docs))
+	for i, doc := range docs {
+		score := cosine(queryVectors[0], vectors[i])
+		if score >= minScore {
+			results = append(results, Result{ID: doc.ID, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results, nil
+}
+
+// vectors returns the embedding of each document, embedding those missing
+// from the index and saving it when any were
+func (ix *Index) vectors(ctx context.Context, docs []Document) ([][]float32, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.loaded {
+		if err := ix.load(); err != nil {
+			return nil, err
+		}
+		ix.loaded = true
+	}
+
+	now := time.Now()
+	hashes := make([]string, len(docs))
+	var missing []string
+	queued := make(map[string]bool)
+	for i, doc := range docs {
+		sum := sha256.Sum256([]byte(doc.Text))
+		hashes[i] = hex.EncodeToString(sum[:])
+		if ix.entries[hashes[i]] == nil && !queued[hashes[i]] {
+			queued[hashes[i]] = true
+			missing = append(missing, doc.Text)
+		}
+	}
+	embedder := ix.currentEmbedder()
+	for start := 0; start < len(missing); start += batchSize {
+		batch := missing[start:min(start+batchSize, len(missing))]
+		embedded, err := embedder.Embed(ctx, batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed documents: %w", err)
+		}
+		if len(embedded) != len(batch) {
+			return nil, fmt.Errorf("failed to embed documents: got %d vectors for %d texts", len(embedded), len(batch))
+		}
+		for i, text := range batch {
+			sum := sha256.Sum256([]byte(text))
+			ix.entries[hex.EncodeToString(sum[:])] = &indexEntry{Vector: embedded[i], Used: now}
+		}
+	}
+
+	// Usage is recorded by the day, so searching rarely rewrites the file
+	changed := len(missing) > 0
+	vectors := make([][]float32, len(docs))
+	for i, hash := range hashes {
+		entry := ix.entries[hash]
+		if now.Sub(entry.Used) > 24*time.Hour {
+			entry.Used = now
+			changed = true
+		}
+		vectors[i] = entry.Vector
+	}
+	if changed {
+		if err := ix.save(now); err != nil {
+			return nil, err
+		}
+	}
+	return vectors, nil
+}
+
+// load reads the index file, if any. A file of another embedder or one that
+// cannot be parsed is ignored, to be rebuilt.
+func (ix *Index) load() error {
+	data, err := os.ReadFile(ix.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read search index: %w", err)
+	}
+	var file indexFile
+	if json.Unmarshal(data, &file) != nil || file.Embedder != ix.currentEmbedder().ID() {
+		return nil
+	}
+	for hash, entry := range file.Entries {
+		if entry != nil && len(entry.Vector) > 0 {
+			ix.entries[hash] = entry
+		}
+	}
+	return nil
+}
+
+// save drops the entries unused for maxUnusedAge and writes the index file
+func (ix *Index) save(now time.Time) error {
+	for hash, entry := range ix.entries {
+		if now.Sub(entry.Used) > maxUnusedAge {
+			delete(ix.entries, hash)
+		}
+	}
+	data, err := json.Marshal(indexFile{Embedder: ix.currentEmbedder().ID(), Entries: ix.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0o755); err != nil {
+		return fmt.Errorf("failed to save search index: %w", err)
+	}
+	// Written aside and renamed, so another process never reads half an index
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save search index: %w", err)
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		return fmt.Errorf("failed to save search index: %w", err)
+	}
+	return nil
+}
+
+// cosine returns the cosine similarity of two vectors, 0 when they differ in
+// length or either is zero
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,140 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// wordEmbedder embeds texts as counts of a fixed vocabulary, counting the texts
+// it embedded
+type wordEmbedder struct {
+	id       string
+	vocab    []string
+	embedded []string
+}
+
+func (e *wordEmbedder) ID() string { return e.id }
+
+func (e *wordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		e.embedded = append(e.embedded, text)
+		vectors[i] = make([]float32, len(e.vocab))
+		for j, word := range e.vocab {
+			vectors[i][j] = float32(strings.Count(text, word))
+		}
+	}
+	return vectors, nil
+}
+
+func newWordEmbedder() *wordEmbedder {
+	return &wordEmbedder{id: "words", vocab: []string{"issue", "ticket", "repo", "message"}}
+}
+
+func TestSearchRanksBySimilarity(t *testing.T) {
+	index := NewIndex(newWordEmbedder(), t.TempDir())
+	docs := []Document{
+		{ID: "create_issue", Text: "create issue ticket"},
+		{ID: "list_repos", Text: "list repo"},
+		{ID: "send", Text: "send message"},
+	}
+
+	results, err := index.Search(context.Background(), docs, "open a ticket", 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != "create_issue" {
+		t.Fatalf("expected only create_issue above the minimum score, got %+v", results)
+	}
+}
+
+func TestIndexEmbedsOnlyChangedDocuments(t *testing.T) {
+	dir := t.TempDir()
+	embedder := newWordEmbedder()
+	docs := []Document{{ID: "a", Text: "issue"}, {ID: "b", Text: "repo"}}
+	if _, err := NewIndex(embedder, dir).Search(context.Background(), docs, "issue", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new index over the same directory loads the saved embeddings
+	embedder.embedded = nil
+	docs[1].Text = "repo message"
+	if _, err := NewIndex(embedder, dir).Search(context.Background(), docs, "issue", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(embedder.embedded) != 2 || embedder.embedded[0] != "repo message" || embedder.embedded[1] != "issue" {
+		t.Fatalf("expected only the changed document and the query embedded, got %q", embedder.embedded)
+	}
+}
+
+func TestIndexOfAnotherEmbedderIsSeparate(t *testing.T) {
+	dir := t.TempDir()
+	docs := []Document{{ID: "a", Text: "issue"}}
+	if _, err := NewIndex(newWordEmbedder(), dir).Search(context.Background(), docs, "issue", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	other := newWordEmbedder()
+	other.id = "other words"
+	if _, err := NewIndex(other, dir).Search(context.Background(), docs, "issue", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(other.embedded) != 2 {
+		t.Fatalf("expected the document embedded again by another embedder, got %q", other.embedded)
+	}
+}
+
+func TestSetEmbedderKeepsTheIndex(t *testing.T) {
+	first, second := newWordEmbedder(), newWordEmbedder()
+	index := NewIndex(first, t.TempDir())
+	docs := []Document{{ID: "a", Text: "issue"}}
+	if _, err := index.Search(context.Background(), docs, "issue", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Such as the same model with a rotated API key
+	index.SetEmbedder(second)
+	if _, err := index.Search(context.Background(), docs, "ticket", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(first.embedded) != 2 || len(second.embedded) != 1 || second.embedded[0] != "ticket" {
+		t.Fatalf("expected only the new query embedded, by the new embedder; got %q then %q", first.embedded, second.embedded)
+	}
+}
+
+func TestAPIEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "m" || r.Header.Get("Authorization") != "Bearer k" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// Answered out of order, as the index of each embedding places it
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": []interface{}{
+			map[string]interface{}{"index": 1, "embedding": []float32{0, 1}},
+			map[string]interface{}{"index": 0, "embedding": []float32{1, 0}},
+		}})
+	}))
+	defer srv.Close()
+
+	embedder := NewAPIEmbedder(srv.URL, "m", map[string]string{"Authorization": "Bearer k"})
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Fatalf("unexpected vectors: %v", vectors)
+	}
+
+	if _, err := NewAPIEmbedder(srv.URL, "other", nil).Embed(context.Background(), []string{"a"}); err == nil {
+		t.Fatal("expected an error status to fail")
+	}
+}
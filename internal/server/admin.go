@@ -0,0 +1,150 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/audit"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// defaultAuditQueryLimit is the number of records GET /audit returns without a limit
+const defaultAuditQueryLimit = 100
+
+// NewAdminHandler serves the operator API. Every request must carry
+// "Authorization: Bearer <token>" when token is set.
+//
+//	GET /audit?session=&status=&codeHash=&tool=&since=&limit=
+//	    Recent execution records, oldest first. tool is "server" or "server.tool",
+//	    since is an RFC 3339 time and limit defaults to 100.
+//	GET /sessions
+//	    Open sessions, oldest first, with their age, servers, bundle size and executions.
+//	GET /sessions/{id}
+//	    One session.
+//	GET /metrics
+//	    Per-session usage counters in the Prometheus text format, labelled by session.
+func NewAdminHandler(sessionMgr *session.Manager, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /audit", func(w http.ResponseWriter, r *http.Request) {
+		trail := sessionMgr.AuditTrail()
+		if trail == nil {
+			writeAdminError(w, http.StatusNotFound, "auditing is disabled")
+			return
+		}
+
+		query := r.URL.Query()
+		filter := audit.Filter{
+			SessionID: query.Get("session"),
+			Status:    query.Get("status"),
+			CodeHash:  query.Get("codeHash"),
+			Tool:      query.Get("tool"),
+			Limit:     defaultAuditQueryLimit,
+		}
+		if since := query.Get("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				writeAdminError(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+				return
+			}
+			filter.Since = t
+		}
+		if limit := query.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n <= 0 {
+				writeAdminError(w, http.StatusBadRequest, "limit must be a positive number")
+				return
+			}
+			filter.Limit = n
+		}
+
+		records := trail.Query(filter)
+		if records == nil {
+			records = []audit.Record{}
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]interface{}{"records": records})
+	})
+
+	mux.HandleFunc("GET /sessions", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, map[string]interface{}{"sessions": sessionMgr.ListSessions()})
+	})
+
+	mux.HandleFunc("GET /sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		sessionCtx := sessionMgr.GetSession(r.PathValue("id"))
+		if sessionCtx == nil {
+			writeAdminError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, sessionCtx.Info())
+	})
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeSessionMetrics(w, sessionMgr.Metrics())
+	})
+
+	if token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeAdminError(w, http.StatusUnauthorized, "a valid bearer token is required")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// sessionCounters are the per-session counters /metrics exports
+var sessionCounters = []struct {
+	name, help string
+	value      func(session.SessionMetrics) int64
+}{
+	{"codebraid_session_executions_total", "Executions run by the session.", func(m session.SessionMetrics) int64 { return m.Executions }},
+	{"codebraid_session_failed_executions_total", "Executions that failed or returned an error result.", func(m session.SessionMetrics) int64 { return m.FailedExecutions }},
+	{"codebraid_session_tool_calls_total", "Downstream MCP tool calls made by the session's executions.", func(m session.SessionMetrics) int64 { return m.ToolCalls }},
+	{"codebraid_session_output_bytes_total", "Bytes of results and console output returned to the session.", func(m session.SessionMetrics) int64 { return m.OutputBytes }},
+	{"codebraid_session_bundle_milliseconds_total", "Time spent bundling the session's code.", func(m session.SessionMetrics) int64 { return m.BundleMs }},
+}
+
+// writeSessionMetrics writes the open sessions' counters in the Prometheus text format
+func writeSessionMetrics(w io.Writer, metrics map[string]session.SessionMetrics) {
+	ids := make([]string, 0, len(metrics))
+	for id := range metrics {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	fmt.Fprintf(w, "# HELP codebraid_sessions Open sessions.\n# TYPE codebraid_sessions gauge\ncodebraid_sessions %d\n", len(ids))
+	for _, counter := range sessionCounters {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name)
+		for _, id := range ids {
+			fmt.Fprintf(w, "%s{session=\"%s\"} %d\n", counter.name, metricLabelEscaper.Replace(id), counter.value(metrics[id]))
+		}
+	}
+}
+
+// metricLabelEscaper escapes a Prometheus label value
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeAdminJSON writes value as the JSON response body
+func writeAdminJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Failed to write admin response: %v", err)
+	}
+}
+
+// writeAdminError writes {"error": message}
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeAdminJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/audit"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// maxAuditErrorBytes bounds the error text kept in an audit record
+const maxAuditErrorBytes = 1 << 10
+
+// NewAuditTrail creates the execution audit trail and its configured sinks.
+// It returns nil when auditing is disabled.
+func NewAuditTrail(cfg *config.Config) (*audit.Trail, error) {
+	if !cfg.GetAuditEnabled() {
+		return nil, nil
+	}
+	auditCfg := cfg.GetAudit()
+
+	var sinks []audit.Sink
+	if auditCfg.File != "" {
+		sink, err := audit.NewFileSink(auditCfg.File)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if webhook := auditCfg.Webhook; webhook != nil {
+		if webhook.URL == "" {
+			return nil, fmt.Errorf("audit webhook requires a url")
+		}
+		sinks = append(sinks, audit.NewWebhookSink(webhook.URL, webhook.Headers))
+	}
+	if otlp := auditCfg.OTLP; otlp != nil {
+		if otlp.URL == "" {
+			return nil, fmt.Errorf("audit otlp requires a url")
+		}
+		sinks = append(sinks, audit.NewOTLPSink(otlp.URL, otlp.Headers))
+	}
+
+	return audit.New(audit.Options{
+		Sinks:        sinks,
+		Retain:       auditCfg.Retain,
+		MaxCodeBytes: auditCfg.MaxCodeBytes,
+		RedactKeys:   auditCfg.RedactKeys,
+	}), nil
+}
+
+// auditStatus derives the status and error text of an execution from its outcome
+func auditStatus(result *mcp.CallToolResult, err error) (string, string) {
+	switch {
+	case err != nil:
+		return audit.StatusFailed, truncateText(err.Error(), maxAuditErrorBytes)
+	case result.Meta["codebraid/cache"] != nil:
+		return audit.StatusCached, ""
+	case result.IsError:
+		var text string
+		if len(result.Content) > 0 {
+			if content, ok := result.Content[0].(*mcp.TextContent); ok {
+				text = content.Text
+			}
+		}
+		return audit.StatusError, truncateText(text, maxAuditErrorBytes)
+	}
+	return audit.StatusOK, ""
+}
+
+// truncateText cuts text to at most max bytes without splitting a character
+func truncateText(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max] + "..."
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/bundler"
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// resultCacheKey hashes everything that decides an execution's result: the code
+// and its helper files, the input, the determinism settings, the restored
+// snapshot, the session's environment and its generated libraries, which change
+// with the downstream tools
+func resultCacheKey(sessionCtx *session.SessionContext, args ExecuteCodeArgs, python, deterministic bool, restore []byte) (string, error) {
+	var libHash string
+	var err error
+	if python {
+		libHash, err = bundler.LibManifestHash(filepath.Join(sessionCtx.BundleDir, sandbox.PythonLibDir))
+	} else {
+		libHash, err = bundler.SessionLibHash(sessionCtx.BundleDir)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	input, err := json.Marshal(args.Input)
+	if err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	env, err := json.Marshal(sessionCtx.Env())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session env: %w", err)
+	}
+
+	restoreHash := sha256.Sum256(restore)
+	parts := []string{args.Language, args.Code, string(input), string(env), libHash, hex.EncodeToString(restoreHash[:])}
+	if deterministic {
+		parts = append(parts, fmt.Sprintf("deterministic:%d", args.Seed))
+	}
+	paths := make([]string, 0, len(args.Files))
+	for path := range args.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		parts = append(parts, path, args.Files[path])
+	}
+
+	h := sha256.New()
+	for _, part := range parts {
+		// Length prefixes keep distinct inputs from hashing alike when concatenated
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheHitResult returns a cached result marked in _meta under "codebraid/cache"
+// with its age, leaving the stored result untouched
+func cacheHitResult(cached *mcp.CallToolResult, age time.Duration) *mcp.CallToolResult {
+	result := *cached
+	result.Meta = mcp.Meta{}
+	for k, v := range cached.Meta {
+		result.Meta[k] = v
+	}
+	result.Meta["codebraid/cache"] = map[string]interface{}{
+		"hit":   true,
+		"ageMs": age.Milliseconds(),
+	}
+	return &result
+}
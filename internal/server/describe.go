@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/codegen"
+)
+
+// maxSuggestions is how many similarly named tools describe_tool suggests for
+// an unknown tool
+const maxSuggestions = 3
+
+// DescribeToolArgs represents the arguments for the describe_tool tool
+type DescribeToolArgs struct {
+	Server string `json:"server" jsonschema:"The MCP server of the tool"`
+	Tool   string `json:"tool" jsonschema:"The tool's name, or its function name in the server's library"`
+}
+
+// addDescribeTool registers describe_tool, which details one tool without
+// reading the library files of its server
+func addDescribeTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "describe_tool",
+		Description: "Describe one tool of an MCP server, e.g. one found with search_tools: its full description, input schema, output schema and annotations (such as readOnlyHint), and a TypeScript example calling it with execute_code.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args DescribeToolArgs) (*mcp.CallToolResult, any, error) {
+		sessionCtx, err := getSessionFromContext(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		tools, ok := sessionCtx.ServerTools(args.Server)
+		if !ok {
+			return nil, nil, fmt.Errorf("server %q not found. Available servers: %v", args.Server, sessionCtx.ClientHub.Servers())
+		}
+		var tool *mcp.Tool
+		for _, candidate := range tools {
+			if candidate.Name == args.Tool || codegen.FunctionName(candidate.Name) == args.Tool {
+				tool = candidate
+				break
+			}
+		}
+		if tool == nil {
+			var suggestions []string
+			for _, match := range searchTools(map[string][]*mcp.Tool{args.Server: tools}, args.Tool) {
+				if len(suggestions) == maxSuggestions {
+					break
+				}
+				suggestions = append(suggestions, match.Tool)
+			}
+			if len(suggestions) > 0 {
+				return nil, nil, fmt.Errorf("tool %q not found on server %q. Did you mean: %s?", args.Tool, args.Server, strings.Join(suggestions, ", "))
+			}
+			return nil, nil, fmt.Errorf("tool %q not found on server %q", args.Tool, args.Server)
+		}
+
+		function := codegen.FunctionName(tool.Name)
+		example := codegen.TypeScriptExample(args.Server, tool)
+		description := map[string]any{
+			"server":      args.Server,
+			"tool":        tool.Name,
+			"function":    function,
+			"file":        fmt.Sprintf("/servers/%s/%s.ts", args.Server, function),
+			"description": tool.Description,
+			"inputSchema": tool.InputSchema,
+			"example":     example,
+		}
+		if tool.Title != "" {
+			description["title"] = tool.Title
+		}
+		if tool.OutputSchema != nil {
+			description["outputSchema"] = tool.OutputSchema
+		}
+		if tool.Annotations != nil {
+			description["annotations"] = tool.Annotations
+		}
+
+		var text strings.Builder
+		fmt.Fprintf(&text, "%s.%s (tool %q, /servers/%s/%s.ts)\n", args.Server, function, tool.Name, args.Server, function)
+		if tool.Description != "" {
+			text.WriteString("\n" + strings.TrimSpace(tool.Description) + "\n")
+		}
+		for _, section := range []struct {
+			title string
+			value any
+		}{
+			{"Input schema", tool.InputSchema},
+			{"Output schema", tool.OutputSchema},
+			{"Annotations", tool.Annotations},
+		} {
+			if section.value == nil || section.value == (*mcp.ToolAnnotations)(nil) {
+				continue
+			}
+			encoded, err := json.MarshalIndent(section.value, "", "  ")
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to encode %s: %w", strings.ToLower(section.title), err)
+			}
+			fmt.Fprintf(&text, "\n%s:\n%s\n", section.title, encoded)
+		}
+		fmt.Fprintf(&text, "\nExample:\n%s", example)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text.String()},
+			},
+			StructuredContent: description,
+		}, nil, nil
+	})
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+)
+
+// plannedCall is a tool call a dry run would have made
+type plannedCall struct {
+	Server string                 `json:"server"`
+	Tool   string                 `json:"tool"`
+	Args   map[string]interface{} `json:"args"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// dryRunPlan collects the tool calls of a dry run in the order they were made
+type dryRunPlan struct {
+	mu    sync.Mutex
+	calls []plannedCall
+}
+
+// record returns a ToolCallFunc adding every call to the plan before passing it to next
+func (p *dryRunPlan) record(next sandbox.ToolCallFunc) sandbox.ToolCallFunc {
+	return func(call sandbox.ToolCall) {
+		p.mu.Lock()
+		p.calls = append(p.calls, plannedCall{
+			Server: call.ServerName,
+			Tool:   call.ToolName,
+			Args:   call.Args,
+			Error:  call.Error,
+		})
+		p.mu.Unlock()
+		if next != nil {
+			next(call)
+		}
+	}
+}
+
+// content renders the plan as {"dryRun": true, "plannedCalls": [...]} for the tool result
+func (p *dryRunPlan) content() mcp.Content {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	calls := p.calls
+	if calls == nil {
+		calls = []plannedCall{}
+	}
+	data, _ := json.Marshal(map[string]interface{}{"dryRun": true, "plannedCalls": calls})
+	return &mcp.TextContent{Text: string(data)}
+}
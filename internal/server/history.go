@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// toolHistoryURI is the resource holding the session's tool call history
+const toolHistoryURI = "codebraid://tool-calls"
+
+// defaultHistoryLimit is how many calls tool_call_history returns by default
+const defaultHistoryLimit = 50
+
+// ToolHistoryArgs represents the arguments for the tool_call_history tool
+type ToolHistoryArgs struct {
+	Server string `json:"server,omitempty" jsonschema:"Only calls to this MCP server"`
+	Tool   string `json:"tool,omitempty" jsonschema:"Only calls to this tool"`
+	Status string `json:"status,omitempty" jsonschema:"Only calls with this status: ok, error or dry_run"`
+	Limit  int    `json:"limit,omitempty" jsonschema:"Return the most recent calls up to this many (default: 50)"`
+}
+
+// addHistoryTools registers the session's tool call history as a resource and a
+// tool to query it
+func addHistoryTools(server *mcp.Server) {
+	server.AddResource(&mcp.Resource{
+		Name:        "tool-call-history",
+		URI:         toolHistoryURI,
+		MIMEType:    "application/json",
+		Description: "Every downstream MCP tool call made by this session's executions, oldest first, with redacted arguments, duration and status",
+	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		sessionCtx, err := getSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		calls, _ := sessionCtx.ToolHistory(session.ToolHistoryQuery{})
+		encoded, err := json.MarshalIndent(calls, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+			{URI: req.Params.URI, MIMEType: "application/json", Text: string(encoded)},
+		}}, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tool_call_history",
+		Description: "List the downstream MCP tool calls this session's executions made, most recent last, with redacted arguments, duration and status (ok, error or dry_run). Filter by server, tool or status. The full history is also readable as the resource " + toolHistoryURI + ".",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args ToolHistoryArgs) (*mcp.CallToolResult, any, error) {
+		sessionCtx, err := getSessionFromContext(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch args.Status {
+		case "", session.CallOK, session.CallError, session.CallDryRun:
+		default:
+			return nil, nil, fmt.Errorf("unknown status %q: use ok, error or dry_run", args.Status)
+		}
+		limit := args.Limit
+		if limit <= 0 {
+			limit = defaultHistoryLimit
+		}
+
+		calls, matched := sessionCtx.ToolHistory(session.ToolHistoryQuery{
+			Server: args.Server,
+			Tool:   args.Tool,
+			Status: args.Status,
+			Limit:  limit,
+		})
+		lines := make([]string, len(calls))
+		for i, call := range calls {
+			line := fmt.Sprintf("#%d %s %s.%s %s (%dms)", call.Seq, call.Time.Format("15:04:05"), call.Server, call.Tool, call.Status, call.DurationMs)
+			if call.Error != "" {
+				line += ": " + call.Error
+			}
+			lines[i] = line
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: strings.Join(lines, "\n")},
+			},
+			StructuredContent: map[string]any{
+				"calls":   calls,
+				"matched": matched,
+			},
+		}, nil, nil
+	})
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// NewHTTPHandler returns the handler serving the MCP server over streamable HTTP.
+//
+// Each MCP session, identified by its Mcp-Session-Id header, is the session of
+// the same ID in sessionMgr, and they end together: a client deleting its MCP
+// session closes the session, and a session the manager closes, expired or
+// evicted, ends the MCP session, so the client's next request is answered 404
+// and it starts a new one instead of silently getting a fresh session under the
+// old ID.
+func NewHTTPHandler(sessionMgr *session.Manager) http.Handler {
+	links := &sessionLinks{sessionMgr: sessionMgr, sessions: make(map[string]*mcp.ServerSession)}
+	sessionMgr.OnSessionDeleted(func(sessionCtx *session.SessionContext) {
+		links.end(sessionCtx.SessionID)
+	})
+
+	return mcp.NewStreamableHTTPHandler(func(req *http.Request) *mcp.Server {
+		// Called for each new MCP session; later requests are routed to its server by session ID
+		server := NewMcpServer(sessionMgr)
+		server.AddReceivingMiddleware(links.middleware())
+		return server
+	}, &mcp.StreamableHTTPOptions{})
+}
+
+// sessionLinks tracks the open MCP sessions, by ID, to tie them to the sessions
+// of the manager
+type sessionLinks struct {
+	sessionMgr *session.Manager
+	mu         sync.Mutex
+	sessions   map[string]*mcp.ServerSession
+}
+
+// middleware links each MCP session as it is initialized
+func (l *sessionLinks) middleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if ss, ok := req.GetSession().(*mcp.ServerSession); ok && method == "initialize" && ss.ID() != "" {
+				l.link(ss)
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// link records an MCP session and closes its session once it ends. Sessions
+// ending because the server shuts down are left to the manager, which may keep
+// them for the next run.
+func (l *sessionLinks) link(ss *mcp.ServerSession) {
+	id := ss.ID()
+	l.mu.Lock()
+	if _, linked := l.sessions[id]; linked {
+		l.mu.Unlock()
+		return
+	}
+	l.sessions[id] = ss
+	l.mu.Unlock()
+
+	go func() {
+		ss.Wait()
+		l.mu.Lock()
+		delete(l.sessions, id)
+		l.mu.Unlock()
+		if l.sessionMgr.Draining() {
+			return
+		}
+		// The session is already gone when the manager ended the MCP session
+		if l.sessionMgr.GetSession(id) == nil {
+			return
+		}
+		if err := l.sessionMgr.DeleteSession(id); err != nil {
+			log.Printf("Session %s: failed to close after its MCP session ended: %v", id, err)
+			return
+		}
+		log.Printf("Session %s: MCP session ended, closed", id)
+	}()
+}
+
+// end ends the MCP session of a closed session, if it is still open
+func (l *sessionLinks) end(id string) {
+	l.mu.Lock()
+	ss := l.sessions[id]
+	l.mu.Unlock()
+	if ss != nil {
+		ss.Close()
+	}
+}
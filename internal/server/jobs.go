@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// maxJobWait bounds how long job_result and cancel_job hold a request open
+const maxJobWait = 60 * time.Second
+
+// JobArgs identifies a background job
+type JobArgs struct {
+	JobID string `json:"jobId" jsonschema:"ID returned by execute_code with background: true"`
+}
+
+// JobLogsArgs represents the arguments for the job_logs tool
+type JobLogsArgs struct {
+	JobID  string `json:"jobId" jsonschema:"ID returned by execute_code with background: true"`
+	Offset int    `json:"offset,omitempty" jsonschema:"Return lines from this offset on; pass the previous nextOffset to read only new lines (default: 0)"`
+}
+
+// JobResultArgs represents the arguments for the job_result tool
+type JobResultArgs struct {
+	JobID string `json:"jobId" jsonschema:"ID returned by execute_code with background: true"`
+	Wait  int    `json:"wait,omitempty" jsonschema:"Seconds to wait for a running job to finish before returning its status (default: 0, at most 60)"`
+}
+
+// startJob starts an execution as a background job and returns its ID at once
+func startJob(ctx context.Context, sessionMgr *session.Manager, sessionCtx *session.SessionContext, args ExecuteCodeArgs) (*mcp.CallToolResult, any, error) {
+	job, err := sessionCtx.StartJob(ctx, func(ctx context.Context, job *session.Job) (*mcp.CallToolResult, error) {
+		return executeCode(ctx, sessionMgr, sessionCtx, args, func(level, message string) {
+			log.Printf("[sandbox:%s] job %s: %s", level, job.ID, message)
+			job.Log(level, message)
+		})
+	})
+	if err != nil {
+		encoded, _ := json.Marshal(map[string]string{"error": err.Error(), "type": "busy"})
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: string(encoded)}},
+		}, nil, nil
+	}
+
+	log.Printf("Session %s: started background job %s", sessionCtx.SessionID, job.ID)
+	return jobStatusResult(job), nil, nil
+}
+
+// addJobTools registers the tools that poll, read and cancel background jobs
+func addJobTools(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "job_status",
+		Description: "Get the status of a background job started with execute_code (running, succeeded, failed or cancelled) and how many console lines it has written.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args JobArgs) (*mcp.CallToolResult, any, error) {
+		job, err := findJob(ctx, args.JobID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return jobStatusResult(job), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "job_logs",
+		Description: "Read the console output of a background job. Pass the returned nextOffset as offset on the next call to read only new lines; the oldest lines are dropped once a job writes a lot of output.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args JobLogsArgs) (*mcp.CallToolResult, any, error) {
+		job, err := findJob(ctx, args.JobID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		logs, next, dropped := job.Logs(args.Offset)
+		status, _ := job.Status()
+		lines := make([]string, len(logs))
+		for i, line := range logs {
+			lines[i] = fmt.Sprintf("[%s] %s", line.Level, line.Message)
+		}
+		if logs == nil {
+			logs = []session.JobLog{}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: strings.Join(lines, "\n")},
+			},
+			StructuredContent: map[string]any{
+				"jobId":      job.ID,
+				"status":     status,
+				"logs":       logs,
+				"nextOffset": next,
+				"dropped":    dropped,
+			},
+		}, nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "job_result",
+		Description: "Fetch the result of a background job, formatted like an execute_code result. While the job is still running its status is returned instead; pass wait to wait up to that many seconds for it to finish.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args JobResultArgs) (*mcp.CallToolResult, any, error) {
+		job, err := findJob(ctx, args.JobID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if args.Wait > 0 {
+			waitForJob(ctx, job, min(time.Duration(args.Wait)*time.Second, maxJobWait))
+		}
+		if result := job.Result(); result != nil {
+			return result, nil, nil
+		}
+		return jobStatusResult(job), nil, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "cancel_job",
+		Description: "Cancel a running background job. Its runtime and pending tool calls are stopped; the console output written so far stays readable.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args JobArgs) (*mcp.CallToolResult, any, error) {
+		job, err := findJob(ctx, args.JobID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		job.Cancel()
+		waitForJob(ctx, job, maxJobWait)
+		return jobStatusResult(job), nil, nil
+	})
+}
+
+// findJob looks up a job of the request's session
+func findJob(ctx context.Context, id string) (*session.Job, error) {
+	sessionCtx, err := getSessionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	job, ok := sessionCtx.Job(id)
+	if !ok {
+		return nil, fmt.Errorf("job %q not found; finished jobs are discarded after a while", id)
+	}
+	return job, nil
+}
+
+// waitForJob waits until the job finishes, the timeout passes or ctx is done
+func waitForJob(ctx context.Context, job *session.Job, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-job.Done():
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// jobStatusResult reports a job's state as text and structuredContent
+func jobStatusResult(job *session.Job) *mcp.CallToolResult {
+	status, finishedAt := job.Status()
+
+	info := map[string]any{
+		"jobId":     job.ID,
+		"status":    status,
+		"startedAt": job.StartedAt.UTC().Format(time.RFC3339),
+		"logLines":  job.LogLines(),
+	}
+	if status != session.JobRunning {
+		info["finishedAt"] = finishedAt.UTC().Format(time.RFC3339)
+	}
+
+	encoded, _ := json.Marshal(info)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(encoded)},
+		},
+		StructuredContent: info,
+	}
+}
@@ -2,11 +2,16 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
 	"github.com/yousuf/codebraid-mcp/internal/session"
 )
 
@@ -25,6 +30,15 @@ func getSessionFromContext(ctx context.Context) (*session.SessionContext, error)
 	return sessionCtx, nil
 }
 
+// requestSessionID returns the ID of the session a request belongs to: its MCP
+// session's, or StdioSessionID for the stdio client
+func requestSessionID(req mcp.Request) string {
+	if id := req.GetSession().ID(); id != "" {
+		return id
+	}
+	return StdioSessionID
+}
+
 // createSessionInjectionMiddleware creates middleware that automatically manages session lifecycle.
 // It stores SessionContext as a value in the request context, keeping request and session lifecycles separate.
 func createSessionInjectionMiddleware(sessionMgr *session.Manager) mcp.Middleware {
@@ -34,20 +48,35 @@ func createSessionInjectionMiddleware(sessionMgr *session.Manager) mcp.Middlewar
 			method string,
 			req mcp.Request,
 		) (mcp.Result, error) {
-			sessionID := req.GetSession().ID()
+			sessionID := requestSessionID(req)
 
-			// Get or create session context
-			sessionCtx, err := sessionMgr.GetOrCreateSession(ctx, sessionID)
+			// Get or create session context, with the servers and overrides the client asked for
+			opts, err := sessionOptions(req.GetExtra())
 			if err != nil {
-				return nil, fmt.Errorf("failed to get/create session: %w", err)
+				return nil, err
 			}
-
-			if sessionCtx == nil {
-				return nil, fmt.Errorf("invalid session context")
+			var sessionCtx *session.SessionContext
+			var end func()
+			for end == nil {
+				sessionCtx, err = sessionMgr.GetOrCreateSession(ctx, sessionID, opts)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get/create session: %w", err)
+				}
+
+				if sessionCtx == nil {
+					return nil, fmt.Errorf("invalid session context")
+				}
+
+				// Mark the session in use, so it is not closed as idle mid-request;
+				// one closed since it was looked up is replaced with a new session
+				end, _ = sessionCtx.BeginRequest()
 			}
+			defer end()
 
-			// Update last accessed timestamp
-			sessionCtx.UpdateLastAccessed()
+			// Clients may inject allowed sandbox environment values with X-Codebraid-Env-* headers
+			if env := injectedEnv(req.GetExtra(), sessionMgr.Config().GetSandboxPolicy().AllowEnvHeaders); len(env) > 0 {
+				sessionCtx.SetEnv(env)
+			}
 
 			// Store SessionContext as value in request context
 			// This keeps session lifecycle independent from request lifecycle
@@ -59,6 +88,97 @@ func createSessionInjectionMiddleware(sessionMgr *session.Manager) mcp.Middlewar
 	}
 }
 
+// Headers choosing the servers a new session connects to.
+// "X-Codebraid-Servers: github, slack" connects only those; X-Codebraid-Server-Overrides
+// holds per-server env and headers as JSON, e.g. {"github": {"headers": {"Authorization": "..."}}}.
+// "X-Codebraid-Fork: <session ID>" forks the new session from an open one instead,
+// authorized by "X-Codebraid-Fork-Token: <admin token>", and
+// "X-Codebraid-Fork-Runtime: true" carries over its persistent runtime's working directory.
+// "X-Codebraid-Read-Only: true" limits the session to tools annotated with readOnlyHint.
+const (
+	serversHeader         = "X-Codebraid-Servers"
+	serverOverridesHeader = "X-Codebraid-Server-Overrides"
+	forkHeader            = "X-Codebraid-Fork"
+	forkTokenHeader       = "X-Codebraid-Fork-Token"
+	forkRuntimeHeader     = "X-Codebraid-Fork-Runtime"
+	readOnlyHeader        = "X-Codebraid-Read-Only"
+)
+
+// sessionOptions reads the session options from request headers
+func sessionOptions(extra *mcp.RequestExtra) (session.Options, error) {
+	var opts session.Options
+	if extra == nil {
+		return opts, nil
+	}
+	for _, name := range strings.Split(extra.Header.Get(serversHeader), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			opts.Servers = append(opts.Servers, name)
+		}
+	}
+	if overrides := extra.Header.Get(serverOverridesHeader); overrides != "" {
+		if err := json.Unmarshal([]byte(overrides), &opts.Overrides); err != nil {
+			return opts, fmt.Errorf("invalid %s header: %w", serverOverridesHeader, err)
+		}
+	}
+	opts.Fork = strings.TrimSpace(extra.Header.Get(forkHeader))
+	opts.ForkToken = extra.Header.Get(forkTokenHeader)
+	if runtime := extra.Header.Get(forkRuntimeHeader); runtime != "" {
+		forkRuntime, err := strconv.ParseBool(runtime)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s header: %w", forkRuntimeHeader, err)
+		}
+		opts.ForkRuntime = forkRuntime
+	}
+	if readOnly := extra.Header.Get(readOnlyHeader); readOnly != "" {
+		ro, err := strconv.ParseBool(readOnly)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s header: %w", readOnlyHeader, err)
+		}
+		opts.ReadOnly = ro
+	}
+	return opts, nil
+}
+
+// envHeaderPrefix marks HTTP headers carrying sandbox environment values
+const envHeaderPrefix = "X-Codebraid-Env-"
+
+// deniedEnvHeaders are the variables clients can never inject, however the
+// allowlist reads: they load code into the runtimes, change their options or
+// where they find programs and modules, or belong to codebraid itself, so they
+// would let a client run code outside the sandbox's flags
+var deniedEnvHeaders = []string{
+	"LD_*", "DYLD_*", "PATH", "HOME", "TMPDIR",
+	"NODE_*", "BUN_*", "DENO_*", "NPM_*",
+	"PYTHON*", "VIRTUAL_ENV", "UV_*",
+	"CODEBRAID_*",
+}
+
+// injectedEnv reads sandbox environment values from request headers, keeping
+// the variables allow matches that are not denied.
+// "X-Codebraid-Env-Github-Token: abc" becomes GITHUB_TOKEN=abc.
+func injectedEnv(extra *mcp.RequestExtra, allow []string) map[string]string {
+	if extra == nil || len(allow) == 0 {
+		return nil
+	}
+	var env map[string]string
+	for key, values := range extra.Header {
+		name, ok := strings.CutPrefix(http.CanonicalHeaderKey(key), envHeaderPrefix)
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+		name = strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if !sandbox.EnvAllowed(name, allow) || sandbox.EnvAllowed(name, deniedEnvHeaders) {
+			log.Printf("Ignoring %s header: %s may not be set by clients", http.CanonicalHeaderKey(key), name)
+			continue
+		}
+		if env == nil {
+			env = map[string]string{}
+		}
+		env[name] = values[0]
+	}
+	return env
+}
+
 // createLoggingMiddleware creates middleware that logs all MCP method calls
 func createLoggingMiddleware() mcp.Middleware {
 	return func(next mcp.MethodHandler) mcp.MethodHandler {
@@ -68,7 +188,7 @@ func createLoggingMiddleware() mcp.Middleware {
 			req mcp.Request,
 		) (mcp.Result, error) {
 			start := time.Now()
-			sessionID := req.GetSession().ID()
+			sessionID := requestSessionID(req)
 
 			// Log request details
 			log.Printf("[REQUEST] Session: %s | Method: %s", sessionID, method)
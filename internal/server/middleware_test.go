@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestInjectedEnv(t *testing.T) {
+	header := http.Header{}
+	for _, name := range []string{
+		"X-Codebraid-Env-Github-Token",
+		"X-Codebraid-Env-Aws-Region",
+		"X-Codebraid-Env-Ld-Preload",
+		"X-Codebraid-Env-Node-Options",
+		"X-Codebraid-Env-Pythonpath",
+		"X-Codebraid-Env-Path",
+		"X-Codebraid-Env-Codebraid-Session",
+		"X-Codebraid-Env-Other",
+		"X-Other",
+	} {
+		header.Set(name, "v")
+	}
+	extra := &mcp.RequestExtra{Header: header}
+
+	cases := []struct {
+		allow []string
+		want  map[string]string
+	}{
+		{nil, nil},
+		{[]string{"GITHUB_TOKEN", "AWS_*"}, map[string]string{"GITHUB_TOKEN": "v", "AWS_REGION": "v"}},
+		// Denied variables stay out even when everything is allowed
+		{[]string{"*"}, map[string]string{"GITHUB_TOKEN": "v", "AWS_REGION": "v", "OTHER": "v"}},
+	}
+	for _, c := range cases {
+		if got := injectedEnv(extra, c.allow); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("injectedEnv(%v) = %v, want %v", c.allow, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"log"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+)
+
+// consoleStreamer forwards sandbox console output to the client while the script runs.
+// Every line is sent as a logging notification (delivered once the client sets a log
+// level), and as a progress notification when the request carries a progress token.
+func consoleStreamer(ctx context.Context, req *mcp.CallToolRequest) sandbox.OutputFunc {
+	progressToken := req.Params.GetProgressToken()
+	lines := 0
+
+	return func(level, message string) {
+		log.Printf("[sandbox:%s] %s", level, message)
+
+		if err := req.Session.Log(ctx, &mcp.LoggingMessageParams{
+			Level:  loggingLevel(level),
+			Logger: "sandbox",
+			Data:   message,
+		}); err != nil {
+			log.Printf("Failed to send log notification: %v", err)
+		}
+
+		if progressToken == nil {
+			return
+		}
+		lines++
+		if err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: progressToken,
+			Message:       message,
+			Progress:      float64(lines),
+		}); err != nil {
+			log.Printf("Failed to send progress notification: %v", err)
+		}
+	}
+}
+
+// loggingLevel maps a console method to an MCP logging level
+func loggingLevel(consoleLevel string) mcp.LoggingLevel {
+	switch consoleLevel {
+	case "error":
+		return "error"
+	case "warn":
+		return "warning"
+	case "debug":
+		return "debug"
+	default:
+		return "info"
+	}
+}
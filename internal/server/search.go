@@ -0,0 +1,419 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/codegen"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+	"github.com/yousuf/codebraid-mcp/internal/fuzzy"
+	"github.com/yousuf/codebraid-mcp/internal/search"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// defaultSearchLimit is how many tools search_tools returns by default
+const defaultSearchLimit = 10
+
+// maxSummaryLength is the length descriptions are cut to in search results
+const maxSummaryLength = 160
+
+// Search modes of search_tools
+const (
+	SearchKeyword  = "keyword"  // Words of the query in tool names, descriptions and parameter names
+	SearchSemantic = "semantic" // Meaning of the query, by embeddings; see config.SemanticSearchConfig
+	SearchHybrid   = "hybrid"   // Both, their rankings fused
+)
+
+// rrfK damps the weight of the top ranks when fusing rankings, as in reciprocal
+// rank fusion
+const rrfK = 60
+
+// SearchToolsArgs represents the arguments for the search_tools tool
+type SearchToolsArgs struct {
+	Query  string `json:"query" jsonschema:"Keywords to look for in tool names, descriptions and parameter names, or a description of the task, e.g. 'create issue'"`
+	Server string `json:"server,omitempty" jsonschema:"Only search the tools of this MCP server"`
+	Limit  int    `json:"limit,omitempty" jsonschema:"Return the best matches up to this many (default: 10)"`
+	Mode   string `json:"mode,omitempty" jsonschema:"keyword, semantic or hybrid (default: hybrid when semantic search is configured, otherwise keyword)"`
+}
+
+// ToolMatch is a tool found by search_tools, summarized. Scores only order the
+// matches of one search.
+type ToolMatch struct {
+	Server      string  `json:"server"`
+	Tool        string  `json:"tool"`
+	Function    string  `json:"function"`
+	Score       float64 `json:"score"`
+	Description string  `json:"description,omitempty"`
+	Params      string  `json:"params"`
+}
+
+// Weights of a query term matching each part of a tool. A term matching a word
+// exactly counts fully, as the start of a word less, and as a word a typo or
+// two away less still.
+const (
+	nameWeight        = 10
+	paramWeight       = 4
+	serverWeight      = 3
+	descriptionWeight = 2
+
+	prefixFactor = 0.6
+	fuzzyFactor  = 0.4
+)
+
+// addSearchTools registers search_tools, which finds tools without reading the
+// libraries of every server
+func addSearchTools(server *mcp.Server, sessionMgr *session.Manager) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "search_tools",
+		Description: "Search the tools of all MCP servers by keywords, matching tool names, descriptions and parameter names, tolerating typos; when semantic search is configured, tools are also matched by meaning, so a description of the task finds them. Returns the best matches first, each with its server, the function to import from /servers/<server>/index.ts, its parameters and the first line of its description; describe_tool gives the details of one.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args SearchToolsArgs) (*mcp.CallToolResult, any, error) {
+		sessionCtx, err := getSessionFromContext(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.TrimSpace(args.Query) == "" {
+			return nil, nil, fmt.Errorf("query is required")
+		}
+		cfg := sessionMgr.Config()
+		mode := args.Mode
+		switch mode {
+		case "":
+			mode = SearchKeyword
+			if cfg.GetSemanticSearchEnabled() {
+				mode = SearchHybrid
+			}
+		case SearchKeyword:
+		case SearchSemantic, SearchHybrid:
+			if !cfg.GetSemanticSearchEnabled() {
+				return nil, nil, fmt.Errorf("%s search is not configured: set search.semantic in the codebraid config, or use keyword mode", mode)
+			}
+		default:
+			return nil, nil, fmt.Errorf("unknown mode %q: use keyword, semantic or hybrid", mode)
+		}
+		tools := sessionCtx.Tools()
+		if args.Server != "" {
+			serverTools, ok := sessionCtx.ServerTools(args.Server)
+			if !ok {
+				return nil, nil, fmt.Errorf("server %q not found. Available servers: %v", args.Server, sessionCtx.ClientHub.Servers())
+			}
+			tools = map[string][]*mcp.Tool{args.Server: serverTools}
+		}
+		limit := args.Limit
+		if limit <= 0 {
+			limit = defaultSearchLimit
+		}
+
+		var matches []ToolMatch
+		var notice string
+		switch mode {
+		case SearchKeyword:
+			matches = searchTools(tools, args.Query)
+		case SearchSemantic:
+			matches, err = semanticSearch(ctx, cfg, tools, args.Query)
+			if err != nil {
+				return nil, nil, err
+			}
+		case SearchHybrid:
+			matches = searchTools(tools, args.Query)
+			semantic, err := semanticSearch(ctx, cfg, tools, args.Query)
+			if err != nil {
+				// Keyword matches are still worth returning
+				log.Printf("Semantic search failed, returning keyword matches: %v", err)
+				notice = "Semantic search failed, only keywords were matched: " + err.Error()
+				mode = SearchKeyword
+			} else {
+				matches = fuseMatches(matches, semantic)
+			}
+		}
+		found := len(matches)
+		if len(matches) > limit {
+			matches = matches[:limit]
+		}
+		lines := make([]string, 0, len(matches)+1)
+		if notice != "" {
+			lines = append(lines, notice)
+		}
+		for _, match := range matches {
+			line := fmt.Sprintf("%s.%s(%s)", match.Server, match.Function, match.Params)
+			if match.Description != "" {
+				line += " - " + match.Description
+			}
+			lines = append(lines, line)
+		}
+		if found == 0 {
+			lines = append(lines, fmt.Sprintf("No tools match %q", args.Query))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: strings.Join(lines, "\n")},
+			},
+			StructuredContent: map[string]any{
+				"tools":   matches,
+				"matched": found,
+				"mode":    mode,
+			},
+		}, nil, nil
+	})
+}
+
+// searchTools scores every tool against the query, returning those matching it
+// best first. A tool's score is the sum, over the query's terms, of the best
+// weighted match of the term in its name, parameter names, server name or
+// description; tools matching no term are left out.
+func searchTools(servers map[string][]*mcp.Tool, query string) []ToolMatch {
+	terms := searchTerms(query)
+	matches := []ToolMatch{}
+	for server, tools := range servers {
+		serverWords := searchTerms(server)
+		for _, tool := range tools {
+			nameWords := searchTerms(tool.Name)
+			paramWords := searchTerms(strings.Join(paramNames(tool.InputSchema), " "))
+			descriptionWords := searchTerms(tool.Description)
+
+			score := 0.0
+			for _, term := range terms {
+				score += max(
+					nameWeight*termScore(term, nameWords),
+					paramWeight*termScore(term, paramWords),
+					serverWeight*termScore(term, serverWords),
+					descriptionWeight*termScore(term, descriptionWords),
+				)
+			}
+			if score == 0 {
+				continue
+			}
+			// Prefer a tool named after the whole query over one mentioning its words
+			if strings.Join(nameWords, "") == strings.Join(terms, "") {
+				score += nameWeight
+			}
+			matches = append(matches, newToolMatch(server, tool, float64(int(score*10))/10))
+		}
+	}
+	sortMatches(matches)
+	return matches
+}
+
+// newToolMatch summarizes a tool found with score
+func newToolMatch(server string, tool *mcp.Tool, score float64) ToolMatch {
+	return ToolMatch{
+		Server:      server,
+		Tool:        tool.Name,
+		Function:    codegen.FunctionName(tool.Name),
+		Score:       score,
+		Description: summarize(tool.Description),
+		Params:      paramSummary(tool.InputSchema),
+	}
+}
+
+// sortMatches orders matches best first, then by server and tool
+func sortMatches(matches []ToolMatch) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].Server != matches[j].Server {
+			return matches[i].Server < matches[j].Server
+		}
+		return matches[i].Tool < matches[j].Tool
+	})
+}
+
+// Semantic search indexes, by index file, shared by all sessions
+var (
+	toolIndexesMu sync.Mutex
+	toolIndexes   = make(map[string]*search.Index)
+)
+
+// toolIndex returns the index of the configured embedder. An index is shared by
+// the configs of one model, so it is given the embedder of this config, whose
+// headers, such as a rotated API key, may differ from the last one's.
+func toolIndex(cfg *config.Config) *search.Index {
+	semantic := cfg.GetSemanticSearch()
+	var embedder search.Embedder
+	if semantic.Embedder == config.EmbedderCommand {
+		embedder = search.NewCommandEmbedder(semantic.Command)
+	} else {
+		embedder = search.NewAPIEmbedder(semantic.URL, semantic.Model, semantic.Headers)
+	}
+	index := search.NewIndex(embedder, semantic.IndexDir)
+
+	toolIndexesMu.Lock()
+	defer toolIndexesMu.Unlock()
+	if existing, ok := toolIndexes[index.Path()]; ok {
+		existing.SetEmbedder(embedder)
+		return existing
+	}
+	toolIndexes[index.Path()] = index
+	return index
+}
+
+// semanticSearch ranks the tools by the similarity of their meaning to the query's
+func semanticSearch(ctx context.Context, cfg *config.Config, servers map[string][]*mcp.Tool, query string) ([]ToolMatch, error) {
+	var docs []search.Document
+	byID := make(map[string]ToolMatch)
+	for server, tools := range servers {
+		for _, tool := range tools {
+			id := server + "/" + tool.Name
+			docs = append(docs, search.Document{ID: id, Text: toolDocument(server, tool)})
+			byID[id] = newToolMatch(server, tool, 0)
+		}
+	}
+	if len(docs) == 0 {
+		return []ToolMatch{}, nil
+	}
+
+	results, err := toolIndex(cfg).Search(ctx, docs, query, cfg.GetSemanticSearch().MinScore)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]ToolMatch, len(results))
+	for i, result := range results {
+		matches[i] = byID[result.ID]
+		matches[i].Score = math.Round(result.Score*1000) / 1000
+	}
+	sortMatches(matches)
+	return matches, nil
+}
+
+// toolDocument is the text a tool is embedded as: its name in words, its
+// server, description and parameter names
+func toolDocument(server string, tool *mcp.Tool) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s (%s)", strings.Join(searchTerms(tool.Name), " "), server)
+	if tool.Description != "" {
+		text.WriteString(": " + strings.TrimSpace(tool.Description))
+	}
+	if params := paramNames(tool.InputSchema); len(params) > 0 {
+		text.WriteString("\nParameters: " + strings.Join(params, ", "))
+	}
+	return text.String()
+}
+
+// fuseMatches merges the keyword and semantic matches of a search by
+// reciprocal rank fusion: each tool scores the sum of 1/(rrfK+rank) over the
+// rankings it is in, so tools ranking well in both come first
+func fuseMatches(keyword, semantic []ToolMatch) []ToolMatch {
+	fused := make(map[string]*ToolMatch)
+	var order []string
+	for _, ranking := range [][]ToolMatch{keyword, semantic} {
+		for rank, match := range ranking {
+			key := match.Server + "/" + match.Tool
+			if fused[key] == nil {
+				match.Score = 0
+				fused[key] = &match
+				order = append(order, key)
+			}
+			fused[key].Score += 1 / float64(rrfK+rank+1)
+		}
+	}
+	matches := make([]ToolMatch, len(order))
+	for i, key := range order {
+		matches[i] = *fused[key]
+		matches[i].Score = math.Round(matches[i].Score*100000) / 100000
+	}
+	sortMatches(matches)
+	return matches
+}
+
+// searchTerms splits text into lowercase words, breaking identifiers such as
+// createIssue, create_issue and create-issue apart
+func searchTerms(text string) []string {
+	var terms []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			terms = append(terms, string(word))
+			word = word[:0]
+		}
+	}
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])):
+			flush()
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	flush()
+	return terms
+}
+
+// termScore returns how well a query term matches the best of words: 1 for the
+// same word, prefixFactor for the start of one and fuzzyFactor for one a typo
+// or two away, depending on the length of the term
+func termScore(term string, words []string) float64 {
+	best := 0.0
+	for _, word := range words {
+		switch {
+		case word == term:
+			return 1
+		case len(term) >= 2 && strings.HasPrefix(word, term):
+			best = max(best, prefixFactor)
+		case best < fuzzyFactor && len(term) >= 4 && fuzzy.Distance(term, word) <= typoAllowance(term):
+			best = fuzzyFactor
+		}
+	}
+	return best
+}
+
+// typoAllowance is how many edits a term may be from a word and still match it
+func typoAllowance(term string) int {
+	if len(term) >= 8 {
+		return 2
+	}
+	return 1
+}
+
+// paramNames returns the sorted names of the parameters of an input schema
+func paramNames(inputSchema any) []string {
+	schema, _ := inputSchema.(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// paramSummary lists the parameters of an input schema, marking optional ones
+// with "?", e.g. "title, body?"
+func paramSummary(inputSchema any) string {
+	schema, _ := inputSchema.(map[string]interface{})
+	required := make(map[string]bool)
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, name := range list {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+	names := paramNames(inputSchema)
+	for i, name := range names {
+		if !required[name] {
+			names[i] = name + "?"
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// summarize returns the first line of a description, cut to maxSummaryLength
+func summarize(description string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	runes := []rune(line)
+	if len(runes) > maxSummaryLength {
+		return string(runes[:maxSummaryLength-3]) + "..."
+	}
+	return line
+}
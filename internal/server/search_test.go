@@ -0,0 +1,102 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestSearchTerms(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"create issue", []string{"create", "issue"}},
+		{"createIssue", []string{"create", "issue"}},
+		{"create_issue", []string{"create", "issue"}},
+		{"create-issue", []string{"create", "issue"}},
+		{"getHTTPResponse", []string{"get", "http", "response"}},
+		{"list_s3_buckets", []string{"list", "s3", "buckets"}},
+		{"  Find, the PR!  ", []string{"find", "the", "pr"}},
+	}
+	for _, c := range cases {
+		if got := searchTerms(c.text); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("searchTerms(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestTermScore(t *testing.T) {
+	words := []string{"create", "pull", "request"}
+	cases := []struct {
+		term string
+		want float64
+	}{
+		{"create", 1},
+		{"req", prefixFactor},
+		{"r", 0},                // Too short to match as a prefix
+		{"reqest", fuzzyFactor}, // One typo
+		{"rqst", 0},             // Too many typos for a short term
+		{"pul", prefixFactor},
+		{"pulls", fuzzyFactor},
+		{"delete", 0},
+	}
+	for _, c := range cases {
+		if got := termScore(c.term, words); got != c.want {
+			t.Errorf("termScore(%q) = %v, want %v", c.term, got, c.want)
+		}
+	}
+	// Long terms tolerate two typos
+	if got := termScore("reposiotyr", []string{"repository"}); got != fuzzyFactor {
+		t.Errorf("termScore of a long term two typos away = %v, want %v", got, fuzzyFactor)
+	}
+}
+
+func TestSearchTools(t *testing.T) {
+	schema := func(params ...string) map[string]interface{} {
+		properties := map[string]interface{}{}
+		for _, param := range params {
+			properties[param] = map[string]interface{}{"type": "string"}
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+	servers := map[string][]*mcp.Tool{
+		"github": {
+			{Name: "create_issue", Description: "Create an issue in a repository", InputSchema: schema("repo", "title")},
+			{Name: "list_issues", Description: "List the issues of a repository", InputSchema: schema("repo")},
+			{Name: "create_pull_request", Description: "Open a pull request", InputSchema: schema("repo", "head", "base")},
+		},
+		"jira": {
+			{Name: "createTicket", Description: "File a new issue", InputSchema: schema("project", "summary")},
+		},
+	}
+	cases := []struct {
+		query string
+		want  []string // server/tool of the matches, best first
+	}{
+		// A tool named after the whole query ranks above ones mentioning its words
+		{"create issue", []string{"github/create_issue", "jira/createTicket", "github/create_pull_request", "github/list_issues"}},
+		{"createIssue", []string{"github/create_issue", "jira/createTicket", "github/create_pull_request", "github/list_issues"}},
+		// Names outweigh parameters, which outweigh descriptions
+		{"project", []string{"jira/createTicket"}},
+		{"issue", []string{"github/create_issue", "github/list_issues", "jira/createTicket"}},
+		// Typos still match
+		{"craete isue", []string{"github/create_issue", "jira/createTicket", "github/create_pull_request"}},
+		{"reposiotry", []string{"github/create_issue", "github/list_issues"}},
+		{"pul reqest", []string{"github/create_pull_request"}},
+		// The server name matches too
+		{"jira", []string{"jira/createTicket"}},
+		{"delete", nil},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, match := range searchTools(servers, c.query) {
+			got = append(got, match.Server+"/"+match.Tool)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("searchTools(%q) = %q, want %q", c.query, got, c.want)
+		}
+	}
+}
@@ -3,19 +3,48 @@ package server
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/yousuf/codebraid-mcp/internal/bundler"
+	"github.com/yousuf/codebraid-mcp/internal/codegen"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+	"github.com/yousuf/codebraid-mcp/internal/limiter"
 	"github.com/yousuf/codebraid-mcp/internal/sandbox"
 	"github.com/yousuf/codebraid-mcp/internal/session"
 )
 
 // ExecuteCodeArgs represents the arguments for the execute_code tool
 type ExecuteCodeArgs struct {
-	Code string `json:"code" jsonschema:"TypeScript code to execute in sandbox"`
+	Code       string `json:"code" jsonschema:"TypeScript code to execute in sandbox"`
+	Language   string `json:"language,omitempty" jsonschema:"Language of the code: 'typescript' (default) or 'python' when enabled by the server"`
+	Timeout    int    `json:"timeout,omitempty" jsonschema:"Optional execution timeout in seconds. Defaults to the session timeout (30 seconds unless configured)."`
+	Persistent bool   `json:"persistent,omitempty" jsonschema:"Run in the session's long-lived runtime so globals and module state set by earlier persistent runs are still available (default: false)"`
+	Input      any    `json:"input,omitempty" jsonschema:"Optional JSON value passed to the code, available as the global 'input' and via codebraid.input()"`
+	TypeCheck  bool   `json:"typeCheck,omitempty" jsonschema:"Type-check the code against the generated libraries first and return the type errors instead of running it (default: false unless enabled by the server)"`
+
+	Background bool `json:"background,omitempty" jsonschema:"Start the run as a background job and return its jobId at once; poll it with job_status, job_logs and job_result, stop it with cancel_job (default: false)"`
+
+	Deterministic bool   `json:"deterministic,omitempty" jsonschema:"Seed Math.random, freeze Date.now() and new Date() at the start time and disable network access (MCP tool calls still work), so runs are reproducible (default: false unless enabled by the server)"`
+	Seed          uint32 `json:"seed,omitempty" jsonschema:"Seed for Math.random in deterministic runs (default: 0)"`
+
+	Files map[string]string `json:"files,omitempty" jsonschema:"Optional helper modules keyed by relative path (e.g. 'lib/math.ts'); the code imports them relatively, e.g. import { add } from './lib/math'"`
+
+	NoCache bool `json:"noCache,omitempty" jsonschema:"Run the code even when the server has cached the result of an identical earlier run (default: false)"`
+
+	DryRun bool `json:"dryRun,omitempty" jsonschema:"Do not call downstream tools: calls are answered with placeholder results derived from the tools' output schemas and the planned calls are returned, to review them before a real run (default: false)"`
+
+	Analyze bool `json:"analyze,omitempty" jsonschema:"Report the bundle size and the bytes each module contributes in _meta['codebraid/bundle'], to see why a script is slow to build or run (default: false)"`
+
+	Snapshot string `json:"snapshot,omitempty" jsonschema:"Save the working directory after the run under this name (e.g. 'step1'), so a later run can restore its files"`
+	Restore  string `json:"restore,omitempty" jsonschema:"Name or ID of a snapshot of this session whose files are restored into the working directory before the run"`
 }
 
 // ListDirectoryArgs represents the arguments for the list_directory tool
@@ -74,23 +103,63 @@ Available Tools:
 1. "list_directory" - List contents of any directory in the virtual filesystem
 2. "read_file" - Read any file by absolute path
 3. "execute_code" - Execute TypeScript code with automatic bundling
+4. "job_status", "job_logs", "job_result", "cancel_job" - Follow background runs of execute_code
+5. "search_tools" - Find tools across all servers by keywords
+6. "describe_tool" - Show one tool's schemas, annotations and an example call
 
 Recommended Workflow:
 1. Call list_directory({ path: "/servers" }) to see available MCP servers
-2. Read server index: read_file({ path: "/servers/github/index.ts" })
-3. Or list specific server functions: list_directory({ path: "/servers/github" })
-4. Read specific functions: read_file({ path: "/servers/github/listRepos.ts" })
-5. Write your TypeScript code using namespace imports
-6. Your code must have an "exec()" function as the entry point
-7. Call execute_code with your complete code
+2. With many tools, find the ones you need: search_tools({ query: "list repositories" }),
+   then describe_tool({ server: "github", tool: "list_repos" }) for how to call one
+3. Read server index: read_file({ path: "/servers/github/index.ts" })
+4. Or list specific server functions: list_directory({ path: "/servers/github" })
+5. Read specific functions: read_file({ path: "/servers/github/listRepos.ts" })
+6. Write your TypeScript code using namespace imports
+7. Define an "exec()" entry point function, or use top-level await and return
+8. Call execute_code with your complete code
 
 Notes:
 - All paths are absolute and start with '/'
 - Use namespace imports (import * as) for best experience
 - Each function file has inline types for arguments and return values
 - All imports are automatically bundled before execution
-- The exec() function is required and serves as your code's entry point
-- Execution timeout: 30 seconds
+- The exec() function serves as your code's entry point; top-level await also works
+- Larger programs can be split into helper modules with "files", e.g.
+  { "lib/math.ts": "export const add = (a: number, b: number) => a + b;" },
+  imported from the code as "./lib/math"
+- Execution timeout: 30 seconds by default (override per run with "timeout")
+- Long runs can be started with "background": true, which returns a jobId at once;
+  poll with job_status, read console output with job_logs, get the result with job_result
+  (optionally waiting) and stop it with cancel_job. Finished jobs are kept for a while.
+- Executions are limited per session and server-wide; when all slots stay taken the result is
+  {"error": "...", "type": "busy"} and the run can be retried later
+- Pass "dryRun": true to run without calling any downstream tool: calls get placeholder results
+  built from the tools' output schemas, and the result lists the plannedCalls to review
+  before running the code for real
+- The server may cap the tool calls a run makes and the time it waits for them; a run over
+  that budget is stopped with {"error": "tool call budget exceeded: ...", "type": "tool_budget"}
+- Cancelling the request stops the run, its runtime and any pending tool calls;
+  the result is {"error": "execution cancelled", "type": "cancelled"}
+- console.log output is streamed live as log (and progress) notifications
+- The result's _meta["codebraid/usage"] reports wallTimeMs, cpuTimeMs, peakRssBytes, toolCalls,
+  outputBytes and resultBytes, to see why a run was slow or expensive
+- Pass "analyze": true to get the bundle size and the bytes each module contributes in
+  _meta["codebraid/bundle"]; a bundle over the server's size limit is rejected with
+  {"error": "...", "type": "bundle_size"}
+- Pass "snapshot": "<name>" to save the files a run left in its working directory, and
+  "restore": "<name>" in a later run to start from them; outputs are not included.
+  The snapshot's content ID is reported in _meta["codebraid/snapshot"]
+- When the server caches results, rerunning identical code with the same input returns the
+  earlier result without running it, marked with _meta["codebraid/cache"]; pass "noCache": true
+  to run it again
+- Console output and results are capped (1 MB each by default); past the cap, output is
+  dropped with a truncation notice and an oversized result is replaced by
+  {"truncated": true, "totalBytes", "limitBytes", "preview"}
+- Pass data with "input" instead of interpolating it into the code; it is available
+  as the global input and via codebraid.input(), e.g. const { owner } = codebraid.input();
+- Pass "persistent": true to reuse the session's runtime across runs; values stored on
+  globalThis (e.g. globalThis.cache = ...) are still there in the next persistent run.
+  The runtime is restarted after a failed or timed-out run.
 `,
 	})
 
@@ -102,7 +171,8 @@ Notes:
 		Name: "execute_code",
 		Description: `Execute TypeScript code in a sandboxed environment.
 
-REQUIRED: Your code must define a function named "exec()" as the entry point.
+Your code should define a function named "exec()" as the entry point.
+Short scripts may instead use top-level await and a top-level return.
 
 Basic structure:
     async function exec() {
@@ -112,11 +182,18 @@ Basic structure:
 
 The exec() function:
 - Can be async or sync
-- Can return any JSON-serializable value
+- Can return any JSON-serializable value; it is returned as text and as structuredContent
+  (objects as-is, other values as {"result": value}), so there is no need to console.log JSON
 - Should have a strong return type (highly recommended for type safety)
 - Is automatically called when your code executes
 - Does not need to be exported
 
+Top-level await works without an exec() function:
+    import * as github from './servers/github';
+
+    const repos = await github.listRepos({ owner: "octocat" });
+    return repos.length;
+
 Complete Example with Strong Typing:
     import * as github from './servers/github';
     import * as filesystem from './servers/filesystem';
@@ -139,9 +216,59 @@ Complete Example with Strong Typing:
 Runtime Environment:
 - Imports from './servers/*' are bundled automatically
 - Use namespace imports (import * as) for best experience
-- Execution timeout: 30 seconds
-- No access to Node.js built-ins or filesystem
+- Pass "background": true to run as a background job and get a jobId immediately; use
+  job_status, job_logs, job_result and cancel_job with it. Console output is kept with the
+  job instead of being streamed
+- Pass "files" to split larger programs into helper modules keyed by relative path,
+  e.g. {"lib/math.ts": "export const add = ..."} imported as './lib/math'
+- Execution timeout: 30 seconds by default (override per run with "timeout")
+- console.log output is streamed live as log (and progress) notifications
+- Console output and results are capped (1 MB each by default); past the cap, output is
+  dropped with a truncation notice and an oversized result is replaced by
+  {"truncated": true, "totalBytes", "limitBytes", "preview"}
+- Pass data with "input" instead of interpolating it into the code; it is available
+  as the global input and via codebraid.input(), e.g. const { owner } = codebraid.input();
+- Pass "persistent": true to reuse the session's runtime across runs; values stored on
+  globalThis (e.g. globalThis.cache = ...) are still there in the next persistent run.
+  The runtime is restarted after a failed or timed-out run.
+- In a read-only session only tools annotated as read-only are generated, and calling
+  any other tool fails
+- The host environment is not visible; only variables allowed by the server config
+  (or injected by the client) can be read
+- Secrets configured on the server are read with secrets.get("name"); their values are
+  redacted from console output, results and errors
+- Stash state between runs in the session's key-value store: await codebraid.kv.set("key", value)
+  stores a JSON value, await codebraid.kv.get("key") reads it back (undefined when unset) and
+  await codebraid.kv.delete("key") removes it. The store holds up to 1 MB
+- Pass "deterministic": true (optionally with a "seed") for reproducible runs: Math.random is
+  seeded, Date.now() and new Date() return the start time, and network access is disabled
+  (MCP tool calls are unaffected); deterministic runs cannot be persistent
+- Pass "typeCheck": true to check the code with tsc first; type errors are returned
+  with file, line and message (in structuredContent.diagnostics) and the code is not run
+- Syntax and import errors found while bundling are returned the same way, with a
+  suggestion when the bundler offers one
+- Files written under codebraid.outputDir (e.g. with Deno.writeTextFile or Bun.write) are returned
+  as resource links (codebraid://outputs/...) readable with resources/read for the rest of the session,
+  unless the server prunes old outputs. When saving outputs or a snapshot would exceed the session's
+  storage quota, its oldest outputs are removed, or else the result is returned without them and
+  _meta["codebraid/quota"] reports what was not saved
+- No access to Node.js built-ins or filesystem outside the scratch and output directories
 - No access to DOM or browser APIs
+
+Python (when enabled by the server):
+Pass "language": "python" to run a Python script instead. Tools are imported from the
+servers package and called with keyword arguments; the value of the last expression is the result:
+    from servers import github
+
+    repos = github.list_repos(owner="octocat")
+    {"totalRepos": len(repos)}
+
+- Generated modules are readable at /python/servers/<server>.py
+- print() output is streamed like console.log; input is available as the global input
+  and via codebraid.input(), secrets via codebraid.secrets.get("name"), and the key-value
+  store via codebraid.kv.get("key"), codebraid.kv.set("key", value) and codebraid.kv.delete("key")
+- Files written under codebraid.output_dir are returned as resource links
+- "files", "typeCheck", "persistent" and "deterministic" are TypeScript-only
 `,
 	}, func(ctx context.Context, req *mcp.CallToolRequest, args ExecuteCodeArgs) (*mcp.CallToolResult, any, error) {
 		sessionCtx, err := getSessionFromContext(ctx)
@@ -149,40 +276,21 @@ Runtime Environment:
 			return nil, nil, err
 		}
 
-		// Step 1: Bundle the code using session's bundle directory
-		b, err := bundler.New()
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to create bundler: %w", err)
-		}
-
-		codeWithCaller := fmt.Sprintf(`%s
-exec();
-`, args.Code)
-		bundledCode, sourceMap, err := b.BundleWithSession(sessionCtx.BundleDir, codeWithCaller)
-		if err != nil {
-			return nil, nil, fmt.Errorf("bundling failed: %w", err)
-		}
-
-		// Step 2: Create sandbox
-		sb, err := sandbox.NewSandbox(ctx, "./wasm/dist/sandbox.wasm", sessionCtx.ClientHub)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to create sandbox: %w", err)
+		if sessionMgr.Draining() {
+			return shuttingDownResult(), nil, nil
 		}
-		defer sb.Close()
-
-		// Step 3: Execute bundled code
-		result, err := sb.ExecuteCode(bundledCode, sourceMap)
-		if err != nil {
-			return nil, nil, fmt.Errorf("execution failed: %w", err)
+		if args.Background {
+			return startJob(ctx, sessionMgr, sessionCtx, args)
 		}
-
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{Text: result},
-			},
-		}, nil, nil
+		result, err := executeCode(ctx, sessionMgr, sessionCtx, args, consoleStreamer(ctx, req))
+		return result, nil, err
 	})
 
+	addJobTools(server)
+	addHistoryTools(server)
+	addSearchTools(server, sessionMgr)
+	addDescribeTool(server)
+
 	// Register list_directory tool
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "list_directory",
@@ -202,6 +310,9 @@ exec();
 		if path == "" {
 			// Root directory
 			output.WriteString("/\n")
+			if sessionMgr.Config().GetSandboxPythonEnabled() {
+				output.WriteString("├── python/servers/ (MCP servers as Python modules)\n")
+			}
 			output.WriteString("└── servers/ (MCP servers)\n")
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{
@@ -210,9 +321,24 @@ exec();
 			}, nil, nil
 		}
 
+		if (path == "python" || path == "python/servers") && sessionMgr.Config().GetSandboxPythonEnabled() {
+			// List the Python module of every MCP server
+			output.WriteString("/python/servers/\n")
+			for _, svr := range sessionCtx.ClientHub.Servers() {
+				tools, _ := sessionCtx.ServerTools(svr)
+				output.WriteString(fmt.Sprintf("├── %s.py (%d functions)\n", codegen.PythonModuleName(svr), len(tools)))
+			}
+			output.WriteString("└── __init__.py\n")
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: output.String()},
+				},
+			}, nil, nil
+		}
+
 		if path == "servers" {
 			// List all MCP servers
-			allTools := sessionCtx.ClientHub.Tools()
+			allTools := sessionCtx.Tools()
 			output.WriteString("/servers/\n")
 
 			serverCount := 0
@@ -236,7 +362,7 @@ exec();
 		if strings.HasPrefix(path, "servers/") {
 			// List specific server directory
 			serverName := strings.TrimPrefix(path, "servers/")
-			tools, ok := sessionCtx.ClientHub.ServerTools(serverName)
+			tools, ok := sessionCtx.ServerTools(serverName)
 			if !ok {
 				availableServers := sessionCtx.ClientHub.Servers()
 				return nil, nil, fmt.Errorf("directory '/servers/%s/' not found. Available servers: %v",
@@ -250,7 +376,7 @@ exec();
 					prefix = "├──"
 				}
 
-				funcName := toCamelCase(tool.Name)
+				funcName := codegen.FunctionName(tool.Name)
 				if args.WithDescriptions && tool.Description != "" {
 					output.WriteString(fmt.Sprintf("%s %s.ts - %s\n", prefix, funcName, tool.Description))
 				} else {
@@ -297,43 +423,733 @@ exec();
 		}, nil, nil
 	})
 
+	// Files written to codebraid.outputDir are served from the session that produced them
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "execution-output",
+		URITemplate: session.ArtifactURITemplate,
+		Description: "A file written to codebraid.outputDir by execute_code; available for the rest of the session unless old outputs are pruned",
+	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		sessionCtx, err := getSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		data, mimeType, err := sessionCtx.ReadArtifact(req.Params.URI)
+		if err != nil {
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
+		}
+
+		contents := &mcp.ResourceContents{URI: req.Params.URI, MIMEType: mimeType}
+		if isTextMIMEType(mimeType) {
+			contents.Text = string(data)
+		} else {
+			contents.Blob = data
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{contents}}, nil
+	})
+
 	return server
 }
 
-// TODO: Extract to util
-// toPascalCase converts a string to PascalCase
-func toPascalCase(s string) string {
-	// Split by underscore, dash, or space
-	parts := strings.FieldsFunc(s, func(r rune) bool {
-		return r == '_' || r == '-' || r == ' '
+// withArtifacts links the execution's output files into the tool result
+func withArtifacts(toolResult *mcp.CallToolResult, artifacts []session.Artifact) *mcp.CallToolResult {
+	for _, artifact := range artifacts {
+		size := artifact.Size
+		toolResult.Content = append(toolResult.Content, &mcp.ResourceLink{
+			URI:      artifact.URI,
+			Name:     artifact.Name,
+			MIMEType: artifact.MIMEType,
+			Size:     &size,
+		})
+	}
+	return toolResult
+}
+
+// isTextMIMEType reports whether a resource of this type is returned as text rather than a blob
+func isTextMIMEType(mimeType string) bool {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "image/svg+xml":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "text/")
+}
+
+// executeCode bundles and runs code for a session, sending console output to onOutput.
+// Failures caused by the script, such as limit violations, are results with IsError;
+// the error result is reserved for failures to run it at all.
+// The execution is recorded to the audit trail when auditing is enabled.
+func executeCode(ctx context.Context, sessionMgr *session.Manager, sessionCtx *session.SessionContext, args ExecuteCodeArgs, onOutput sandbox.OutputFunc) (result *mcp.CallToolResult, err error) {
+	// Count the execution, its output and its downstream calls in the session's
+	// metrics, and keep the calls in its tool call history
+	defer func() {
+		if err != nil {
+			sessionCtx.RecordExecution(true, 0)
+		} else {
+			sessionCtx.RecordExecution(result.IsError, resultSize(result))
+		}
+	}()
+	if onOutput != nil {
+		next := onOutput
+		onOutput = func(level, message string) {
+			sessionCtx.RecordOutput(len(message))
+			next(level, message)
+		}
+	}
+	countCall := func(call sandbox.ToolCall) {
+		sessionCtx.LogToolCall(call, args.DryRun)
+		if !args.DryRun {
+			sessionCtx.RecordToolCall()
+		}
+	}
+
+	trail := sessionMgr.AuditTrail()
+	if trail == nil {
+		return runCode(ctx, sessionMgr, sessionCtx, args, onOutput, countCall)
+	}
+
+	execution := trail.Start(sessionCtx.SessionID, args.Language, args.Code)
+	if args.DryRun {
+		execution.MarkDryRun()
+	}
+	result, err = runCode(ctx, sessionMgr, sessionCtx, args, onOutput, func(call sandbox.ToolCall) {
+		execution.ToolCall(call.ServerName, call.ToolName, call.Args, call.Duration, call.Error)
+		countCall(call)
+	})
+	execution.Finish(auditStatus(result, err))
+	return result, err
+}
+
+// ExecuteCode bundles and runs code for a session outside of an MCP request, as
+// "codebraid run" does, sending console output to onOutput; see executeCode
+func ExecuteCode(ctx context.Context, sessionMgr *session.Manager, sessionCtx *session.SessionContext, args ExecuteCodeArgs, onOutput sandbox.OutputFunc) (*mcp.CallToolResult, error) {
+	return executeCode(ctx, sessionMgr, sessionCtx, args, onOutput)
+}
+
+// resultSize is the size of a result's text and structured content
+func resultSize(result *mcp.CallToolResult) int {
+	size := 0
+	for _, content := range result.Content {
+		switch c := content.(type) {
+		case *mcp.TextContent:
+			size += len(c.Text)
+		case *mcp.ImageContent:
+			size += len(c.Data)
+		case *mcp.EmbeddedResource:
+			if c.Resource != nil {
+				size += len(c.Resource.Text) + len(c.Resource.Blob)
+			}
+		}
+	}
+	if result.StructuredContent != nil {
+		if encoded, err := json.Marshal(result.StructuredContent); err == nil {
+			size += len(encoded)
+		}
+	}
+	return size
+}
+
+// runCode is executeCode without auditing; tool calls are reported to onToolCall
+func runCode(ctx context.Context, sessionMgr *session.Manager, sessionCtx *session.SessionContext, args ExecuteCodeArgs, onOutput sandbox.OutputFunc, onToolCall sandbox.ToolCallFunc) (*mcp.CallToolResult, error) {
+	cfg := sessionMgr.Config()
+	python, err := isPython(cfg, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deterministic runs have no network, which a persistent runtime created with it cannot take away
+	deterministic := args.Deterministic || cfg.GetSandboxDeterministic()
+	if deterministic && args.Persistent {
+		return nil, fmt.Errorf("deterministic runs cannot be persistent")
+	}
+	if deterministic && python {
+		return nil, fmt.Errorf("deterministic runs are not supported for Python")
+	}
+
+	var restore []byte
+	if args.Restore != "" {
+		if restore, err = sessionCtx.LoadSnapshot(args.Restore); err != nil {
+			return nil, err
+		}
+	}
+	if args.Snapshot != "" {
+		if err := session.ValidateSnapshotName(args.Snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	// Persistent runs depend on the state earlier runs left behind, and a cached
+	// result would not save the requested snapshot, so neither is cached, nor are
+	// dry runs. Runs that used the key-value store are not cached either, see below.
+	var cacheKey string
+	if sessionCtx.ResultCacheEnabled() && !args.Persistent && args.Snapshot == "" && !args.DryRun && !args.NoCache && !args.Analyze {
+		if cacheKey, err = resultCacheKey(sessionCtx, args, python, deterministic, restore); err != nil {
+			return nil, err
+		}
+		if cached, age, ok := sessionCtx.CachedResult(cacheKey); ok {
+			log.Printf("Session %s: returning cached result from %s ago", sessionCtx.SessionID, age.Round(time.Millisecond))
+			return cacheHitResult(cached, age), nil
+		}
+	}
+
+	// Step 1: Bundle the code using session's bundle directory; Python scripts run as written.
+	// Unless a toolchain is configured, the bun runtime also bundles with bun instead of the embedded esbuild
+	bundledCode, sourceMap := args.Code, ""
+	var bundleReport map[string]any
+	if !python {
+		b, err := bundler.New(BundlerToolchain(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bundler: %w", err)
+		}
+
+		out, diagnostics, err := bundle(ctx, sessionMgr.BundleLimiter(), b, sessionCtx.BundleDir, args.Code, args.Files, args.TypeCheck || cfg.GetBundlerTypeCheck(), args.Analyze)
+		sessionCtx.RecordBundle(out.Stats.Duration)
+		if args.Analyze {
+			bundleReport = bundleMeta(b.Toolchain(), out.Stats)
+		}
+		if err != nil {
+			if busy, ok := busyResult(ctx, err); ok {
+				return busy, nil
+			}
+			var sizeErr *bundler.SizeError
+			if errors.As(err, &sizeErr) {
+				return bundleSizeResult(sizeErr, bundleReport), nil
+			}
+			var buildErr *bundler.BuildError
+			if errors.As(err, &buildErr) {
+				return buildErrorResult(buildErr), nil
+			}
+			return nil, err
+		}
+		if len(diagnostics) > 0 {
+			return typeCheckResult(diagnostics), nil
+		}
+		bundledCode, sourceMap = out.JS, out.SourceMap
+	}
+
+	// Executions take a session slot first, so one busy session queues on its
+	// own limit instead of filling the global queue
+	for _, l := range []*limiter.Limiter{sessionCtx.ExecutionLimiter(), sessionMgr.ExecutionLimiter()} {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			if busy, ok := busyResult(ctx, err); ok {
+				return busy, nil
+			}
+			return nil, err
+		}
+		defer release()
+	}
+
+	var plan *dryRunPlan
+	if args.DryRun {
+		plan = &dryRunPlan{}
+		onToolCall = plan.record(onToolCall)
+	}
+
+	// Step 2: Create sandbox
+	timeout := executionTimeout(args.Timeout, sessionCtx.ExecTimeout, cfg)
+	newExecutor := func(ctx context.Context) (sandbox.Executor, error) {
+		opts := sandboxOptions(cfg)
+		opts.Limits.Timeout = timeout
+		opts.LibDir = sessionCtx.BundleDir
+		opts.Persistent = args.Persistent
+		// Warm processes are started without session values, so sessions that inject some start their own
+		if sessionEnv := sessionCtx.Env(); len(sessionEnv) == 0 {
+			opts.Pool = sessionMgr.SandboxPool()
+		} else {
+			opts.Env = mergeEnv(opts.Env, sessionEnv)
+		}
+		opts.SessionID = sessionCtx.SessionID
+		opts.ClientHub = sessionCtx.ClientHub
+		if python {
+			// Warm processes run the configured JavaScript runtime
+			opts.Runtime = sandbox.RuntimePython
+			opts.Pool = nil
+		}
+		if deterministic {
+			// Warm processes were started with the configured network access
+			opts.Policy.AllowNet = nil
+			opts.Docker.Network = "none"
+			opts.Pool = nil
+		}
+
+		sb, err := sandbox.New(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sandbox: %w", err)
+		}
+		return sb, nil
+	}
+
+	// Step 3: Execute bundled code
+	run := sandbox.Run{
+		Code:      bundledCode,
+		SourceMap: sourceMap,
+		OnOutput:  onOutput,
+		Timeout:   timeout,
+
+		OnToolCall: onToolCall,
+
+		Restore:  restore,
+		Snapshot: args.Snapshot != "",
+		DryRun:   args.DryRun,
+
+		KV:       sessionCtx.KV(),
+		ReadOnly: sessionCtx.ReadOnly(),
+	}
+	if deterministic {
+		run.Deterministic = &sandbox.Determinism{Seed: args.Seed, Now: time.Now()}
+	}
+	if args.Input != nil {
+		if run.Input, err = json.Marshal(args.Input); err != nil {
+			return nil, fmt.Errorf("invalid input: %w", err)
+		}
+	}
+	var result sandbox.Result
+	if args.Persistent {
+		// The runtime outlives this request, so it must not inherit its cancellation
+		err = sessionCtx.RunPersistent(ctx, func() (sandbox.Executor, error) {
+			return newExecutor(context.WithoutCancel(ctx))
+		}, func(sb sandbox.Executor) error {
+			result, err = sb.ExecuteCode(ctx, run)
+			return err
+		})
+	} else {
+		var sb sandbox.Executor
+		sb, err = newExecutor(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer sb.Close()
+		result, err = sb.ExecuteCode(ctx, run)
+	}
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		// Cancelled by the client, possibly before the run started (a persistent run
+		// waiting for its turn); the runtime and its tool calls have been stopped
+		err = sandbox.CancelledError()
+	}
+	if err != nil {
+		// Limit violations are the script's fault, report them to the model as a result
+		var limitErr *sandbox.LimitError
+		if errors.As(err, &limitErr) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: limitErr.Output()},
+				},
+			}, nil
+		}
+		return nil, fmt.Errorf("execution failed: %w", err)
+	}
+
+	usage := result.Usage
+	log.Printf("Session %s: execution took %s (cpu %s, peak RSS %d KB, %d tool calls, %d bytes of output)",
+		sessionCtx.SessionID, usage.WallTime.Round(time.Millisecond), usage.CPUTime.Round(time.Millisecond), usage.PeakRSS>>10, usage.ToolCalls, usage.OutputBytes)
+
+	// The script has run, so its result is returned even when what it saved is over the quota
+	artifacts, err := sessionCtx.SaveArtifacts(result.Artifacts)
+	var quotaErr *session.QuotaError
+	if err != nil && !errors.As(err, &quotaErr) {
+		return nil, err
+	}
+
+	toolResult := withArtifacts(executionResult(result), artifacts)
+	if quotaErr != nil {
+		withQuotaWarning(toolResult, "outputs", quotaErr)
+	}
+	if plan != nil {
+		toolResult.Content = append(toolResult.Content, plan.content())
+	}
+	if len(result.Snapshot) > 0 {
+		id, err := sessionCtx.SaveSnapshot(args.Snapshot, result.Snapshot)
+		switch {
+		case errors.As(err, &quotaErr):
+			withQuotaWarning(toolResult, "snapshot", quotaErr)
+		case err != nil:
+			return nil, err
+		default:
+			toolResult.Meta["codebraid/snapshot"] = map[string]interface{}{
+				"name":  args.Snapshot,
+				"id":    id,
+				"bytes": len(result.Snapshot),
+			}
+		}
+	}
+	if bundleReport != nil {
+		toolResult.Meta["codebraid/bundle"] = bundleReport
+	}
+	// A result missing what it saved is not replayed, nor is one read from or
+	// written to the key-value store, which later runs may find changed
+	if _, overQuota := toolResult.Meta["codebraid/quota"]; cacheKey != "" && !toolResult.IsError && !overQuota && !result.UsedKV {
+		sessionCtx.CacheResult(cacheKey, toolResult)
+	}
+	return toolResult, nil
+}
+
+// isPython reports whether args asks for a Python run, rejecting options that
+// only apply to TypeScript
+func isPython(cfg *config.Config, args ExecuteCodeArgs) (bool, error) {
+	switch args.Language {
+	case "", "typescript":
+		return false, nil
+	case "python":
+	default:
+		return false, fmt.Errorf("unsupported language %q: use \"typescript\" or \"python\"", args.Language)
+	}
+
+	switch {
+	case !cfg.GetSandboxPythonEnabled():
+		return false, fmt.Errorf("python execution is not enabled on this server")
+	case len(args.Files) > 0:
+		return false, fmt.Errorf("files are only supported for TypeScript")
+	case args.TypeCheck:
+		return false, fmt.Errorf("typeCheck is only supported for TypeScript")
+	case args.Persistent:
+		// The session's persistent runtime is a JavaScript one
+		return false, fmt.Errorf("persistent runs are only supported for TypeScript")
+	}
+	return true, nil
+}
+
+// executionResult converts an execution outcome to the tool result.
+// The script's return value is sent as text and, for clients that read it, as
+// structuredContent; values that are not JSON objects are wrapped as {"result": value}.
+// A thrown error is reported with IsError so callers need not inspect the text.
+// Resource usage is reported in _meta under "codebraid/usage".
+func executionResult(result sandbox.Result) *mcp.CallToolResult {
+	toolResult := &mcp.CallToolResult{
+		Meta:    mcp.Meta{"codebraid/usage": usageMeta(result.Usage)},
+		IsError: result.Failed,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: result.Output},
+		},
+	}
+	if result.Failed || result.Output == "" {
+		return toolResult
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(result.Output), &value); err != nil {
+		return toolResult
+	}
+	if object, ok := value.(map[string]any); ok {
+		toolResult.StructuredContent = object
+	} else {
+		toolResult.StructuredContent = map[string]any{"result": value}
+	}
+	return toolResult
+}
+
+// usageMeta renders execution usage with durations in milliseconds; figures the
+// runtime could not measure are omitted
+func usageMeta(usage sandbox.Usage) map[string]any {
+	meta := map[string]any{
+		"wallTimeMs":  usage.WallTime.Milliseconds(),
+		"toolCalls":   usage.ToolCalls,
+		"outputBytes": usage.OutputBytes,
+		"resultBytes": usage.ResultBytes,
+	}
+	if usage.CPUTime > 0 {
+		meta["cpuTimeMs"] = usage.CPUTime.Milliseconds()
+	}
+	if usage.PeakRSS > 0 {
+		meta["peakRssBytes"] = usage.PeakRSS
+	}
+	return meta
+}
+
+// bundle type-checks (when requested) and bundles code and its helper files while
+// holding a bundler slot. Type errors are returned as diagnostics, with no bundle.
+// A bundle over the size limit is returned with its *bundler.SizeError, for its stats.
+func bundle(ctx context.Context, slots *limiter.Limiter, b *bundler.Bundler, bundleDir, code string, files map[string]string, typeCheck, analyze bool) (out bundler.Output, diagnostics []bundler.Diagnostic, err error) {
+	release, err := slots.Acquire(ctx)
+	if err != nil {
+		return bundler.Output{}, nil, err
+	}
+	defer release()
+
+	if typeCheck {
+		diagnostics, err := b.TypeCheck(bundleDir, code, files)
+		if err != nil {
+			return bundler.Output{}, nil, fmt.Errorf("type check failed: %w", err)
+		}
+		if len(diagnostics) > 0 {
+			return bundler.Output{}, diagnostics, nil
+		}
+	}
+
+	out, err = b.Bundle(bundleDir, bundler.PrepareEntry(code), files, analyze)
+	if err != nil {
+		return out, nil, fmt.Errorf("bundling failed: %w", err)
+	}
+	return out, nil, nil
+}
+
+// bundleMeta renders bundle stats for _meta["codebraid/bundle"], listing the
+// modules largest first
+func bundleMeta(toolchain string, stats bundler.Stats) map[string]any {
+	meta := map[string]any{
+		"toolchain":  toolchain,
+		"bytes":      stats.Bytes,
+		"durationMs": stats.Duration.Milliseconds(),
+	}
+	if stats.Cached {
+		meta["cached"] = true
+	}
+	if len(stats.ModuleBytes) > 0 {
+		type moduleSize struct {
+			Path  string `json:"path"`
+			Bytes int    `json:"bytes"`
+		}
+		modules := make([]moduleSize, 0, len(stats.ModuleBytes))
+		for path, size := range stats.ModuleBytes {
+			modules = append(modules, moduleSize{Path: path, Bytes: size})
+		}
+		sort.Slice(modules, func(i, j int) bool {
+			if modules[i].Bytes != modules[j].Bytes {
+				return modules[i].Bytes > modules[j].Bytes
+			}
+			return modules[i].Path < modules[j].Path
+		})
+		meta["modules"] = modules
+	}
+	return meta
+}
+
+// bundleSizeResult reports a bundle over the size limit as a tool result the
+// model can act on, with the bundle analysis when it was requested
+func bundleSizeResult(err *bundler.SizeError, report map[string]any) *mcp.CallToolResult {
+	encoded, _ := json.Marshal(map[string]any{
+		"error":      err.Error(),
+		"type":       "bundle_size",
+		"bytes":      err.Bytes,
+		"limitBytes": err.Limit,
+	})
+	result := &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(encoded)},
+		},
+	}
+	if report != nil {
+		result.Meta = mcp.Meta{"codebraid/bundle": report}
+	}
+	return result
+}
+
+// busyResult reports a request turned away by a concurrency limit, or cancelled
+// while it waited for a slot, as a tool result the model can act on (e.g., retry later)
+func busyResult(ctx context.Context, err error) (*mcp.CallToolResult, bool) {
+	var output string
+	var busyErr *limiter.BusyError
+	switch {
+	case errors.As(err, &busyErr):
+		encoded, _ := json.Marshal(map[string]string{"error": busyErr.Error(), "type": "busy"})
+		output = string(encoded)
+	case errors.Is(ctx.Err(), context.Canceled):
+		output = sandbox.CancelledError().Output()
+	default:
+		return nil, false
+	}
+
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: output},
+		},
+	}, true
+}
+
+// shuttingDownResult refuses an execution while the server drains before stopping
+func shuttingDownResult() *mcp.CallToolResult {
+	encoded, _ := json.Marshal(map[string]string{"error": session.ErrShuttingDown.Error(), "type": "shutting_down"})
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(encoded)},
+		},
+	}
+}
+
+// withQuotaWarning notes on an execution's result that its outputs or snapshot
+// (what names them) were not saved because the session is over its storage quota
+func withQuotaWarning(toolResult *mcp.CallToolResult, what string, err *session.QuotaError) {
+	toolResult.Content = append(toolResult.Content, &mcp.TextContent{
+		Text: fmt.Sprintf("Warning: this execution's %s could not be saved: %v", what, err),
+	})
+	var notSaved []string
+	if quota, ok := toolResult.Meta["codebraid/quota"].(map[string]interface{}); ok {
+		notSaved, _ = quota["notSaved"].([]string)
+	}
+	toolResult.Meta["codebraid/quota"] = map[string]interface{}{
+		"notSaved":   append(notSaved, what),
+		"error":      err.Error(),
+		"usedBytes":  err.Used,
+		"limitBytes": err.Limit,
+	}
+}
+
+// typeCheckResult reports type errors in place of an execution result.
+// Each diagnostic is a line of text and an entry of structuredContent.diagnostics.
+func typeCheckResult(diagnostics []bundler.Diagnostic) *mcp.CallToolResult {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Type check failed with %d error(s); the code was not run:\n", len(diagnostics))
+	for _, d := range diagnostics {
+		sb.WriteString(d.String())
+		sb.WriteString("\n")
+	}
+
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: sb.String()},
+		},
+		StructuredContent: map[string]any{"diagnostics": diagnostics},
+	}
+}
+
+// buildErrorResult reports code the bundler rejected in place of an execution
+// result, with the parsed errors as structured diagnostics the model can repair from.
+// Unparsed toolchain output is passed through as is.
+func buildErrorResult(err *bundler.BuildError) *mcp.CallToolResult {
+	if len(err.Diagnostics) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Bundling failed; the code was not run:\n" + err.Error()},
+			},
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Bundling failed with %d error(s); the code was not run:\n", len(err.Diagnostics))
+	for _, d := range err.Diagnostics {
+		sb.WriteString(d.String())
+		sb.WriteString("\n")
+	}
+
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: sb.String()},
+		},
+		StructuredContent: map[string]any{"diagnostics": err.Diagnostics},
+	}
+}
+
+// sandboxOptions returns the executor settings shared by every execution
+func sandboxOptions(cfg *config.Config) sandbox.Options {
+	policy := cfg.GetSandboxPolicy()
+	return sandbox.Options{
+		Runtime:      cfg.GetSandboxRuntime(),
+		WasmPath:     cfg.GetSandboxWasmPath(),
+		WasmCacheDir: cfg.GetSandboxWasmCache(),
+		Policy:       policy,
+		Limits: sandbox.Limits{
+			Timeout:  time.Duration(cfg.GetSandboxTimeout()) * time.Second,
+			MemoryMB: policy.MaxMemoryMB,
+			CPUTime:  time.Duration(policy.MaxCPUSeconds) * time.Second,
+
+			OutputBytes: cfg.GetSandboxMaxOutputBytes(),
+			ResultBytes: cfg.GetSandboxMaxResultBytes(),
+
+			MaxToolCalls: policy.MaxToolCalls,
+			MaxToolTime:  time.Duration(policy.MaxToolSeconds) * time.Second,
+		},
+		Docker:      cfg.GetSandboxDocker(),
+		Env:         policy.Env,
+		Secrets:     cfg.GetSandboxSecrets(),
+		OSSandbox:   cfg.GetSandboxOSSandbox(),
+		Unsandboxed: cfg.GetSandboxAllowUnsandboxed(),
+		Python:      cfg.GetSandboxPython(),
+	}
+}
+
+// mergeEnv returns base with overrides applied on top
+func mergeEnv(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// SetSandboxHooks installs the configured pre- and post-execution hooks.
+// Functions they name must have been registered with sandbox.RegisterHook.
+func SetSandboxHooks(cfg *config.Config) error {
+	hooksCfg := cfg.GetSandboxHooks()
+	return sandbox.SetHooks(sandbox.Hooks{
+		Pre:  sandboxHooks(hooksCfg.Pre),
+		Post: sandboxHooks(hooksCfg.Post),
 	})
+}
 
-	for i, part := range parts {
-		if len(part) > 0 {
-			parts[i] = strings.ToUpper(part[0:1]) + part[1:]
+// sandboxHooks converts configured hooks to the sandbox's form
+func sandboxHooks(hooksCfg []config.HookConfig) []sandbox.Hook {
+	hooks := make([]sandbox.Hook, len(hooksCfg))
+	for i, hook := range hooksCfg {
+		hooks[i] = sandbox.Hook{
+			Name:         hook.Name,
+			Command:      hook.Command,
+			Func:         hook.Func,
+			Timeout:      time.Duration(hook.Timeout) * time.Second,
+			IgnoreErrors: hook.IgnoreErrors,
 		}
 	}
+	return hooks
+}
 
-	return strings.Join(parts, "")
+// BundlerToolchain picks the toolchain code is bundled with: the configured one,
+// or bun for the bun runtime when it is left to auto-detect
+func BundlerToolchain(cfg *config.Config) string {
+	toolchain := cfg.GetBundlerToolchain()
+	if toolchain == bundler.ToolchainAuto && cfg.GetSandboxRuntime() == sandbox.RuntimeBun {
+		toolchain = bundler.ToolchainBun
+	}
+	return toolchain
 }
 
-// TODO: Extract to util
-// toCamelCase converts a string to camelCase
-// Handles: snake_case, kebab-case, space-separated, PascalCase, and already-camelCase
-func toCamelCase(s string) string {
-	if len(s) == 0 {
-		return s
+// WarmBundler returns the warm-up for the sessions of the warm pool: it bundles an
+// empty script in the session's bundle directory, so the toolchain has loaded the
+// generated libraries before the first real request
+func WarmBundler(sessionMgr *session.Manager) func(*session.SessionContext) error {
+	return func(sessionCtx *session.SessionContext) error {
+		b, err := bundler.New(BundlerToolchain(sessionMgr.Config()))
+		if err != nil {
+			return err
+		}
+		_, _, err = bundle(context.Background(), sessionMgr.BundleLimiter(), b, sessionCtx.BundleDir, "", nil, false, false)
+		return err
 	}
+}
 
-	// If already camelCase or PascalCase (no underscores/dashes/spaces), just ensure first char is lowercase
-	if !strings.ContainsAny(s, "_- ") {
-		return strings.ToLower(s[0:1]) + s[1:]
+// NewSandboxPool starts the warm process pool configured for the sandbox runtime.
+// It returns nil when the pool is disabled.
+func NewSandboxPool(cfg *config.Config) (*sandbox.Pool, error) {
+	poolCfg := cfg.GetSandboxPool()
+	if poolCfg.Size <= 0 {
+		return nil, nil
 	}
+	return sandbox.NewPool(sandboxOptions(cfg), sandbox.PoolOptions{
+		Size:        poolCfg.Size,
+		IdleTTL:     time.Duration(poolCfg.IdleTTL) * time.Second,
+		PinSessions: poolCfg.PinSessions,
+	})
+}
 
-	// Otherwise, convert from snake_case/kebab-case/space-separated to camelCase
-	pascal := toPascalCase(s)
-	if len(pascal) == 0 {
-		return pascal
+// executionTimeout picks the timeout for one run: the requested value capped at
+// the configured maximum, otherwise the session default
+func executionTimeout(requestedSeconds int, sessionDefault time.Duration, cfg *config.Config) time.Duration {
+	if requestedSeconds <= 0 {
+		if sessionDefault > 0 {
+			return sessionDefault
+		}
+		return time.Duration(cfg.GetSandboxTimeout()) * time.Second
+	}
+	if max := cfg.GetSandboxMaxTimeout(); requestedSeconds > max {
+		requestedSeconds = max
 	}
-	return strings.ToLower(pascal[0:1]) + pascal[1:]
+	return time.Duration(requestedSeconds) * time.Second
 }
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/session"
+)
+
+// StdioSessionID is the session of the client served over stdio, whose MCP
+// session has no ID of its own. It is random, like the IDs of HTTP sessions, so
+// HTTP clients can't name the stdio session, e.g. to fork it.
+var StdioSessionID = "stdio-" + rand.Text()
+
+// ServeStdio serves the MCP server to one client over stdin and stdout, until the
+// client disconnects or ctx is done. The client's session is closed when it
+// disconnects; at shutdown it is left to the manager.
+func ServeStdio(ctx context.Context, sessionMgr *session.Manager) error {
+	err := NewMcpServer(sessionMgr).Run(ctx, &mcp.StdioTransport{})
+	if sessionMgr.Draining() || sessionMgr.GetSession(StdioSessionID) == nil {
+		return err
+	}
+	if err := sessionMgr.DeleteSession(StdioSessionID); err != nil {
+		log.Printf("Session %s: failed to close after the client disconnected: %v", StdioSessionID, err)
+	}
+	return err
+}
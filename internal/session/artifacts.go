@@ -0,0 +1,112 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+)
+
+// ArtifactURITemplate matches the URIs of execution outputs
+const ArtifactURITemplate = "codebraid://outputs/{execution}/{+name}"
+
+const artifactURIPrefix = "codebraid://outputs/"
+
+// Artifact describes an execution output stored in the session
+type Artifact struct {
+	URI      string
+	Name     string
+	MIMEType string
+	Size     int64
+}
+
+// SaveArtifacts stores the files an execution wrote under the session's bundle
+// directory, where they live until the session is deleted or they are pruned, and
+// returns their URIs. Over the session's storage quota it fails with a *QuotaError.
+func (s *SessionContext) SaveArtifacts(files []sandbox.Artifact) ([]Artifact, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+	var size int64
+	for _, file := range files {
+		size += int64(len(file.Data))
+	}
+	if err := s.reserve(size); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate execution ID: %w", err)
+	}
+	executionID := hex.EncodeToString(id)
+	dir := filepath.Join(s.BundleDir, "outputs", executionID)
+
+	artifacts := make([]Artifact, 0, len(files))
+	for _, file := range files {
+		if !filepath.IsLocal(filepath.FromSlash(file.Name)) {
+			return nil, fmt.Errorf("invalid output file name %q", file.Name)
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(file.Name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create outputs dir: %w", err)
+		}
+		if err := os.WriteFile(dst, file.Data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to save output %s: %w", file.Name, err)
+		}
+
+		segments := strings.Split(file.Name, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		artifacts = append(artifacts, Artifact{
+			URI:      artifactURIPrefix + executionID + "/" + strings.Join(segments, "/"),
+			Name:     file.Name,
+			MIMEType: artifactMIMEType(file.Name, file.Data),
+			Size:     int64(len(file.Data)),
+		})
+	}
+	return artifacts, nil
+}
+
+// ReadArtifact returns the content and MIME type of an output saved in this session.
+// URIs of other sessions' outputs are not found.
+func (s *SessionContext) ReadArtifact(uri string) ([]byte, string, error) {
+	rest, ok := strings.CutPrefix(uri, artifactURIPrefix)
+	if !ok {
+		return nil, "", os.ErrNotExist
+	}
+	executionID, escaped, ok := strings.Cut(rest, "/")
+	if !ok || executionID == "" || strings.ContainsAny(executionID, `.\`) {
+		return nil, "", os.ErrNotExist
+	}
+	name, err := url.PathUnescape(escaped)
+	if err != nil || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return nil, "", os.ErrNotExist
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.BundleDir, "outputs", executionID, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, artifactMIMEType(name, data), nil
+}
+
+// artifactMIMEType guesses a file's type from its extension, then its content
+func artifactMIMEType(name string, data []byte) string {
+	if mimeType := mime.TypeByExtension(path.Ext(name)); mimeType != "" {
+		return mimeType
+	}
+	return http.DetectContentType(data)
+}
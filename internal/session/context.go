@@ -1,10 +1,16 @@
 package session
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/yousuf/codebraid-mcp/internal/audit"
 	"github.com/yousuf/codebraid-mcp/internal/client"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+	"github.com/yousuf/codebraid-mcp/internal/limiter"
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
 )
 
 // SessionContext represents a session with its associated resources and lifecycle.
@@ -13,20 +19,65 @@ type SessionContext struct {
 	SessionID      string
 	ClientHub      *client.McpClientHub
 	CreatedAt      time.Time
-	BundleDir      string // Persistent directory for libs and bundling workspace
+	BundleDir      string        // Persistent directory for libs and bundling workspace
+	ExecTimeout    time.Duration // Default execution timeout for this session
 	lastAccessedAt time.Time
+	requests       int               // Requests in progress, see BeginRequest
+	retired        bool              // The manager is closing the session for being idle, see retire
+	env            map[string]string // Sandbox environment values injected by the client
 	mu             sync.RWMutex
+
+	executions *limiter.Limiter // Simultaneous executions within this session
+	executed   atomic.Int64     // Executions run, see RecordExecution
+	failed     atomic.Int64     // Executions that failed or returned an error result
+	toolCalls  atomic.Int64     // Downstream tool calls made by executions
+	outputs    atomic.Int64     // Bytes of results and console output returned
+	bundleTime atomic.Int64     // Nanoseconds spent bundling
+
+	persistent sandbox.Executor // Long-lived executor shared by persistent runs
+	execSlot   chan struct{}    // Held by the persistent run in progress; a channel so waiting can be cancelled
+	inherited  []byte           // Working directory of the session's fork source, restored into the next persistent executor
+
+	hub      *sharedHub // Sessions sharing ClientHub: this one and its forks or fork source
+	readOnly bool       // Only tools annotated readOnlyHint are generated and callable
+
+	// The servers the session chose and its overrides, see Options, so Reload
+	// connects it to what it asked for
+	servers   []string
+	overrides map[string]config.ServerOverride
+
+	jobsMu    sync.Mutex
+	jobs      map[string]*Job // Background jobs by ID, see StartJob
+	jobOrder  []string        // Job IDs, oldest first
+	jobLimits JobLimits
+
+	results *resultCache // Results of earlier executions, nil when caching is disabled
+
+	kvMu sync.Mutex // Serializes access to the key-value store, see KV
+
+	bundleQuota int64      // Bytes the bundle directory may hold; zero means no quota
+	storageMu   sync.Mutex // Serializes saving and pruning outputs and snapshots
+	scratchDir  string     // Working directory of the persistent executor, counted against the quota; guarded by storageMu
+
+	historyMu  sync.Mutex
+	history    []ToolCallEntry // Recent downstream tool calls, oldest first, see LogToolCall
+	historySeq int64           // Tool calls logged so far
+	redactor   *audit.Redactor // Redacts the arguments of logged tool calls
 }
 
 // NewSessionContext creates a new session context.
 func NewSessionContext(sessionID string, clientHub *client.McpClientHub) *SessionContext {
 	now := time.Now()
-	return &SessionContext{
+	s := &SessionContext{
 		SessionID:      sessionID,
 		ClientHub:      clientHub,
 		CreatedAt:      now,
 		lastAccessedAt: now,
+		execSlot:       make(chan struct{}, 1),
+		redactor:       audit.NewRedactor(nil),
 	}
+	s.hub = &sharedHub{sessions: []*SessionContext{s}}
+	return s
 }
 
 // UpdateLastAccessed updates the last accessed timestamp (thread-safe)
@@ -43,6 +94,67 @@ func (s *SessionContext) LastAccessedAt() time.Time {
 	return s.lastAccessedAt
 }
 
+// BeginRequest marks a request to the session as in progress until the returned
+// function is called, which also counts as an access. A session is not closed for
+// being idle while it has requests in progress. It returns false, beginning
+// nothing, when the manager has already retired the session to close it; the
+// session should then be looked up again.
+func (s *SessionContext) BeginRequest() (end func(), ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retired {
+		return nil, false
+	}
+	s.requests++
+	s.lastAccessedAt = time.Now()
+
+	return func() {
+		s.mu.Lock()
+		s.requests--
+		s.lastAccessedAt = time.Now()
+		s.mu.Unlock()
+	}, true
+}
+
+// retire marks the session as being closed unless it is busy, in the same step,
+// so no request can begin between the check and the close. It reports whether
+// the session was retired.
+func (s *SessionContext) retire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requests > 0 || s.runningJobs() > 0 {
+		return false
+	}
+	s.retired = true
+	return true
+}
+
+// Busy reports whether the session has requests or background jobs in progress
+func (s *SessionContext) Busy() bool {
+	s.mu.RLock()
+	requests := s.requests
+	s.mu.RUnlock()
+	return requests > 0 || s.runningJobs() > 0
+}
+
+// SetEnv replaces the environment values injected into this session's executions (thread-safe)
+func (s *SessionContext) SetEnv(env map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.env = env
+}
+
+// Env returns a copy of the session's injected environment values (thread-safe)
+func (s *SessionContext) Env() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	env := make(map[string]string, len(s.env))
+	for key, value := range s.env {
+		env[key] = value
+	}
+	return env
+}
+
 // Age returns the duration since the session was created
 func (s *SessionContext) Age() time.Duration {
 	return time.Since(s.CreatedAt)
@@ -52,3 +164,72 @@ func (s *SessionContext) Age() time.Duration {
 func (s *SessionContext) IdleDuration() time.Duration {
 	return time.Since(s.LastAccessedAt())
 }
+
+// ExecutionLimiter bounds simultaneous executions within this session
+func (s *SessionContext) ExecutionLimiter() *limiter.Limiter {
+	return s.executions
+}
+
+// RunPersistent runs fn with the session's persistent executor, calling create
+// to start one on first use, so successive runs share runtime state. The first
+// executor of a fork starts with its source's working directory, see ForkSession.
+// Runs are serialized; a run still waiting for its turn returns when ctx is done.
+// When fn fails the executor is closed and the next run starts fresh.
+func (s *SessionContext) RunPersistent(ctx context.Context, create func() (sandbox.Executor, error), fn func(sandbox.Executor) error) error {
+	select {
+	case s.execSlot <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.execSlot }()
+
+	if s.persistent == nil {
+		executor, err := create()
+		if err != nil {
+			return err
+		}
+		if s.inherited != nil {
+			if state, ok := sandbox.AsScratchState(executor); ok {
+				if err := state.RestoreScratch(s.inherited); err != nil {
+					executor.Close()
+					return err
+				}
+			}
+			s.inherited = nil
+		}
+		s.persistent = executor
+	}
+
+	defer s.trackScratch()
+	if err := fn(s.persistent); err != nil {
+		s.persistent.Close()
+		s.persistent = nil
+		return err
+	}
+	return nil
+}
+
+// trackScratch records the working directory of the persistent executor, which
+// outlives runs and so counts against the storage quota. Must be called with
+// execSlot held.
+func (s *SessionContext) trackScratch() {
+	dir := ""
+	if state, ok := sandbox.AsScratchState(s.persistent); ok {
+		dir = state.ScratchDir()
+	}
+	s.storageMu.Lock()
+	s.scratchDir = dir
+	s.storageMu.Unlock()
+}
+
+// ResetPersistent closes the persistent executor, discarding its runtime state
+func (s *SessionContext) ResetPersistent() {
+	s.execSlot <- struct{}{}
+	defer func() { <-s.execSlot }()
+
+	if s.persistent != nil {
+		s.persistent.Close()
+		s.persistent = nil
+	}
+	s.trackScratch()
+}
@@ -0,0 +1,22 @@
+package session
+
+import "testing"
+
+func TestRetireRefusesBusySessionsAndLaterRequests(t *testing.T) {
+	s := NewSessionContext("a", nil)
+	end, ok := s.BeginRequest()
+	if !ok {
+		t.Fatal("expected a request to begin on an open session")
+	}
+	if s.retire() {
+		t.Fatal("expected a session with a request in progress not to be retired")
+	}
+	end()
+
+	if !s.retire() {
+		t.Fatal("expected an idle session to be retired")
+	}
+	if _, ok := s.BeginRequest(); ok {
+		t.Error("expected no request to begin on a retired session")
+	}
+}
@@ -0,0 +1,258 @@
+package session
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+)
+
+// sharedHub tracks the sessions using a client hub. A fork shares the hub of its
+// source, and the hub is closed with the last session using it.
+type sharedHub struct {
+	mu       sync.Mutex
+	sessions []*SessionContext
+}
+
+// join adds a fork to the hub. It returns false when the hub was already closed.
+func (h *sharedHub) join(s *SessionContext) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.sessions) == 0 {
+		return false
+	}
+	h.sessions = append(h.sessions, s)
+	s.hub = h
+	return true
+}
+
+// leave removes a session from the hub and reports whether it was the last one.
+// Must be called with h.mu held.
+func (h *sharedHub) leave(s *SessionContext) bool {
+	for i, member := range h.sessions {
+		if member == s {
+			h.sessions = append(h.sessions[:i], h.sessions[i+1:]...)
+			return len(h.sessions) == 0
+		}
+	}
+	return false
+}
+
+// members returns the sessions using the hub
+func (h *sharedHub) members() []*SessionContext {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*SessionContext(nil), h.sessions...)
+}
+
+// releaseHub closes the session's client hub, unless a fork or the fork source
+// still uses it. The hub is closed under its lock, so Reload does not reconnect
+// servers of a closed hub.
+func (s *SessionContext) releaseHub() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if !s.hub.leave(s) {
+		return nil
+	}
+	return s.ClientHub.Close()
+}
+
+// snapshotRuntime archives the working directory of the session's persistent
+// runtime, waiting for the persistent run in progress. It returns nil when there
+// is no runtime or it has no working directory.
+func (s *SessionContext) snapshotRuntime(ctx context.Context) ([]byte, error) {
+	select {
+	case s.execSlot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-s.execSlot }()
+
+	if state, ok := sandbox.AsScratchState(s.persistent); ok {
+		return state.SnapshotScratch()
+	}
+	return nil, nil
+}
+
+// ForkSession opens session sessionID as a copy of session srcID, so an agent can
+// explore a branch without disturbing the source or initializing a session from
+// scratch. The fork shares the source's server connections and gets a copy of its
+// bundle directory, with the generated libraries, packages, snapshots and artifacts.
+// A fork of a read-only session is read-only.
+// With runtime set, the working directory of the source's persistent runtime is
+// restored into the fork's first persistent run; globals and module state live in
+// the runtime process and are not carried over. It fails if sessionID is open.
+func (m *Manager) ForkSession(ctx context.Context, srcID, sessionID string, runtime bool) (*SessionContext, error) {
+	session, created, err := m.fork(ctx, srcID, sessionID, runtime)
+	if err == nil && !created {
+		return nil, fmt.Errorf("session %q already exists", sessionID)
+	}
+	return session, err
+}
+
+// authorizeFork checks that a client asking for a fork presented the admin token.
+// Forking copies another session's files and connections, and a session ID is
+// not a secret from the operator's tooling, so it alone does not authorize one.
+func (m *Manager) authorizeFork(token string) error {
+	admin := m.Config().GetAdminToken()
+	if admin == "" {
+		return fmt.Errorf("forking a session requires admin.token to be set")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(admin)) != 1 {
+		return fmt.Errorf("forking a session requires the admin token")
+	}
+	return nil
+}
+
+// fork implements ForkSession and runs the session hooks. When sessionID is
+// already open, that session is returned instead, with created unset.
+func (m *Manager) fork(ctx context.Context, srcID, sessionID string, runtime bool) (session *SessionContext, created bool, err error) {
+	if existing := m.GetSession(sessionID); existing != nil {
+		return existing, false, nil
+	}
+	if m.draining.Load() {
+		return nil, false, ErrShuttingDown
+	}
+	src := m.GetSession(srcID)
+	if src == nil {
+		return nil, false, fmt.Errorf("session %q to fork not found", srcID)
+	}
+
+	var state []byte
+	if runtime {
+		if state, err = src.snapshotRuntime(ctx); err != nil {
+			return nil, false, fmt.Errorf("failed to snapshot the runtime of session %q: %w", srcID, err)
+		}
+	}
+
+	// Copying the bundle directory can be slow, so it happens before locking
+	session, err = m.copySession(src, sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+	session.inherited = state
+	discard := func() {
+		session.releaseHub()
+		os.RemoveAll(session.BundleDir)
+	}
+
+	m.mu.Lock()
+	if existing, exists := m.sessions[sessionID]; exists {
+		m.mu.Unlock()
+		discard()
+		return existing, false, nil
+	}
+	if m.sessions[srcID] != src {
+		m.mu.Unlock()
+		discard()
+		return nil, false, fmt.Errorf("session %q was closed while forking it", srcID)
+	}
+	evicted, err := m.makeRoomLocked()
+	if err == nil {
+		m.sessions[sessionID] = session
+	}
+	m.mu.Unlock()
+
+	if evicted != nil {
+		m.runEvictedHooks(evicted, EvictedLRU)
+	}
+	if err != nil {
+		discard()
+		return nil, false, err
+	}
+	log.Printf("Session %s: forked from session %s", sessionID, srcID)
+	m.runHooks(hookCreated, session)
+	return session, true, nil
+}
+
+// copySession creates a session sharing src's client hub, with a copy of its
+// bundle directory and injected environment
+func (m *Manager) copySession(src *SessionContext, sessionID string) (*SessionContext, error) {
+	session := m.newSessionContext(sessionID, src.ClientHub)
+	if !src.hub.join(session) {
+		return nil, fmt.Errorf("session %q was closed while forking it", src.SessionID)
+	}
+	session.env = src.Env()
+	session.readOnly = src.readOnly
+	session.servers, session.overrides = src.servers, src.overrides
+
+	dir, err := os.MkdirTemp("", fmt.Sprintf("codebraid-%s-", sessionID))
+	if err != nil {
+		session.releaseHub()
+		return nil, fmt.Errorf("failed to create bundle dir: %w", err)
+	}
+
+	// Libraries are regenerated under the session lock, so they are copied consistently
+	src.mu.RLock()
+	err = copyBundleDir(src.BundleDir, dir)
+	src.mu.RUnlock()
+	if err != nil {
+		session.releaseHub()
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to copy bundle dir: %w", err)
+	}
+	session.BundleDir = dir
+	return session, nil
+}
+
+// copyBundleDir copies a session bundle directory into dst, except the bundler's
+// work directories. Symlinks, such as linked packages, are recreated as they are.
+func copyBundleDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.IsDir():
+			if rel == "work" {
+				return filepath.SkipDir
+			}
+			return os.Mkdir(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		}
+		return nil
+	})
+}
+
+// copyFile copies a regular file, keeping its permissions
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+)
+
+// maxToolHistory bounds the tool calls a session remembers; the oldest are dropped first
+const maxToolHistory = 1000
+
+// Statuses of a tool call in the history
+const (
+	CallOK     = "ok"      // The tool returned a result
+	CallError  = "error"   // The call failed or the tool returned an error result
+	CallDryRun = "dry_run" // A dry run answered the call without calling the server
+)
+
+// ToolCallEntry is a downstream tool call made by one of the session's executions
+type ToolCallEntry struct {
+	Seq        int64                  `json:"seq"` // Position among all calls the session made, from 1
+	Time       time.Time              `json:"time"`
+	Server     string                 `json:"server"`
+	Tool       string                 `json:"tool"`
+	Args       map[string]interface{} `json:"args,omitempty"` // With secrets and sensitive keys redacted
+	DurationMs int64                  `json:"durationMs"`
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// ToolHistoryQuery selects tool calls from the history; empty fields match every call
+type ToolHistoryQuery struct {
+	Server string
+	Tool   string
+	Status string
+	Limit  int // The most recent calls returned; zero returns every match
+}
+
+// LogToolCall adds a finished tool call to the session's history, redacting its
+// arguments as the audit trail does
+func (s *SessionContext) LogToolCall(call sandbox.ToolCall, dryRun bool) {
+	entry := ToolCallEntry{
+		Time:       time.Now().Add(-call.Duration),
+		Server:     call.ServerName,
+		Tool:       call.ToolName,
+		Args:       s.redactor.Redact(call.Args),
+		DurationMs: call.Duration.Milliseconds(),
+		Status:     CallOK,
+		Error:      call.Error,
+	}
+	switch {
+	case call.Error != "":
+		entry.Status = CallError
+	case dryRun:
+		entry.Status = CallDryRun
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	s.historySeq++
+	entry.Seq = s.historySeq
+	s.history = append(s.history, entry)
+	if len(s.history) > maxToolHistory {
+		s.history = append(s.history[:0:0], s.history[len(s.history)-maxToolHistory:]...)
+	}
+}
+
+// ToolHistory returns the calls in the session's history matching q, oldest first,
+// and how many calls matched before q.Limit was applied
+func (s *SessionContext) ToolHistory(q ToolHistoryQuery) ([]ToolCallEntry, int) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	entries := []ToolCallEntry{}
+	for _, entry := range s.history {
+		if (q.Server == "" || entry.Server == q.Server) &&
+			(q.Tool == "" || entry.Tool == q.Tool) &&
+			(q.Status == "" || entry.Status == q.Status) {
+			entries = append(entries, entry)
+		}
+	}
+	matched := len(entries)
+	if q.Limit > 0 && matched > q.Limit {
+		entries = entries[matched-q.Limit:]
+	}
+	return entries, matched
+}
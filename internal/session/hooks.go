@@ -0,0 +1,90 @@
+package session
+
+import "log"
+
+// hookEvent identifies a point in a session's lifecycle hooks can run at
+type hookEvent string
+
+const (
+	hookCreated     hookEvent = "created"
+	hookDeleted     hookEvent = "deleted"
+	hookIdleEvicted hookEvent = "idle-evicted"
+)
+
+// EvictionReason says why the manager closed a session its client had not deleted
+type EvictionReason string
+
+const (
+	EvictedIdle   EvictionReason = "idle"    // Idle past the idle TTL
+	EvictedMaxAge EvictionReason = "max-age" // Older than the maximum age
+	EvictedLRU    EvictionReason = "lru"     // Least recently used when the session limit was reached
+)
+
+// OnSessionCreated registers fn to run after a session is created and connected
+// to its servers. Hooks run in the goroutine of the request that created the
+// session, in registration order, and should return quickly.
+func (m *Manager) OnSessionCreated(fn func(*SessionContext)) {
+	m.addHook(hookCreated, fn)
+}
+
+// OnSessionDeleted registers fn to run after a session is closed: deleted by its
+// client, evicted to make room for another, or expired. It does not run for the
+// sessions closed by CloseAll at shutdown.
+func (m *Manager) OnSessionDeleted(fn func(*SessionContext)) {
+	m.addHook(hookDeleted, fn)
+}
+
+// OnSessionIdleEvicted registers fn to run after the manager closes a session on
+// its own, with the reason: it was idle past the idle TTL, older than the
+// maximum age, or the least recently used when the session limit was reached.
+// The deleted hooks run after it.
+func (m *Manager) OnSessionIdleEvicted(fn func(*SessionContext, EvictionReason)) {
+	m.hooksMu.Lock()
+	defer m.hooksMu.Unlock()
+	m.evictedHooks = append(m.evictedHooks, fn)
+}
+
+func (m *Manager) addHook(event hookEvent, fn func(*SessionContext)) {
+	m.hooksMu.Lock()
+	defer m.hooksMu.Unlock()
+
+	if m.hooks == nil {
+		m.hooks = make(map[hookEvent][]func(*SessionContext))
+	}
+	m.hooks[event] = append(m.hooks[event], fn)
+}
+
+// runHooks calls the hooks registered for event
+func (m *Manager) runHooks(event hookEvent, session *SessionContext) {
+	m.hooksMu.RLock()
+	hooks := m.hooks[event]
+	m.hooksMu.RUnlock()
+
+	for _, fn := range hooks {
+		callHook(event, session, func() { fn(session) })
+	}
+}
+
+// runEvictedHooks calls the idle-evicted hooks, then the deleted hooks, for a
+// session the manager closed for reason
+func (m *Manager) runEvictedHooks(session *SessionContext, reason EvictionReason) {
+	m.hooksMu.RLock()
+	hooks := m.evictedHooks
+	m.hooksMu.RUnlock()
+
+	for _, fn := range hooks {
+		callHook(hookIdleEvicted, session, func() { fn(session, reason) })
+	}
+	m.runHooks(hookDeleted, session)
+}
+
+// callHook calls one hook. A panicking hook is logged and does not stop the
+// others, or take the reaper down with it.
+func callHook(event hookEvent, session *SessionContext, call func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Session %s: %s hook panicked: %v", session.SessionID, event, r)
+		}
+	}()
+	call()
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SessionInfo describes a session for operators
+type SessionInfo struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"createdAt"`
+	AgeSeconds  int64     `json:"ageSeconds"`
+	IdleSeconds int64     `json:"idleSeconds"`
+	Busy        bool      `json:"busy"`    // A request or background job is in progress
+	Servers     []string  `json:"servers"` // Connected MCP servers
+	BundleDir   string    `json:"bundleDir"`
+	BundleBytes int64     `json:"bundleBytes"`          // Size of the bundle directory; linked packages are not counted
+	QuotaBytes  int64     `json:"quotaBytes,omitempty"` // Storage quota of the bundle directory, if any
+	ReadOnly    bool      `json:"readOnly,omitempty"`   // Only read-only tools may be called
+
+	SessionMetrics
+}
+
+// Info describes the session. It walks the bundle directory to size it.
+func (s *SessionContext) Info() SessionInfo {
+	servers := s.ClientHub.Servers()
+	sort.Strings(servers)
+	return SessionInfo{
+		ID:             s.SessionID,
+		CreatedAt:      s.CreatedAt,
+		AgeSeconds:     int64(s.Age() / time.Second),
+		IdleSeconds:    int64(s.IdleDuration() / time.Second),
+		Busy:           s.Busy(),
+		Servers:        servers,
+		BundleDir:      s.BundleDir,
+		BundleBytes:    dirSize(s.BundleDir),
+		QuotaBytes:     s.bundleQuota,
+		ReadOnly:       s.readOnly,
+		SessionMetrics: s.Metrics(),
+	}
+}
+
+// ListSessions describes the open sessions, oldest first
+func (m *Manager) ListSessions() []SessionInfo {
+	m.mu.RLock()
+	sessions := make([]*SessionContext, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	m.mu.RUnlock()
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, session.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].CreatedAt.Equal(infos[j].CreatedAt) {
+			return infos[i].CreatedAt.Before(infos[j].CreatedAt)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// dirSize sums the sizes of the regular files under dir without following symlinks
+func dirSize(dir string) int64 {
+	if dir == "" {
+		return 0
+	}
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
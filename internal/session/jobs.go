@@ -0,0 +1,253 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// JobStatus is the state of a background job
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// JobLimits bounds the background jobs a session keeps
+type JobLimits struct {
+	MaxJobs     int           // Jobs retained per session, running or finished
+	MaxLogBytes int           // Console output kept per job; the oldest lines are dropped first
+	Retention   time.Duration // How long a finished job is kept
+}
+
+// JobLog is a console line written by a background job
+type JobLog struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// Job is an execution running in the background of a session.
+// Its console output and result outlive the request that started it.
+type Job struct {
+	ID        string
+	StartedAt time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu          sync.Mutex
+	status      JobStatus
+	finishedAt  time.Time
+	cancelled   bool
+	logs        []JobLog
+	logStart    int // Offset of logs[0] among all lines the job wrote
+	logBytes    int
+	maxLogBytes int
+	result      *mcp.CallToolResult
+}
+
+// Log records a console line, dropping the oldest lines past the size limit
+func (j *Job) Log(level, message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.logs = append(j.logs, JobLog{Level: level, Message: message})
+	j.logBytes += len(message)
+	for j.maxLogBytes > 0 && j.logBytes > j.maxLogBytes && len(j.logs) > 1 {
+		j.logBytes -= len(j.logs[0].Message)
+		j.logs = j.logs[1:]
+		j.logStart++
+	}
+}
+
+// Logs returns the retained lines from offset on and the offset to read from next.
+// Lines already dropped are skipped; dropped reports how many were.
+func (j *Job) Logs(offset int) (logs []JobLog, next, dropped int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	end := j.logStart + len(j.logs)
+	if offset < j.logStart {
+		dropped = j.logStart - max(offset, 0)
+		offset = j.logStart
+	}
+	if offset >= end {
+		return nil, end, dropped
+	}
+	return append([]JobLog(nil), j.logs[offset-j.logStart:]...), end, dropped
+}
+
+// LogLines returns how many console lines the job has written, including dropped ones
+func (j *Job) LogLines() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.logStart + len(j.logs)
+}
+
+// Status returns the job's state and, once it has finished, when it did
+func (j *Job) Status() (JobStatus, time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status, j.finishedAt
+}
+
+// Result returns the execution result, or nil while the job is running
+func (j *Job) Result() *mcp.CallToolResult {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.result
+}
+
+// Done is closed when the job finishes
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Cancel stops the job; it finishes as cancelled shortly after
+func (j *Job) Cancel() {
+	j.mu.Lock()
+	if j.status == JobRunning {
+		j.cancelled = true
+	}
+	j.mu.Unlock()
+	j.cancel()
+}
+
+// finish records the outcome of the job's run
+func (j *Job) finish(result *mcp.CallToolResult, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch {
+	case j.cancelled:
+		j.status = JobCancelled
+	case err != nil || result.IsError:
+		j.status = JobFailed
+	default:
+		j.status = JobSucceeded
+	}
+	if err != nil {
+		result = &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}},
+		}
+	}
+	j.result = result
+	j.finishedAt = time.Now()
+	close(j.done)
+}
+
+// StartJob runs fn in the background and returns the job tracking it.
+// fn runs with a context that keeps ctx's values but not its cancellation, so
+// the job outlives the request; it is cancelled by Cancel or when the session closes.
+// When the session already keeps its limit of jobs, the oldest finished job is
+// discarded; if all of them are still running the job is refused.
+func (s *SessionContext) StartJob(ctx context.Context, fn func(ctx context.Context, job *Job) (*mcp.CallToolResult, error)) (*Job, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate job ID: %w", err)
+	}
+
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	s.pruneJobsLocked()
+	if s.jobLimits.MaxJobs > 0 && len(s.jobOrder) >= s.jobLimits.MaxJobs {
+		if !s.evictFinishedJobLocked() {
+			return nil, fmt.Errorf("too many background jobs: %d are running, at most %d are kept per session", len(s.jobOrder), s.jobLimits.MaxJobs)
+		}
+	}
+
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	job := &Job{
+		ID:          hex.EncodeToString(id),
+		StartedAt:   time.Now(),
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		status:      JobRunning,
+		maxLogBytes: s.jobLimits.MaxLogBytes,
+	}
+	if s.jobs == nil {
+		s.jobs = make(map[string]*Job)
+	}
+	s.jobs[job.ID] = job
+	s.jobOrder = append(s.jobOrder, job.ID)
+
+	go func() {
+		defer cancel()
+		result, err := fn(jobCtx, job)
+		job.finish(result, err)
+	}()
+	return job, nil
+}
+
+// Job returns a background job of this session by ID
+func (s *SessionContext) Job(id string) (*Job, bool) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	s.pruneJobsLocked()
+	job, ok := s.jobs[id]
+	return job, ok
+}
+
+// CancelJobs cancels every running background job of this session
+func (s *SessionContext) CancelJobs() {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	for _, job := range s.jobs {
+		job.Cancel()
+	}
+}
+
+// runningJobs counts the session's background jobs still running
+func (s *SessionContext) runningJobs() int {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	running := 0
+	for _, job := range s.jobs {
+		if status, _ := job.Status(); status == JobRunning {
+			running++
+		}
+	}
+	return running
+}
+
+// pruneJobsLocked discards finished jobs older than the retention period
+func (s *SessionContext) pruneJobsLocked() {
+	if s.jobLimits.Retention <= 0 {
+		return
+	}
+	kept := s.jobOrder[:0]
+	for _, id := range s.jobOrder {
+		status, finishedAt := s.jobs[id].Status()
+		if status != JobRunning && time.Since(finishedAt) > s.jobLimits.Retention {
+			delete(s.jobs, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.jobOrder = kept
+}
+
+// evictFinishedJobLocked discards the oldest finished job, reporting whether there was one
+func (s *SessionContext) evictFinishedJobLocked() bool {
+	for i, id := range s.jobOrder {
+		if status, _ := s.jobs[id].Status(); status != JobRunning {
+			delete(s.jobs, id)
+			s.jobOrder = append(s.jobOrder[:i], s.jobOrder[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
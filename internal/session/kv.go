@@ -0,0 +1,98 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+)
+
+// kvFile holds a session's key-value store in its bundle directory, so the store
+// is copied into forks
+const kvFile = "kv.json"
+
+// Limits of a session's key-value store
+const (
+	maxKVKeyBytes = 256
+	maxKVBytes    = 1 << 20 // The whole store, encoded
+)
+
+// sessionKV implements sandbox.KVStore on the session's kv.json
+type sessionKV struct {
+	s *SessionContext
+}
+
+// KV returns the session's key-value store, which code reaches through codebraid.kv
+func (s *SessionContext) KV() sandbox.KVStore {
+	return sessionKV{s}
+}
+
+// Get implements sandbox.KVStore
+func (kv sessionKV) Get(key string) (json.RawMessage, bool, error) {
+	kv.s.kvMu.Lock()
+	defer kv.s.kvMu.Unlock()
+
+	values, err := kv.load()
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := values[key]
+	return value, ok, nil
+}
+
+// Set implements sandbox.KVStore
+func (kv sessionKV) Set(key string, value json.RawMessage) error {
+	if len(key) > maxKVKeyBytes {
+		return fmt.Errorf("key is longer than %d bytes", maxKVKeyBytes)
+	}
+
+	kv.s.kvMu.Lock()
+	defer kv.s.kvMu.Unlock()
+
+	values, err := kv.load()
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		if _, ok := values[key]; !ok {
+			return nil
+		}
+		delete(values, key)
+	} else {
+		values[key] = value
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxKVBytes {
+		return fmt.Errorf("the store would exceed its limit of %d KB", maxKVBytes>>10)
+	}
+	if err := writeFileAtomic(kv.path(), data); err != nil {
+		return fmt.Errorf("failed to save the store: %w", err)
+	}
+	return nil
+}
+
+// load reads the store; a session that never wrote to it has an empty one
+func (kv sessionKV) load() (map[string]json.RawMessage, error) {
+	values := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(kv.path())
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the store: %w", err)
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to read the store: %w", err)
+	}
+	return values, nil
+}
+
+func (kv sessionKV) path() string {
+	return filepath.Join(kv.s.BundleDir, kvFile)
+}
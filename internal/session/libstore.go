@@ -0,0 +1,242 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/codegen"
+)
+
+// libStore keeps the generated libraries of servers by content, so sessions whose
+// servers expose the same tools hard-link the same read-only files instead of
+// generating their own. A session whose tools of a server differ, because the
+// server changed them or the session is read-only, links or generates another
+// entry. The store belongs to this process and is removed by CloseAll.
+type libStore struct {
+	mu  sync.Mutex // Serializes generating, linking and pruning entries
+	dir string     // Created on first use
+}
+
+// Kinds of library kept in the store
+const (
+	libTypeScript = "ts" // A server's directory under servers/
+	libPython     = "py" // A server's module in the Python servers package
+)
+
+// libKey derives the store entry of a server's library from everything the
+// generated code depends on
+func libKey(kind, serverName string, tools []*mcp.Tool) (string, error) {
+	encoded, err := json.Marshal(tools)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash tools of server %q: %w", serverName, err)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", kind, serverName)
+	h.Write(encoded)
+	return kind + "-" + hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+// root returns the store directory, creating it if needed. Must be called with mu held.
+func (st *libStore) root() (string, error) {
+	if st.dir != "" {
+		return st.dir, nil
+	}
+	dir, err := os.MkdirTemp("", bundleDirPrefix+"libs-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create lib store: %w", err)
+	}
+	if err := writeOwner(dir); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to mark lib store: %w", err)
+	}
+	st.dir = dir
+	return dir, nil
+}
+
+// writeServerLibs places the TypeScript library of every server under serversDir,
+// generating the ones not in the store yet concurrently
+func (st *libStore) writeServerLibs(serversDir string, grouped map[string][]*mcp.Tool) error {
+	return st.place(libTypeScript, grouped, func(staging string, missing map[string][]*mcp.Tool) error {
+		return codegen.GenerateServerLibs(staging, missing, codegen.LibOptions{})
+	}, func(entry, serverName string) error {
+		return linkDir(entry, filepath.Join(serversDir, serverName))
+	})
+}
+
+// writePythonModules places the Python module of every server in packageDir
+func (st *libStore) writePythonModules(packageDir string, grouped map[string][]*mcp.Tool) error {
+	return st.place(libPython, grouped, func(staging string, missing map[string][]*mcp.Tool) error {
+		generator := codegen.NewPythonGenerator()
+		for serverName, tools := range missing {
+			dir := filepath.Join(staging, serverName)
+			if err := os.Mkdir(dir, 0755); err != nil {
+				return err
+			}
+			if err := generator.WriteServerModule(dir, serverName, tools); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(entry, _ string) error {
+		return linkDir(entry, packageDir)
+	})
+}
+
+// place generates the missing entries of grouped's servers into a staging directory
+// with generate, which writes one directory per server, moves them into the store
+// and hands every server's entry to link
+func (st *libStore) place(kind string, grouped map[string][]*mcp.Tool, generate func(staging string, missing map[string][]*mcp.Tool) error, link func(entry, serverName string) error) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	root, err := st.root()
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]string, len(grouped))
+	missing := make(map[string][]*mcp.Tool)
+	for serverName, tools := range grouped {
+		key, err := libKey(kind, serverName, tools)
+		if err != nil {
+			return err
+		}
+		entries[serverName] = filepath.Join(root, key)
+		if _, err := os.Stat(entries[serverName]); os.IsNotExist(err) {
+			missing[serverName] = tools
+		}
+	}
+
+	if len(missing) > 0 {
+		staging, err := os.MkdirTemp(root, ".staging-")
+		if err != nil {
+			return fmt.Errorf("failed to create lib store staging dir: %w", err)
+		}
+		defer os.RemoveAll(staging)
+		if err := generate(staging, missing); err != nil {
+			return err
+		}
+		for serverName := range missing {
+			if err := commitEntry(filepath.Join(staging, serverName), entries[serverName]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for serverName, entry := range entries {
+		if err := link(entry, serverName); err != nil {
+			return fmt.Errorf("failed to link library of server %q: %w", serverName, err)
+		}
+	}
+	return nil
+}
+
+// commitEntry makes a generated library read-only, so no session can change what
+// the others link, and moves it into the store
+func commitEntry(src, entry string) error {
+	files, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Chmod(filepath.Join(src, file.Name()), 0444); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(src, entry); err != nil {
+		return fmt.Errorf("failed to add library to the lib store: %w", err)
+	}
+	return nil
+}
+
+// linkDir hard-links the files of a store entry into dst, creating it if needed.
+// Files are copied where they cannot be linked, e.g. across filesystems.
+func linkDir(entry, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	files, err := os.ReadDir(entry)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		src, target := filepath.Join(entry, file.Name()), filepath.Join(dst, file.Name())
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Link(src, target); err != nil {
+			if err := copyFile(src, target); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// prune removes the entries no session links anymore that are older than minAge,
+// and returns how many it removed. Where link counts are unknown, nothing is removed.
+func (st *libStore) prune(minAge time.Duration) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.dir == "" {
+		return 0
+	}
+
+	entries, err := os.ReadDir(st.dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, entry := range entries {
+		path := filepath.Join(st.dir, entry.Name())
+		info, err := entry.Info()
+		if !entry.IsDir() || err != nil || time.Since(info.ModTime()) < minAge || !unlinked(path) {
+			continue
+		}
+		if os.RemoveAll(path) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// unlinked reports whether no file of a store entry is linked from a session
+func unlinked(entry string) bool {
+	files, err := os.ReadDir(entry)
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			return false
+		}
+		if links, known := linkCount(info); !known || links > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// close removes the store
+func (st *libStore) close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.dir != "" {
+		os.RemoveAll(st.dir)
+		st.dir = ""
+	}
+}
+
+// path returns the store directory, or "" before it is first used
+func (st *libStore) path() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.dir
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package session
+
+import "os"
+
+// linkCount is unknown where file info does not carry link counts
+func linkCount(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
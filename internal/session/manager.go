@@ -2,56 +2,113 @@ package session
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/audit"
 	"github.com/yousuf/codebraid-mcp/internal/bundler"
 	"github.com/yousuf/codebraid-mcp/internal/client"
 	"github.com/yousuf/codebraid-mcp/internal/codegen"
 	"github.com/yousuf/codebraid-mcp/internal/config"
+	"github.com/yousuf/codebraid-mcp/internal/limiter"
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
 )
 
-// toCamelCase converts snake_case to camelCase
-func toCamelCase(s string) string {
-	parts := strings.Split(s, "_")
-	if len(parts) == 0 {
-		return s
-	}
-
-	// First part stays lowercase
-	result := parts[0]
-
-	// Capitalize first letter of remaining parts
-	for _, part := range parts[1:] {
-		if len(part) > 0 {
-			result += strings.ToUpper(part[:1]) + part[1:]
-		}
-	}
-
-	return result
-}
-
 // Manager manages session contexts
 type Manager struct {
 	sessions map[string]*SessionContext
 	mu       sync.RWMutex
-	config   *config.Config
+	config   atomic.Pointer[config.Config] // Replaced by Reload
+	pool     *sandbox.Pool                 // Warm runtime processes shared by all sessions (optional)
+	audit    *audit.Trail                  // Records every execution (optional)
+
+	executions atomic.Pointer[limiter.Limiter] // Simultaneous executions across sessions
+	bundles    atomic.Pointer[limiter.Limiter] // Simultaneous transform, bundle and type-check runs
+
+	hooksMu      sync.RWMutex
+	hooks        map[hookEvent][]func(*SessionContext)   // See OnSessionCreated and friends
+	evictedHooks []func(*SessionContext, EvictionReason) // See OnSessionIdleEvicted
+
+	maxSessions int    // Concurrent sessions allowed; zero means no limit
+	limitPolicy string // What creating a session over maxSessions does, see config.SessionsConfig
+
+	allowOverrides bool            // Sessions may override server env and headers
+	redactor       *audit.Redactor // Redacts the arguments in sessions' tool call histories
+	readOnly       bool            // Every session is read-only, see Options.ReadOnly
+
+	idleTTL           time.Duration // Sessions idle this long are closed; zero keeps them
+	maxAge            time.Duration // Sessions this old are closed; zero keeps them
+	artifactRetention time.Duration // Execution outputs this old are removed; zero keeps them
+	orphanAge         time.Duration // Orphaned temp directories this old are removed; zero keeps them
+	libs              *libStore     // Generated libraries shared by sessions; nil generates them per session
+	done              chan struct{} // Closed by CloseAll to stop the reaper
+	wg                sync.WaitGroup
+	stop              sync.Once
+
+	draining atomic.Bool // Set by Shutdown; new sessions and executions are refused
+
+	warmSize int                         // Blank sessions kept ready; zero disables the warm pool
+	warmMu   sync.Mutex                  // Guards warm
+	warm     []*SessionContext           // Ready sessions, not yet assigned an ID
+	refill   chan struct{}               // Signals the warm pool to replenish
+	warmup   func(*SessionContext) error // Warms a blank session's bundler (optional)
 }
 
 // NewManager creates a new session manager
 func NewManager(cfg *config.Config) *Manager {
-	return &Manager{
-		sessions: make(map[string]*SessionContext),
-		config:   cfg,
+	sessionLimits := cfg.GetSessions()
+	m := &Manager{
+		sessions:          make(map[string]*SessionContext),
+		maxSessions:       sessionLimits.MaxSessions,
+		limitPolicy:       sessionLimits.LimitPolicy,
+		allowOverrides:    sessionLimits.AllowOverrides,
+		readOnly:          sessionLimits.ReadOnly,
+		redactor:          audit.NewRedactor(cfg.GetAudit().RedactKeys),
+		idleTTL:           time.Duration(sessionLimits.IdleTTL) * time.Second,
+		maxAge:            time.Duration(sessionLimits.MaxAge) * time.Second,
+		artifactRetention: time.Duration(sessionLimits.ArtifactRetention) * time.Second,
+		done:              make(chan struct{}),
+		warmSize:          sessionLimits.WarmSessions,
+		refill:            make(chan struct{}, 1),
+	}
+	m.config.Store(cfg)
+	m.setLimiters(cfg.GetConcurrency())
+	if !sessionLimits.DisableLibSharing {
+		m.libs = &libStore{}
 	}
+	if !sessionLimits.DisableOrphanCleanup {
+		m.orphanAge = time.Duration(sessionLimits.OrphanAge) * time.Second
+	}
+	// The reaper runs even when nothing expires yet, since Reload may set limits
+	m.wg.Add(1)
+	go m.reap()
+	return m
+}
+
+// Options configure a session when it is created; they are ignored for an existing one
+type Options struct {
+	Servers   []string                         // Configured servers to connect to; empty connects all
+	Overrides map[string]config.ServerOverride // Per-server env and headers, by server name
+
+	Fork        string // Session to fork the new session from instead, see ForkSession
+	ForkToken   string // Admin token authorizing the fork
+	ForkRuntime bool   // Carry over the persistent runtime's working directory when forking
+
+	// ReadOnly limits the session to the tools annotated with readOnlyHint: the
+	// others are left out of its libraries and calls to them are refused
+	ReadOnly bool
 }
 
-// GetOrCreateSession gets an existing session or creates a new one
-func (m *Manager) GetOrCreateSession(ctx context.Context, sessionID string) (*SessionContext, error) {
+// GetOrCreateSession gets an existing session or creates a new one with opts
+func (m *Manager) GetOrCreateSession(ctx context.Context, sessionID string, opts Options) (*SessionContext, error) {
 	// Try to get existing session
 	m.mu.RLock()
 	session, exists := m.sessions[sessionID]
@@ -60,46 +117,226 @@ func (m *Manager) GetOrCreateSession(ctx context.Context, sessionID string) (*Se
 	if exists {
 		return session, nil
 	}
+	if m.draining.Load() {
+		return nil, ErrShuttingDown
+	}
+	if opts.Fork != "" {
+		if err := m.authorizeFork(opts.ForkToken); err != nil {
+			return nil, err
+		}
+		if len(opts.Servers) > 0 || len(opts.Overrides) > 0 {
+			return nil, fmt.Errorf("a fork uses the servers of the session it is forked from")
+		}
+		if src := m.GetSession(opts.Fork); opts.ReadOnly && src != nil && !src.ReadOnly() {
+			return nil, fmt.Errorf("a fork is read-only only when the session it is forked from is")
+		}
+		session, _, err := m.fork(ctx, opts.Fork, sessionID, opts.ForkRuntime)
+		return session, err
+	}
 
-	// Create new session
+	// Hooks run once the manager is unlocked, so they may call back into it
+	session, created, evicted, err := m.createSession(ctx, sessionID, opts)
+	if evicted != nil {
+		m.runEvictedHooks(evicted, EvictedLRU)
+	}
+	if created {
+		m.runHooks(hookCreated, session)
+	}
+	return session, err
+}
+
+// createSession creates a session unless another request created it first.
+// evicted is the session closed to make room for it, if any.
+func (m *Manager) createSession(ctx context.Context, sessionID string, opts Options) (session *SessionContext, created bool, evicted *SessionContext, err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Double-check after acquiring write lock
 	if session, exists := m.sessions[sessionID]; exists {
-		return session, nil
+		return session, false, nil, nil
+	}
+
+	if evicted, err = m.makeRoomLocked(); err != nil {
+		return nil, false, evicted, err
+	}
+
+	if len(opts.Overrides) > 0 && !m.allowOverrides {
+		return nil, false, evicted, fmt.Errorf("server overrides are not allowed by the server config")
 	}
 
-	// Create new McpClientHub and connect to all servers
+	// A session connecting the default servers takes a warm one from the pool.
+	// Warm sessions are read-only only when every session is.
+	readOnly := opts.ReadOnly || m.readOnly
+	if len(opts.Servers) == 0 && len(opts.Overrides) == 0 && readOnly == m.readOnly {
+		if session = m.takeWarm(); session != nil {
+			m.assignWarm(session, sessionID)
+			m.sessions[sessionID] = session
+			return session, true, evicted, nil
+		}
+	}
+
+	sessionCfg, err := m.Config().ForSession(opts.Servers, opts.Overrides)
+	if err != nil {
+		return nil, false, evicted, err
+	}
+
+	// Create new McpClientHub and connect to the session's servers
 	clientHub := client.NewMcpClientHub()
-	if err := clientHub.Connect(ctx, m.config); err != nil {
-		return nil, fmt.Errorf("failed to connect client hub: %w", err)
+	if err := clientHub.Connect(ctx, sessionCfg); err != nil {
+		return nil, false, evicted, fmt.Errorf("failed to connect client hub: %w", err)
 	}
 
 	// Initialize session context
-	session = NewSessionContext(sessionID, clientHub)
+	session = m.newSessionContext(sessionID, clientHub)
+	session.readOnly = readOnly
+	session.servers = opts.Servers
+	session.overrides = opts.Overrides
 
 	// Setup bundle directory and generate library files
-	if err := m.initializeSessionBundleDir(ctx, session); err != nil {
+	if err := m.initializeSessionBundleDir(ctx, session, ""); err != nil {
 		// Clean up client hub on error
 		clientHub.Close()
-		return nil, fmt.Errorf("failed to initialize session bundle directory: %w", err)
+		return nil, false, evicted, fmt.Errorf("failed to initialize session bundle directory: %w", err)
 	}
 
-	// Setup automatic library regeneration when MCP servers notify of tool changes
-	clientHub.SetToolsRefreshedCallback(func(serverName string) {
-		log.Printf("Session %s: tools changed for server %q, regenerating libraries...", sessionID, serverName)
+	m.watchTools(session, sessionID)
+	m.sessions[sessionID] = session
+
+	return session, true, evicted, nil
+}
+
+// newSessionContext creates a session context with the configured limits
+func (m *Manager) newSessionContext(sessionID string, clientHub *client.McpClientHub) *SessionContext {
+	session := NewSessionContext(sessionID, clientHub)
+	session.ExecTimeout = time.Duration(m.Config().GetSandboxTimeout()) * time.Second
+	session.readOnly = m.readOnly
+	session.redactor = m.redactor
+	session.bundleQuota = int64(m.Config().GetSessions().MaxBundleMB) << 20
+	limits := m.Config().GetConcurrency()
+	session.executions = limiter.New("executions in this session", limits.MaxSessionExecutions, limits.MaxQueue, time.Duration(limits.QueueTimeout)*time.Second)
+	jobs := m.Config().GetJobs()
+	session.jobLimits = JobLimits{
+		MaxJobs:     jobs.MaxJobs,
+		MaxLogBytes: jobs.MaxLogKB << 10,
+		Retention:   time.Duration(jobs.Retention) * time.Second,
+	}
+	if cache := m.Config().GetResultCache(); cache.Enabled {
+		session.results = newResultCache(ResultCacheLimits{
+			TTL:        time.Duration(cache.TTL) * time.Second,
+			MaxEntries: cache.MaxEntries,
+		})
+	}
+	return session
+}
 
-		if err := regenerateLibForServer(session, serverName); err != nil {
-			log.Printf("Session %s: failed to regenerate libs for %q: %v", sessionID, serverName, err)
-		} else {
-			log.Printf("Session %s: successfully regenerated libs for %q", sessionID, serverName)
+// watchTools sets up automatic library regeneration when the session's MCP servers
+// notify of tool changes, for the session and its forks. sessionID is what the
+// session is logged as; its record is only saved once it is open under that ID.
+func (m *Manager) watchTools(session *SessionContext, sessionID string) {
+	session.ClientHub.SetToolsRefreshedCallback(func(serverName string) {
+		// Forks share the hub, so their libraries are regenerated too
+		for _, member := range session.hub.members() {
+			memberID := sessionID
+			if member != session {
+				memberID = member.SessionID
+			}
+			m.regenerateLibs(member, memberID, serverName)
 		}
 	})
+}
 
-	m.sessions[sessionID] = session
+// regenerateLibs regenerates a session's libraries for a server whose tools changed
+func (m *Manager) regenerateLibs(session *SessionContext, sessionID, serverName string) {
+	log.Printf("Session %s: tools changed for server %q, regenerating libraries...", sessionID, serverName)
+
+	if err := m.regenerateLibForServer(session, serverName, m.Config().GetSandboxPythonEnabled()); err != nil {
+		log.Printf("Session %s: failed to regenerate libs for %q: %v", sessionID, serverName, err)
+		return
+	}
+	log.Printf("Session %s: successfully regenerated libs for %q", sessionID, serverName)
+}
+
+// ErrSessionLimit is returned when a session cannot be created because the
+// maximum number of sessions is open
+var ErrSessionLimit = errors.New("too many sessions")
+
+// makeRoomLocked ensures a new session fits under the session limit, closing the
+// least recently used idle session when the policy allows it. It returns the
+// session it closed, if any.
+func (m *Manager) makeRoomLocked() (*SessionContext, error) {
+	if m.maxSessions <= 0 || len(m.sessions) < m.maxSessions {
+		return nil, nil
+	}
+	if m.limitPolicy == config.SessionLimitReject {
+		return nil, fmt.Errorf("%w: the limit of %d is reached; close an existing session or try again later", ErrSessionLimit, m.maxSessions)
+	}
+
+	// The least recently used session that is not busy is retired
+	sessions := make([]*SessionContext, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastAccessedAt().Before(sessions[j].LastAccessedAt()) })
+	var lru *SessionContext
+	for _, session := range sessions {
+		if session.retire() {
+			lru = session
+			break
+		}
+	}
+	if lru == nil {
+		return nil, fmt.Errorf("%w: all %d sessions are busy; try again later", ErrSessionLimit, m.maxSessions)
+	}
+
+	log.Printf("Session %s: least recently used of %d sessions, closing to make room", lru.SessionID, len(m.sessions))
+	delete(m.sessions, lru.SessionID)
+	if err := m.closeSession(lru); err != nil {
+		log.Printf("Session %s: %v", lru.SessionID, err)
+	}
+	return lru, nil
+}
+
+// Config returns the configuration the manager creates sessions from
+func (m *Manager) Config() *config.Config {
+	return m.config.Load()
+}
+
+// setLimiters creates the limiters shared by all sessions. Work already holding
+// a slot of the limiters they replace keeps it.
+func (m *Manager) setLimiters(limits config.ConcurrencyConfig) {
+	queueTimeout := time.Duration(limits.QueueTimeout) * time.Second
+	m.executions.Store(limiter.New("executions", limits.MaxExecutions, limits.MaxQueue, queueTimeout))
+	m.bundles.Store(limiter.New("bundler runs", limits.MaxBundles, limits.MaxQueue, queueTimeout))
+}
+
+// SetSandboxPool sets the warm process pool executions are dispatched to
+func (m *Manager) SetSandboxPool(pool *sandbox.Pool) {
+	m.pool = pool
+}
 
-	return session, nil
+// SandboxPool returns the warm process pool, or nil when pooling is disabled
+func (m *Manager) SandboxPool() *sandbox.Pool {
+	return m.pool
+}
+
+// SetAuditTrail sets the trail every execution is recorded to
+func (m *Manager) SetAuditTrail(trail *audit.Trail) {
+	m.audit = trail
+}
+
+// AuditTrail returns the execution audit trail, or nil when auditing is disabled
+func (m *Manager) AuditTrail() *audit.Trail {
+	return m.audit
+}
+
+// ExecutionLimiter bounds simultaneous executions across all sessions
+func (m *Manager) ExecutionLimiter() *limiter.Limiter {
+	return m.executions.Load()
+}
+
+// BundleLimiter bounds simultaneous transform, bundle and type-check runs
+func (m *Manager) BundleLimiter() *limiter.Limiter {
+	return m.bundles.Load()
 }
 
 // GetSession retrieves an existing session
@@ -112,42 +349,197 @@ func (m *Manager) GetSession(sessionID string) *SessionContext {
 // DeleteSession removes a session and cleans up its resources
 func (m *Manager) DeleteSession(sessionID string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	session, exists := m.sessions[sessionID]
 	if !exists {
+		m.mu.Unlock()
 		return fmt.Errorf("session %q not found", sessionID)
 	}
 
-	// Close all client connections
-	if err := session.ClientHub.Close(); err != nil {
+	if err := m.closeSession(session); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+
+	delete(m.sessions, sessionID)
+	m.mu.Unlock()
+
+	m.runHooks(hookDeleted, session)
+	return nil
+}
+
+// closeSession stops a session's jobs and runtimes, closes its client connections
+// and removes its bundle directory
+func (m *Manager) closeSession(session *SessionContext) error {
+	session.CancelJobs()
+	session.ResetPersistent()
+	if m.pool != nil {
+		m.pool.ReleaseSession(session.SessionID)
+	}
+
+	// Close all client connections, unless a fork still uses them
+	if err := session.releaseHub(); err != nil {
 		return fmt.Errorf("failed to close client hub: %w", err)
 	}
 
 	// Clean up bundle directory
 	if session.BundleDir != "" {
+		bundler.ReleaseSession(session.BundleDir)
 		if err := os.RemoveAll(session.BundleDir); err != nil {
 			log.Printf("Warning: failed to clean up bundle dir %s: %v", session.BundleDir, err)
 		}
 	}
-
-	delete(m.sessions, sessionID)
 	return nil
 }
 
-// CloseAll closes all sessions
+// reap closes expired sessions, prunes old execution outputs and removes orphaned
+// temp directories until CloseAll is called
+func (m *Manager) reap() {
+	defer m.wg.Done()
+
+	timer := time.NewTimer(m.reapInterval())
+	defer timer.Stop()
+
+	// Orphans are looked for less often, since that scans the temp directory
+	lastSweep := time.Now()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-timer.C:
+			// The interval follows the limits, which Reload may change
+			timer.Reset(m.reapInterval())
+			m.expire()
+			if m.artifactRetention > 0 {
+				m.pruneArtifacts()
+			}
+			if m.orphanAge > 0 && time.Since(lastSweep) >= m.orphanAge/2 {
+				if removed := m.CollectOrphans(m.orphanAge); removed > 0 {
+					log.Printf("Removed %d orphaned temp director(ies)", removed)
+				}
+				lastSweep = time.Now()
+			}
+		}
+	}
+}
+
+// reapInterval is how often the reaper runs: often enough to close sessions and
+// prune outputs within half their limit, and at least once a minute
+func (m *Manager) reapInterval() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	interval := time.Minute
+	for _, ttl := range []time.Duration{m.idleTTL, m.maxAge, m.artifactRetention} {
+		if ttl > 0 && ttl/2 < interval {
+			interval = max(ttl/2, time.Second)
+		}
+	}
+	return interval
+}
+
+// expire closes the sessions idle longer than the idle TTL or older than the
+// maximum age. Busy sessions are left for a later pass.
+func (m *Manager) expire() {
+	type expiry struct {
+		session *SessionContext
+		reason  EvictionReason
+	}
+	var expired []expiry
+	m.mu.Lock()
+	for sessionID, session := range m.sessions {
+		var reason EvictionReason
+		var detail string
+		switch {
+		case m.maxAge > 0 && session.Age() > m.maxAge:
+			reason, detail = EvictedMaxAge, fmt.Sprintf("reached the maximum age of %s", m.maxAge)
+		case m.idleTTL > 0 && session.IdleDuration() > m.idleTTL:
+			reason, detail = EvictedIdle, fmt.Sprintf("idle for %s", session.IdleDuration().Round(time.Second))
+		}
+		if reason == "" || !session.retire() {
+			continue
+		}
+		log.Printf("Session %s: %s, closing", sessionID, detail)
+		delete(m.sessions, sessionID)
+		expired = append(expired, expiry{session, reason})
+	}
+	m.mu.Unlock()
+
+	// Closing client connections can be slow, so it happens outside the lock
+	for _, e := range expired {
+		if err := m.closeSession(e.session); err != nil {
+			log.Printf("Session %s: %v", e.session.SessionID, err)
+		}
+		m.runEvictedHooks(e.session, e.reason)
+	}
+}
+
+// ErrShuttingDown is returned for new sessions and executions once Shutdown was called
+var ErrShuttingDown = errors.New("the server is shutting down")
+
+// Draining reports whether Shutdown was called. Existing sessions are still
+// served, so clients can collect the results of their running jobs, but no new
+// executions should be started.
+func (m *Manager) Draining() bool {
+	return m.draining.Load()
+}
+
+// Shutdown stops accepting new sessions and executions, waits until the running
+// executions and background jobs finish or ctx is done, and then closes every
+// session with CloseAll
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.draining.Store(true)
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		busy := m.busySessions()
+		if busy == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("Shutdown: %d session(s) still busy, closing them anyway", busy)
+			return m.CloseAll()
+		case <-ticker.C:
+		}
+	}
+	return m.CloseAll()
+}
+
+// busySessions counts the sessions with requests or background jobs in progress
+func (m *Manager) busySessions() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	busy := 0
+	for _, session := range m.sessions {
+		if session.Busy() {
+			busy++
+		}
+	}
+	return busy
+}
+
+// CloseAll stops the reaper and the warm pool and closes all sessions, removing their bundle directories.
 func (m *Manager) CloseAll() error {
+	m.stop.Do(func() { close(m.done) })
+	m.wg.Wait()
+	m.closeWarm()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	var errs []error
 	for sessionID, session := range m.sessions {
-		if err := session.ClientHub.Close(); err != nil {
+		session.CancelJobs()
+		session.ResetPersistent()
+		if err := session.releaseHub(); err != nil {
 			errs = append(errs, fmt.Errorf("session %q: %w", sessionID, err))
 		}
 
 		// Clean up bundle directory
 		if session.BundleDir != "" {
+			bundler.ReleaseSession(session.BundleDir)
 			if err := os.RemoveAll(session.BundleDir); err != nil {
 				log.Printf("Warning: failed to clean up bundle dir %s: %v", session.BundleDir, err)
 			}
@@ -155,6 +547,9 @@ func (m *Manager) CloseAll() error {
 	}
 
 	m.sessions = make(map[string]*SessionContext)
+	if m.libs != nil {
+		m.libs.close()
+	}
 
 	if len(errs) > 0 {
 		return fmt.Errorf("errors closing sessions: %v", errs)
@@ -163,67 +558,61 @@ func (m *Manager) CloseAll() error {
 	return nil
 }
 
-// initializeSessionBundleDir creates the bundle directory and writes library files
-func (m *Manager) initializeSessionBundleDir(ctx context.Context, session *SessionContext) error {
+// initializeSessionBundleDir creates the bundle directory and writes library files.
+// When reuse names an earlier run's bundle directory, its libraries are regenerated
+// in place and everything else in it is kept; it is left in place on failure.
+func (m *Manager) initializeSessionBundleDir(ctx context.Context, session *SessionContext, reuse string) error {
 	// Create persistent bundle directory for this session
-	bundleDir, err := os.MkdirTemp("", fmt.Sprintf("codebraid-%s-", session.SessionID))
-	if err != nil {
-		return fmt.Errorf("failed to create bundle dir: %w", err)
+	bundleDir := reuse
+	if bundleDir == "" {
+		dir, err := os.MkdirTemp("", fmt.Sprintf("codebraid-%s-", session.SessionID))
+		if err != nil {
+			return fmt.Errorf("failed to create bundle dir: %w", err)
+		}
+		bundleDir = dir
+	}
+	cleanup := func() {
+		if reuse == "" {
+			os.RemoveAll(bundleDir)
+		}
 	}
 
-	// Create servers directory
+	// Create servers directory, replacing the libraries of the earlier run
 	serversDir := filepath.Join(bundleDir, "servers")
+	if reuse != "" {
+		if err := os.RemoveAll(serversDir); err != nil {
+			return fmt.Errorf("failed to remove old server libraries: %w", err)
+		}
+		if err := os.RemoveAll(filepath.Join(bundleDir, sandbox.PythonLibDir)); err != nil {
+			return fmt.Errorf("failed to remove old Python libraries: %w", err)
+		}
+	}
 	if err := os.Mkdir(serversDir, 0755); err != nil {
-		os.RemoveAll(bundleDir)
+		cleanup()
 		return fmt.Errorf("failed to create servers dir: %w", err)
 	}
 
-	// Get all tools from connected MCP servers and generate TypeScript libraries
-	allTools := session.ClientHub.Tools()
-	generator := codegen.NewTypeScriptGenerator()
+	// Get all tools the session may call and generate per-function
+	// TypeScript library files for each server concurrently
+	allTools := session.Tools()
+	if err := m.writeServerLibs(serversDir, allTools); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to generate server libraries: %w", err)
+	}
 
-	// Generate and write per-function library files for each server
 	serverNames := make([]string, 0, len(allTools))
-	for serverName, tools := range allTools {
-		// Create server directory
-		serverDir := filepath.Join(serversDir, serverName)
-		if err := os.Mkdir(serverDir, 0755); err != nil {
-			os.RemoveAll(bundleDir)
-			return fmt.Errorf("failed to create server dir %s: %w", serverName, err)
-		}
-
-		// Generate a file for each tool/function
-		for _, tool := range tools {
-			functionName := toCamelCase(tool.Name)
-			functionContent, err := generator.GenerateFunctionFile(serverName, tool)
-			if err != nil {
-				os.RemoveAll(bundleDir)
-				return fmt.Errorf("failed to generate function %s for %s: %w", functionName, serverName, err)
-			}
-
-			functionPath := filepath.Join(serverDir, fmt.Sprintf("%s.ts", functionName))
-			if err := os.WriteFile(functionPath, []byte(functionContent), 0644); err != nil {
-				os.RemoveAll(bundleDir)
-				return fmt.Errorf("failed to write function %s for %s: %w", functionName, serverName, err)
-			}
-		}
-
-		// Generate server index.ts
-		indexContent := generator.GenerateServerIndexFile(serverName, tools)
-		indexPath := filepath.Join(serverDir, "index.ts")
-		if err := os.WriteFile(indexPath, []byte(indexContent), 0644); err != nil {
-			os.RemoveAll(bundleDir)
-			return fmt.Errorf("failed to write index.ts for %s: %w", serverName, err)
-		}
-
+	for serverName := range allTools {
 		serverNames = append(serverNames, serverName)
 	}
+	sort.Strings(serverNames)
+
+	generator := codegen.NewTypeScriptGenerator()
 
 	// Generate top-level index.ts
 	topIndexContent := generator.GenerateIndexFile(serverNames)
 	topIndexPath := filepath.Join(serversDir, "index.ts")
 	if err := os.WriteFile(topIndexPath, []byte(topIndexContent), 0644); err != nil {
-		os.RemoveAll(bundleDir)
+		cleanup()
 		return fmt.Errorf("failed to write top-level index.ts: %w", err)
 	}
 
@@ -231,16 +620,35 @@ func (m *Manager) initializeSessionBundleDir(ctx context.Context, session *Sessi
 	mcpTypesContent := generator.GenerateMCPTypesFile()
 	mcpTypesPath := filepath.Join(serversDir, "mcp-types.ts")
 	if err := os.WriteFile(mcpTypesPath, []byte(mcpTypesContent), 0644); err != nil {
-		os.RemoveAll(bundleDir)
+		cleanup()
 		return fmt.Errorf("failed to write mcp-types.ts: %w", err)
 	}
 
-	// Write rspack config
-	rspackConfigPath := filepath.Join(bundleDir, "rspack.config.ts")
-	rspackConfig := bundler.GetEmbeddedConfig()
-	if err := os.WriteFile(rspackConfigPath, []byte(rspackConfig), 0644); err != nil {
-		os.RemoveAll(bundleDir)
-		return fmt.Errorf("failed to write rspack config: %w", err)
+	if err := bundler.WriteLibManifest(bundleDir); err != nil {
+		cleanup()
+		return err
+	}
+
+	// A user-supplied rspack config is rendered for the session now, so a broken
+	// template fails here instead of on every bundle
+	if m.Config().GetBundlerRspackConfig() != "" {
+		if err := bundler.CheckRspackConfig(bundleDir); err != nil {
+			cleanup()
+			return err
+		}
+	}
+
+	// Python scripts import the same tools from a "servers" package
+	if m.Config().GetSandboxPythonEnabled() {
+		if err := m.writePythonLibs(filepath.Join(bundleDir, sandbox.PythonLibDir), allTools); err != nil {
+			cleanup()
+			return fmt.Errorf("failed to generate Python libraries: %w", err)
+		}
+	}
+
+	if err := writeOwner(bundleDir); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to mark bundle dir: %w", err)
 	}
 
 	// Update session
@@ -249,14 +657,15 @@ func (m *Manager) initializeSessionBundleDir(ctx context.Context, session *Sessi
 	return nil
 }
 
-// regenerateLibForServer regenerates TypeScript library for a specific server
+// regenerateLibForServer regenerates TypeScript library for a specific server,
+// and its Python module when python is set.
 // This is called automatically when the MCP server notifies of tool changes
-func regenerateLibForServer(session *SessionContext, serverName string) error {
+func (m *Manager) regenerateLibForServer(session *SessionContext, serverName string, python bool) error {
 	session.mu.Lock()
 	defer session.mu.Unlock()
 
 	// Get tools from the server (already refreshed by ClientHub notification handler)
-	tools, ok := session.ClientHub.ServerTools(serverName)
+	tools, ok := session.ServerTools(serverName)
 	if !ok {
 		return fmt.Errorf("server %q not found", serverName)
 	}
@@ -267,34 +676,46 @@ func regenerateLibForServer(session *SessionContext, serverName string) error {
 		return fmt.Errorf("failed to remove old server dir: %w", err)
 	}
 
-	// Create fresh server directory
-	if err := os.Mkdir(serverDir, 0755); err != nil {
-		return fmt.Errorf("failed to create server dir: %w", err)
+	// Generate TypeScript files for this server into a fresh directory
+	grouped := map[string][]*mcp.Tool{serverName: tools}
+	if err := m.writeServerLibs(filepath.Dir(serverDir), grouped); err != nil {
+		return err
+	}
+	if err := bundler.WriteLibManifest(session.BundleDir); err != nil {
+		return err
 	}
 
-	// Generate TypeScript files for this server
-	generator := codegen.NewTypeScriptGenerator()
-
-	// Generate a file for each tool/function
-	for _, tool := range tools {
-		functionName := toCamelCase(tool.Name)
-		functionContent, err := generator.GenerateFunctionFile(serverName, tool)
-		if err != nil {
-			return fmt.Errorf("failed to generate function %s: %w", functionName, err)
+	if python {
+		packageDir := filepath.Join(session.BundleDir, sandbox.PythonLibDir, "servers")
+		if m.libs != nil {
+			return m.libs.writePythonModules(packageDir, grouped)
 		}
-
-		functionPath := filepath.Join(serverDir, fmt.Sprintf("%s.ts", functionName))
-		if err := os.WriteFile(functionPath, []byte(functionContent), 0644); err != nil {
-			return fmt.Errorf("failed to write function %s: %w", functionName, err)
+		if err := codegen.NewPythonGenerator().WriteServerModule(packageDir, serverName, tools); err != nil {
+			return err
 		}
 	}
 
-	// Generate server index.ts
-	indexContent := generator.GenerateServerIndexFile(serverName, tools)
-	indexPath := filepath.Join(serverDir, "index.ts")
-	if err := os.WriteFile(indexPath, []byte(indexContent), 0644); err != nil {
-		return fmt.Errorf("failed to write index.ts: %w", err)
+	return nil
+}
+
+// writeServerLibs writes the TypeScript library of every server under serversDir,
+// linking them from the lib store when libraries are shared
+func (m *Manager) writeServerLibs(serversDir string, grouped map[string][]*mcp.Tool) error {
+	if m.libs != nil {
+		return m.libs.writeServerLibs(serversDir, grouped)
 	}
+	return codegen.GenerateServerLibs(serversDir, grouped, codegen.LibOptions{})
+}
 
-	return nil
+// writePythonLibs writes the Python servers package under outputDir, linking the
+// server modules from the lib store when libraries are shared
+func (m *Manager) writePythonLibs(outputDir string, grouped map[string][]*mcp.Tool) error {
+	if m.libs == nil {
+		return codegen.WritePythonLibs(outputDir, grouped)
+	}
+	packageDir := filepath.Join(outputDir, "servers")
+	if err := m.libs.writePythonModules(packageDir, grouped); err != nil {
+		return err
+	}
+	return codegen.WritePythonPackageInit(packageDir, grouped)
 }
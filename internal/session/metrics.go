@@ -0,0 +1,60 @@
+package session
+
+import "time"
+
+// SessionMetrics are a session's usage counters, for per-tenant reporting
+type SessionMetrics struct {
+	Executions       int64 `json:"executions"`
+	FailedExecutions int64 `json:"failedExecutions"` // Failed to run, or returned an error result
+	ToolCalls        int64 `json:"toolCalls"`        // Calls to downstream MCP servers; dry runs make none
+	OutputBytes      int64 `json:"outputBytes"`      // Results and console output returned to the client
+	BundleMs         int64 `json:"bundleMs"`         // Time spent bundling
+}
+
+// RecordExecution counts an execution of the session, whether it failed, and the
+// bytes of its result
+func (s *SessionContext) RecordExecution(failed bool, resultBytes int) {
+	s.executed.Add(1)
+	if failed {
+		s.failed.Add(1)
+	}
+	s.outputs.Add(int64(resultBytes))
+}
+
+// RecordToolCall counts a downstream tool call made by an execution
+func (s *SessionContext) RecordToolCall() {
+	s.toolCalls.Add(1)
+}
+
+// RecordOutput counts console output streamed to the client
+func (s *SessionContext) RecordOutput(bytes int) {
+	s.outputs.Add(int64(bytes))
+}
+
+// RecordBundle adds time spent bundling code for the session
+func (s *SessionContext) RecordBundle(d time.Duration) {
+	s.bundleTime.Add(int64(d))
+}
+
+// Metrics returns the session's usage counters
+func (s *SessionContext) Metrics() SessionMetrics {
+	return SessionMetrics{
+		Executions:       s.executed.Load(),
+		FailedExecutions: s.failed.Load(),
+		ToolCalls:        s.toolCalls.Load(),
+		OutputBytes:      s.outputs.Load(),
+		BundleMs:         time.Duration(s.bundleTime.Load()).Milliseconds(),
+	}
+}
+
+// Metrics returns the usage counters of the open sessions by session ID
+func (m *Manager) Metrics() map[string]SessionMetrics {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	metrics := make(map[string]SessionMetrics, len(m.sessions))
+	for id, session := range m.sessions {
+		metrics[id] = session.Metrics()
+	}
+	return metrics
+}
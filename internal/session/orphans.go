@@ -0,0 +1,135 @@
+package session
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+)
+
+// ownerFile in a bundle directory holds the ID of the server process using it
+const ownerFile = ".owner"
+
+// bundleDirPrefix starts the name of every session bundle directory
+const bundleDirPrefix = "codebraid-"
+
+// writeOwner marks a bundle directory as used by this process
+func writeOwner(bundleDir string) error {
+	return os.WriteFile(filepath.Join(bundleDir, ownerFile), []byte(strconv.Itoa(os.Getpid())), 0644)
+}
+
+// CollectOrphans removes the bundle and scratch directories that crashed or killed
+// server processes left in the temp directory and the sandbox scratch directory.
+// A directory is removed when it was last modified more than minAge ago and no
+// running process owns it: bundle directories of open and warm sessions
+// are kept, as are directories of other running servers. It returns how many
+// directories were removed.
+func (m *Manager) CollectOrphans(minAge time.Duration) int {
+	live := m.liveBundleDirs()
+	roots := []string{os.TempDir()}
+	if scratch := m.Config().GetSandboxPolicy().ScratchDir; scratch != "" {
+		roots = append(roots, scratch)
+	}
+
+	removed := 0
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			path := filepath.Join(root, name)
+			if !entry.IsDir() || seen[path] || live[path] || !isOurDir(path, name) {
+				continue
+			}
+			seen[path] = true
+			info, err := entry.Info()
+			if err != nil || time.Since(info.ModTime()) < minAge {
+				continue
+			}
+
+			// Directories of other running servers are theirs to clean up, and so
+			// are the scratch directories of this server's executions
+			if pid, known := dirOwner(path, name); known {
+				if pid != os.Getpid() && sandbox.ProcessAlive(pid) {
+					continue
+				}
+				if pid == os.Getpid() && strings.HasPrefix(name, sandbox.ScratchPrefix) {
+					continue
+				}
+			}
+			if err := os.RemoveAll(path); err != nil {
+				log.Printf("Warning: failed to remove orphaned directory %s: %v", path, err)
+				continue
+			}
+			removed++
+		}
+	}
+	if m.libs != nil {
+		removed += m.libs.prune(minAge)
+	}
+	return removed
+}
+
+// isOurDir reports whether a directory is a scratch or session bundle directory.
+// Other directories named like them, such as caches, are left alone.
+func isOurDir(path, name string) bool {
+	if strings.HasPrefix(name, sandbox.ScratchPrefix) {
+		return true
+	}
+	if !strings.HasPrefix(name, bundleDirPrefix) {
+		return false
+	}
+	for _, marker := range []string{ownerFile, "servers"} {
+		if _, err := os.Lstat(filepath.Join(path, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// dirOwner returns the ID of the process that created a scratch or bundle
+// directory, if it is recorded
+func dirOwner(path, name string) (int, bool) {
+	if rest, ok := strings.CutPrefix(name, sandbox.ScratchPrefix); ok {
+		pid, _, _ := strings.Cut(rest, "-")
+		n, err := strconv.Atoi(pid)
+		return n, err == nil
+	}
+	data, err := os.ReadFile(filepath.Join(path, ownerFile))
+	if err != nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return n, err == nil
+}
+
+// liveBundleDirs returns the bundle directories of open and warm sessions,
+// and the lib store
+func (m *Manager) liveBundleDirs() map[string]bool {
+	live := make(map[string]bool)
+	m.mu.RLock()
+	for _, session := range m.sessions {
+		live[session.BundleDir] = true
+	}
+	m.mu.RUnlock()
+
+	m.warmMu.Lock()
+	for _, session := range m.warm {
+		live[session.BundleDir] = true
+	}
+	m.warmMu.Unlock()
+
+	if m.libs != nil {
+		if dir := m.libs.path(); dir != "" {
+			live[dir] = true
+		}
+	}
+	return live
+}
@@ -0,0 +1,124 @@
+package session
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// QuotaError is returned when saving outputs or snapshots would take a session's
+// storage over its quota, even after its oldest outputs were removed
+type QuotaError struct {
+	Used  int64 // Bytes in the bundle directory and the persistent runtime's working directory
+	Need  int64 // Bytes that were to be saved
+	Limit int64 // The quota
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("session storage quota exceeded: saving %d KB would take the session over its %d MB quota (%d KB in use)",
+		e.Need>>10, e.Limit>>20, e.Used>>10)
+}
+
+// artifactDir is the outputs directory of one execution
+type artifactDir struct {
+	path    string
+	modTime time.Time
+}
+
+// artifactDirs lists the session's execution output directories, oldest first
+func (s *SessionContext) artifactDirs() []artifactDir {
+	root := filepath.Join(s.BundleDir, "outputs")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	dirs := make([]artifactDir, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		dirs = append(dirs, artifactDir{path: filepath.Join(root, entry.Name()), modTime: info.ModTime()})
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i].modTime.Before(dirs[j].modTime)
+	})
+	return dirs
+}
+
+// reserve makes room for need bytes in the bundle directory under the session's
+// quota, removing the oldest execution outputs if that is enough. The working
+// directory of the persistent runtime counts too, as it outlives runs. Must be
+// called with storageMu held.
+func (s *SessionContext) reserve(need int64) error {
+	if s.bundleQuota <= 0 {
+		return nil
+	}
+	used := dirSize(s.BundleDir)
+	if s.scratchDir != "" {
+		used += dirSize(s.scratchDir)
+	}
+	if used+need <= s.bundleQuota {
+		return nil
+	}
+
+	// Find how many of the oldest outputs have to go before removing any
+	dirs := s.artifactDirs()
+	free, drop := used, 0
+	for drop < len(dirs) && free+need > s.bundleQuota {
+		free -= dirSize(dirs[drop].path)
+		drop++
+	}
+	if free+need > s.bundleQuota {
+		return &QuotaError{Used: used, Need: need, Limit: s.bundleQuota}
+	}
+	for _, dir := range dirs[:drop] {
+		if err := os.RemoveAll(dir.path); err != nil {
+			return fmt.Errorf("failed to remove old outputs: %w", err)
+		}
+	}
+	log.Printf("Session %s: removed the outputs of %d old execution(s) to stay under the storage quota", s.SessionID, drop)
+	return nil
+}
+
+// PruneArtifacts removes the execution outputs older than retention and returns
+// how many executions' outputs were removed
+func (s *SessionContext) PruneArtifacts(retention time.Duration) int {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	removed := 0
+	for _, dir := range s.artifactDirs() {
+		if time.Since(dir.modTime) <= retention {
+			break
+		}
+		if err := os.RemoveAll(dir.path); err != nil {
+			log.Printf("Session %s: failed to remove old outputs %s: %v", s.SessionID, dir.path, err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// pruneArtifacts removes the outputs older than the artifact retention from every session
+func (m *Manager) pruneArtifacts() {
+	m.mu.RLock()
+	sessions := make([]*SessionContext, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	m.mu.RUnlock()
+
+	for _, session := range sessions {
+		if removed := session.PruneArtifacts(m.artifactRetention); removed > 0 {
+			log.Printf("Session %s: removed the outputs of %d execution(s) older than %s", session.SessionID, removed, m.artifactRetention)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package session
+
+import (
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yousuf/codebraid-mcp/internal/sandbox"
+)
+
+// ReadOnly reports whether the session may only call read-only tools
+func (s *SessionContext) ReadOnly() bool {
+	return s.readOnly
+}
+
+// Tools returns the tools the session may call, by server
+func (s *SessionContext) Tools() map[string][]*mcp.Tool {
+	tools := s.ClientHub.Tools()
+	if !s.readOnly {
+		return tools
+	}
+	// The hub's map is cached and shared with forks, so it is not filtered in place
+	readOnly := make(map[string][]*mcp.Tool, len(tools))
+	for server, list := range tools {
+		readOnly[server] = sandbox.ReadOnlyTools(list)
+	}
+	return readOnly
+}
+
+// ServerTools returns the tools of one server the session may call
+func (s *SessionContext) ServerTools(serverName string) ([]*mcp.Tool, bool) {
+	tools, ok := s.ClientHub.ServerTools(serverName)
+	if ok && s.readOnly {
+		tools = sandbox.ReadOnlyTools(tools)
+	}
+	return tools, ok
+}
@@ -0,0 +1,147 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// Reload applies a changed config without restarting. New sessions are created
+// from cfg, and session and concurrency limits apply at once. Open sessions are
+// connected to the servers cfg configures, disconnecting removed ones and
+// reconnecting changed ones, and the libraries of sessions whose servers changed
+// are regenerated. A session that chose its servers only gets those still
+// configured. Warm sessions are replaced. Settings read only at startup, see
+// config.RestartRequired, keep their old values.
+func (m *Manager) Reload(ctx context.Context, cfg *config.Config) error {
+	old := m.config.Swap(cfg)
+	if !reflect.DeepEqual(old.GetConcurrency(), cfg.GetConcurrency()) {
+		m.setLimiters(cfg.GetConcurrency())
+	}
+
+	limits := cfg.GetSessions()
+	m.mu.Lock()
+	m.maxSessions = limits.MaxSessions
+	m.limitPolicy = limits.LimitPolicy
+	m.allowOverrides = limits.AllowOverrides
+	m.idleTTL = time.Duration(limits.IdleTTL) * time.Second
+	m.maxAge = time.Duration(limits.MaxAge) * time.Second
+	m.mu.Unlock()
+
+	if reflect.DeepEqual(old.McpServers, cfg.McpServers) {
+		return nil
+	}
+
+	// Warm sessions are connected to the old servers, so the pool is refilled
+	m.closeWarm()
+	select {
+	case m.refill <- struct{}{}:
+	default:
+	}
+
+	var errs []error
+	for _, session := range m.hubOwners() {
+		if err := m.reconnect(ctx, cfg, session); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", session.SessionID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hubOwners returns one open session of every client hub; forks share their source's
+func (m *Manager) hubOwners() []*SessionContext {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[*sharedHub]bool)
+	var owners []*SessionContext
+	for _, session := range m.sessions {
+		if !seen[session.hub] {
+			seen[session.hub] = true
+			owners = append(owners, session)
+		}
+	}
+	return owners
+}
+
+// reconnect connects a session's hub to the servers it should use under cfg and
+// regenerates the libraries of the sessions sharing it when they changed
+func (m *Manager) reconnect(ctx context.Context, cfg *config.Config, session *SessionContext) error {
+	servers, err := sessionServers(cfg, session.servers, session.overrides)
+	if err != nil {
+		return err
+	}
+
+	hub := session.hub
+	hub.mu.Lock()
+	if len(hub.sessions) == 0 {
+		hub.mu.Unlock()
+		return nil
+	}
+	changed, err := session.ClientHub.Reconcile(ctx, servers)
+	members := append([]*SessionContext(nil), hub.sessions...)
+	hub.mu.Unlock()
+
+	if len(changed) == 0 {
+		return err
+	}
+	for _, member := range members {
+		log.Printf("Session %s: servers changed (%s), regenerating libraries...", member.SessionID, strings.Join(changed, ", "))
+		m.rebuildLibs(ctx, member)
+	}
+	return err
+}
+
+// sessionServers returns the servers a session connects to under cfg: every
+// configured server, or the ones it chose that are still configured, with its
+// overrides of those
+func sessionServers(cfg *config.Config, servers []string, overrides map[string]config.ServerOverride) (map[string]config.McpServerConfig, error) {
+	var kept []string
+	for _, name := range servers {
+		if _, ok := cfg.McpServers[name]; ok {
+			kept = append(kept, name)
+		}
+	}
+	if len(servers) > 0 && len(kept) == 0 {
+		return map[string]config.McpServerConfig{}, nil
+	}
+
+	keptOverrides := make(map[string]config.ServerOverride, len(overrides))
+	for name, override := range overrides {
+		if _, ok := cfg.McpServers[name]; ok && (len(kept) == 0 || contains(kept, name)) {
+			keptOverrides[name] = override
+		}
+	}
+	sessionCfg, err := cfg.ForSession(kept, keptOverrides)
+	if err != nil {
+		return nil, err
+	}
+	return sessionCfg.McpServers, nil
+}
+
+// contains reports whether names holds name
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildLibs regenerates all of a session's libraries in place
+func (m *Manager) rebuildLibs(ctx context.Context, session *SessionContext) {
+	session.mu.Lock()
+	err := m.initializeSessionBundleDir(ctx, session, session.BundleDir)
+	session.mu.Unlock()
+	if err != nil {
+		log.Printf("Session %s: failed to regenerate libraries: %v", session.SessionID, err)
+		return
+	}
+}
@@ -0,0 +1,96 @@
+package session
+
+import (
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ResultCacheLimits bounds the execution results a session caches
+type ResultCacheLimits struct {
+	TTL        time.Duration // How long a result is reused
+	MaxEntries int           // Results kept; the oldest is discarded first
+}
+
+// cachedResult is an execution result and when it was produced
+type cachedResult struct {
+	result   *mcp.CallToolResult
+	storedAt time.Time
+}
+
+// resultCache keeps the results of successful executions so identical reruns
+// are answered without running the code again
+type resultCache struct {
+	mu      sync.Mutex
+	limits  ResultCacheLimits
+	entries map[string]cachedResult
+	order   []string // Keys, oldest first
+}
+
+// CachedResult returns the result stored under key and how old it is.
+// It reports false when result caching is disabled or the entry has expired.
+func (s *SessionContext) CachedResult(key string) (*mcp.CallToolResult, time.Duration, bool) {
+	c := s.results
+	if c == nil {
+		return nil, 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+	age := time.Since(entry.storedAt)
+	if age > c.limits.TTL {
+		c.removeLocked(key)
+		return nil, 0, false
+	}
+	return entry.result, age, true
+}
+
+// CacheResult stores the result of an execution under key, discarding the
+// oldest entries past the limit. It does nothing when result caching is disabled.
+func (s *SessionContext) CacheResult(key string, result *mcp.CallToolResult) {
+	c := s.results
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		c.removeLocked(key)
+	}
+	c.entries[key] = cachedResult{result: result, storedAt: time.Now()}
+	c.order = append(c.order, key)
+	for len(c.order) > c.limits.MaxEntries {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// ResultCacheEnabled reports whether the session caches execution results
+func (s *SessionContext) ResultCacheEnabled() bool {
+	return s.results != nil
+}
+
+// removeLocked deletes one entry
+func (c *resultCache) removeLocked(key string) {
+	delete(c.entries, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// newResultCache returns a cache with the given limits, or nil when the TTL is zero
+func newResultCache(limits ResultCacheLimits) *resultCache {
+	if limits.TTL <= 0 || limits.MaxEntries <= 0 {
+		return nil
+	}
+	return &resultCache{limits: limits, entries: make(map[string]cachedResult)}
+}
@@ -0,0 +1,105 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// snapshotNamePattern restricts the names snapshots can be saved under
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// snapshotIDPattern matches the content hashes snapshots are stored under
+var snapshotIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ValidateSnapshotName checks that name can be used as a snapshot name
+func ValidateSnapshotName(name string) error {
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use up to 64 letters, digits, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+// SaveSnapshot stores a working directory snapshot under the session's bundle
+// directory, keyed by the sha256 of its content, and points name at it.
+// Identical snapshots are stored once. It returns the snapshot's ID.
+// Over the session's storage quota it fails with a *QuotaError.
+func (s *SessionContext) SaveSnapshot(name string, data []byte) (string, error) {
+	if err := ValidateSnapshotName(name); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:])
+
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	dir := filepath.Join(s.BundleDir, "snapshots")
+	if err := os.MkdirAll(filepath.Join(dir, "names"), 0755); err != nil {
+		return "", fmt.Errorf("failed to create snapshots dir: %w", err)
+	}
+	path := filepath.Join(dir, id+".tar.gz")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := s.reserve(int64(len(data))); err != nil {
+			return "", err
+		}
+		if err := writeFileAtomic(path, data); err != nil {
+			return "", fmt.Errorf("failed to save snapshot: %w", err)
+		}
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "names", name), []byte(id)); err != nil {
+		return "", fmt.Errorf("failed to name snapshot: %w", err)
+	}
+	return id, nil
+}
+
+// LoadSnapshot returns the snapshot saved under a name or ID in this session
+func (s *SessionContext) LoadSnapshot(ref string) ([]byte, error) {
+	dir := filepath.Join(s.BundleDir, "snapshots")
+	id := ref
+	if !snapshotIDPattern.MatchString(ref) {
+		if err := ValidateSnapshotName(ref); err != nil {
+			return nil, err
+		}
+		named, err := os.ReadFile(filepath.Join(dir, "names", ref))
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no snapshot named %q in this session", ref)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot name: %w", err)
+		}
+		id = strings.TrimSpace(string(named))
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, id+".tar.gz"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no snapshot %q in this session", ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,155 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yousuf/codebraid-mcp/internal/client"
+	"github.com/yousuf/codebraid-mcp/internal/config"
+)
+
+// warmRetryInterval is how long the warm pool waits after failing to create a session
+const warmRetryInterval = 30 * time.Second
+
+// StartWarmPool keeps the configured number of blank sessions ready, with their
+// servers connected and libraries generated, so a new client does not wait for
+// either. warmup, when set, runs on each blank session to warm its bundler; a
+// failure is logged and the session is kept. It does nothing when no warm
+// sessions are configured.
+func (m *Manager) StartWarmPool(warmup func(*SessionContext) error) {
+	if m.warmSize <= 0 {
+		return
+	}
+	m.warmup = warmup
+	m.wg.Add(1)
+	go m.keepWarm()
+}
+
+// keepWarm replenishes the warm pool until CloseAll is called
+func (m *Manager) keepWarm() {
+	defer m.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-m.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	created := 0
+	for {
+		var retry <-chan time.Time
+		for m.warmCount() < m.warmSize {
+			created++
+			cfg := m.Config()
+			session, err := m.newWarmSession(ctx, cfg, fmt.Sprintf("warm-%d", created))
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Warm session pool: %v", err)
+					retry = time.After(warmRetryInterval)
+				}
+				break
+			}
+
+			// A session connected while Reload replaced the config is discarded
+			m.warmMu.Lock()
+			stale := m.Config() != cfg
+			if !stale {
+				m.warm = append(m.warm, session)
+			}
+			m.warmMu.Unlock()
+			if stale {
+				if err := m.closeSession(session); err != nil {
+					log.Printf("Session %s: %v", session.SessionID, err)
+				}
+			}
+		}
+
+		select {
+		case <-m.done:
+			return
+		case <-m.refill:
+		case <-retry:
+		}
+	}
+}
+
+// newWarmSession creates a blank session connected to every server of cfg.
+// placeholder is what it is logged as until a client takes it.
+func (m *Manager) newWarmSession(ctx context.Context, cfg *config.Config, placeholder string) (*SessionContext, error) {
+	clientHub := client.NewMcpClientHub()
+	if err := clientHub.Connect(ctx, cfg); err != nil {
+		return nil, fmt.Errorf("failed to connect client hub: %w", err)
+	}
+
+	session := m.newSessionContext(placeholder, clientHub)
+	if err := m.initializeSessionBundleDir(ctx, session, ""); err != nil {
+		clientHub.Close()
+		return nil, fmt.Errorf("failed to initialize session bundle directory: %w", err)
+	}
+	m.watchTools(session, placeholder)
+
+	if m.warmup != nil {
+		if err := m.warmup(session); err != nil {
+			log.Printf("Session %s: failed to warm the bundler: %v", placeholder, err)
+		}
+	}
+	return session, nil
+}
+
+// warmCount returns the number of ready sessions in the warm pool
+func (m *Manager) warmCount() int {
+	m.warmMu.Lock()
+	defer m.warmMu.Unlock()
+	return len(m.warm)
+}
+
+// takeWarm removes a ready session from the warm pool, if there is one, and
+// signals the pool to replenish
+func (m *Manager) takeWarm() *SessionContext {
+	m.warmMu.Lock()
+	defer m.warmMu.Unlock()
+	if len(m.warm) == 0 {
+		return nil
+	}
+	session := m.warm[0]
+	m.warm = m.warm[1:]
+
+	select {
+	case m.refill <- struct{}{}:
+	default:
+	}
+	return session
+}
+
+// assignWarm gives a session taken from the warm pool its ID. Its age and idle
+// time start now. Must be called with m.mu held, before the session is opened.
+func (m *Manager) assignWarm(session *SessionContext, sessionID string) {
+	now := time.Now()
+	session.mu.Lock()
+	session.SessionID = sessionID
+	session.CreatedAt = now
+	session.lastAccessedAt = now
+	session.mu.Unlock()
+
+	m.watchTools(session, sessionID)
+}
+
+// closeWarm closes the sessions left in the warm pool
+func (m *Manager) closeWarm() {
+	m.warmMu.Lock()
+	warm := m.warm
+	m.warm = nil
+	m.warmMu.Unlock()
+
+	for _, session := range warm {
+		if err := m.closeSession(session); err != nil {
+			log.Printf("Session %s: %v", session.SessionID, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package sourcemap
+
+import (
+	"path"
+	"strings"
+
+	gosourcemap "github.com/go-sourcemap/sourcemap"
+)
+
+// Location identifies a position in the original sources
+type Location struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Source string `json:"source,omitempty"` // The original source line, when the map embeds sources
+}
+
+// Result is a stack trace mapped back to the original sources
+type Result struct {
+	Stack    string    // Mapped stack trace, one frame per line
+	Location *Location // Innermost frame in user code, nil if nothing mapped
+}
+
+// generatedLibPrefix is where generated server libraries live in the bundle
+const generatedLibPrefix = "servers/"
+
+// Resolve maps a stack trace back to the original sources described by sourceMap.
+// Source paths are normalized (e.g., "webpack:///./index.ts" becomes "index.ts") and
+// unmapped frames from runtime internals are kept unchanged. The reported Location
+// prefers user code over generated server libraries, since that is the code to fix.
+func Resolve(sourceMap, stack string) (*Result, error) {
+	if strings.TrimSpace(sourceMap) == "" {
+		return &Result{Stack: stack}, nil
+	}
+
+	consumer, err := gosourcemap.Parse("", []byte(sourceMap))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := newStackParser()
+	frames := mapStackFrames(consumer, parser.ParseStackTrace(stack))
+
+	var userLoc, libLoc *Location
+	for i := range frames {
+		frame := &frames[i]
+		if !frame.Mapped {
+			continue
+		}
+
+		rawFile := *frame.OriginalFileName
+		cleaned := cleanSourcePath(rawFile)
+		frame.OriginalFileName = &cleaned
+
+		loc := &Location{
+			File:   cleaned,
+			Line:   *frame.OriginalLineNumber,
+			Column: *frame.OriginalColumnNumber,
+			Source: sourceLine(consumer.SourceContent(rawFile), *frame.OriginalLineNumber),
+		}
+		if strings.HasPrefix(cleaned, generatedLibPrefix) {
+			if libLoc == nil {
+				libLoc = loc
+			}
+		} else if userLoc == nil {
+			userLoc = loc
+		}
+	}
+
+	result := &Result{
+		Stack:    newFormatter().FormatStackTrace(frames),
+		Location: userLoc,
+	}
+	if result.Location == nil {
+		result.Location = libLoc
+	}
+	return result, nil
+}
+
+// cleanSourcePath strips bundler URL schemes and relative prefixes from a source path
+func cleanSourcePath(p string) string {
+	if i := strings.Index(p, "://"); i >= 0 {
+		p = p[i+3:]
+	}
+	p = path.Clean(strings.TrimLeft(p, "/"))
+	for strings.HasPrefix(p, "../") {
+		p = p[3:]
+	}
+	return p
+}
+
+// sourceLine returns the 1-indexed line of content, trimmed
+func sourceLine(content string, line int) string {
+	if content == "" || line < 1 {
+		return ""
+	}
+	lines := strings.Split(content, "\n")
+	if line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}